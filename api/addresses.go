@@ -0,0 +1,118 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/common"
+	"blockbook/db"
+	"math/big"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// GetAddresses returns balances and, depending on option and utxo, txids and unspent outputs of multiple addresses
+// the balances of all addresses are read from the db in one batch; the details higher than txids are not supported
+// and the errors of individual addresses are returned in the Error field of the items
+func (w *Worker) GetAddresses(addresses []string, txsOnPage int, option AccountDetails, filter *AddressFilter, utxo bool) (items []AddressesItem, err error) {
+	w, span := w.startSpan("api.GetAddresses")
+	defer func() { common.EndSpan(span, err) }()
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	start := time.Now()
+	rv := make([]AddressesItem, len(addresses))
+	addrDescs := make([]bchain.AddressDescriptor, 0, len(addresses))
+	indexes := make([]int, 0, len(addresses))
+	for i, a := range addresses {
+		ad, address, err := w.getAddrDescAndNormalizeAddress(a)
+		if err != nil {
+			rv[i].AddrStr = a
+			rv[i].Error = err.Error()
+			continue
+		}
+		rv[i].AddrStr = address
+		addrDescs = append(addrDescs, ad)
+		indexes = append(indexes, i)
+	}
+	var detail db.AddressBalanceDetail = db.AddressBalanceDetailNoUTXO
+	if utxo {
+		detail = db.AddressBalanceDetailUTXO
+	}
+	bas, err := w.db.GetAddrDescBalances(addrDescs, detail)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetAddrDescBalances")
+	}
+	for i, ad := range addrDescs {
+		item := &rv[indexes[i]]
+		item.Address, err = w.addressFromBalance(ad, item.AddrStr, bas[i], txsOnPage, option, filter)
+		if err != nil {
+			item.Error = err.Error()
+			continue
+		}
+		if utxo {
+			// ba is nil if the address is only in mempool, the utxos must not be read again in that case
+			ba := bas[i]
+			if ba == nil {
+				ba = &db.AddrBalance{}
+			}
+			item.Utxos, err = w.getAddrDescUtxo(ad, ba, filter.OnlyConfirmed, false)
+			if err != nil {
+				item.Error = err.Error()
+			}
+		}
+	}
+	glog.Info("GetAddresses ", len(addresses), " addresses, finished in ", time.Since(start))
+	return rv, nil
+}
+
+// addressFromBalance returns address with given balance, unconfirmed balance and if requested, the first page of txids
+func (w *Worker) addressFromBalance(addrDesc bchain.AddressDescriptor, address string, ba *db.AddrBalance, txsOnPage int, option AccountDetails, filter *AddressFilter) (*Address, error) {
+	var (
+		uBalSat        big.Int
+		unconfirmedTxs int
+		txids          []string
+		pg             Paging
+		nextCursor     string
+	)
+	// process mempool, only if toHeight is not specified
+	if filter.ToHeight == 0 && !filter.OnlyConfirmed {
+		txm, err := w.getAddrDescMempoolTxs(addrDesc, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range txm {
+			unconfirmedTxs++
+			uBalSat.Add(&uBalSat, tx.getAddrVoutValue(addrDesc))
+			uBalSat.Sub(&uBalSat, tx.getAddrVinValue(addrDesc))
+			if option >= AccountDetailsTxidHistory {
+				txids = append(txids, tx.Txid)
+			}
+		}
+	}
+	if ba == nil {
+		ba = &db.AddrBalance{}
+	} else if option >= AccountDetailsTxidHistory {
+		txc, next, err := w.getAddressTxidsFromCursor(addrDesc, filter, nil, txsOnPage)
+		if err != nil {
+			return nil, errors.Annotatef(err, "getAddressTxidsFromCursor %v", addrDesc)
+		}
+		txids = append(txids, txc...)
+		pg.ItemsOnPage = txsOnPage
+		if next != nil {
+			nextCursor = next.String()
+		}
+	}
+	return &Address{
+		Paging:                pg,
+		NextCursor:            nextCursor,
+		AddrStr:               address,
+		BalanceSat:            (*Amount)(&ba.BalanceSat),
+		TotalReceivedSat:      (*Amount)(ba.ReceivedSat()),
+		TotalSentSat:          (*Amount)(&ba.SentSat),
+		Txs:                   int(ba.Txs),
+		UnconfirmedBalanceSat: (*Amount)(&uBalSat),
+		UnconfirmedTxs:        unconfirmedTxs,
+		Txids:                 txids,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// maxBlockFilters is the maximum number of filters returned by one request
+const maxBlockFilters = 1000
+
+// GetBlockFilters returns BIP158 basic filters of the blocks in the range from-to
+// negative to means the best block, negative from means the same block as to
+func (w *Worker) GetBlockFilters(from, to int) (*BlockFilters, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	start := time.Now()
+	bestheight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	if to < 0 || to > int(bestheight) {
+		to = int(bestheight)
+	}
+	if from < 0 {
+		from = to
+	}
+	if from > to {
+		return nil, NewAPIError(fmt.Sprintf("Invalid range %v-%v", from, to), true)
+	}
+	if to-from >= maxBlockFilters {
+		return nil, NewAPIError(fmt.Sprintf("Range %v-%v is too large, maximum is %v blocks", from, to, maxBlockFilters), true)
+	}
+	r := &BlockFilters{BlockFilters: make([]BlockFilter, 0, to-from+1)}
+	for h := from; h <= to; h++ {
+		bf, err := w.db.GetBlockFilter(uint32(h))
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetBlockFilter %v", h)
+		}
+		// the filters are missing only for the blocks below the first block of the index
+		if bf == nil {
+			continue
+		}
+		hash, err := w.db.GetBlockHash(uint32(h))
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetBlockHash %v", h)
+		}
+		r.BlockFilters = append(r.BlockFilters, BlockFilter{
+			Height:    bf.Height,
+			BlockHash: hash,
+			Filter:    hex.EncodeToString(bf.Filter),
+			// the header is returned in the displayed byte order of hashes
+			Header: hex.EncodeToString(reverseHash(bf.Header)),
+		})
+	}
+	glog.Info("GetBlockFilters ", from, "-", to, " finished in ", time.Since(start))
+	return r, nil
+}
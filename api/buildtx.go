@@ -0,0 +1,246 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/btc"
+	"blockbook/common"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+	"github.com/martinboehm/btcd/wire"
+)
+
+// BuildTx selects the utxos of the xpub or address in req.Descriptor to pay the outputs and returns the unsigned transaction in the psbt format
+// the change is sent to the first unused change address of the xpub (found by the gap scan) or back to the address
+func (w *Worker) BuildTx(req *BuildTxRequest) (res *BuildTxResult, err error) {
+	w, span := w.startSpan("api.BuildTx")
+	defer func() { common.EndSpan(span, err) }()
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	start := time.Now()
+	if len(req.Outputs) == 0 {
+		return nil, NewAPIError("Missing outputs", true)
+	}
+	if req.FeeRate <= 0 {
+		return nil, NewAPIError("Missing or invalid feeRate", true)
+	}
+	var fingerprint []byte
+	if req.Fingerprint != "" {
+		var err error
+		fingerprint, err = hex.DecodeString(req.Fingerprint)
+		if err != nil || len(fingerprint) != 4 {
+			return nil, NewAPIError(fmt.Sprintf("Invalid fingerprint '%v'", req.Fingerprint), true)
+		}
+	}
+	msgTx := wire.NewMsgTx(2)
+	p := selectionParams{baseVsize: txOverheadVsize, feeRate: req.FeeRate}
+	for i := range req.Outputs {
+		o := &req.Outputs[i]
+		addrDesc, err := w.chainParser.GetAddrDescFromAddress(o.Address)
+		if err != nil {
+			return nil, NewAPIError(fmt.Sprintf("Invalid address '%v', %v", o.Address, err), true)
+		}
+		if o.AmountSat == nil || !(*big.Int)(o.AmountSat).IsInt64() || (*big.Int)(o.AmountSat).Int64() < dustThreshold {
+			return nil, NewAPIError(fmt.Sprintf("Invalid amount of output %d, the amount must be at least %d", i, dustThreshold), true)
+		}
+		v := (*big.Int)(o.AmountSat).Int64()
+		msgTx.AddTxOut(wire.NewTxOut(v, addrDesc))
+		p.target += v
+		p.baseVsize += outputVsize(addrDesc)
+	}
+	var (
+		xpub          string
+		changeDesc    bchain.AddressDescriptor
+		changeAddress string
+		changePath    string
+	)
+	var utxos Utxos
+	// fall back to the address only if the descriptor is not an xpub, not on the errors of the xpub scan
+	if _, errXpub := w.chainParser.DerivationBasePath(req.Descriptor); errXpub == nil {
+		utxos, err = w.GetXpubUtxo(req.Descriptor, req.OnlyConfirmed, req.Gap)
+		if err != nil {
+			return nil, err
+		}
+		xpub = req.Descriptor
+		data, _, err := w.getXpubData(xpub, 0, 1, AccountDetailsBasic, &AddressFilter{Vout: AddressFilterVoutOff, OnlyConfirmed: req.OnlyConfirmed}, req.Gap)
+		if err != nil {
+			return nil, err
+		}
+		// there is always an unused address at the end of the change addresses, the gap scan guarantees it
+		for i := range data.changeAddresses {
+			ad := &data.changeAddresses[i]
+			if ad.balance == nil {
+				t := w.tokenFromXpubAddress(data, ad, 1, i, AccountDetailsBasic)
+				changeDesc, changeAddress, changePath = ad.addrDesc, t.Name, t.Path
+				break
+			}
+		}
+	} else {
+		utxos, err = w.GetAddressUtxo(req.Descriptor, req.OnlyConfirmed)
+		if err != nil {
+			return nil, err
+		}
+		changeDesc, changeAddress, err = w.getAddrDescAndNormalizeAddress(req.Descriptor)
+		if err != nil {
+			return nil, err
+		}
+		for i := range utxos {
+			utxos[i].Address = changeAddress
+		}
+	}
+	if changeDesc == nil {
+		return nil, errors.Errorf("No change address found for %v", req.Descriptor)
+	}
+	p.changeVsize = outputVsize(changeDesc)
+	p.changeSpendVsize = inputVsize(changeDesc)
+	addrDescs := make([]bchain.AddressDescriptor, len(utxos))
+	candidates := make([]selectionUtxo, 0, len(utxos))
+	minConfirmations := w.chainParser.MinimumCoinbaseConfirmations()
+	for i := range utxos {
+		u := &utxos[i]
+		// do not spend the tokens and immature coinbase outputs
+		if u.Token != nil || u.Coinbase && u.Confirmations < minConfirmations {
+			continue
+		}
+		addrDescs[i], err = w.chainParser.GetAddrDescFromAddress(u.Address)
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetAddrDescFromAddress %v", u.Address)
+		}
+		candidates = append(candidates, selectionUtxo{
+			index: i,
+			group: u.Address,
+			value: (*big.Int)(u.AmountSat).Int64(),
+			vsize: inputVsize(addrDescs[i]),
+		})
+	}
+	var r *selectionResult
+	var ok bool
+	switch req.Strategy {
+	case "", CoinSelectionBranchAndBound:
+		r, ok = selectBranchAndBound(candidates, &p)
+	case CoinSelectionLargestFirst:
+		r, ok = selectLargestFirst(candidates, &p)
+	case CoinSelectionPrivacy:
+		r, ok = selectPrivacy(candidates, &p)
+	default:
+		return nil, NewAPIError(fmt.Sprintf("Unknown strategy '%v'", req.Strategy), true)
+	}
+	if !ok {
+		return nil, NewAPIError("Insufficient funds", true)
+	}
+	rv := &BuildTxResult{
+		FeeSat: (*Amount)(big.NewInt(r.feeSat)),
+		Vsize:  r.vsize,
+		Inputs: make(Utxos, 0, len(r.selected)),
+	}
+	for _, s := range r.selected {
+		u := &utxos[s.index]
+		hash, err := chainhash.NewHashFromStr(u.Txid)
+		if err != nil {
+			return nil, errors.Annotatef(err, "NewHashFromStr %v", u.Txid)
+		}
+		in := wire.NewTxIn(wire.NewOutPoint(hash, uint32(u.Vout)), nil, nil)
+		// signal replace-by-fee
+		in.Sequence = wire.MaxTxInSequenceNum - 2
+		msgTx.AddTxIn(in)
+		rv.Inputs = append(rv.Inputs, *u)
+	}
+	changeOutput := -1
+	if r.changeSat > 0 {
+		changeOutput = len(msgTx.TxOut)
+		if req.Strategy == CoinSelectionPrivacy {
+			// the change at a random position does not reveal which output is the change
+			changeOutput = rand.Intn(len(msgTx.TxOut) + 1)
+		}
+		msgTx.TxOut = append(msgTx.TxOut, nil)
+		copy(msgTx.TxOut[changeOutput+1:], msgTx.TxOut[changeOutput:])
+		msgTx.TxOut[changeOutput] = wire.NewTxOut(r.changeSat, changeDesc)
+		rv.ChangeAddress = changeAddress
+		rv.ChangePath = changePath
+		rv.ChangeSat = (*Amount)(big.NewInt(r.changeSat))
+	}
+	psbt := btc.NewPsbt(msgTx)
+	for i, s := range r.selected {
+		if err = w.fillPsbtInput(&psbt.Inputs[i], &utxos[s.index], addrDescs[s.index], xpub, fingerprint); err != nil {
+			return nil, err
+		}
+	}
+	if changeOutput >= 0 && xpub != "" {
+		d, err := w.bip32Derivation(xpub, changePath, fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		psbt.Outputs[changeOutput].Bip32Derivation = []btc.Bip32Derivation{*d}
+		psbt.Outputs[changeOutput].RedeemScript = btc.P2shP2wpkhRedeemScript(d.PubKey, changeDesc)
+	}
+	rv.Psbt, err = psbt.B64Encode()
+	if err != nil {
+		return nil, errors.Annotatef(err, "B64Encode")
+	}
+	glog.Info("BuildTx ", len(rv.Inputs), " inputs of ", len(candidates), " utxos, strategy ", req.Strategy, ", finished in ", time.Since(start))
+	return rv, nil
+}
+
+// bip32Derivation returns the origin of the public key of the xpub address with the path
+// with the fingerprint of the master key the full path is used, otherwise the path relative to the xpub with the fingerprint of the xpub
+func (w *Worker) bip32Derivation(xpub string, path string, fingerprint []byte) (*btc.Bip32Derivation, error) {
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 {
+		return nil, errors.Errorf("Invalid path %v", path)
+	}
+	relative, err := btc.ParseBip32Path(strings.Join(parts[len(parts)-2:], "/"))
+	if err != nil {
+		return nil, err
+	}
+	xpubFingerprint, pubKeys, err := w.chainParser.DerivePublicKeys(xpub, relative[0], relative[1:])
+	if err != nil {
+		return nil, errors.Annotatef(err, "DerivePublicKeys %v", path)
+	}
+	if fingerprint == nil {
+		return &btc.Bip32Derivation{PubKey: pubKeys[0], Fingerprint: xpubFingerprint, Path: relative}, nil
+	}
+	full, err := btc.ParseBip32Path(path)
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Derivation path of the xpub is unknown, the fingerprint cannot be used, %v", err), true)
+	}
+	return &btc.Bip32Derivation{PubKey: pubKeys[0], Fingerprint: fingerprint, Path: full}, nil
+}
+
+// isWitnessProgram returns true if the script is a segwit output script, witness version and the pushed program
+func isWitnessProgram(script []byte) bool {
+	return len(script) >= 4 && len(script) <= 42 && (script[0] == 0x00 || script[0] >= 0x51 && script[0] <= 0x60) && int(script[1]) == len(script)-2
+}
+
+// fillPsbtInput sets the spent output and if the utxo belongs to the xpub, the origin of the public key
+func (w *Worker) fillPsbtInput(in *btc.PsbtInput, u *Utxo, addrDesc bchain.AddressDescriptor, xpub string, fingerprint []byte) error {
+	if xpub != "" {
+		d, err := w.bip32Derivation(xpub, u.Path, fingerprint)
+		if err != nil {
+			return err
+		}
+		in.Bip32Derivation = []btc.Bip32Derivation{*d}
+		in.RedeemScript = btc.P2shP2wpkhRedeemScript(d.PubKey, addrDesc)
+	}
+	if isWitnessProgram(addrDesc) || in.RedeemScript != nil {
+		in.WitnessUtxo = wire.NewTxOut((*big.Int)(u.AmountSat).Int64(), addrDesc)
+		return nil
+	}
+	// the whole spent transaction is necessary to sign non segwit input
+	tx, err := w.chain.GetTransaction(u.Txid)
+	if err != nil {
+		return errors.Annotatef(err, "GetTransaction %v", u.Txid)
+	}
+	in.NonWitnessUtxo, err = hex.DecodeString(tx.Hex)
+	if err != nil || len(in.NonWitnessUtxo) == 0 {
+		return errors.Errorf("Raw transaction %v not available", u.Txid)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"math"
+	"sort"
+)
+
+// coin selection strategies of BuildTx
+const (
+	CoinSelectionBranchAndBound = "branch-and-bound"
+	CoinSelectionLargestFirst   = "largest-first"
+	CoinSelectionPrivacy        = "privacy-aware"
+)
+
+// vsize of version, locktime, counts of inputs and outputs and of the segwit marker
+const txOverheadVsize = 11
+const dustThreshold = 546
+const bnbMaxTries = 100000
+
+type selectionUtxo struct {
+	// index of the utxo in the list of candidates
+	index int
+	// group is the address of the utxo, used by the privacy strategy
+	group string
+	value int64
+	// vsize of the input spending the utxo
+	vsize int
+}
+
+type selectionParams struct {
+	// target is the sum of the outputs
+	target int64
+	// baseVsize is the vsize of the transaction without inputs and without change
+	baseVsize int
+	// changeVsize is the vsize of the change output, changeSpendVsize the vsize of the input spending it in the future
+	changeVsize      int
+	changeSpendVsize int
+	feeRate          float64
+}
+
+type selectionResult struct {
+	selected []selectionUtxo
+	feeSat   int64
+	// changeSat is zero if there is no change output
+	changeSat int64
+	vsize     int
+}
+
+func (p *selectionParams) fee(vsize int) int64 {
+	return int64(math.Ceil(float64(vsize) * p.feeRate))
+}
+
+func (p *selectionParams) effectiveValue(u *selectionUtxo) int64 {
+	return u.value - p.fee(u.vsize)
+}
+
+// inputVsize returns estimated vsize of the input spending the output script
+func inputVsize(script []byte) int {
+	switch {
+	case len(script) == 22 && script[0] == 0x00 && script[1] == 0x14:
+		// P2WPKH
+		return 68
+	case len(script) == 34 && script[0] == 0x51 && script[1] == 0x20:
+		// P2TR key path
+		return 58
+	case len(script) == 23 && script[0] == 0xa9 && script[1] == 0x14 && script[22] == 0x87:
+		// P2SH, expected P2SH-P2WPKH
+		return 91
+	default:
+		// P2PKH and others
+		return 148
+	}
+}
+
+// outputVsize returns vsize of the output with the script
+func outputVsize(script []byte) int {
+	return 8 + 1 + len(script)
+}
+
+// finishSelection computes fee and change of the selected utxos, it fails if the utxos do not cover the target and the fee
+// the change output is created only if the change is not dust, otherwise the remainder is left to the fee
+func finishSelection(selected []selectionUtxo, p *selectionParams) (*selectionResult, bool) {
+	var sum int64
+	vsize := p.baseVsize
+	for i := range selected {
+		sum += selected[i].value
+		vsize += selected[i].vsize
+	}
+	if sum < p.target+p.fee(vsize) {
+		return nil, false
+	}
+	change := sum - p.target - p.fee(vsize+p.changeVsize)
+	if change >= dustThreshold {
+		return &selectionResult{
+			selected:  selected,
+			feeSat:    sum - p.target - change,
+			changeSat: change,
+			vsize:     vsize + p.changeVsize,
+		}, true
+	}
+	return &selectionResult{
+		selected: selected,
+		feeSat:   sum - p.target,
+		vsize:    vsize,
+	}, true
+}
+
+// selectLargestFirst adds the largest utxos until the target and the fee is covered
+func selectLargestFirst(utxos []selectionUtxo, p *selectionParams) (*selectionResult, bool) {
+	sorted := make([]selectionUtxo, len(utxos))
+	copy(sorted, utxos)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].value > sorted[j].value })
+	for i := range sorted {
+		if p.effectiveValue(&sorted[i]) <= 0 {
+			break
+		}
+		if r, ok := finishSelection(sorted[:i+1], p); ok {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// selectBranchAndBound searches for a set of utxos which covers the target and the fee without a change output,
+// minimizing the excess, in the same way as Bitcoin Core; if there is no such set, it falls back to largest-first
+func selectBranchAndBound(utxos []selectionUtxo, p *selectionParams) (*selectionResult, bool) {
+	pool := make([]selectionUtxo, 0, len(utxos))
+	values := make([]int64, 0, len(utxos))
+	var available int64
+	for i := range utxos {
+		if v := p.effectiveValue(&utxos[i]); v > 0 {
+			pool = append(pool, utxos[i])
+			values = append(values, v)
+			available += v
+		}
+	}
+	sort.Sort(&byEffectiveValue{pool, values})
+	target := p.target + p.fee(p.baseVsize)
+	costOfChange := p.fee(p.changeVsize) + p.fee(p.changeSpendVsize)
+	if available >= target {
+		var best []bool
+		bestExcess := int64(math.MaxInt64)
+		selected := make([]bool, len(pool))
+		tries := 0
+		var search func(depth int, value int64, remaining int64)
+		search = func(depth int, value int64, remaining int64) {
+			if tries >= bnbMaxTries || bestExcess == 0 {
+				return
+			}
+			tries++
+			if value > target+costOfChange || value+remaining < target {
+				return
+			}
+			if value >= target {
+				if value-target < bestExcess {
+					bestExcess = value - target
+					best = make([]bool, len(selected))
+					copy(best, selected)
+				}
+				return
+			}
+			if depth == len(pool) {
+				return
+			}
+			// including a utxo of the same value as the previous excluded one would only repeat the search
+			if depth == 0 || selected[depth-1] || values[depth-1] != values[depth] {
+				selected[depth] = true
+				search(depth+1, value+values[depth], remaining-values[depth])
+				selected[depth] = false
+			}
+			search(depth+1, value, remaining-values[depth])
+		}
+		search(0, 0, available)
+		if best != nil {
+			r := make([]selectionUtxo, 0, 8)
+			for i := range best {
+				if best[i] {
+					r = append(r, pool[i])
+				}
+			}
+			return finishSelection(r, p)
+		}
+	}
+	return selectLargestFirst(utxos, p)
+}
+
+type byEffectiveValue struct {
+	utxos  []selectionUtxo
+	values []int64
+}
+
+func (a *byEffectiveValue) Len() int { return len(a.utxos) }
+func (a *byEffectiveValue) Swap(i, j int) {
+	a.utxos[i], a.utxos[j] = a.utxos[j], a.utxos[i]
+	a.values[i], a.values[j] = a.values[j], a.values[i]
+}
+func (a *byEffectiveValue) Less(i, j int) bool { return a.values[i] > a.values[j] }
+
+// selectPrivacy spends always all utxos of an address together, so that the address does not link more transactions,
+// and uses as few addresses as possible - the single address with the smallest sufficient balance, or the largest addresses
+func selectPrivacy(utxos []selectionUtxo, p *selectionParams) (*selectionResult, bool) {
+	groups := make([][]selectionUtxo, 0, 8)
+	sums := make([]int64, 0, 8)
+	groupIndex := make(map[string]int)
+	for i := range utxos {
+		gi, found := groupIndex[utxos[i].group]
+		if !found {
+			gi = len(groups)
+			groupIndex[utxos[i].group] = gi
+			groups = append(groups, nil)
+			sums = append(sums, 0)
+		}
+		groups[gi] = append(groups[gi], utxos[i])
+		sums[gi] += utxos[i].value
+	}
+	order := make([]int, len(groups))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return sums[order[i]] < sums[order[j]] })
+	for _, gi := range order {
+		if r, ok := finishSelection(groups[gi], p); ok {
+			return r, true
+		}
+	}
+	selected := make([]selectionUtxo, 0, 8)
+	for i := len(order) - 1; i >= 0; i-- {
+		selected = append(selected, groups[order[i]]...)
+		if r, ok := finishSelection(selected, p); ok {
+			return r, true
+		}
+	}
+	return nil, false
+}
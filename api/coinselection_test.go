@@ -0,0 +1,93 @@
+// +build unittest
+
+package api
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func Test_coinSelection(t *testing.T) {
+	p := &selectionParams{target: 100000, baseVsize: txOverheadVsize + 31, changeVsize: 31, changeSpendVsize: 68, feeRate: 2}
+	utxos := []selectionUtxo{
+		{index: 0, group: "a", value: 50000, vsize: 68},
+		{index: 1, group: "a", value: 30000, vsize: 68},
+		{index: 2, group: "b", value: 70000, vsize: 68},
+		{index: 3, group: "c", value: 100400, vsize: 68},
+		{index: 4, group: "d", value: 200000, vsize: 68},
+		{index: 5, group: "e", value: 100, vsize: 68},
+	}
+	indexes := func(r *selectionResult) []int {
+		rv := make([]int, len(r.selected))
+		for i := range r.selected {
+			rv[i] = r.selected[i].index
+		}
+		return rv
+	}
+	tests := []struct {
+		name       string
+		f          func([]selectionUtxo, *selectionParams) (*selectionResult, bool)
+		want       []int
+		wantFee    int64
+		wantChange int64
+	}{
+		{name: "largest-first", f: selectLargestFirst, want: []int{4}, wantFee: 282, wantChange: 99718},
+		// the exact match without change
+		{name: "branch-and-bound", f: selectBranchAndBound, want: []int{3}, wantFee: 400},
+		// the address a with sum 80000 does not cover the target, c is the smallest sufficient address
+		{name: "privacy", f: selectPrivacy, want: []int{3}, wantFee: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, ok := tt.f(utxos, p)
+			if !ok {
+				t.Fatal("selection failed")
+			}
+			if !reflect.DeepEqual(indexes(r), tt.want) || r.feeSat != tt.wantFee || r.changeSat != tt.wantChange {
+				t.Errorf("selection = %v, fee %v, change %v, want %v, fee %v, change %v", indexes(r), r.feeSat, r.changeSat, tt.want, tt.wantFee, tt.wantChange)
+			}
+		})
+	}
+	p.target = 1000000
+	if _, ok := selectBranchAndBound(utxos, p); ok {
+		t.Error("selectBranchAndBound() expected insufficient funds")
+	}
+
+	// utxos with duplicate values, without fee
+	values := func(v ...int64) []selectionUtxo {
+		rv := make([]selectionUtxo, len(v))
+		for i := range v {
+			rv[i] = selectionUtxo{index: i, group: "a", value: v[i], vsize: 68}
+		}
+		return rv
+	}
+	duplicates := []struct {
+		name   string
+		utxos  []selectionUtxo
+		target int64
+		want   []int64
+	}{
+		// the exact match sits after the duplicates
+		{name: "exact after duplicates", utxos: values(5000, 5000, 3000), target: 3000, want: []int64{3000}},
+		{name: "two duplicates", utxos: values(4000, 4000, 4000, 1000), target: 8000, want: []int64{4000, 4000}},
+		{name: "all duplicates without larger", utxos: values(2000, 5000, 2000, 2000), target: 6000, want: []int64{2000, 2000, 2000}},
+		{name: "duplicates and smaller", utxos: values(3000, 3000, 1500, 1500, 700), target: 5200, want: []int64{3000, 1500, 700}},
+	}
+	for _, tt := range duplicates {
+		t.Run(tt.name, func(t *testing.T) {
+			r, ok := selectBranchAndBound(tt.utxos, &selectionParams{target: tt.target, baseVsize: txOverheadVsize + 31, changeVsize: 31, changeSpendVsize: 68})
+			if !ok {
+				t.Fatal("selection failed")
+			}
+			got := make([]int64, len(r.selected))
+			for i := range r.selected {
+				got[i] = r.selected[i].value
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] > got[j] })
+			if !reflect.DeepEqual(got, tt.want) || r.feeSat != 0 || r.changeSat != 0 {
+				t.Errorf("selection = %v, fee %v, change %v, want %v without fee and change", got, r.feeSat, r.changeSat, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/db"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+)
+
+// txCursor is a stable position in the transaction history, it is the height of the block
+// and the position of the transaction among the transactions of the address (or xpub) in the block
+// the page following the cursor starts with the transaction after the position of the cursor
+type txCursor struct {
+	height   uint32
+	position uint32
+}
+
+// String returns the opaque form of the cursor, used in the API
+func (c *txCursor) String() string {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, c.height)
+	binary.BigEndian.PutUint32(b[4:], c.position)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// parseTxCursor parses the cursor from its opaque form, empty string means no cursor
+func parseTxCursor(s string) (*txCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) != 8 {
+		return nil, NewAPIError(fmt.Sprintf("Invalid cursor '%v'", s), true)
+	}
+	return &txCursor{
+		height:   binary.BigEndian.Uint32(b),
+		position: binary.BigEndian.Uint32(b[4:]),
+	}, nil
+}
+
+// after returns true if the transaction at given height and position follows the cursor in the history ordered from the newest
+func (c *txCursor) after(height uint32, position uint32) bool {
+	return height < c.height || height == c.height && position > c.position
+}
+
+// getAddressTxidsFromCursor returns up to maxResults confirmed txids following the cursor (or from the newest if cursor is nil)
+// the txids are read directly in the order of the addresses column, starting at the height of the cursor
+// the returned cursor points to the last returned txid, it is nil if there are no more matching transactions
+func (w *Worker) getAddressTxidsFromCursor(addrDesc bchain.AddressDescriptor, filter *AddressFilter, cursor *txCursor, maxResults int) ([]string, *txCursor, error) {
+	higher := maxUint32
+	if filter.ToHeight != 0 {
+		higher = filter.ToHeight
+	}
+	if cursor != nil && cursor.height < higher {
+		higher = cursor.height
+	}
+	txids := make([]string, 0, maxResults)
+	var last, next *txCursor
+	var height, position uint32
+	started := false
+	err := w.db.GetAddrDescTransactions(addrDesc, filter.FromHeight, higher, func(txid string, h uint32, indexes []int32) error {
+		// the position counts all transactions of the address in the block so that it does not depend on the filter
+		if !started || h != height {
+			started = true
+			height = h
+			position = 0
+		} else {
+			position++
+		}
+		if cursor != nil && !cursor.after(height, position) {
+			return nil
+		}
+		if !filter.matchIndexes(indexes) {
+			return nil
+		}
+		if len(txids) >= maxResults {
+			next = last
+			return &db.StopIteration{}
+		}
+		txids = append(txids, txid)
+		last = &txCursor{height: height, position: position}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return txids, next, nil
+}
+
+// xpubTxidsFromCursor returns up to maxResults txids from the sorted xpub txids following the cursor (or from the newest if cursor is nil)
+// and the cursor of the last returned txid, the cursor is nil if there are no more txids
+// the positions are counted in the filtered txids, therefore the cursor must be used with the same filter
+func xpubTxidsFromCursor(txc xpubTxids, cursor *txCursor, maxResults int) (xpubTxids, *txCursor) {
+	var last *txCursor
+	var position uint32
+	rv := make(xpubTxids, 0, maxResults)
+	for i := range txc {
+		if i == 0 || txc[i].height != txc[i-1].height {
+			position = 0
+		} else {
+			position++
+		}
+		if cursor != nil && !cursor.after(txc[i].height, position) {
+			continue
+		}
+		if len(rv) >= maxResults {
+			return rv, last
+		}
+		rv = append(rv, txc[i])
+		last = &txCursor{height: txc[i].height, position: position}
+	}
+	return rv, nil
+}
@@ -0,0 +1,62 @@
+// +build unittest
+
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_txCursor(t *testing.T) {
+	c := &txCursor{height: 225494, position: 3}
+	s := c.String()
+	got, err := parseTxCursor(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Errorf("parseTxCursor(%v) = %+v, want %+v", s, got, c)
+	}
+	if got, err := parseTxCursor(""); got != nil || err != nil {
+		t.Errorf("parseTxCursor(\"\") = %+v, %v, want nil, nil", got, err)
+	}
+	for _, s := range []string{"not base64!", "AAAA"} {
+		if _, err := parseTxCursor(s); err == nil {
+			t.Errorf("parseTxCursor(%v) expected error", s)
+		}
+	}
+	if c.after(225494, 3) || c.after(225495, 0) || !c.after(225494, 4) || !c.after(225493, 0) {
+		t.Error("txCursor.after() returned unexpected result")
+	}
+}
+
+func Test_xpubTxidsFromCursor(t *testing.T) {
+	txc := xpubTxids{
+		{txid: "a", height: 30},
+		{txid: "b", height: 20},
+		{txid: "c", height: 20},
+		{txid: "d", height: 20},
+		{txid: "e", height: 10},
+	}
+	txids := func(t xpubTxids) []string {
+		r := make([]string, len(t))
+		for i := range t {
+			r[i] = t[i].txid
+		}
+		return r
+	}
+	got, next := xpubTxidsFromCursor(txc, nil, 2)
+	if !reflect.DeepEqual(txids(got), []string{"a", "b"}) || !reflect.DeepEqual(next, &txCursor{height: 20, position: 0}) {
+		t.Fatalf("xpubTxidsFromCursor() = %v, %+v", txids(got), next)
+	}
+	got, next = xpubTxidsFromCursor(txc, next, 2)
+	if !reflect.DeepEqual(txids(got), []string{"c", "d"}) || !reflect.DeepEqual(next, &txCursor{height: 20, position: 2}) {
+		t.Fatalf("xpubTxidsFromCursor() = %v, %+v", txids(got), next)
+	}
+	// a new transaction in a new block does not shift the following pages
+	txc = append(xpubTxids{{txid: "new", height: 40}}, txc...)
+	got, next = xpubTxidsFromCursor(txc, next, 2)
+	if !reflect.DeepEqual(txids(got), []string{"e"}) || next != nil {
+		t.Fatalf("xpubTxidsFromCursor() = %v, %+v", txids(got), next)
+	}
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/db"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// maxBlockTimeDrift is the maximum difference of the block time from the time of the following blocks,
+// the blocks older than fromTime by more than this value cannot contain transactions in the exported range
+const maxBlockTimeDrift = 2 * 60 * 60
+
+// ExportAddressHistory passes the confirmed transactions of the address to onRow, starting from the newest
+// the transactions are limited by the block heights in the filter and by the block time range [fromTime, toTime], zero means no limit
+func (w *Worker) ExportAddressHistory(address string, filter *AddressFilter, fromTime, toTime int64, onRow func(*HistoryRow) error) error {
+	if w.chainType != bchain.ChainBitcoinType {
+		return NewAPIError("Not supported", true)
+	}
+	start := time.Now()
+	addrDesc, _, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return err
+	}
+	ba, err := w.db.GetAddrDescBalance(addrDesc, db.AddressBalanceDetailNoUTXO)
+	if err != nil {
+		return NewAPIError(fmt.Sprintf("Address not found, %v", err), true)
+	}
+	if ba == nil {
+		return nil
+	}
+	txc, _, err := w.xpubGetAddressTxids(addrDesc, false, 0, maxUint32, maxInt)
+	if err != nil {
+		return errors.Annotatef(err, "xpubGetAddressTxids %v", addrDesc)
+	}
+	addrDescs := map[string]struct{}{string(addrDesc): {}}
+	if err = w.exportHistory(txc, addrDescs, &ba.BalanceSat, filter, fromTime, toTime, onRow); err != nil {
+		return err
+	}
+	glog.Info("ExportAddressHistory ", address, ", ", len(txc), " txs, finished in ", time.Since(start))
+	return nil
+}
+
+// ExportXpubHistory passes the confirmed transactions of the xpub to onRow, starting from the newest
+// the transactions are limited in the same way as by ExportAddressHistory
+func (w *Worker) ExportXpubHistory(xpub string, filter *AddressFilter, fromTime, toTime int64, gap int, onRow func(*HistoryRow) error) error {
+	start := time.Now()
+	data, _, err := w.getXpubData(xpub, 0, 1, AccountDetailsTxidHistory, &AddressFilter{Vout: AddressFilterVoutOff}, gap)
+	if err != nil {
+		return err
+	}
+	addrDescs := make(map[string]struct{})
+	txcMap := make(map[string]struct{})
+	txc := make(xpubTxids, 0, 32)
+	for _, da := range [][]xpubAddress{data.addresses, data.changeAddresses} {
+		for i := range da {
+			ad := &da[i]
+			addrDescs[string(ad.addrDesc)] = struct{}{}
+			for _, txid := range ad.txids {
+				if _, found := txcMap[txid.txid]; !found {
+					txcMap[txid.txid] = struct{}{}
+					txc = append(txc, txid)
+				}
+			}
+		}
+	}
+	sort.Stable(txc)
+	if err = w.exportHistory(txc, addrDescs, &data.balanceSat, filter, fromTime, toTime, onRow); err != nil {
+		return err
+	}
+	glog.Info("ExportXpubHistory ", xpub[:16], ", ", len(txc), " txs, finished in ", time.Since(start))
+	return nil
+}
+
+// exportHistory computes the history rows of the transactions txc sorted from the newest, of the addresses addrDescs
+// the running balance is computed backwards from the current balance, therefore all newer transactions must be processed
+// even if they are out of the requested range; the transactions are read from the txAddresses column, not from the backend
+func (w *Worker) exportHistory(txc xpubTxids, addrDescs map[string]struct{}, balanceSat *big.Int, filter *AddressFilter, fromTime, toTime int64, onRow func(*HistoryRow) error) error {
+	bestheight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return errors.Annotatef(err, "GetBestBlock")
+	}
+	toHeight := maxUint32
+	if filter.ToHeight != 0 {
+		toHeight = filter.ToHeight
+	}
+	var bi *db.BlockInfo
+	var balance big.Int
+	balance.Set(balanceSat)
+	for i := range txc {
+		t := &txc[i]
+		if t.height < filter.FromHeight {
+			break
+		}
+		if bi == nil || bi.Height != t.height {
+			bi, err = w.db.GetBlockInfo(t.height)
+			if err != nil {
+				return errors.Annotatef(err, "GetBlockInfo %v", t.height)
+			}
+			if bi == nil {
+				glog.Warning("DB inconsistency:  block height ", t.height, ": not found in db")
+				bi = &db.BlockInfo{Height: t.height}
+			}
+		}
+		if fromTime > 0 && bi.Time > 0 && bi.Time < fromTime-maxBlockTimeDrift {
+			break
+		}
+		ta, err := w.db.GetTxAddresses(t.txid)
+		if err != nil {
+			return errors.Annotatef(err, "GetTxAddresses %v", t.txid)
+		}
+		if ta == nil {
+			glog.Warning("DB inconsistency:  tx ", t.txid, ": not found in txAddresses")
+			continue
+		}
+		tx := w.txFromTxAddress(t.txid, ta, bi, bestheight)
+		var receivedSat, sentSat big.Int
+		for j := range tx.Vout {
+			if _, found := addrDescs[string(tx.Vout[j].AddrDesc)]; found {
+				receivedSat.Add(&receivedSat, (*big.Int)(tx.Vout[j].ValueSat))
+			}
+		}
+		for j := range tx.Vin {
+			if _, found := addrDescs[string(tx.Vin[j].AddrDesc)]; found {
+				sentSat.Add(&sentSat, (*big.Int)(tx.Vin[j].ValueSat))
+			}
+		}
+		row := HistoryRow{
+			Time:        bi.Time,
+			Height:      t.height,
+			Txid:        t.txid,
+			ReceivedSat: &receivedSat,
+			SentSat:     &sentSat,
+			BalanceSat:  new(big.Int).Set(&balance),
+		}
+		if sentSat.Sign() > 0 {
+			row.FeeSat = (*big.Int)(tx.FeesSat)
+		}
+		// the balance before this transaction is the balance after the preceding (older) transaction
+		balance.Sub(&balance, &receivedSat)
+		balance.Add(&balance, &sentSat)
+		if t.height > toHeight || toTime > 0 && bi.Time > toTime || fromTime > 0 && bi.Time < fromTime {
+			continue
+		}
+		if err = onRow(&row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// reverseHash converts hash between the byte order used in the hashing and the displayed (RPC) byte order
+func reverseHash(h []byte) []byte {
+	r := make([]byte, len(h))
+	for i := range h {
+		r[len(h)-1-i] = h[i]
+	}
+	return r
+}
+
+func doubleSha256(b []byte) []byte {
+	h := sha256.Sum256(b)
+	h = sha256.Sum256(h[:])
+	return h[:]
+}
+
+// merkleBranch returns the hashes of the merkle branch of the transaction at position pos in the block
+// the hashes are in the same byte order as txids, the last hash of an odd level is paired with itself
+func merkleBranch(txids []string, pos int) ([]string, error) {
+	if pos < 0 || pos >= len(txids) {
+		return nil, errors.Errorf("Position %v out of range", pos)
+	}
+	level := make([][]byte, len(txids))
+	for i, txid := range txids {
+		h, err := hex.DecodeString(txid)
+		if err != nil || len(h) != sha256.Size {
+			return nil, errors.Errorf("Invalid txid %v", txid)
+		}
+		level[i] = reverseHash(h)
+	}
+	branch := make([]string, 0, 16)
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		branch = append(branch, hex.EncodeToString(reverseHash(level[pos^1])))
+		next := make([][]byte, len(level)/2)
+		for i := range next {
+			next[i] = doubleSha256(append(append(make([]byte, 0, 2*sha256.Size), level[2*i]...), level[2*i+1]...))
+		}
+		level = next
+		pos >>= 1
+	}
+	return branch, nil
+}
+
+// GetTransactionProof returns merkle inclusion proof of the confirmed transaction
+func (w *Worker) GetTransactionProof(txid string) (*MerkleProof, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	start := time.Now()
+	var height uint32
+	ta, err := w.db.GetTxAddresses(txid)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetTxAddresses %v", txid)
+	}
+	if ta != nil {
+		height = ta.Height
+	} else {
+		_, h, err := w.txCache.GetTransaction(txid)
+		if err != nil {
+			return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found", txid), true)
+		}
+		if h > 0 {
+			height = uint32(h)
+		}
+	}
+	if height == 0 {
+		return nil, NewAPIError(fmt.Sprintf("Transaction '%v' is not confirmed", txid), true)
+	}
+	bi, err := w.db.GetBlockInfo(height)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlockInfo %v", height)
+	}
+	if bi == nil {
+		return nil, NewAPIError(fmt.Sprintf("Block %v not found", height), true)
+	}
+	txids, err := w.db.GetBlockTxids(height)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlockTxids %v", height)
+	}
+	// only the last blocks are in the blockTxs column, get the older blocks from the backend
+	if txids == nil {
+		block, err := w.chain.GetBlock(bi.Hash, height)
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetBlock %v", height)
+		}
+		txids = make([]string, len(block.Txs))
+		for i := range block.Txs {
+			txids[i] = block.Txs[i].Txid
+		}
+	}
+	pos := -1
+	for i := range txids {
+		if txids[i] == txid {
+			pos = i
+			break
+		}
+	}
+	if pos < 0 {
+		return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found in block %v", txid, height), true)
+	}
+	branch, err := merkleBranch(txids, pos)
+	if err != nil {
+		return nil, err
+	}
+	glog.Info("GetTransactionProof ", txid, " finished in ", time.Since(start))
+	return &MerkleProof{
+		BlockHash:   bi.Hash,
+		BlockHeight: height,
+		Merkle:      branch,
+		Pos:         pos,
+	}, nil
+}
@@ -0,0 +1,77 @@
+// +build unittest
+
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_merkleBranch(t *testing.T) {
+	// block 100000 of Bitcoin mainnet
+	block100000 := []string{
+		"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+		"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+		"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+		"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+	}
+	tests := []struct {
+		name    string
+		txids   []string
+		pos     int
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "first tx",
+			txids: block100000,
+			pos:   0,
+			want: []string{
+				"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+				"8e30899078ca1813be036a073bbf80b86cdddde1c96e9e9c99e9e3782df4ae49",
+			},
+		},
+		{
+			name:  "third tx",
+			txids: block100000,
+			pos:   2,
+			want: []string{
+				"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+				"ccdafb73d8dcd0173d5d5c3c9a0770d0b3953db889dab99ef05b1907518cb815",
+			},
+		},
+		{
+			name:  "odd number of txs",
+			txids: block100000[:3],
+			pos:   2,
+			want: []string{
+				"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+				"ccdafb73d8dcd0173d5d5c3c9a0770d0b3953db889dab99ef05b1907518cb815",
+			},
+		},
+		{
+			name:  "single tx",
+			txids: block100000[:1],
+			pos:   0,
+			want:  []string{},
+		},
+		{
+			name:    "out of range",
+			txids:   block100000,
+			pos:     4,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := merkleBranch(tt.txids, tt.pos)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("merkleBranch() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merkleBranch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
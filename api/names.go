@@ -0,0 +1,72 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+var nameOperations = map[bchain.NameOp]string{
+	bchain.NameNew:         "name_new",
+	bchain.NameFirstUpdate: "name_firstupdate",
+	bchain.NameUpdate:      "name_update",
+}
+
+// GetName returns the current value, owner and expiry of the name with the history of the operations with the name
+func (w *Worker) GetName(name string) (*Name, error) {
+	depth := w.chainParser.GetNameExpirationDepth()
+	if depth == 0 {
+		return nil, NewAPIError("Names are not supported", true)
+	}
+	if len(name) == 0 {
+		return nil, NewAPIError("Missing name", true)
+	}
+	start := time.Now()
+	rs, err := w.db.GetNameHistory([]byte(name))
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetNameHistory %v", name)
+	}
+	bestheight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	r := &Name{
+		Name:    name,
+		History: make([]NameOperation, len(rs)),
+	}
+	current := -1
+	for i := range rs {
+		nr := &rs[i]
+		txid, err := w.chainParser.UnpackTxid(nr.BtxID)
+		if err != nil {
+			return nil, err
+		}
+		no := NameOperation{
+			Txid:      txid,
+			Vout:      nr.Vout,
+			Height:    nr.Height,
+			Operation: nameOperations[nr.Op],
+			Value:     string(nr.Value),
+		}
+		if a, _, err := w.chainParser.GetAddressesFromAddrDesc(nr.AddrDesc); err == nil && len(a) == 1 {
+			no.Address = a[0]
+		}
+		r.History[i] = no
+		// the name is registered by name_firstupdate, name_new contains only its hash
+		if current < 0 && nr.Op != bchain.NameNew {
+			current = i
+		}
+	}
+	if current < 0 {
+		return nil, NewAPIError("Name not found", true)
+	}
+	r.Value = r.History[current].Value
+	r.Address = r.History[current].Address
+	r.Height = r.History[current].Height
+	r.ExpiryHeight = r.Height + depth
+	r.Expired = bestheight >= r.ExpiryHeight
+	glog.Info("GetName ", name, ", ", len(rs), " operations, finished in ", time.Since(start))
+	return r, nil
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"blockbook/db"
+
+	"github.com/juju/errors"
+)
+
+const (
+	orphanedCauseReorg    = "reorg"
+	orphanedCauseRollback = "rollback"
+)
+
+// GetOrphanedBlocks returns the blocks disconnected by reorgs and rollbacks, the most recent first, with the block which replaced them
+// and the fate of their transactions
+func (w *Worker) GetOrphanedBlocks(page int, blocksOnPage int) (*OrphanedBlocks, error) {
+	page--
+	if page < 0 {
+		page = 0
+	}
+	obs, err := w.db.GetLastOrphanedBlocks(db.MaxOrphanedBlocks)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetLastOrphanedBlocks")
+	}
+	pg, from, to, _ := computePaging(len(obs), page, blocksOnPage)
+	r := &OrphanedBlocks{Paging: pg, Blocks: make([]OrphanedBlock, to-from)}
+	for i := from; i < to; i++ {
+		ob := obs[i]
+		b := &r.Blocks[i-from]
+		b.Hash = ob.Hash
+		b.Height = ob.Height
+		b.Time = ob.Time
+		b.Size = ob.Size
+		b.Disconnected = ob.Disconnected
+		if ob.Cause == db.OrphanedByRollback {
+			b.Cause = orphanedCauseRollback
+		} else {
+			b.Cause = orphanedCauseReorg
+		}
+		b.Txs = len(ob.Txids)
+		if b.ReplacedBy, err = w.db.GetBlockHash(ob.Height); err != nil {
+			return nil, errors.Annotatef(err, "GetBlockHash %v", ob.Height)
+		}
+		if b.ReplacedBy == b.Hash {
+			// the block was connected again later
+			b.ReplacedBy = ""
+		}
+		if ob.FateKnown {
+			b.Reconfirmed = int(ob.Reconfirmed)
+			b.InMempool = int(ob.InMempool)
+			b.Dropped = int(ob.Dropped)
+		} else {
+			b.FatePending = true
+		}
+	}
+	return r, nil
+}
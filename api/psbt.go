@@ -0,0 +1,205 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/btc"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcd/wire"
+)
+
+// psbtUtxo is the output spent by an input of the psbt
+type psbtUtxo struct {
+	txOut *wire.TxOut
+	spent bool
+}
+
+func (w *Worker) decodePsbt(b64 string) (*btc.Psbt, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	p, err := btc.B64DecodePsbt(b64)
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Invalid psbt, %v", err), true)
+	}
+	return p, nil
+}
+
+// psbtUtxos returns the outputs spent by the inputs of the psbt, the value and the spent flag primarily from the index;
+// the output is nil if it is not found
+// the address descriptors in the index are not the output scripts for all outputs (P2PK is stored as P2PKH),
+// the script is therefore taken from the utxo in the psbt or from the spent transaction
+func (w *Worker) psbtUtxos(p *btc.Psbt) ([]*psbtUtxo, error) {
+	utxos := make([]*psbtUtxo, len(p.UnsignedTx.TxIn))
+	for i, in := range p.UnsignedTx.TxIn {
+		txOut, err := p.InputUtxo(i)
+		if err != nil {
+			return nil, NewAPIError(fmt.Sprintf("Input %d, %v", i, err), true)
+		}
+		txid := in.PreviousOutPoint.Hash.String()
+		vout := in.PreviousOutPoint.Index
+		ta, err := w.db.GetTxAddresses(txid)
+		if err != nil {
+			return nil, errors.Annotatef(err, "GetTxAddresses %v", txid)
+		}
+		if ta != nil && int(vout) < len(ta.Outputs) {
+			o := &ta.Outputs[vout]
+			var script []byte
+			if txOut != nil {
+				script = txOut.PkScript
+			} else if script, err = w.outputScript(txid, vout); err != nil {
+				return nil, err
+			}
+			utxos[i] = &psbtUtxo{txOut: wire.NewTxOut(o.ValueSat.Int64(), script), spent: o.Spent}
+			continue
+		}
+		if txOut != nil {
+			utxos[i] = &psbtUtxo{txOut: txOut}
+		}
+	}
+	return utxos, nil
+}
+
+// outputScript returns the script of the output txid:vout of a transaction in the block chain
+func (w *Worker) outputScript(txid string, vout uint32) ([]byte, error) {
+	tx, _, err := w.txCache.GetTransaction(txid)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetTransaction %v", txid)
+	}
+	if int(vout) >= len(tx.Vout) {
+		return nil, errors.Errorf("Transaction %v does not have output %d", txid, vout)
+	}
+	script, err := hex.DecodeString(tx.Vout[vout].ScriptPubKey.Hex)
+	if err != nil {
+		return nil, errors.Annotatef(err, "ScriptPubKey %v:%d", txid, vout)
+	}
+	return script, nil
+}
+
+// DecodePsbt decodes the psbt in base64 and reports the signatures missing to finalize it and the fee of the transaction
+func (w *Worker) DecodePsbt(b64 string) (*PsbtInfo, error) {
+	p, err := w.decodePsbt(b64)
+	if err != nil {
+		return nil, err
+	}
+	utxos, err := w.psbtUtxos(p)
+	if err != nil {
+		return nil, err
+	}
+	tx := p.UnsignedTx
+	r := &PsbtInfo{
+		Txid:     tx.TxHash().String(),
+		Version:  tx.Version,
+		LockTime: tx.LockTime,
+		Vin:      make([]PsbtInput, len(tx.TxIn)),
+		Vout:     make([]Vout, len(tx.TxOut)),
+		Vsize:    txOverheadVsize,
+		Complete: true,
+	}
+	var valueInSat, valueOutSat big.Int
+	feeKnown := true
+	for i, in := range tx.TxIn {
+		vin := &r.Vin[i]
+		vin.Txid = in.PreviousOutPoint.Hash.String()
+		vin.Vout = in.PreviousOutPoint.Index
+		vin.Sequence = in.Sequence
+		u := utxos[i]
+		if u == nil {
+			vin.Error = "Spent output not found"
+			r.Vsize += inputVsize(nil)
+			r.Complete = false
+			feeKnown = false
+			continue
+		}
+		vin.ValueSat = (*Amount)(big.NewInt(u.txOut.Value))
+		valueInSat.Add(&valueInSat, (*big.Int)(vin.ValueSat))
+		vin.Addresses, vin.IsAddress, err = w.chainParser.GetAddressesFromAddrDesc(u.txOut.PkScript)
+		if err != nil {
+			glog.Warning("GetAddressesFromAddrDesc psbt ", r.Txid, ", input ", i, ": ", err)
+		}
+		r.Vsize += inputVsize(u.txOut.PkScript)
+		if u.spent {
+			vin.Error = "Output is already spent"
+		}
+		s, err := p.Signatures(i, u.txOut.PkScript)
+		if err != nil {
+			vin.Error = err.Error()
+			r.Complete = false
+			continue
+		}
+		vin.Finalized = s.Finalized
+		vin.RequiredSignatures = s.Required
+		vin.Signatures = s.Signed
+		for _, k := range s.Missing {
+			vin.MissingSignatures = append(vin.MissingSignatures, hex.EncodeToString(k))
+		}
+		if !s.Finalized && s.Signed < s.Required {
+			r.Complete = false
+		}
+	}
+	for i, out := range tx.TxOut {
+		vout := &r.Vout[i]
+		vout.N = i
+		vout.ValueSat = (*Amount)(big.NewInt(out.Value))
+		valueOutSat.Add(&valueOutSat, (*big.Int)(vout.ValueSat))
+		vout.AddrDesc = out.PkScript
+		vout.Hex = hex.EncodeToString(out.PkScript)
+		vout.Addresses, vout.IsAddress, err = w.chainParser.GetAddressesFromAddrDesc(out.PkScript)
+		if err != nil {
+			glog.Warning("GetAddressesFromAddrDesc psbt ", r.Txid, ", output ", i, ": ", err)
+		}
+		r.Vsize += outputVsize(out.PkScript)
+	}
+	if feeKnown {
+		var fee big.Int
+		fee.Sub(&valueInSat, &valueOutSat)
+		r.FeeSat = (*Amount)(&fee)
+		r.FeeRate = float64(fee.Int64()) / float64(r.Vsize)
+	}
+	return r, nil
+}
+
+// FinalizePsbt finalizes all inputs of the psbt in base64 and returns the signed transaction in hex, ready to be broadcasted
+func (w *Worker) FinalizePsbt(b64 string) (string, error) {
+	p, err := w.decodePsbt(b64)
+	if err != nil {
+		return "", err
+	}
+	utxos, err := w.psbtUtxos(p)
+	if err != nil {
+		return "", err
+	}
+	var valueInSat, valueOutSat int64
+	for i := range p.Inputs {
+		if utxos[i] == nil {
+			return "", NewAPIError(fmt.Sprintf("Input %d, spent output not found", i), true)
+		}
+		if utxos[i].spent {
+			return "", NewAPIError(fmt.Sprintf("Input %d, output is already spent", i), true)
+		}
+		if err = p.FinalizeInput(i, utxos[i].txOut.PkScript); err != nil {
+			return "", NewAPIError(fmt.Sprintf("Input %d, %v", i, err), true)
+		}
+		valueInSat += utxos[i].txOut.Value
+	}
+	for _, out := range p.UnsignedTx.TxOut {
+		valueOutSat += out.Value
+	}
+	if valueInSat < valueOutSat {
+		return "", NewAPIError("Outputs exceed the inputs", true)
+	}
+	tx, err := p.Extract()
+	if err != nil {
+		return "", NewAPIError(err.Error(), true)
+	}
+	var b bytes.Buffer
+	if err = tx.Serialize(&b); err != nil {
+		return "", errors.Annotatef(err, "Serialize")
+	}
+	return hex.EncodeToString(b.Bytes()), nil
+}
@@ -0,0 +1,278 @@
+package api
+
+import (
+	"blockbook/bchain"
+	"blockbook/common"
+	"blockbook/db"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcd/wire"
+)
+
+// error codes of the transactions rejected by the backend or by the validation
+const (
+	ErrorCodeInvalidTx        = "invalid_tx"
+	ErrorCodeInvalidSignature = "invalid_signature"
+	ErrorCodeMissingInputs    = "missing_inputs"
+	ErrorCodeInputsSpent      = "inputs_spent"
+	ErrorCodeMempoolConflict  = "mempool_conflict"
+	ErrorCodeFeeTooLow        = "fee_too_low"
+	ErrorCodeDust             = "dust"
+	ErrorCodeAlreadyInMempool = "already_in_mempool"
+	ErrorCodeAlreadyInChain   = "already_in_chain"
+	ErrorCodeRejected         = "rejected"
+)
+
+// defaultMinRelayFeePerKB is used if the backend does not report its minimum relay fee
+const defaultMinRelayFeePerKB = 1000
+
+// sendTxErrorReasons maps the substrings of the reject reasons of the backend (Bitcoin Core and forks) to the error codes,
+// the first match wins
+var sendTxErrorReasons = []struct {
+	reason string
+	code   string
+}{
+	{"txn-already-in-mempool", ErrorCodeAlreadyInMempool},
+	{"txn-already-known", ErrorCodeAlreadyInMempool},
+	{"already in block chain", ErrorCodeAlreadyInChain},
+	{"outputs already in utxo set", ErrorCodeAlreadyInChain},
+	{"txn-mempool-conflict", ErrorCodeMempoolConflict},
+	{"missing-inputs", ErrorCodeMissingInputs},
+	{"missingorspent", ErrorCodeMissingInputs},
+	{"min relay fee not met", ErrorCodeFeeTooLow},
+	{"mempool min fee not met", ErrorCodeFeeTooLow},
+	{"insufficient fee", ErrorCodeFeeTooLow},
+	{"dust", ErrorCodeDust},
+	{"script-verify-flag", ErrorCodeInvalidSignature},
+	{"decode failed", ErrorCodeInvalidTx},
+	{"bad-txns-", ErrorCodeInvalidTx},
+}
+
+// rpc error codes of Bitcoin Core, used if the reject reason is not recognized
+const (
+	rpcDeserializationError = -22
+	rpcVerifyError          = -25
+	rpcVerifyRejected       = -26
+	rpcVerifyAlreadyInChain = -27
+)
+
+// sendTxErrorCode classifies the reason of the rejection of the transaction, returns empty string if the reason is not known
+func sendTxErrorCode(reason string, rpcCode int) string {
+	reason = strings.ToLower(reason)
+	for _, r := range sendTxErrorReasons {
+		if strings.Contains(reason, r.reason) {
+			return r.code
+		}
+	}
+	switch rpcCode {
+	case rpcDeserializationError:
+		return ErrorCodeInvalidTx
+	case rpcVerifyError:
+		return ErrorCodeMissingInputs
+	case rpcVerifyRejected:
+		return ErrorCodeRejected
+	case rpcVerifyAlreadyInChain:
+		return ErrorCodeAlreadyInChain
+	}
+	return ""
+}
+
+// SendTxError converts the error returned by the backend when sending or testing a transaction to the public APIError with the error code
+func SendTxError(err error) error {
+	var rpcCode int
+	if e, ok := errors.Cause(err).(*bchain.RPCError); ok {
+		rpcCode = e.Code
+	}
+	return NewAPIErrorWithCode(err.Error(), sendTxErrorCode(err.Error(), rpcCode))
+}
+
+// ValidateTransaction checks if the transaction in hex would be accepted to the mempool, without sending it
+// the check is done by the backend (testmempoolaccept); if the backend does not support it, the inputs are checked
+// in the index and in the mempool and the fee is compared to the minimum relay fee
+func (w *Worker) ValidateTransaction(txHex string) (v *TxValidation, err error) {
+	w, span := w.startSpan("api.ValidateTransaction")
+	defer func() { common.EndSpan(span, err) }()
+	res, err := w.chain.TestMempoolAccept(txHex)
+	if err == bchain.ErrNotSupported {
+		return w.validateTransactionInIndex(txHex)
+	}
+	if err != nil {
+		return nil, SendTxError(err)
+	}
+	if !res.Allowed {
+		return nil, NewAPIErrorWithCode(res.RejectReason, sendTxErrorCode(res.RejectReason, 0))
+	}
+	r := &TxValidation{
+		Txid:        res.Txid,
+		Vsize:       res.Vsize,
+		ValidatedBy: "backend",
+	}
+	if res.FeeSat != nil {
+		r.FeeSat = (*Amount)(res.FeeSat)
+		if res.Vsize > 0 {
+			r.FeeRate = float64(res.FeeSat.Int64()) / float64(res.Vsize)
+		}
+	}
+	return r, nil
+}
+
+// txVsize returns the virtual size of the serialized transaction, for coins not using the bitcoin serialization the size in bytes
+func txVsize(b []byte) int {
+	var tx wire.MsgTx
+	if err := tx.Deserialize(bytes.NewReader(b)); err != nil || tx.SerializeSize() != len(b) {
+		return len(b)
+	}
+	return (tx.SerializeSizeStripped()*3 + len(b) + 3) / 4
+}
+
+// minRelayFeePerKB returns the minimum relay fee of the backend in satoshis per 1000 vbytes
+func (w *Worker) minRelayFeePerKB() *big.Int {
+	ci, err := w.chain.GetChainInfo()
+	if err == nil && ci.RelayFee != "" {
+		fee, err := w.chainParser.AmountToBigInt(json.Number(ci.RelayFee))
+		if err == nil && fee.Sign() > 0 {
+			return &fee
+		}
+	}
+	return big.NewInt(defaultMinRelayFeePerKB)
+}
+
+// spentOutput returns the output txid:vout from the index or from the mempool, nil if the output is not known
+func (w *Worker) spentOutput(txid string, vout uint32) (*db.TxOutput, error) {
+	ta, err := w.db.GetTxAddresses(txid)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetTxAddresses %v", txid)
+	}
+	if ta != nil {
+		if int(vout) < len(ta.Outputs) {
+			return &ta.Outputs[vout], nil
+		}
+		return nil, nil
+	}
+	if w.mempool.GetTransactionTime(txid) == 0 {
+		return nil, nil
+	}
+	tx, err := w.chain.GetTransactionForMempool(txid)
+	if err != nil {
+		if err == bchain.ErrTxNotFound {
+			return nil, nil
+		}
+		return nil, errors.Annotatef(err, "GetTransactionForMempool %v", txid)
+	}
+	if int(vout) >= len(tx.Vout) {
+		return nil, nil
+	}
+	addrDesc, err := w.chainParser.GetAddrDescFromVout(&tx.Vout[vout])
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetAddrDescFromVout %v", txid)
+	}
+	return &db.TxOutput{AddrDesc: addrDesc, ValueSat: tx.Vout[vout].ValueSat}, nil
+}
+
+// mempoolSpendingTx returns the mempool transaction other than txid spending the output of the address, empty string if there is none
+func (w *Worker) mempoolSpendingTx(addrDesc bchain.AddressDescriptor, prevTxid string, prevVout uint32, txid string) (string, error) {
+	outpoints, err := w.mempool.GetAddrDescTransactions(addrDesc)
+	if err != nil {
+		return "", errors.Annotatef(err, "GetAddrDescTransactions %v", addrDesc)
+	}
+	for _, o := range outpoints {
+		// the inputs are stored in the mempool as bitwise negated vouts of the spent outputs
+		if o.Vout >= 0 || o.Txid == txid || uint32(^o.Vout) != prevVout {
+			continue
+		}
+		tx, err := w.chain.GetTransactionForMempool(o.Txid)
+		if err != nil {
+			if err == bchain.ErrTxNotFound {
+				continue
+			}
+			return "", errors.Annotatef(err, "GetTransactionForMempool %v", o.Txid)
+		}
+		for i := range tx.Vin {
+			if tx.Vin[i].Txid == prevTxid && tx.Vin[i].Vout == prevVout {
+				return o.Txid, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// validateTransactionInIndex checks the inputs of the transaction in the index and in the mempool and its fee
+func (w *Worker) validateTransactionInIndex(txHex string) (*TxValidation, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	b, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, NewAPIErrorWithCode("Invalid hex, "+err.Error(), ErrorCodeInvalidTx)
+	}
+	tx, err := w.chainParser.ParseTx(b)
+	if err != nil {
+		return nil, NewAPIErrorWithCode("Invalid transaction, "+err.Error(), ErrorCodeInvalidTx)
+	}
+	if len(tx.Vin) == 0 || len(tx.Vout) == 0 {
+		return nil, NewAPIErrorWithCode("Transaction without inputs or outputs", ErrorCodeInvalidTx)
+	}
+	if w.mempool.GetTransactionTime(tx.Txid) != 0 {
+		return nil, NewAPIErrorWithCode("Transaction already in mempool", ErrorCodeAlreadyInMempool)
+	}
+	ta, err := w.db.GetTxAddresses(tx.Txid)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetTxAddresses %v", tx.Txid)
+	}
+	if ta != nil {
+		return nil, NewAPIErrorWithCode("Transaction already in block chain", ErrorCodeAlreadyInChain)
+	}
+	var valueInSat, valueOutSat big.Int
+	for i := range tx.Vin {
+		vin := &tx.Vin[i]
+		if vin.Coinbase != "" {
+			return nil, NewAPIErrorWithCode("Coinbase transaction cannot be sent", ErrorCodeInvalidTx)
+		}
+		o, err := w.spentOutput(vin.Txid, vin.Vout)
+		if err != nil {
+			return nil, err
+		}
+		if o == nil {
+			return nil, NewAPIErrorWithCode(fmt.Sprintf("Input %d spends unknown output %v:%d", i, vin.Txid, vin.Vout), ErrorCodeMissingInputs)
+		}
+		if o.Spent {
+			return nil, NewAPIErrorWithCode(fmt.Sprintf("Input %d spends already spent output %v:%d", i, vin.Txid, vin.Vout), ErrorCodeInputsSpent)
+		}
+		conflict, err := w.mempoolSpendingTx(o.AddrDesc, vin.Txid, vin.Vout, tx.Txid)
+		if err != nil {
+			return nil, err
+		}
+		if conflict != "" {
+			return nil, NewAPIErrorWithCode(fmt.Sprintf("Input %d spends output %v:%d spent by mempool transaction %v", i, vin.Txid, vin.Vout, conflict), ErrorCodeMempoolConflict)
+		}
+		valueInSat.Add(&valueInSat, &o.ValueSat)
+	}
+	for i := range tx.Vout {
+		valueOutSat.Add(&valueOutSat, &tx.Vout[i].ValueSat)
+	}
+	var fee big.Int
+	fee.Sub(&valueInSat, &valueOutSat)
+	if fee.Sign() < 0 {
+		return nil, NewAPIErrorWithCode("Outputs exceed the inputs", ErrorCodeInvalidTx)
+	}
+	vsize := txVsize(b)
+	var minFee big.Int
+	minFee.Mul(w.minRelayFeePerKB(), big.NewInt(int64(vsize)))
+	minFee.Div(&minFee, big.NewInt(1000))
+	if fee.Cmp(&minFee) < 0 {
+		return nil, NewAPIErrorWithCode(fmt.Sprintf("min relay fee not met, %v < %v", fee.String(), minFee.String()), ErrorCodeFeeTooLow)
+	}
+	return &TxValidation{
+		Txid:        tx.Txid,
+		Vsize:       vsize,
+		FeeSat:      (*Amount)(&fee),
+		FeeRate:     float64(fee.Int64()) / float64(vsize),
+		ValidatedBy: "index",
+	}, nil
+}
@@ -0,0 +1,111 @@
+// +build unittest
+
+package api
+
+import (
+	"blockbook/bchain"
+	"errors"
+	"testing"
+)
+
+type testSpendingMempool struct {
+	bchain.Mempool
+	outpoints []bchain.Outpoint
+}
+
+func (m *testSpendingMempool) GetAddrDescTransactions(addrDesc bchain.AddressDescriptor) ([]bchain.Outpoint, error) {
+	return m.outpoints, nil
+}
+
+type testSpendingChain struct {
+	bchain.BlockChain
+	txs map[string]*bchain.Tx
+}
+
+func (c *testSpendingChain) GetTransactionForMempool(txid string) (*bchain.Tx, error) {
+	if tx, ok := c.txs[txid]; ok {
+		return tx, nil
+	}
+	return nil, bchain.ErrTxNotFound
+}
+
+func Test_sendTxErrorCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		reason  string
+		rpcCode int
+		want    string
+	}{
+		{name: "missing inputs", reason: "bad-txns-inputs-missingorspent", rpcCode: -25, want: ErrorCodeMissingInputs},
+		{name: "old missing inputs", reason: "Missing inputs", rpcCode: -25, want: ErrorCodeMissingInputs},
+		{name: "mempool conflict", reason: "txn-mempool-conflict (code 18)", rpcCode: -26, want: ErrorCodeMempoolConflict},
+		{name: "min relay fee", reason: "min relay fee not met, 100 < 141 (code 66)", rpcCode: -26, want: ErrorCodeFeeTooLow},
+		{name: "signature", reason: "non-mandatory-script-verify-flag (Signature must be zero for failed CHECK(MULTI)SIG operation)", rpcCode: -26, want: ErrorCodeInvalidSignature},
+		{name: "in mempool", reason: "txn-already-in-mempool", rpcCode: -26, want: ErrorCodeAlreadyInMempool},
+		{name: "in chain", reason: "Transaction already in block chain", rpcCode: -27, want: ErrorCodeAlreadyInChain},
+		{name: "decode", reason: "TX decode failed", rpcCode: -22, want: ErrorCodeInvalidTx},
+		{name: "dust", reason: "dust", rpcCode: -26, want: ErrorCodeDust},
+		{name: "unknown reason", reason: "non-final", rpcCode: -26, want: ErrorCodeRejected},
+		{name: "unknown", reason: "Invalid data", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sendTxErrorCode(tt.reason, tt.rpcCode); got != tt.want {
+				t.Errorf("sendTxErrorCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendTxError(t *testing.T) {
+	err := SendTxError(&bchain.RPCError{Code: -26, Message: "txn-mempool-conflict"})
+	apiErr, ok := err.(*APIError)
+	if !ok || !apiErr.Public || apiErr.Code != ErrorCodeMempoolConflict {
+		t.Errorf("SendTxError() = %+v", err)
+	}
+	apiErr = SendTxError(errors.New("Invalid data")).(*APIError)
+	if apiErr.Text != "Invalid data" || apiErr.Code != "" {
+		t.Errorf("SendTxError() = %+v", apiErr)
+	}
+}
+
+func TestWorker_mempoolSpendingTx(t *testing.T) {
+	w := &Worker{
+		workerState: &workerState{mempool: &testSpendingMempool{outpoints: []bchain.Outpoint{
+			// inputs of the mempool transactions spending the outputs of the address
+			{Txid: "other", Vout: ^int32(1)},
+			{Txid: "missing", Vout: ^int32(3)},
+			{Txid: "conflict", Vout: ^int32(3)},
+			{Txid: "sent", Vout: ^int32(3)},
+			{Txid: "conflict", Vout: 0},
+		}}},
+		chain: &testSpendingChain{txs: map[string]*bchain.Tx{
+			"other": {Txid: "other", Vin: []bchain.Vin{{Txid: "prev", Vout: 1}}},
+			// the conflicting input is not at the index of the spent vout
+			"conflict": {Txid: "conflict", Vin: []bchain.Vin{{Txid: "another", Vout: 3}, {Txid: "prev", Vout: 3}}},
+			"sent":     {Txid: "sent", Vin: []bchain.Vin{{Txid: "prev", Vout: 3}}},
+		}},
+	}
+	tests := []struct {
+		name     string
+		prevTxid string
+		prevVout uint32
+		want     string
+	}{
+		{name: "conflict", prevTxid: "prev", prevVout: 3, want: "conflict"},
+		{name: "other txid", prevTxid: "unknown", prevVout: 3, want: ""},
+		{name: "other vout", prevTxid: "prev", prevVout: 2, want: ""},
+		{name: "other spent output", prevTxid: "prev", prevVout: 1, want: "other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.mempoolSpendingTx(nil, tt.prevTxid, tt.prevVout, "sent")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("mempoolSpendingTx() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"math/big"
+	"strings"
 	"time"
 )
 
@@ -39,6 +40,8 @@ var ErrUnsupportedXpub = errors.New("XPUB not supported")
 type APIError struct {
 	Text   string
 	Public bool
+	// Code classifies the error for the clients, it is empty if the error is not classified
+	Code string
 }
 
 func (e *APIError) Error() string {
@@ -53,6 +56,15 @@ func NewAPIError(s string, public bool) error {
 	}
 }
 
+// NewAPIErrorWithCode creates public ApiError with the error code
+func NewAPIErrorWithCode(s string, code string) error {
+	return &APIError{
+		Text:   s,
+		Public: true,
+		Code:   code,
+	}
+}
+
 // Amount is datatype holding amounts
 type Amount big.Int
 
@@ -69,6 +81,15 @@ func (a *Amount) MarshalJSON() (out []byte, err error) {
 	return []byte(`"` + (*big.Int)(a).String() + `"`), nil
 }
 
+// UnmarshalJSON Amount deserialization, the amount can be a string or a number
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if _, ok := (*big.Int)(a).SetString(s, 10); !ok {
+		return errors.New("Invalid amount " + string(data))
+	}
+	return nil
+}
+
 func (a *Amount) String() string {
 	if a == nil {
 		return ""
@@ -112,6 +133,7 @@ type Vin struct {
 	Hex       string                   `json:"hex,omitempty"`
 	Asm       string                   `json:"asm,omitempty"`
 	Coinbase  string                   `json:"coinbase,omitempty"`
+	Token     *UtxoToken               `json:"token,omitempty"`
 }
 
 // Vout contains information about single transaction output
@@ -128,6 +150,7 @@ type Vout struct {
 	Addresses   []string                 `json:"addresses"`
 	IsAddress   bool                     `json:"isAddress"`
 	Type        string                   `json:"type,omitempty"`
+	Token       *UtxoToken               `json:"token,omitempty"`
 }
 
 // TokenType specifies type of token
@@ -141,17 +164,34 @@ const XPUBAddressTokenType TokenType = "XPUBAddress"
 
 // Token contains info about tokens held by an address
 type Token struct {
-	Type             TokenType `json:"type"`
-	Name             string    `json:"name"`
-	Path             string    `json:"path,omitempty"`
-	Contract         string    `json:"contract,omitempty"`
-	Transfers        int       `json:"transfers"`
-	Symbol           string    `json:"symbol,omitempty"`
-	Decimals         int       `json:"decimals,omitempty"`
-	BalanceSat       *Amount   `json:"balance,omitempty"`
-	TotalReceivedSat *Amount   `json:"totalReceived,omitempty"`
-	TotalSentSat     *Amount   `json:"totalSent,omitempty"`
-	ContractIndex    string    `json:"-"`
+	Type             TokenType  `json:"type"`
+	Name             string     `json:"name"`
+	Path             string     `json:"path,omitempty"`
+	Contract         string     `json:"contract,omitempty"`
+	Transfers        int        `json:"transfers"`
+	Symbol           string     `json:"symbol,omitempty"`
+	Decimals         int        `json:"decimals,omitempty"`
+	BalanceSat       *Amount    `json:"balance,omitempty"`
+	TotalReceivedSat *Amount    `json:"totalReceived,omitempty"`
+	TotalSentSat     *Amount    `json:"totalSent,omitempty"`
+	Nfts             []TokenNft `json:"nfts,omitempty"`
+	ContractIndex    string     `json:"-"`
+}
+
+// TokenNft is non-fungible token held by an address
+type TokenNft struct {
+	Capability string `json:"capability"`
+	Commitment string `json:"commitment,omitempty"`
+}
+
+// UtxoToken is token carried by an unspent transaction output
+type UtxoToken struct {
+	Type          TokenType `json:"type"`
+	ID            string    `json:"id"`
+	AmountSat     *Amount   `json:"amount,omitempty"`
+	Decimals      int       `json:"decimals,omitempty"`
+	NftCapability string    `json:"nftCapability,omitempty"`
+	NftCommitment string    `json:"nftCommitment,omitempty"`
 }
 
 // TokenTransfer contains info about a token transfer done in a transaction
@@ -192,6 +232,8 @@ type Tx struct {
 	FeesSat          *Amount           `json:"fees,omitempty"`
 	Hex              string            `json:"hex,omitempty"`
 	Rbf              bool              `json:"rbf,omitempty"`
+	InstantLock      bool              `json:"instantLock,omitempty"`
+	ChainLock        bool              `json:"chainLock,omitempty"`
 	CoinSpecificData interface{}       `json:"-"`
 	CoinSpecificJSON json.RawMessage   `json:"-"`
 	TokenTransfers   []TokenTransfer   `json:"tokenTransfers,omitempty"`
@@ -241,11 +283,14 @@ type AddressFilter struct {
 	TokensToReturn TokensToReturn
 	// OnlyConfirmed set to true will ignore mempool transactions; mempool is also ignored if FromHeight/ToHeight filter is specified
 	OnlyConfirmed bool
+	// Cursor returned as NextCursor by the previous request; if set, the page is ignored and the transactions following the cursor are returned
+	Cursor string
 }
 
 // Address holds information about address and its transactions
 type Address struct {
 	Paging
+	NextCursor            string                `json:"nextCursor,omitempty"`
 	AddrStr               string                `json:"address"`
 	BalanceSat            *Amount               `json:"balance"`
 	TotalReceivedSat      *Amount               `json:"totalReceived,omitempty"`
@@ -265,17 +310,110 @@ type Address struct {
 	XPubAddresses map[string]struct{} `json:"-"`
 }
 
+// AddressesItem is the result for one address of the bulk addresses request
+type AddressesItem struct {
+	AddrStr string `json:"address"`
+	*Address
+	Utxos Utxos  `json:"utxos,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// HistoryRow is one confirmed transaction in the exported history of an address or xpub
+type HistoryRow struct {
+	Time        int64
+	Height      uint32
+	Txid        string
+	ReceivedSat *big.Int
+	SentSat     *big.Int
+	// FeeSat is set only for the transactions spending the funds of the address or xpub
+	FeeSat *big.Int
+	// BalanceSat is the confirmed balance after the transaction
+	BalanceSat *big.Int
+}
+
+// BuildTxOutput is an output of the transaction built by BuildTx
+type BuildTxOutput struct {
+	Address   string  `json:"address"`
+	AmountSat *Amount `json:"amount"`
+}
+
+// BuildTxRequest is the request to build unsigned transaction spending the utxos of an xpub or address
+type BuildTxRequest struct {
+	Descriptor    string          `json:"descriptor"`
+	Outputs       []BuildTxOutput `json:"outputs"`
+	FeeRate       float64         `json:"feeRate"`
+	Strategy      string          `json:"strategy"`
+	OnlyConfirmed bool            `json:"confirmed"`
+	Fingerprint   string          `json:"fingerprint"`
+	Gap           int             `json:"gap"`
+}
+
+// BuildTxResult is the unsigned transaction in the psbt format with the selected inputs and the change
+type BuildTxResult struct {
+	Psbt          string  `json:"psbt"`
+	FeeSat        *Amount `json:"fee"`
+	Vsize         int     `json:"vsize"`
+	Inputs        Utxos   `json:"inputs"`
+	ChangeAddress string  `json:"changeAddress,omitempty"`
+	ChangePath    string  `json:"changePath,omitempty"`
+	ChangeSat     *Amount `json:"change,omitempty"`
+}
+
+// TxValidation is the result of the successful validation of a transaction without sending it
+type TxValidation struct {
+	Txid    string  `json:"txid"`
+	Vsize   int     `json:"vsize,omitempty"`
+	FeeSat  *Amount `json:"fee,omitempty"`
+	FeeRate float64 `json:"feeRate,omitempty"`
+	// ValidatedBy is "backend" if the transaction was checked by the backend (testmempoolaccept), "index" if by the checks against the index and the mempool
+	ValidatedBy string `json:"validatedBy"`
+}
+
+// PsbtInput is the input of the decoded psbt with the state of its signing
+type PsbtInput struct {
+	Txid      string   `json:"txid"`
+	Vout      uint32   `json:"vout"`
+	Sequence  uint32   `json:"sequence"`
+	ValueSat  *Amount  `json:"value,omitempty"`
+	Addresses []string `json:"addresses,omitempty"`
+	IsAddress bool     `json:"isAddress"`
+	Finalized bool     `json:"finalized"`
+	// RequiredSignatures is the number of signatures necessary to spend the input, Signatures the number of present signatures
+	RequiredSignatures int `json:"requiredSignatures,omitempty"`
+	Signatures         int `json:"signatures,omitempty"`
+	// MissingSignatures are the public keys which can still sign the input
+	MissingSignatures []string `json:"missingSignatures,omitempty"`
+	Error             string   `json:"error,omitempty"`
+}
+
+// PsbtInfo is the decoded psbt, the values of the inputs are taken from the index if possible
+type PsbtInfo struct {
+	Txid     string      `json:"txid"`
+	Version  int32       `json:"version"`
+	LockTime uint32      `json:"lockTime"`
+	Vin      []PsbtInput `json:"vin"`
+	Vout     []Vout      `json:"vout"`
+	// FeeSat and FeeRate are missing if the value of some input is not known
+	FeeSat *Amount `json:"fee,omitempty"`
+	// FeeRate is in satoshis per vbyte, computed from the estimated vsize of the signed transaction
+	FeeRate float64 `json:"feeRate,omitempty"`
+	Vsize   int     `json:"vsize"`
+	// Complete is true if all inputs have enough signatures to be finalized
+	Complete bool `json:"complete"`
+}
+
 // Utxo is one unspent transaction output
 type Utxo struct {
-	Txid          string  `json:"txid"`
-	Vout          int32   `json:"vout"`
-	AmountSat     *Amount `json:"value"`
-	Height        int     `json:"height,omitempty"`
-	Confirmations int     `json:"confirmations"`
-	Address       string  `json:"address,omitempty"`
-	Path          string  `json:"path,omitempty"`
-	Locktime      uint32  `json:"lockTime,omitempty"`
-	Coinbase      bool    `json:"coinbase,omitempty"`
+	Txid          string     `json:"txid"`
+	Vout          int32      `json:"vout"`
+	AmountSat     *Amount    `json:"value"`
+	Height        int        `json:"height,omitempty"`
+	Confirmations int        `json:"confirmations"`
+	Address       string     `json:"address,omitempty"`
+	Path          string     `json:"path,omitempty"`
+	Locktime      uint32     `json:"lockTime,omitempty"`
+	Coinbase      bool       `json:"coinbase,omitempty"`
+	Token         *UtxoToken `json:"token,omitempty"`
 }
 
 // Utxos is array of Utxo
@@ -302,6 +440,29 @@ type Blocks struct {
 	Blocks []db.BlockInfo `json:"blocks"`
 }
 
+// OrphanedBlock is a block disconnected from the chain by a reorg or a rollback with the fate of its transactions,
+// the fate is pending until the index is synchronized again after the disconnection
+type OrphanedBlock struct {
+	Hash         string `json:"hash"`
+	Height       uint32 `json:"height"`
+	Time         int64  `json:"time,omitempty"`
+	Size         uint32 `json:"size,omitempty"`
+	Disconnected int64  `json:"disconnected"`
+	Cause        string `json:"cause"`
+	ReplacedBy   string `json:"replacedBy,omitempty"`
+	Txs          int    `json:"txs"`
+	Reconfirmed  int    `json:"reconfirmed"`
+	InMempool    int    `json:"inMempool"`
+	Dropped      int    `json:"dropped"`
+	FatePending  bool   `json:"fatePending,omitempty"`
+}
+
+// OrphanedBlocks is a list of orphaned blocks with paging
+type OrphanedBlocks struct {
+	Paging
+	Blocks []OrphanedBlock `json:"blocks"`
+}
+
 // BlockInfo contains extended block header data and a list of block txids
 type BlockInfo struct {
 	Hash          string      `json:"hash"`
@@ -324,6 +485,7 @@ type Block struct {
 	Paging
 	BlockInfo
 	TxCount      int   `json:"txCount"`
+	ChainLock    bool  `json:"chainLock,omitempty"`
 	Transactions []*Tx `json:"txs,omitempty"`
 }
 
@@ -383,3 +545,46 @@ type MempoolTxids struct {
 	Mempool     []MempoolTxid `json:"mempool"`
 	MempoolSize int           `json:"mempoolSize"`
 }
+
+// NameOperation contains an operation with a name, for example Namecoin name_update
+type NameOperation struct {
+	Txid      string `json:"txid"`
+	Vout      int32  `json:"vout"`
+	Height    uint32 `json:"height"`
+	Operation string `json:"operation"`
+	Value     string `json:"value,omitempty"`
+	Address   string `json:"address,omitempty"`
+}
+
+// Name contains the current state of a name and its history
+type Name struct {
+	Name         string          `json:"name"`
+	Value        string          `json:"value"`
+	Address      string          `json:"address,omitempty"`
+	Height       uint32          `json:"height"`
+	ExpiryHeight uint32          `json:"expiryHeight"`
+	Expired      bool            `json:"expired"`
+	History      []NameOperation `json:"history"`
+}
+
+// MerkleProof contains merkle inclusion proof of a transaction
+// the block_height, merkle and pos fields are compatible with Electrum blockchain.transaction.get_merkle
+type MerkleProof struct {
+	BlockHash   string   `json:"blockHash"`
+	BlockHeight uint32   `json:"block_height"`
+	Merkle      []string `json:"merkle"`
+	Pos         int      `json:"pos"`
+}
+
+// BlockFilter contains BIP158 basic filter of a block with the filter header
+type BlockFilter struct {
+	Height    uint32 `json:"height"`
+	BlockHash string `json:"blockHash"`
+	Filter    string `json:"filter"`
+	Header    string `json:"header"`
+}
+
+// BlockFilters contains basic filters of a range of blocks
+type BlockFilters struct {
+	BlockFilters []BlockFilter `json:"blockFilters"`
+}
@@ -49,3 +49,19 @@ func TestAmount_MarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestAmount_UnmarshalJSON(t *testing.T) {
+	var a struct {
+		A1 *Amount `json:"a1"`
+		A2 Amount  `json:"a2"`
+	}
+	if err := json.Unmarshal([]byte(`{"a1":"123456","a2":787901}`), &a); err != nil {
+		t.Fatal(err)
+	}
+	if (*big.Int)(a.A1).Int64() != 123456 || (*big.Int)(&a.A2).Int64() != 787901 {
+		t.Errorf("json.Unmarshal() = %v, %v", a.A1, &a.A2)
+	}
+	if err := json.Unmarshal([]byte(`{"a1":"1.5"}`), &a); err == nil {
+		t.Error("json.Unmarshal() expected error")
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"blockbook/common"
 	"blockbook/db"
 	"bytes"
+	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -21,9 +23,19 @@ import (
 
 // Worker is handle to api worker
 type Worker struct {
-	db          *db.RocksDB
-	txCache     *db.TxCache
-	chain       bchain.BlockChain
+	*workerState
+	db      *db.TracedDB
+	txCache *db.TracedTxCache
+	chain   bchain.BlockChain
+	// ctx is the context of the traced request, nil if the worker is not traced
+	ctx context.Context
+}
+
+// workerState is shared by the worker and by the workers of the traced requests created from it
+type workerState struct {
+	rdb         *db.RocksDB
+	cache       *db.TxCache
+	backend     bchain.BlockChain
 	chainParser bchain.BlockChainParser
 	chainType   bchain.ChainType
 	mempool     bchain.Mempool
@@ -32,16 +44,48 @@ type Worker struct {
 
 // NewWorker creates new api worker
 func NewWorker(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, is *common.InternalState) (*Worker, error) {
-	w := &Worker{
-		db:          db,
-		txCache:     txCache,
-		chain:       chain,
+	s := &workerState{
+		rdb:         db,
+		cache:       txCache,
+		backend:     chain,
 		chainParser: chain.GetChainParser(),
 		chainType:   chain.GetChainParser().GetChainType(),
 		mempool:     mempool,
 		is:          is,
 	}
-	return w, nil
+	return s.worker(nil), nil
+}
+
+// worker returns the worker accessing the db, the cache and the blockchain traced as spans of the request in ctx, nil ctx means no tracing
+func (s *workerState) worker(ctx context.Context) *Worker {
+	w := &Worker{
+		workerState: s,
+		db:          s.rdb.Traced(ctx),
+		txCache:     s.cache.Traced(ctx),
+		chain:       s.backend,
+		ctx:         ctx,
+	}
+	if ctx != nil {
+		w.chain = bchain.BlockChainWithContext(s.backend, ctx)
+	}
+	return w
+}
+
+// WithContext returns the worker tracing its calls as spans of the request in ctx, if the tracing is enabled
+func (w *Worker) WithContext(ctx context.Context) *Worker {
+	if ctx == nil || !common.TracingEnabled() {
+		return w
+	}
+	return w.worker(ctx)
+}
+
+// startSpan starts the span of the call, the returned worker traces the nested calls as children of the span
+func (w *Worker) startSpan(name string, attrs ...common.SpanAttr) (*Worker, *common.Span) {
+	ctx, span := common.StartSpan(w.ctx, name, attrs...)
+	if ctx == nil || ctx == w.ctx {
+		return w, span
+	}
+	return w.worker(ctx), span
 }
 
 func (w *Worker) getAddressesFromVout(vout *bchain.Vout) (bchain.AddressDescriptor, []string, bool, error) {
@@ -54,8 +98,21 @@ func (w *Worker) getAddressesFromVout(vout *bchain.Vout) (bchain.AddressDescript
 }
 
 // setSpendingTxToVout is helper function, that finds transaction that spent given output and sets it to the output
-// there is no direct index for the operation, it must be found using addresses -> txaddresses -> tx
+// the spending is looked up in the spentBy index, the outputs spent before the index was created
+// must be found using addresses -> txaddresses -> tx
 func (w *Worker) setSpendingTxToVout(vout *Vout, txid string, height uint32) error {
+	if w.chainType == bchain.ChainBitcoinType {
+		sb, err := w.db.GetSpentBy(txid, uint32(vout.N))
+		if err != nil {
+			return err
+		}
+		if sb != nil {
+			vout.SpentTxID = sb.Txid
+			vout.SpentHeight = int(sb.Height)
+			vout.SpentIndex = int(sb.Vin)
+			return nil
+		}
+	}
 	err := w.db.GetAddrDescTransactions(vout.AddrDesc, height, maxUint32, func(t string, height uint32, indexes []int32) error {
 		for _, index := range indexes {
 			// take only inputs
@@ -93,6 +150,13 @@ func (w *Worker) setSpendingTxToVout(vout *Vout, txid string, height uint32) err
 // GetSpendingTxid returns transaction id of transaction that spent given output
 func (w *Worker) GetSpendingTxid(txid string, n int) (string, error) {
 	start := time.Now()
+	if w.chainType == bchain.ChainBitcoinType && n >= 0 {
+		// invalid txid is reported by GetTransaction below
+		if sb, err := w.db.GetSpentBy(txid, uint32(n)); err == nil && sb != nil {
+			glog.Info("GetSpendingTxid ", txid, " ", n, " finished in ", time.Since(start))
+			return sb.Txid, nil
+		}
+	}
 	tx, err := w.GetTransaction(txid, false, false)
 	if err != nil {
 		return "", err
@@ -109,7 +173,9 @@ func (w *Worker) GetSpendingTxid(txid string, n int) (string, error) {
 }
 
 // GetTransaction reads transaction data from txid
-func (w *Worker) GetTransaction(txid string, spendingTxs bool, specificJSON bool) (*Tx, error) {
+func (w *Worker) GetTransaction(txid string, spendingTxs bool, specificJSON bool) (tx *Tx, err error) {
+	w, span := w.startSpan("api.GetTransaction", common.StringAttr("txid", txid))
+	defer func() { common.EndSpan(span, err) }()
 	bchainTx, height, err := w.txCache.GetTransaction(txid)
 	if err != nil {
 		if err == bchain.ErrTxNotFound {
@@ -143,6 +209,7 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 	var pValInSat *big.Int
 	vins := make([]Vin, len(bchainTx.Vin))
 	rbf := false
+	utxoTokens := w.chainType == bchain.ChainBitcoinType && w.chainParser.GetUtxoTokenType() != ""
 	for i := range bchainTx.Vin {
 		bchainVin := &bchainTx.Vin[i]
 		vin := &vins[i]
@@ -194,6 +261,9 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 						if err != nil {
 							glog.Errorf("getAddressesFromVout error %v, vout %+v", err, vout)
 						}
+						if utxoTokens {
+							vin.Token = w.getUtxoTokenFromVout(vout)
+						}
 					}
 				} else {
 					if len(tas.Outputs) > int(vin.Vout) {
@@ -204,6 +274,9 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 						if err != nil {
 							glog.Errorf("output.Addresses error %v, tx %v, output %v", err, bchainVin.Txid, i)
 						}
+						if utxoTokens {
+							vin.Token = w.getIndexedUtxoToken(bchainVin.Txid, vin.Vout)
+						}
 					}
 				}
 				if vin.ValueSat != nil {
@@ -233,6 +306,9 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 		if err != nil {
 			glog.V(2).Infof("getAddressesFromVout error %v, %v, output %v", err, bchainTx.Txid, bchainVout.N)
 		}
+		if utxoTokens {
+			vout.Token = w.getUtxoTokenFromVout(bchainVout)
+		}
 		if ta != nil {
 			vout.Spent = ta.Outputs[i].Spent
 			if spendingTxs && vout.Spent {
@@ -250,6 +326,12 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 			feesSat.SetUint64(0)
 		}
 		pValInSat = &valInSat
+		if bchainTx.Confirmations > 0 && w.chainParser.GetContractTokenType() != "" {
+			tokens = w.getContractTokenTransfers(bchainTx.Txid)
+		}
+		if utxoTokens {
+			tokens = append(tokens, utxoTokenTransfers(vins, vouts)...)
+		}
 	} else if w.chainType == bchain.ChainEthereumType {
 		ets, err := w.chainParser.EthereumTypeGetErc20FromTx(bchainTx)
 		if err != nil {
@@ -323,6 +405,8 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 		Version:          bchainTx.Version,
 		Hex:              bchainTx.Hex,
 		Rbf:              rbf,
+		InstantLock:      w.chain.IsInstantLocked(bchainTx.Txid),
+		ChainLock:        height > 0 && w.isChainLocked(uint32(height)),
 		Vin:              vins,
 		Vout:             vouts,
 		CoinSpecificData: bchainTx.CoinSpecificData,
@@ -333,37 +417,36 @@ func (w *Worker) GetTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 	return r, nil
 }
 
+// matchIndexes returns true if the transaction with given input/output indexes of the address passes the vout filter
+func (filter *AddressFilter) matchIndexes(indexes []int32) bool {
+	if filter.Vout == AddressFilterVoutOff {
+		return true
+	}
+	for _, index := range indexes {
+		vout := index
+		if vout < 0 {
+			vout = ^vout
+		}
+		if (filter.Vout == AddressFilterVoutInputs && index < 0) ||
+			(filter.Vout == AddressFilterVoutOutputs && index >= 0) ||
+			(vout == int32(filter.Vout)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (w *Worker) getAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool, filter *AddressFilter, maxResults int) ([]string, error) {
 	var err error
 	txids := make([]string, 0, 4)
-	var callback db.GetTransactionsCallback
-	if filter.Vout == AddressFilterVoutOff {
-		callback = func(txid string, height uint32, indexes []int32) error {
+	callback := func(txid string, height uint32, indexes []int32) error {
+		if filter.matchIndexes(indexes) {
 			txids = append(txids, txid)
 			if len(txids) >= maxResults {
 				return &db.StopIteration{}
 			}
-			return nil
-		}
-	} else {
-		callback = func(txid string, height uint32, indexes []int32) error {
-			for _, index := range indexes {
-				vout := index
-				if vout < 0 {
-					vout = ^vout
-				}
-				if (filter.Vout == AddressFilterVoutInputs && index < 0) ||
-					(filter.Vout == AddressFilterVoutOutputs && index >= 0) ||
-					(vout == int32(filter.Vout)) {
-					txids = append(txids, txid)
-					if len(txids) >= maxResults {
-						return &db.StopIteration{}
-					}
-					break
-				}
-			}
-			return nil
 		}
+		return nil
 	}
 	if mempool {
 		uniqueTxs := make(map[string]struct{})
@@ -437,6 +520,7 @@ func (w *Worker) txFromTxAddress(txid string, ta *db.TxAddresses, bi *db.BlockIn
 		tai := &ta.Inputs[i]
 		vin := &vins[i]
 		vin.N = i
+		vin.AddrDesc = tai.AddrDesc
 		vin.ValueSat = (*Amount)(&tai.ValueSat)
 		valInSat.Add(&valInSat, &tai.ValueSat)
 		vin.Addresses, vin.IsAddress, err = tai.Addresses(w.chainParser)
@@ -449,6 +533,7 @@ func (w *Worker) txFromTxAddress(txid string, ta *db.TxAddresses, bi *db.BlockIn
 		tao := &ta.Outputs[i]
 		vout := &vouts[i]
 		vout.N = i
+		vout.AddrDesc = tao.AddrDesc
 		vout.ValueSat = (*Amount)(&tao.ValueSat)
 		valOutSat.Add(&valOutSat, &tao.ValueSat)
 		vout.Addresses, vout.IsAddress, err = tao.Addresses(w.chainParser)
@@ -471,12 +556,19 @@ func (w *Worker) txFromTxAddress(txid string, ta *db.TxAddresses, bi *db.BlockIn
 		Txid:          txid,
 		ValueInSat:    (*Amount)(&valInSat),
 		ValueOutSat:   (*Amount)(&valOutSat),
+		InstantLock:   w.chain.IsInstantLocked(txid),
+		ChainLock:     w.isChainLocked(ta.Height),
 		Vin:           vins,
 		Vout:          vouts,
 	}
 	return r
 }
 
+// isChainLocked returns true if the block at given height is ChainLocked
+func (w *Worker) isChainLocked(height uint32) bool {
+	return height > 0 && height <= w.chain.GetBestChainLockHeight()
+}
+
 func computePaging(count, page, itemsOnPage int) (Paging, int, int, int) {
 	from := page * itemsOnPage
 	totalPages := (count - 1) / itemsOnPage
@@ -665,7 +757,9 @@ func (w *Worker) getAddrDescAndNormalizeAddress(address string) (bchain.AddressD
 }
 
 // GetAddress computes address value and gets transactions for given address
-func (w *Worker) GetAddress(address string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter) (*Address, error) {
+func (w *Worker) GetAddress(address string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter) (a *Address, err error) {
+	w, span := w.startSpan("api.GetAddress", common.IntAttr("page", page))
+	defer func() { common.EndSpan(span, err) }()
 	start := time.Now()
 	page--
 	if page < 0 {
@@ -675,7 +769,6 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 		ba                       *db.AddrBalance
 		tokens                   []Token
 		erc20c                   *bchain.Erc20Contract
-		txm                      []string
 		txs                      []*Tx
 		txids                    []string
 		pg                       Paging
@@ -685,7 +778,12 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 		unconfirmedTxs           int
 		nonTokenTxs              int
 		totalResults             int
+		nextCursor               string
 	)
+	cursor, err := parseTxCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
 	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
 	if err != nil {
 		return nil, err
@@ -698,12 +796,32 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 		}
 		nonce = strconv.Itoa(int(n))
 	} else {
+		// utxos are needed to compute the balances of utxo tokens
+		utxoTokens := option > AccountDetailsBasic && w.chainParser.GetUtxoTokenType() != ""
+		var detail db.AddressBalanceDetail = db.AddressBalanceDetailNoUTXO
+		if utxoTokens {
+			detail = db.AddressBalanceDetailUTXO
+		}
 		// ba can be nil if the address is only in mempool!
-		ba, err = w.db.GetAddrDescBalance(addrDesc, db.AddressBalanceDetailNoUTXO)
+		ba, err = w.db.GetAddrDescBalance(addrDesc, detail)
 		if err != nil {
 			return nil, NewAPIError(fmt.Sprintf("Address not found, %v", err), true)
 		}
+		// contract tokens can be held also by addresses without any transactions
+		if option > AccountDetailsBasic && w.chainParser.GetContractTokenType() != "" {
+			tokens, err = w.getContractTokens(addrDesc, option)
+			if err != nil {
+				return nil, err
+			}
+		}
 		if ba != nil {
+			if utxoTokens {
+				ut, err := w.getUtxoTokenBalances(addrDesc, ba)
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, ut...)
+			}
 			// totalResults is known only if there is no filter
 			if filter.Vout == AddressFilterVoutOff && filter.FromHeight == 0 && filter.ToHeight == 0 {
 				totalResults = int(ba.Txs)
@@ -719,49 +837,58 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 	}
 	// process mempool, only if toHeight is not specified
 	if filter.ToHeight == 0 && !filter.OnlyConfirmed {
-		txm, err = w.getAddressTxids(addrDesc, true, filter, maxInt)
+		txm, err := w.getAddrDescMempoolTxs(addrDesc, filter)
 		if err != nil {
-			return nil, errors.Annotatef(err, "getAddressTxids %v true", addrDesc)
+			return nil, err
 		}
-		for _, txid := range txm {
-			tx, err := w.GetTransaction(txid, false, false)
-			// mempool transaction may fail
-			if err != nil || tx == nil {
-				glog.Warning("GetTransaction in mempool: ", err)
-			} else {
-				// skip already confirmed txs, mempool may be out of sync
-				if tx.Confirmations == 0 {
-					unconfirmedTxs++
-					uBalSat.Add(&uBalSat, tx.getAddrVoutValue(addrDesc))
-					uBalSat.Sub(&uBalSat, tx.getAddrVinValue(addrDesc))
-					if page == 0 {
-						if option == AccountDetailsTxidHistory {
-							txids = append(txids, tx.Txid)
-						} else if option >= AccountDetailsTxHistoryLight {
-							txs = append(txs, tx)
-						}
-					}
+		for _, tx := range txm {
+			unconfirmedTxs++
+			uBalSat.Add(&uBalSat, tx.getAddrVoutValue(addrDesc))
+			uBalSat.Sub(&uBalSat, tx.getAddrVinValue(addrDesc))
+			// mempool txs are returned only on the first page
+			if page == 0 && cursor == nil {
+				if option == AccountDetailsTxidHistory {
+					txids = append(txids, tx.Txid)
+				} else if option >= AccountDetailsTxHistoryLight {
+					txs = append(txs, tx)
 				}
 			}
 		}
 	}
 	// get tx history if requested by option or check mempool if there are some transactions for a new address
 	if option >= AccountDetailsTxidHistory {
-		txc, err := w.getAddressTxids(addrDesc, false, filter, (page+1)*txsOnPage)
-		if err != nil {
-			return nil, errors.Annotatef(err, "getAddressTxids %v false", addrDesc)
+		var txc []string
+		// the first page and the pages requested by cursor are read from the cursor position, which provides the next cursor
+		if page == 0 || cursor != nil {
+			var next *txCursor
+			txc, next, err = w.getAddressTxidsFromCursor(addrDesc, filter, cursor, txsOnPage)
+			if err != nil {
+				return nil, errors.Annotatef(err, "getAddressTxidsFromCursor %v", addrDesc)
+			}
+			if next != nil {
+				nextCursor = next.String()
+			}
+		} else {
+			txc, err = w.getAddressTxids(addrDesc, false, filter, (page+1)*txsOnPage)
+			if err != nil {
+				return nil, errors.Annotatef(err, "getAddressTxids %v false", addrDesc)
+			}
 		}
 		bestheight, _, err := w.db.GetBestBlock()
 		if err != nil {
 			return nil, errors.Annotatef(err, "GetBestBlock")
 		}
 		var from, to int
-		pg, from, to, page = computePaging(len(txc), page, txsOnPage)
-		if len(txc) >= txsOnPage {
-			if totalResults < 0 {
-				pg.TotalPages = -1
-			} else {
-				pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+		if cursor != nil {
+			pg, from, to = Paging{ItemsOnPage: txsOnPage}, 0, len(txc)
+		} else {
+			pg, from, to, page = computePaging(len(txc), page, txsOnPage)
+			if len(txc) >= txsOnPage {
+				if totalResults < 0 {
+					pg.TotalPages = -1
+				} else {
+					pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+				}
 			}
 		}
 		for i := from; i < to; i++ {
@@ -783,6 +910,7 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 	}
 	r := &Address{
 		Paging:                pg,
+		NextCursor:            nextCursor,
 		AddrStr:               address,
 		BalanceSat:            (*Amount)(&ba.BalanceSat),
 		TotalReceivedSat:      (*Amount)(totalReceived),
@@ -801,6 +929,26 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 	return r, nil
 }
 
+// getAddrDescMempoolTxs returns the unconfirmed transactions of the address descriptor passing the filter
+func (w *Worker) getAddrDescMempoolTxs(addrDesc bchain.AddressDescriptor, filter *AddressFilter) ([]*Tx, error) {
+	txm, err := w.getAddressTxids(addrDesc, true, filter, maxInt)
+	if err != nil {
+		return nil, errors.Annotatef(err, "getAddressTxids %v true", addrDesc)
+	}
+	txs := make([]*Tx, 0, len(txm))
+	for _, txid := range txm {
+		tx, err := w.GetTransaction(txid, false, false)
+		// mempool transaction may fail
+		if err != nil || tx == nil {
+			glog.Warning("GetTransaction in mempool: ", err)
+		} else if tx.Confirmations == 0 {
+			// skip already confirmed txs, mempool may be out of sync
+			txs = append(txs, tx)
+		}
+	}
+	return txs, nil
+}
+
 func (w *Worker) waitForBackendSync() {
 	// wait a short time if blockbook is synchronizing with backend
 	inSync, _, _ := w.is.GetSyncState()
@@ -812,11 +960,279 @@ func (w *Worker) waitForBackendSync() {
 	}
 }
 
+// getContractTokens returns contract tokens (QRC20) of the address of Bitcoin type coin
+func (w *Worker) getContractTokens(addrDesc bchain.AddressDescriptor, details AccountDetails) ([]Token, error) {
+	acs, err := w.db.GetAddrDescTokenContracts(addrDesc)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetAddrDescTokenContracts %v", addrDesc)
+	}
+	tokenType := TokenType(w.chainParser.GetContractTokenType())
+	tokens := make([]Token, len(acs))
+	for i, c := range acs {
+		var b *big.Int
+		ci, err := w.chain.EthereumTypeGetErc20ContractInfo(c.Contract)
+		if err != nil {
+			return nil, errors.Annotatef(err, "EthereumTypeGetErc20ContractInfo %v", c.Contract)
+		}
+		if ci == nil {
+			contract := hex.EncodeToString(c.Contract)
+			ci = &bchain.Erc20Contract{
+				Contract: contract,
+				Name:     contract,
+			}
+		} else if details >= AccountDetailsTokenBalances {
+			// do not read contract balances in case of Basic option
+			b, err = w.chain.EthereumTypeGetErc20ContractBalance(addrDesc, c.Contract)
+			if err != nil {
+				glog.Warningf("EthereumTypeGetErc20ContractBalance addr %v, contract %v, %v", addrDesc, c.Contract, err)
+			}
+		}
+		tokens[i] = Token{
+			Type:       tokenType,
+			BalanceSat: (*Amount)(b),
+			Contract:   ci.Contract,
+			Name:       ci.Name,
+			Symbol:     ci.Symbol,
+			Transfers:  int(c.Txs),
+			Decimals:   ci.Decimals,
+		}
+	}
+	return tokens, nil
+}
+
+// getContractTokenTransfers returns contract token (QRC20) transfers of the confirmed transaction of Bitcoin type coin
+func (w *Worker) getContractTokenTransfers(txid string) []TokenTransfer {
+	btxID, err := w.chainParser.PackTxid(txid)
+	if err != nil {
+		glog.Errorf("PackTxid error %v, %v", err, txid)
+		return nil
+	}
+	tts, err := w.db.GetTokenTransfers(btxID)
+	if err != nil {
+		glog.Errorf("GetTokenTransfers error %v, %v", err, txid)
+		return nil
+	}
+	tokenType := TokenType(w.chainParser.GetContractTokenType())
+	tokens := make([]TokenTransfer, len(tts))
+	for i := range tts {
+		tt := &tts[i]
+		contract := hex.EncodeToString(tt.Contract)
+		erc20c, err := w.chain.EthereumTypeGetErc20ContractInfo(tt.Contract)
+		if err != nil {
+			glog.Errorf("GetErc20ContractInfo error %v, contract %v", err, contract)
+		}
+		if erc20c == nil {
+			erc20c = &bchain.Erc20Contract{Name: contract}
+		}
+		var from, to string
+		if a, _, err := w.chainParser.GetAddressesFromAddrDesc(tt.From); err == nil && len(a) == 1 {
+			from = a[0]
+		}
+		if a, _, err := w.chainParser.GetAddressesFromAddrDesc(tt.To); err == nil && len(a) == 1 {
+			to = a[0]
+		}
+		tokens[i] = TokenTransfer{
+			Type:     tokenType,
+			Token:    contract,
+			From:     from,
+			To:       to,
+			Decimals: erc20c.Decimals,
+			Value:    (*Amount)(&tt.Value),
+			Name:     erc20c.Name,
+			Symbol:   erc20c.Symbol,
+		}
+	}
+	return tokens
+}
+
+// getUtxoTokenFromVout returns token carried by the transaction output or nil
+func (w *Worker) getUtxoTokenFromVout(vout *bchain.Vout) *UtxoToken {
+	t, err := w.chainParser.GetUtxoTokenFromVout(vout)
+	if err != nil {
+		glog.Warning("GetUtxoTokenFromVout ", vout.N, ": ", err)
+		return nil
+	}
+	if t == nil {
+		return nil
+	}
+	return w.utxoTokenFromBchain(t)
+}
+
+// getIndexedUtxoToken returns token carried by the output of the indexed transaction or nil
+func (w *Worker) getIndexedUtxoToken(txid string, n uint32) *UtxoToken {
+	btxID, err := w.chainParser.PackTxid(txid)
+	if err != nil {
+		glog.Errorf("PackTxid error %v, %v", err, txid)
+		return nil
+	}
+	tt, err := w.db.GetUtxoTokens(btxID)
+	if err != nil {
+		glog.Errorf("GetUtxoTokens error %v, %v", err, txid)
+		return nil
+	}
+	if t, found := tt[int32(n)]; found {
+		return w.utxoTokenFromBchain(t)
+	}
+	return nil
+}
+
+// utxoTokenTransfers creates token transfers from the outputs carrying tokens
+// the sender is the address of the first input carrying the same token, empty in case of issuance
+func utxoTokenTransfers(vins []Vin, vouts []Vout) []TokenTransfer {
+	var tokens []TokenTransfer
+	for i := range vouts {
+		vout := &vouts[i]
+		if vout.Token == nil {
+			continue
+		}
+		var from, to string
+		for j := range vins {
+			if vins[j].Token != nil && vins[j].Token.ID == vout.Token.ID && len(vins[j].Addresses) > 0 {
+				from = vins[j].Addresses[0]
+				break
+			}
+		}
+		if len(vout.Addresses) > 0 {
+			to = vout.Addresses[0]
+		}
+		tokens = append(tokens, TokenTransfer{
+			Type:     vout.Token.Type,
+			From:     from,
+			To:       to,
+			Token:    vout.Token.ID,
+			Name:     vout.Token.ID,
+			Decimals: vout.Token.Decimals,
+			Value:    vout.Token.AmountSat,
+		})
+	}
+	return tokens
+}
+
+func (w *Worker) utxoTokenFromBchain(t *bchain.UtxoToken) *UtxoToken {
+	ut := &UtxoToken{
+		Type:     TokenType(w.chainParser.GetUtxoTokenType()),
+		ID:       t.ID,
+		Decimals: w.chainParser.GetUtxoTokenDecimals(),
+	}
+	if t.Amount.Sign() > 0 {
+		ut.AmountSat = (*Amount)(&t.Amount)
+	}
+	if t.Nft {
+		ut.NftCapability = t.NftCapability
+		ut.NftCommitment = hex.EncodeToString(t.NftCommitment)
+	}
+	return ut
+}
+
+// getUtxoTokenBalances sums the tokens carried by the unspent outputs of the address, ba must contain utxos
+func (w *Worker) getUtxoTokenBalances(addrDesc bchain.AddressDescriptor, ba *db.AddrBalance) ([]Token, error) {
+	tokenType := TokenType(w.chainParser.GetUtxoTokenType())
+	var tokens []Token
+	ti := make(map[string]int)
+	txTokens := make(map[string]map[int32]*bchain.UtxoToken)
+	for i := range ba.Utxos {
+		utxo := &ba.Utxos[i]
+		tt, found := txTokens[string(utxo.BtxID)]
+		if !found {
+			var err error
+			tt, err = w.db.GetUtxoTokens(utxo.BtxID)
+			if err != nil {
+				return nil, errors.Annotatef(err, "GetUtxoTokens")
+			}
+			txTokens[string(utxo.BtxID)] = tt
+		}
+		t, found := tt[utxo.Vout]
+		if !found {
+			continue
+		}
+		j, found := ti[t.ID]
+		if !found {
+			j = len(tokens)
+			ti[t.ID] = j
+			tokens = append(tokens, Token{
+				Type:       tokenType,
+				Name:       t.ID,
+				Contract:   t.ID,
+				Decimals:   w.chainParser.GetUtxoTokenDecimals(),
+				BalanceSat: &Amount{},
+			})
+		}
+		token := &tokens[j]
+		(*big.Int)(token.BalanceSat).Add((*big.Int)(token.BalanceSat), &t.Amount)
+		if t.Nft {
+			token.Nfts = append(token.Nfts, TokenNft{
+				Capability: t.NftCapability,
+				Commitment: hex.EncodeToString(t.NftCommitment),
+			})
+		}
+	}
+	if len(tokens) > 0 {
+		if err := w.countUtxoTokenTxs(addrDesc, tokens, ti); err != nil {
+			return nil, err
+		}
+	}
+	return tokens, nil
+}
+
+// countUtxoTokenTxs sets Transfers of the tokens to the number of confirmed transactions of the address
+// which received or spent the token; ti maps the token ID to its index in tokens
+func (w *Worker) countUtxoTokenTxs(addrDesc bchain.AddressDescriptor, tokens []Token, ti map[string]int) error {
+	txs := make([]map[string]struct{}, len(tokens))
+	for i := range txs {
+		txs[i] = make(map[string]struct{})
+	}
+	// the spending transactions are found from the outputs of the address by the spentBy column
+	err := w.db.GetAddrDescTransactions(addrDesc, 0, maxUint32, func(txid string, height uint32, indexes []int32) error {
+		var tt map[int32]*bchain.UtxoToken
+		loaded := false
+		for _, index := range indexes {
+			if index < 0 {
+				continue
+			}
+			if !loaded {
+				btxID, err := w.chainParser.PackTxid(txid)
+				if err != nil {
+					return err
+				}
+				if tt, err = w.db.GetUtxoTokens(btxID); err != nil {
+					return err
+				}
+				loaded = true
+			}
+			t, found := tt[index]
+			if !found {
+				continue
+			}
+			j, found := ti[t.ID]
+			if !found {
+				continue
+			}
+			txs[j][txid] = struct{}{}
+			sb, err := w.db.GetSpentBy(txid, uint32(index))
+			if err != nil {
+				return err
+			}
+			if sb != nil {
+				txs[j][sb.Txid] = struct{}{}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Annotatef(err, "GetAddrDescTransactions")
+	}
+	for i := range tokens {
+		tokens[i].Transfers = len(txs[i])
+	}
+	return nil
+}
+
 func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrBalance, onlyConfirmed bool, onlyMempool bool) (Utxos, error) {
 	w.waitForBackendSync()
 	var err error
 	r := make(Utxos, 0, 8)
 	spentInMempool := make(map[string]struct{})
+	utxoTokens := w.chainParser.GetUtxoTokenType() != ""
 	if !onlyConfirmed {
 		// get utxo from mempool
 		txm, err := w.getAddressTxids(addrDesc, true, &AddressFilter{Vout: AddressFilterVoutOff}, maxInt)
@@ -853,13 +1269,17 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 								if len(bchainTx.Vin) == 1 && len(bchainTx.Vin[0].Coinbase) > 0 {
 									coinbase = true
 								}
-								r = append(r, Utxo{
+								u := Utxo{
 									Txid:      bchainTx.Txid,
 									Vout:      int32(i),
 									AmountSat: (*Amount)(&vout.ValueSat),
 									Locktime:  bchainTx.LockTime,
 									Coinbase:  coinbase,
-								})
+								}
+								if utxoTokens {
+									u.Token = w.getUtxoTokenFromVout(vout)
+								}
+								r = append(r, u)
 							}
 						}
 					}
@@ -905,14 +1325,24 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 							coinbase = true
 						}
 					}
-					r = append(r, Utxo{
+					u := Utxo{
 						Txid:          txid,
 						Vout:          utxo.Vout,
 						AmountSat:     (*Amount)(&utxo.ValueSat),
 						Height:        int(utxo.Height),
 						Confirmations: confirmations,
 						Coinbase:      coinbase,
-					})
+					}
+					if utxoTokens {
+						tt, err := w.db.GetUtxoTokens(utxo.BtxID)
+						if err != nil {
+							return nil, err
+						}
+						if t, found := tt[utxo.Vout]; found {
+							u.Token = w.utxoTokenFromBchain(t)
+						}
+					}
+					r = append(r, u)
 				}
 				checksum.Sub(&checksum, &utxo.ValueSat)
 			}
@@ -925,7 +1355,9 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 }
 
 // GetAddressUtxo returns unspent outputs for given address
-func (w *Worker) GetAddressUtxo(address string, onlyConfirmed bool) (Utxos, error) {
+func (w *Worker) GetAddressUtxo(address string, onlyConfirmed bool) (utxos Utxos, err error) {
+	w, span := w.startSpan("api.GetAddressUtxo")
+	defer func() { common.EndSpan(span, err) }()
 	if w.chainType != bchain.ChainBitcoinType {
 		return nil, NewAPIError("Not supported", true)
 	}
@@ -942,6 +1374,14 @@ func (w *Worker) GetAddressUtxo(address string, onlyConfirmed bool) (Utxos, erro
 	return r, nil
 }
 
+// GetAddrDescUtxo returns unspent outputs for given address descriptor
+func (w *Worker) GetAddrDescUtxo(addrDesc bchain.AddressDescriptor, onlyConfirmed bool) (Utxos, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	return w.getAddrDescUtxo(addrDesc, nil, onlyConfirmed, false)
+}
+
 // GetBlocks returns BlockInfo for blocks on given page
 func (w *Worker) GetBlocks(page int, blocksOnPage int) (*Blocks, error) {
 	start := time.Now()
@@ -1099,7 +1539,9 @@ func (w *Worker) GetFeeStats(bid string) (*FeeStats, error) {
 }
 
 // GetBlock returns paged data about block
-func (w *Worker) GetBlock(bid string, page int, txsOnPage int) (*Block, error) {
+func (w *Worker) GetBlock(bid string, page int, txsOnPage int) (b *Block, err error) {
+	w, span := w.startSpan("api.GetBlock", common.StringAttr("block", bid), common.IntAttr("page", page))
+	defer func() { common.EndSpan(span, err) }()
 	start := time.Now()
 	page--
 	if page < 0 {
@@ -1159,6 +1601,7 @@ func (w *Worker) GetBlock(bid string, page int, txsOnPage int) (*Block, error) {
 			Version:       bi.Version,
 		},
 		TxCount:      txCount,
+		ChainLock:    w.isChainLocked(bi.Height),
 		Transactions: txs,
 	}, nil
 }
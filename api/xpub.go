@@ -2,6 +2,7 @@ package api
 
 import (
 	"blockbook/bchain"
+	"blockbook/common"
 	"blockbook/db"
 	"fmt"
 	"math/big"
@@ -355,7 +356,9 @@ func (w *Worker) getXpubData(xpub string, page int, txsOnPage int, option Accoun
 }
 
 // GetXpubAddress computes address value and gets transactions for given address
-func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter, gap int) (*Address, error) {
+func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option AccountDetails, filter *AddressFilter, gap int) (a *Address, err error) {
+	w, span := w.startSpan("api.GetXpubAddress", common.IntAttr("page", page))
+	defer func() { common.EndSpan(span, err) }()
 	start := time.Now()
 	page--
 	if page < 0 {
@@ -373,10 +376,14 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 		txids          []string
 		pg             Paging
 		filtered       bool
-		err            error
 		uBalSat        big.Int
 		unconfirmedTxs int
+		nextCursor     string
 	)
+	cursor, err := parseTxCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
 	data, bestheight, err := w.getXpubData(xpub, page, txsOnPage, option, filter, gap)
 	if err != nil {
 		return nil, err
@@ -433,7 +440,7 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 						uBalSat.Add(&uBalSat, tx.getAddrVoutValue(ad.addrDesc))
 						uBalSat.Sub(&uBalSat, tx.getAddrVinValue(ad.addrDesc))
 						// mempool txs are returned only on the first page, uniquely and filtered
-						if page == 0 && !foundTx && (txidFilter == nil || txidFilter(&txid, ad)) {
+						if page == 0 && cursor == nil && !foundTx && (txidFilter == nil || txidFilter(&txid, ad)) {
 							mempoolEntries = append(mempoolEntries, bchain.MempoolTxidEntry{Txid: txid.txid, Time: uint32(tx.Blocktime)})
 						}
 					}
@@ -480,12 +487,25 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 			totalResults = -1
 		}
 		var from, to int
-		pg, from, to, page = computePaging(len(txc), page, txsOnPage)
-		if len(txc) >= txsOnPage {
-			if totalResults < 0 {
-				pg.TotalPages = -1
-			} else {
-				pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+		if cursor != nil {
+			pg = Paging{ItemsOnPage: txsOnPage}
+		} else {
+			pg, from, to, page = computePaging(len(txc), page, txsOnPage)
+			if len(txc) >= txsOnPage {
+				if totalResults < 0 {
+					pg.TotalPages = -1
+				} else {
+					pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+				}
+			}
+		}
+		// the first page and the pages requested by cursor are selected by the cursor, which provides the next cursor
+		if page == 0 || cursor != nil {
+			var next *txCursor
+			txc, next = xpubTxidsFromCursor(txc, cursor, txsOnPage)
+			from, to = 0, len(txc)
+			if next != nil {
+				nextCursor = next.String()
 			}
 		}
 		// get confirmed transactions
@@ -532,6 +552,7 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 	totalReceived.Add(&data.balanceSat, &data.sentSat)
 	addr := Address{
 		Paging:                pg,
+		NextCursor:            nextCursor,
 		AddrStr:               xpub,
 		BalanceSat:            (*Amount)(&data.balanceSat),
 		TotalReceivedSat:      (*Amount)(&totalReceived),
@@ -550,7 +571,9 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 }
 
 // GetXpubUtxo returns unspent outputs for given xpub
-func (w *Worker) GetXpubUtxo(xpub string, onlyConfirmed bool, gap int) (Utxos, error) {
+func (w *Worker) GetXpubUtxo(xpub string, onlyConfirmed bool, gap int) (res Utxos, err error) {
+	w, span := w.startSpan("api.GetXpubUtxo")
+	defer func() { common.EndSpan(span, err) }()
 	start := time.Now()
 	data, _, err := w.getXpubData(xpub, 0, 1, AccountDetailsBasic, &AddressFilter{
 		Vout:          AddressFilterVoutOff,
@@ -34,6 +34,26 @@ func (b *BaseChain) GetMempoolEntry(txid string) (*MempoolEntry, error) {
 	return nil, errors.New("GetMempoolEntry: not supported")
 }
 
+// TestMempoolAccept is not supported by default
+func (b *BaseChain) TestMempoolAccept(tx string) (*MempoolAcceptResult, error) {
+	return nil, ErrNotSupported
+}
+
+// InitializeLocks does nothing, InstantSend and ChainLock are not supported by default
+func (b *BaseChain) InitializeLocks(onNewInstantLock OnNewInstantLockFunc, onNewChainLock OnNewChainLockFunc) error {
+	return nil
+}
+
+// IsInstantLocked returns false, InstantSend is not supported by default
+func (b *BaseChain) IsInstantLocked(txid string) bool {
+	return false
+}
+
+// GetBestChainLockHeight returns 0, ChainLock is not supported by default
+func (b *BaseChain) GetBestChainLockHeight() uint32 {
+	return 0
+}
+
 // EthereumTypeGetBalance is not supported
 func (b *BaseChain) EthereumTypeGetBalance(addrDesc AddressDescriptor) (*big.Int, error) {
 	return nil, errors.New("Not supported")
@@ -294,7 +294,42 @@ func (p *BaseParser) DeriveAddressDescriptorsFromTo(xpub string, change uint32,
 	return nil, errors.New("Not supported")
 }
 
+// DerivePublicKeys is unsupported
+func (p *BaseParser) DerivePublicKeys(xpub string, change uint32, indexes []uint32) ([]byte, [][]byte, error) {
+	return nil, nil, errors.New("Not supported")
+}
+
 // EthereumTypeGetErc20FromTx is unsupported
 func (p *BaseParser) EthereumTypeGetErc20FromTx(tx *Tx) ([]Erc20Transfer, error) {
 	return nil, errors.New("Not supported")
 }
+
+// GetUtxoTokenType returns empty string, UTXO tokens are not supported by default
+func (p *BaseParser) GetUtxoTokenType() string {
+	return ""
+}
+
+// GetUtxoTokenDecimals returns 0, the amounts of UTXO tokens are integers by default
+func (p *BaseParser) GetUtxoTokenDecimals() int {
+	return 0
+}
+
+// GetUtxoTokenFromVout returns nil, UTXO tokens are not supported by default
+func (p *BaseParser) GetUtxoTokenFromVout(output *Vout) (*UtxoToken, error) {
+	return nil, nil
+}
+
+// GetContractTokenType returns empty string, contract tokens of Bitcoin type coins are not supported by default
+func (p *BaseParser) GetContractTokenType() string {
+	return ""
+}
+
+// GetNameExpirationDepth returns 0, name operations are not supported by default
+func (p *BaseParser) GetNameExpirationDepth() uint32 {
+	return 0
+}
+
+// GetNameOperationFromVout returns nil, name operations are not supported by default
+func (p *BaseParser) GetNameOperationFromVout(output *Vout) (*NameOperation, error) {
+	return nil, nil
+}
@@ -3,6 +3,8 @@ package bch
 import (
 	"blockbook/bchain"
 	"blockbook/bchain/coins/btc"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 
 	"github.com/martinboehm/bchutil"
@@ -109,6 +111,32 @@ func (p *BCashParser) GetAddrDescFromAddress(address string) (bchain.AddressDesc
 	return p.addressToOutputScript(address)
 }
 
+// GetAddrDescFromVout returns internal address representation of given transaction output
+// the CashTokens token prefix is not part of the address descriptor
+func (p *BCashParser) GetAddrDescFromVout(output *bchain.Vout) (bchain.AddressDescriptor, error) {
+	ad, err := hex.DecodeString(output.ScriptPubKey.Hex)
+	if err != nil {
+		return ad, err
+	}
+	_, ad = parseTokenPrefix(ad)
+	// convert possible P2PK script to P2PKH
+	// so that all transactions by given public key are indexed together
+	return txscript.ConvertP2PKtoP2PKH(p.Params.Base58CksumHasher, ad)
+}
+
+// ParseTxFromJson parses JSON message containing transaction and returns Tx struct
+// token data of outputs are added to the ScriptPubKey as the CashTokens token prefix
+func (p *BCashParser) ParseTxFromJson(msg json.RawMessage) (*bchain.Tx, error) {
+	tx, err := p.BitcoinParser.ParseTxFromJson(msg)
+	if err != nil {
+		return nil, err
+	}
+	if err = addTokenPrefixes(tx, msg); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
 // addressToOutputScript converts bitcoin address to ScriptPubKey
 func (p *BCashParser) addressToOutputScript(address string) ([]byte, error) {
 	if isCashAddr(address) {
@@ -149,6 +177,8 @@ func isCashAddr(addr string) bool {
 
 // outputScriptToAddresses converts ScriptPubKey to bitcoin addresses
 func (p *BCashParser) outputScriptToAddresses(script []byte) ([]string, bool, error) {
+	// strip CashTokens token prefix
+	_, script = parseTokenPrefix(script)
 	// convert possible P2PK script to P2PK, which bchutil can process
 	var err error
 	script, err = txscript.ConvertP2PKtoP2PKH(p.Params.Base58CksumHasher, script)
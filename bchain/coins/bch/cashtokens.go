@@ -0,0 +1,256 @@
+package bch
+
+import (
+	"blockbook/bchain"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// CashTokens token prefix of the locking bytecode, see https://github.com/bitjson/cashtokens
+const (
+	tokenPrefix = 0xef
+
+	tokenCategoryLen      = 32
+	tokenMaxCommitmentLen = 40
+
+	tokenFlagReserved            = 0x80
+	tokenFlagHasCommitmentLength = 0x40
+	tokenFlagHasNft              = 0x20
+	tokenFlagHasAmount           = 0x10
+	tokenCapabilityMask          = 0x0f
+)
+
+// CashTokensTokenType is the type of tokens returned by the parser
+const CashTokensTokenType = "CashTokens"
+
+var tokenCapabilities = []string{"none", "mutable", "minting"}
+
+var errInvalidTokenPrefix = errors.New("Invalid token prefix")
+
+// readCompactSize reads bitcoin CompactSize encoded number, returns the number and its length
+func readCompactSize(b []byte) (uint64, int, error) {
+	if len(b) == 0 {
+		return 0, 0, errInvalidTokenPrefix
+	}
+	switch b[0] {
+	case 0xfd:
+		if len(b) < 3 {
+			return 0, 0, errInvalidTokenPrefix
+		}
+		return uint64(binary.LittleEndian.Uint16(b[1:])), 3, nil
+	case 0xfe:
+		if len(b) < 5 {
+			return 0, 0, errInvalidTokenPrefix
+		}
+		return uint64(binary.LittleEndian.Uint32(b[1:])), 5, nil
+	case 0xff:
+		if len(b) < 9 {
+			return 0, 0, errInvalidTokenPrefix
+		}
+		return binary.LittleEndian.Uint64(b[1:]), 9, nil
+	default:
+		return uint64(b[0]), 1, nil
+	}
+}
+
+func appendCompactSize(b []byte, n uint64) []byte {
+	switch {
+	case n < 0xfd:
+		return append(b, byte(n))
+	case n <= 0xffff:
+		b = append(b, 0xfd, 0, 0)
+		binary.LittleEndian.PutUint16(b[len(b)-2:], uint16(n))
+	case n <= 0xffffffff:
+		b = append(b, 0xfe, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(b[len(b)-4:], uint32(n))
+	default:
+		b = append(b, 0xff, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint64(b[len(b)-8:], n)
+	}
+	return b
+}
+
+// reverseBytes returns reversed copy of the slice, the token category is stored in the internal byte order of txid
+func reverseBytes(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+// parseTokenPrefix splits the script to the token and the locking bytecode
+// if the script does not contain a valid token prefix, nil token and unchanged script are returned
+func parseTokenPrefix(script []byte) (*bchain.UtxoToken, []byte) {
+	if len(script) < 1+tokenCategoryLen+1 || script[0] != tokenPrefix {
+		return nil, script
+	}
+	t, l, err := unpackTokenPrefix(script)
+	if err != nil {
+		return nil, script
+	}
+	return t, script[l:]
+}
+
+func unpackTokenPrefix(script []byte) (*bchain.UtxoToken, int, error) {
+	t := bchain.UtxoToken{
+		ID: hex.EncodeToString(reverseBytes(script[1 : 1+tokenCategoryLen])),
+	}
+	l := 1 + tokenCategoryLen
+	flags := script[l]
+	l++
+	capability := int(flags & tokenCapabilityMask)
+	if flags&tokenFlagReserved != 0 || capability >= len(tokenCapabilities) {
+		return nil, 0, errInvalidTokenPrefix
+	}
+	if flags&(tokenFlagHasNft|tokenFlagHasAmount) == 0 {
+		return nil, 0, errInvalidTokenPrefix
+	}
+	if flags&tokenFlagHasNft == 0 && (capability != 0 || flags&tokenFlagHasCommitmentLength != 0) {
+		return nil, 0, errInvalidTokenPrefix
+	}
+	if flags&tokenFlagHasNft != 0 {
+		t.Nft = true
+		t.NftCapability = tokenCapabilities[capability]
+	}
+	if flags&tokenFlagHasCommitmentLength != 0 {
+		cl, ll, err := readCompactSize(script[l:])
+		if err != nil || cl == 0 || cl > tokenMaxCommitmentLen || len(script) < l+ll+int(cl) {
+			return nil, 0, errInvalidTokenPrefix
+		}
+		l += ll
+		t.NftCommitment = append([]byte(nil), script[l:l+int(cl)]...)
+		l += int(cl)
+	}
+	if flags&tokenFlagHasAmount != 0 {
+		a, ll, err := readCompactSize(script[l:])
+		if err != nil || a == 0 || a > 1<<63-1 {
+			return nil, 0, errInvalidTokenPrefix
+		}
+		l += ll
+		t.Amount.SetUint64(a)
+	}
+	return &t, l, nil
+}
+
+// packTokenPrefix creates the token prefix of the locking bytecode from the token
+func packTokenPrefix(t *bchain.UtxoToken) ([]byte, error) {
+	category, err := hex.DecodeString(t.ID)
+	if err != nil || len(category) != tokenCategoryLen {
+		return nil, errInvalidTokenPrefix
+	}
+	b := make([]byte, 0, 1+tokenCategoryLen+1+1+len(t.NftCommitment)+9)
+	b = append(b, tokenPrefix)
+	b = append(b, reverseBytes(category)...)
+	var flags byte
+	if t.Nft {
+		flags |= tokenFlagHasNft
+		capability := -1
+		for i := range tokenCapabilities {
+			if tokenCapabilities[i] == t.NftCapability {
+				capability = i
+				break
+			}
+		}
+		if capability < 0 {
+			return nil, errInvalidTokenPrefix
+		}
+		flags |= byte(capability)
+		if len(t.NftCommitment) > 0 {
+			flags |= tokenFlagHasCommitmentLength
+		}
+	}
+	if t.Amount.Sign() > 0 {
+		flags |= tokenFlagHasAmount
+	}
+	b = append(b, flags)
+	if flags&tokenFlagHasCommitmentLength != 0 {
+		b = appendCompactSize(b, uint64(len(t.NftCommitment)))
+		b = append(b, t.NftCommitment...)
+	}
+	if flags&tokenFlagHasAmount != 0 {
+		b = appendCompactSize(b, t.Amount.Uint64())
+	}
+	return b, nil
+}
+
+// tokenData is token of an output as returned by the backend in verbose transaction
+type tokenData struct {
+	Category string      `json:"category"`
+	Amount   json.Number `json:"amount"`
+	Nft      *struct {
+		Capability string `json:"capability"`
+		Commitment string `json:"commitment"`
+	} `json:"nft"`
+}
+
+// addTokenPrefixes prepends the token prefix to the ScriptPubKey of outputs carrying tokens
+// the backend returns the token data separately from the locking bytecode in the verbose transaction
+func addTokenPrefixes(tx *bchain.Tx, msg json.RawMessage) error {
+	var td struct {
+		Vout []struct {
+			N         uint32     `json:"n"`
+			TokenData *tokenData `json:"tokenData"`
+		} `json:"vout"`
+	}
+	if err := json.Unmarshal(msg, &td); err != nil {
+		return err
+	}
+	for i := range td.Vout {
+		d := td.Vout[i].TokenData
+		n := int(td.Vout[i].N)
+		if d == nil || n >= len(tx.Vout) {
+			continue
+		}
+		t := bchain.UtxoToken{ID: d.Category}
+		if d.Amount != "" {
+			if _, ok := t.Amount.SetString(string(d.Amount), 10); !ok {
+				return errInvalidTokenPrefix
+			}
+		}
+		if d.Nft != nil {
+			var err error
+			t.Nft = true
+			t.NftCapability = d.Nft.Capability
+			if t.NftCommitment, err = hex.DecodeString(d.Nft.Commitment); err != nil {
+				return err
+			}
+		}
+		prefix, err := packTokenPrefix(&t)
+		if err != nil {
+			return err
+		}
+		ph := hex.EncodeToString(prefix)
+		// do not add the prefix if the backend already returned it as a part of the script
+		if !strings.HasPrefix(tx.Vout[n].ScriptPubKey.Hex, ph) {
+			tx.Vout[n].ScriptPubKey.Hex = ph + tx.Vout[n].ScriptPubKey.Hex
+		}
+	}
+	return nil
+}
+
+// hasTokenPrefix checks the hex encoded script without decoding it
+func hasTokenPrefix(scriptHex string) bool {
+	return len(scriptHex) > 2 && (scriptHex[:2] == "ef" || scriptHex[:2] == "EF")
+}
+
+// GetUtxoTokenType returns the type of the UTXO tokens
+func (p *BCashParser) GetUtxoTokenType() string {
+	return CashTokensTokenType
+}
+
+// GetUtxoTokenFromVout returns CashTokens token carried by the output or nil if the output does not carry any token
+func (p *BCashParser) GetUtxoTokenFromVout(output *bchain.Vout) (*bchain.UtxoToken, error) {
+	if !hasTokenPrefix(output.ScriptPubKey.Hex) {
+		return nil, nil
+	}
+	script, err := hex.DecodeString(output.ScriptPubKey.Hex)
+	if err != nil {
+		return nil, err
+	}
+	t, _ := parseTokenPrefix(script)
+	return t, nil
+}
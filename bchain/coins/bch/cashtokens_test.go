@@ -0,0 +1,135 @@
+// +build unittest
+
+package bch
+
+import (
+	"blockbook/bchain"
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func Test_packTokenPrefix_parseTokenPrefix(t *testing.T) {
+	tests := []struct {
+		name  string
+		token bchain.UtxoToken
+		hex   string
+	}{
+		{
+			name: "fungible",
+			token: bchain.UtxoToken{
+				ID:     "0000000000000000000000000000000000000000000000000000000000000001",
+				Amount: *big.NewInt(1000),
+			},
+			hex: "ef010000000000000000000000000000000000000000000000000000000000000010fde803",
+		},
+		{
+			name: "minting nft with commitment",
+			token: bchain.UtxoToken{
+				ID:            "0000000000000000000000000000000000000000000000000000000000000001",
+				Nft:           true,
+				NftCapability: "minting",
+				NftCommitment: []byte{0xca, 0xfe},
+			},
+			hex: "ef01000000000000000000000000000000000000000000000000000000000000006202cafe",
+		},
+		{
+			name: "nft and fungible",
+			token: bchain.UtxoToken{
+				ID:            "0000000000000000000000000000000000000000000000000000000000000001",
+				Amount:        *big.NewInt(5),
+				Nft:           true,
+				NftCapability: "none",
+			},
+			hex: "ef01000000000000000000000000000000000000000000000000000000000000003005",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := packTokenPrefix(&tt.token)
+			if err != nil {
+				t.Errorf("packTokenPrefix() error = %v", err)
+				return
+			}
+			if h := hex.EncodeToString(b); h != tt.hex {
+				t.Errorf("packTokenPrefix() = %v, want %v", h, tt.hex)
+			}
+			script, _ := hex.DecodeString("76a9140c8967e6382c7a2ca64d8e850bfc99b7736e1a0d88ac")
+			got, rest := parseTokenPrefix(append(b, script...))
+			if !reflect.DeepEqual(got, &tt.token) {
+				t.Errorf("parseTokenPrefix() = %+v, want %+v", got, tt.token)
+			}
+			if !reflect.DeepEqual(rest, script) {
+				t.Errorf("parseTokenPrefix() script = %x, want %x", rest, script)
+			}
+		})
+	}
+}
+
+func Test_parseTokenPrefix_invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+	}{
+		{
+			name: "no prefix",
+			hex:  "76a9140c8967e6382c7a2ca64d8e850bfc99b7736e1a0d88ac",
+		},
+		{
+			name: "no token",
+			hex:  "ef01000000000000000000000000000000000000000000000000000000000000000076a9140c8967e6382c7a2ca64d8e850bfc99b7736e1a0d88ac",
+		},
+		{
+			name: "reserved flag",
+			hex:  "ef010000000000000000000000000000000000000000000000000000000000000090fde80376a9140c8967e6382c7a2ca64d8e850bfc99b7736e1a0d88ac",
+		},
+		{
+			name: "zero amount",
+			hex:  "ef0100000000000000000000000000000000000000000000000000000000000000100076a9140c8967e6382c7a2ca64d8e850bfc99b7736e1a0d88ac",
+		},
+		{
+			name: "capability without nft",
+			hex:  "ef010000000000000000000000000000000000000000000000000000000000000011fde80376a9140c8967e6382c7a2ca64d8e850bfc99b7736e1a0d88ac",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, _ := hex.DecodeString(tt.hex)
+			got, rest := parseTokenPrefix(script)
+			if got != nil {
+				t.Errorf("parseTokenPrefix() = %+v, want nil", got)
+			}
+			if !reflect.DeepEqual(rest, script) {
+				t.Errorf("parseTokenPrefix() script = %x, want %x", rest, script)
+			}
+		})
+	}
+}
+
+func Test_GetAddrDescFromVout_TokenPrefix(t *testing.T) {
+	mainParserCashAddr, _, _, _ := setupParsers(t)
+	vout := bchain.Vout{
+		ScriptPubKey: bchain.ScriptPubKey{
+			Hex: "ef010000000000000000000000000000000000000000000000000000000000000010fde80376a9140c8967e6382c7a2ca64d8e850bfc99b7736e1a0d88ac",
+		},
+	}
+	got, err := mainParserCashAddr.GetAddrDescFromVout(&vout)
+	if err != nil {
+		t.Fatalf("GetAddrDescFromVout() error = %v", err)
+	}
+	if h := hex.EncodeToString(got); h != "76a9140c8967e6382c7a2ca64d8e850bfc99b7736e1a0d88ac" {
+		t.Errorf("GetAddrDescFromVout() = %v, want 76a9140c8967e6382c7a2ca64d8e850bfc99b7736e1a0d88ac", h)
+	}
+	token, err := mainParserCashAddr.GetUtxoTokenFromVout(&vout)
+	if err != nil {
+		t.Fatalf("GetUtxoTokenFromVout() error = %v", err)
+	}
+	want := &bchain.UtxoToken{
+		ID:     "0000000000000000000000000000000000000000000000000000000000000001",
+		Amount: *big.NewInt(1000),
+	}
+	if !reflect.DeepEqual(token, want) {
+		t.Errorf("GetUtxoTokenFromVout() = %+v, want %+v", token, want)
+	}
+}
@@ -156,14 +156,34 @@ func NewBlockChain(coin string, configfile string, pushHandler func(bchain.Notif
 type blockChainWithMetrics struct {
 	b bchain.BlockChain
 	m *common.Metrics
+	// ctx is the context of the traced request, nil if the calls are not traced
+	ctx context.Context
 }
 
-func (c *blockChainWithMetrics) observeRPCLatency(method string, start time.Time, err error) {
+// WithContext returns the block chain tracing the calls as spans of the request in ctx
+func (c *blockChainWithMetrics) WithContext(ctx context.Context) bchain.BlockChain {
+	return &blockChainWithMetrics{b: c.b, m: c.m, ctx: ctx}
+}
+
+// rpcCall is a call to the backend, it is observed in the latency metric and traced as a span
+type rpcCall struct {
+	method string
+	start  time.Time
+	span   *common.Span
+}
+
+func (c *blockChainWithMetrics) startCall(method string) rpcCall {
+	_, span := common.StartSpan(c.ctx, "rpc."+method)
+	return rpcCall{method: method, start: time.Now(), span: span}
+}
+
+func (c *blockChainWithMetrics) observeRPCLatency(call rpcCall, err error) {
 	var e string
 	if err != nil {
 		e = "failure"
 	}
-	c.m.RPCLatency.With(common.Labels{"method": method, "error": e}).Observe(float64(time.Since(start)) / 1e6) // in milliseconds
+	c.m.RPCLatency.With(common.Labels{"method": call.method, "error": e}).Observe(float64(time.Since(call.start)) / 1e6) // in milliseconds
+	common.EndSpan(call.span, err)
 }
 
 func (c *blockChainWithMetrics) Initialize() error {
@@ -178,6 +198,10 @@ func (c *blockChainWithMetrics) InitializeMempool(addrDescForOutpoint bchain.Add
 	return c.b.InitializeMempool(addrDescForOutpoint, onNewTxAddr)
 }
 
+func (c *blockChainWithMetrics) InitializeLocks(onNewInstantLock bchain.OnNewInstantLockFunc, onNewChainLock bchain.OnNewChainLockFunc) error {
+	return c.b.InitializeLocks(onNewInstantLock, onNewChainLock)
+}
+
 func (c *blockChainWithMetrics) Shutdown(ctx context.Context) error {
 	return c.b.Shutdown(ctx)
 }
@@ -199,77 +223,82 @@ func (c *blockChainWithMetrics) GetSubversion() string {
 }
 
 func (c *blockChainWithMetrics) GetChainInfo() (v *bchain.ChainInfo, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("GetChainInfo", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("GetChainInfo"))
 	return c.b.GetChainInfo()
 }
 
 func (c *blockChainWithMetrics) GetBestBlockHash() (v string, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("GetBestBlockHash", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("GetBestBlockHash"))
 	return c.b.GetBestBlockHash()
 }
 
 func (c *blockChainWithMetrics) GetBestBlockHeight() (v uint32, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("GetBestBlockHeight", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("GetBestBlockHeight"))
 	return c.b.GetBestBlockHeight()
 }
 
 func (c *blockChainWithMetrics) GetBlockHash(height uint32) (v string, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("GetBlockHash", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("GetBlockHash"))
 	return c.b.GetBlockHash(height)
 }
 
 func (c *blockChainWithMetrics) GetBlockHeader(hash string) (v *bchain.BlockHeader, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("GetBlockHeader", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("GetBlockHeader"))
 	return c.b.GetBlockHeader(hash)
 }
 
 func (c *blockChainWithMetrics) GetBlock(hash string, height uint32) (v *bchain.Block, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("GetBlock", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("GetBlock"))
 	return c.b.GetBlock(hash, height)
 }
 
 func (c *blockChainWithMetrics) GetBlockInfo(hash string) (v *bchain.BlockInfo, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("GetBlockInfo", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("GetBlockInfo"))
 	return c.b.GetBlockInfo(hash)
 }
 
 func (c *blockChainWithMetrics) GetMempoolTransactions() (v []string, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("GetMempoolTransactions", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("GetMempoolTransactions"))
 	return c.b.GetMempoolTransactions()
 }
 
 func (c *blockChainWithMetrics) GetTransaction(txid string) (v *bchain.Tx, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("GetTransaction", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("GetTransaction"))
 	return c.b.GetTransaction(txid)
 }
 
 func (c *blockChainWithMetrics) GetTransactionSpecific(tx *bchain.Tx) (v json.RawMessage, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("GetTransactionSpecific", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("GetTransactionSpecific"))
 	return c.b.GetTransactionSpecific(tx)
 }
 
 func (c *blockChainWithMetrics) GetTransactionForMempool(txid string) (v *bchain.Tx, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("GetTransactionForMempool", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("GetTransactionForMempool"))
 	return c.b.GetTransactionForMempool(txid)
 }
 
 func (c *blockChainWithMetrics) EstimateSmartFee(blocks int, conservative bool) (v big.Int, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("EstimateSmartFee", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("EstimateSmartFee"))
 	return c.b.EstimateSmartFee(blocks, conservative)
 }
 
 func (c *blockChainWithMetrics) EstimateFee(blocks int) (v big.Int, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("EstimateFee", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("EstimateFee"))
 	return c.b.EstimateFee(blocks)
 }
 
 func (c *blockChainWithMetrics) SendRawTransaction(tx string) (v string, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("SendRawTransaction", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("SendRawTransaction"))
 	return c.b.SendRawTransaction(tx)
 }
 
+func (c *blockChainWithMetrics) TestMempoolAccept(tx string) (v *bchain.MempoolAcceptResult, err error) {
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("TestMempoolAccept"))
+	return c.b.TestMempoolAccept(tx)
+}
+
 func (c *blockChainWithMetrics) GetMempoolEntry(txid string) (v *bchain.MempoolEntry, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("GetMempoolEntry", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("GetMempoolEntry"))
 	return c.b.GetMempoolEntry(txid)
 }
 
@@ -278,30 +307,38 @@ func (c *blockChainWithMetrics) GetChainParser() bchain.BlockChainParser {
 }
 
 func (c *blockChainWithMetrics) EthereumTypeGetBalance(addrDesc bchain.AddressDescriptor) (v *big.Int, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeGetBalance", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("EthereumTypeGetBalance"))
 	return c.b.EthereumTypeGetBalance(addrDesc)
 }
 
 func (c *blockChainWithMetrics) EthereumTypeGetNonce(addrDesc bchain.AddressDescriptor) (v uint64, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeGetNonce", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("EthereumTypeGetNonce"))
 	return c.b.EthereumTypeGetNonce(addrDesc)
 }
 
 func (c *blockChainWithMetrics) EthereumTypeEstimateGas(params map[string]interface{}) (v uint64, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeEstimateGas", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("EthereumTypeEstimateGas"))
 	return c.b.EthereumTypeEstimateGas(params)
 }
 
 func (c *blockChainWithMetrics) EthereumTypeGetErc20ContractInfo(contractDesc bchain.AddressDescriptor) (v *bchain.Erc20Contract, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeGetErc20ContractInfo", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("EthereumTypeGetErc20ContractInfo"))
 	return c.b.EthereumTypeGetErc20ContractInfo(contractDesc)
 }
 
 func (c *blockChainWithMetrics) EthereumTypeGetErc20ContractBalance(addrDesc, contractDesc bchain.AddressDescriptor) (v *big.Int, err error) {
-	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeGetErc20ContractInfo", s, err) }(time.Now())
+	defer func(call rpcCall) { c.observeRPCLatency(call, err) }(c.startCall("EthereumTypeGetErc20ContractInfo"))
 	return c.b.EthereumTypeGetErc20ContractBalance(addrDesc, contractDesc)
 }
 
+func (c *blockChainWithMetrics) IsInstantLocked(txid string) bool {
+	return c.b.IsInstantLocked(txid)
+}
+
+func (c *blockChainWithMetrics) GetBestChainLockHeight() uint32 {
+	return c.b.GetBestChainLockHeight()
+}
+
 type mempoolWithMetrics struct {
 	mempool bchain.Mempool
 	m       *common.Metrics
@@ -408,6 +408,28 @@ func (p *BitcoinParser) DeriveAddressDescriptorsFromTo(xpub string, change uint3
 	return ad, nil
 }
 
+// DerivePublicKeys derives compressed public keys from given xpub for listed indexes
+// and returns them together with the fingerprint of the xpub
+func (p *BitcoinParser) DerivePublicKeys(xpub string, change uint32, indexes []uint32) ([]byte, [][]byte, error) {
+	extKey, err := hdkeychain.NewKeyFromString(xpub, p.Params.Base58CksumHasher)
+	if err != nil {
+		return nil, nil, err
+	}
+	changeExtKey, err := extKey.Child(change)
+	if err != nil {
+		return nil, nil, err
+	}
+	pks := make([][]byte, len(indexes))
+	for i, index := range indexes {
+		indexExtKey, err := changeExtKey.Child(index)
+		if err != nil {
+			return nil, nil, err
+		}
+		pks[i] = indexExtKey.PubKeyBytes()
+	}
+	return btcutil.Hash160(extKey.PubKeyBytes())[:4], pks, nil
+}
+
 // DerivationBasePath returns base path of xpub
 func (p *BitcoinParser) DerivationBasePath(xpub string) (string, error) {
 	extKey, err := hdkeychain.NewKeyFromString(xpub, p.Params.Base58CksumHasher)
@@ -32,6 +32,8 @@ type BitcoinRPC struct {
 	mq           *bchain.MQ
 	ChainConfig  *Configuration
 	RPCMarshaler RPCMarshaler
+	// MQTopicHandlers are handlers of coin specific ZeroMQ topics, set by coins overriding BitcoinRPC
+	MQTopicHandlers map[string]bchain.MQTopicHandler
 }
 
 // Configuration represents json config file
@@ -161,7 +163,7 @@ func (b *BitcoinRPC) InitializeMempool(addrDescForOutpoint bchain.AddrDescForOut
 	b.Mempool.AddrDescForOutpoint = addrDescForOutpoint
 	b.Mempool.OnNewTxAddr = onNewTxAddr
 	if b.mq == nil {
-		mq, err := bchain.NewMQ(b.ChainConfig.MessageQueueBinding, b.pushHandler)
+		mq, err := bchain.NewMQWithTopics(b.ChainConfig.MessageQueueBinding, b.pushHandler, b.MQTopicHandlers)
 		if err != nil {
 			glog.Error("mq: ", err)
 			return err
@@ -260,6 +262,7 @@ type ResGetNetworkInfo struct {
 		Subversion      json.Number `json:"subversion"`
 		ProtocolVersion json.Number `json:"protocolversion"`
 		Timeoffset      float64     `json:"timeoffset"`
+		RelayFee        json.Number `json:"relayfee"`
 		Warnings        string      `json:"warnings"`
 	} `json:"result"`
 }
@@ -389,6 +392,26 @@ type ResSendRawTransaction struct {
 	Result string           `json:"result"`
 }
 
+// testmempoolaccept
+
+type CmdTestMempoolAccept struct {
+	Method string     `json:"method"`
+	Params [][]string `json:"params"`
+}
+
+type ResTestMempoolAccept struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result []struct {
+		Txid    string `json:"txid"`
+		Allowed bool   `json:"allowed"`
+		Vsize   int    `json:"vsize"`
+		Fees    struct {
+			Base json.Number `json:"base"`
+		} `json:"fees"`
+		RejectReason string `json:"reject-reason"`
+	} `json:"result"`
+}
+
 // getmempoolentry
 
 type CmdGetMempoolEntry struct {
@@ -468,6 +491,7 @@ func (b *BitcoinRPC) GetChainInfo() (*bchain.ChainInfo, error) {
 		SizeOnDisk:    resCi.Result.SizeOnDisk,
 		Subversion:    string(resNi.Result.Subversion),
 		Timeoffset:    resNi.Result.Timeoffset,
+		RelayFee:      string(resNi.Result.RelayFee),
 	}
 	rv.Version = string(resNi.Result.Version)
 	rv.ProtocolVersion = string(resNi.Result.ProtocolVersion)
@@ -835,6 +859,45 @@ func (b *BitcoinRPC) SendRawTransaction(tx string) (string, error) {
 	return res.Result, nil
 }
 
+// TestMempoolAccept checks if the transaction would be accepted to the mempool, without sending it
+func (b *BitcoinRPC) TestMempoolAccept(tx string) (*bchain.MempoolAcceptResult, error) {
+	glog.V(1).Info("rpc: testmempoolaccept")
+
+	res := ResTestMempoolAccept{}
+	req := CmdTestMempoolAccept{Method: "testmempoolaccept"}
+	req.Params = [][]string{{tx}}
+	err := b.Call(&req, &res)
+
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != nil {
+		// RPC_METHOD_NOT_FOUND, the backend is older or the coin does not implement the method
+		if res.Error.Code == -32601 {
+			return nil, bchain.ErrNotSupported
+		}
+		return nil, res.Error
+	}
+	if len(res.Result) != 1 {
+		return nil, errors.New("testmempoolaccept: unexpected number of results")
+	}
+	r := res.Result[0]
+	rv := &bchain.MempoolAcceptResult{
+		Txid:         r.Txid,
+		Allowed:      r.Allowed,
+		RejectReason: r.RejectReason,
+		Vsize:        r.Vsize,
+	}
+	if r.Fees.Base != "" {
+		fee, err := b.Parser.AmountToBigInt(r.Fees.Base)
+		if err != nil {
+			return nil, err
+		}
+		rv.FeeSat = &fee
+	}
+	return rv, nil
+}
+
 // GetMempoolEntry returns mempool data for given transaction
 func (b *BitcoinRPC) GetMempoolEntry(txid string) (*bchain.MempoolEntry, error) {
 	glog.V(1).Info("rpc: getmempoolentry")
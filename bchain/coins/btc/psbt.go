@@ -0,0 +1,766 @@
+package btc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcd/wire"
+	"github.com/martinboehm/btcutil"
+	"github.com/martinboehm/btcutil/txscript"
+)
+
+// types of the psbt keys, BIP174
+const (
+	psbtGlobalUnsignedTx     = 0x00
+	psbtInNonWitnessUtxo     = 0x00
+	psbtInWitnessUtxo        = 0x01
+	psbtInPartialSig         = 0x02
+	psbtInSighashType        = 0x03
+	psbtInRedeemScript       = 0x04
+	psbtInWitnessScript      = 0x05
+	psbtInBip32Derivation    = 0x06
+	psbtInFinalScriptSig     = 0x07
+	psbtInFinalScriptWitness = 0x08
+	psbtInTapKeySig          = 0x13
+	psbtOutRedeemScript      = 0x00
+	psbtOutWitnessScript     = 0x01
+	psbtOutBip32Derivation   = 0x02
+)
+
+const psbtSeparator = 0x00
+
+// psbtMaxKeyValueSize limits the size of the keys and values read from the psbt
+const psbtMaxKeyValueSize = 4000000
+
+var psbtMagic = []byte{0x70, 0x73, 0x62, 0x74, 0xff}
+
+// Bip32Derivation is the origin of a public key, the fingerprint of the master key and the derivation path
+type Bip32Derivation struct {
+	PubKey      []byte
+	Fingerprint []byte
+	Path        []uint32
+}
+
+// PartialSig is a signature of the input by the public key
+type PartialSig struct {
+	PubKey    []byte
+	Signature []byte
+}
+
+// PsbtUnknown is a key-value pair of the psbt not interpreted by blockbook, it is kept to be serialized back
+type PsbtUnknown struct {
+	Key   []byte
+	Value []byte
+}
+
+// PsbtInput contains the data of one input of the psbt necessary for signing
+type PsbtInput struct {
+	// NonWitnessUtxo is the serialized transaction containing the spent output
+	NonWitnessUtxo     []byte
+	WitnessUtxo        *wire.TxOut
+	PartialSigs        []PartialSig
+	SighashType        uint32
+	RedeemScript       []byte
+	WitnessScript      []byte
+	Bip32Derivation    []Bip32Derivation
+	FinalScriptSig     []byte
+	FinalScriptWitness [][]byte
+	// TapKeySig is the signature of the taproot key path spend
+	TapKeySig []byte
+	Unknowns  []PsbtUnknown
+}
+
+// PsbtOutput contains the data of one output of the psbt, typically of the change output
+type PsbtOutput struct {
+	RedeemScript    []byte
+	WitnessScript   []byte
+	Bip32Derivation []Bip32Derivation
+	Unknowns        []PsbtUnknown
+}
+
+// Psbt is a partially signed bitcoin transaction as defined by BIP174
+type Psbt struct {
+	UnsignedTx *wire.MsgTx
+	Inputs     []PsbtInput
+	Outputs    []PsbtOutput
+	Unknowns   []PsbtUnknown
+}
+
+// InputSignatures describes the state of signing of one input
+type InputSignatures struct {
+	Finalized bool
+	// Required is the number of signatures required to spend the input, Signed the number of present signatures
+	Required int
+	Signed   int
+	// Missing are the public keys which can still sign the input, for taproot the x-only output key
+	Missing [][]byte
+}
+
+// NewPsbt creates psbt from the unsigned transaction, with empty inputs and outputs data
+func NewPsbt(tx *wire.MsgTx) *Psbt {
+	return &Psbt{
+		UnsignedTx: tx,
+		Inputs:     make([]PsbtInput, len(tx.TxIn)),
+		Outputs:    make([]PsbtOutput, len(tx.TxOut)),
+	}
+}
+
+func writePsbtKV(w *bytes.Buffer, key []byte, value []byte) error {
+	if err := wire.WriteVarBytes(w, 0, key); err != nil {
+		return err
+	}
+	return wire.WriteVarBytes(w, 0, value)
+}
+
+func (d *Bip32Derivation) pack() []byte {
+	b := make([]byte, 4+4*len(d.Path))
+	copy(b, d.Fingerprint)
+	for i, p := range d.Path {
+		binary.LittleEndian.PutUint32(b[4+4*i:], p)
+	}
+	return b
+}
+
+func writeBip32Derivations(w *bytes.Buffer, keyType byte, derivations []Bip32Derivation) error {
+	for i := range derivations {
+		d := &derivations[i]
+		if err := writePsbtKV(w, append([]byte{keyType}, d.PubKey...), d.pack()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeUnknowns(w *bytes.Buffer, unknowns []PsbtUnknown) error {
+	for i := range unknowns {
+		if err := writePsbtKV(w, unknowns[i].Key, unknowns[i].Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeWitness(w *bytes.Buffer, witness [][]byte) error {
+	if err := wire.WriteVarInt(w, 0, uint64(len(witness))); err != nil {
+		return err
+	}
+	for _, item := range witness {
+		if err := wire.WriteVarBytes(w, 0, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Serialize returns the binary form of the psbt
+func (p *Psbt) Serialize() ([]byte, error) {
+	if len(p.Inputs) != len(p.UnsignedTx.TxIn) || len(p.Outputs) != len(p.UnsignedTx.TxOut) {
+		return nil, errors.New("Psbt inputs or outputs do not match the transaction")
+	}
+	var b, tx bytes.Buffer
+	b.Write(psbtMagic)
+	if err := p.UnsignedTx.SerializeNoWitness(&tx); err != nil {
+		return nil, err
+	}
+	if err := writePsbtKV(&b, []byte{psbtGlobalUnsignedTx}, tx.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writeUnknowns(&b, p.Unknowns); err != nil {
+		return nil, err
+	}
+	b.WriteByte(psbtSeparator)
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		if in.NonWitnessUtxo != nil {
+			if err := writePsbtKV(&b, []byte{psbtInNonWitnessUtxo}, in.NonWitnessUtxo); err != nil {
+				return nil, err
+			}
+		}
+		if in.WitnessUtxo != nil {
+			var o bytes.Buffer
+			v := make([]byte, 8)
+			binary.LittleEndian.PutUint64(v, uint64(in.WitnessUtxo.Value))
+			o.Write(v)
+			if err := wire.WriteVarBytes(&o, 0, in.WitnessUtxo.PkScript); err != nil {
+				return nil, err
+			}
+			if err := writePsbtKV(&b, []byte{psbtInWitnessUtxo}, o.Bytes()); err != nil {
+				return nil, err
+			}
+		}
+		for j := range in.PartialSigs {
+			ps := &in.PartialSigs[j]
+			if err := writePsbtKV(&b, append([]byte{psbtInPartialSig}, ps.PubKey...), ps.Signature); err != nil {
+				return nil, err
+			}
+		}
+		if in.SighashType != 0 {
+			v := make([]byte, 4)
+			binary.LittleEndian.PutUint32(v, in.SighashType)
+			if err := writePsbtKV(&b, []byte{psbtInSighashType}, v); err != nil {
+				return nil, err
+			}
+		}
+		if in.RedeemScript != nil {
+			if err := writePsbtKV(&b, []byte{psbtInRedeemScript}, in.RedeemScript); err != nil {
+				return nil, err
+			}
+		}
+		if in.WitnessScript != nil {
+			if err := writePsbtKV(&b, []byte{psbtInWitnessScript}, in.WitnessScript); err != nil {
+				return nil, err
+			}
+		}
+		if err := writeBip32Derivations(&b, psbtInBip32Derivation, in.Bip32Derivation); err != nil {
+			return nil, err
+		}
+		if in.FinalScriptSig != nil {
+			if err := writePsbtKV(&b, []byte{psbtInFinalScriptSig}, in.FinalScriptSig); err != nil {
+				return nil, err
+			}
+		}
+		if in.FinalScriptWitness != nil {
+			var w bytes.Buffer
+			if err := writeWitness(&w, in.FinalScriptWitness); err != nil {
+				return nil, err
+			}
+			if err := writePsbtKV(&b, []byte{psbtInFinalScriptWitness}, w.Bytes()); err != nil {
+				return nil, err
+			}
+		}
+		if in.TapKeySig != nil {
+			if err := writePsbtKV(&b, []byte{psbtInTapKeySig}, in.TapKeySig); err != nil {
+				return nil, err
+			}
+		}
+		if err := writeUnknowns(&b, in.Unknowns); err != nil {
+			return nil, err
+		}
+		b.WriteByte(psbtSeparator)
+	}
+	for i := range p.Outputs {
+		out := &p.Outputs[i]
+		if out.RedeemScript != nil {
+			if err := writePsbtKV(&b, []byte{psbtOutRedeemScript}, out.RedeemScript); err != nil {
+				return nil, err
+			}
+		}
+		if out.WitnessScript != nil {
+			if err := writePsbtKV(&b, []byte{psbtOutWitnessScript}, out.WitnessScript); err != nil {
+				return nil, err
+			}
+		}
+		if err := writeBip32Derivations(&b, psbtOutBip32Derivation, out.Bip32Derivation); err != nil {
+			return nil, err
+		}
+		if err := writeUnknowns(&b, out.Unknowns); err != nil {
+			return nil, err
+		}
+		b.WriteByte(psbtSeparator)
+	}
+	return b.Bytes(), nil
+}
+
+// B64Encode returns the psbt serialized and encoded in base64
+func (p *Psbt) B64Encode() (string, error) {
+	b, err := p.Serialize()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// ParseBip32Path parses the derivation path in the form m/49'/0'/0'/0/1, the prefix m/ is optional
+func ParseBip32Path(path string) ([]uint32, error) {
+	path = strings.TrimPrefix(path, "m/")
+	if path == "" || path == "m" {
+		return []uint32{}, nil
+	}
+	parts := strings.Split(path, "/")
+	r := make([]uint32, len(parts))
+	for i, p := range parts {
+		var hardened uint32
+		if strings.HasSuffix(p, "'") || strings.HasSuffix(p, "h") {
+			p = p[:len(p)-1]
+			hardened = 0x80000000
+		}
+		n, err := strconv.ParseUint(p, 10, 31)
+		if err != nil {
+			return nil, errors.Errorf("Invalid derivation path %v", path)
+		}
+		r[i] = uint32(n) | hardened
+	}
+	return r, nil
+}
+
+// P2shP2wpkhRedeemScript returns the redeem script of the P2SH-P2WPKH output script pkScript
+// if pkScript pays to the public key pubKey, otherwise returns nil
+func P2shP2wpkhRedeemScript(pubKey []byte, pkScript []byte) []byte {
+	// P2SH script: OP_HASH160 <20-byte-hash> OP_EQUAL
+	if len(pkScript) != 23 || pkScript[0] != 0xa9 || pkScript[1] != 0x14 || pkScript[22] != 0x87 {
+		return nil
+	}
+	// redeemScript <witness version: OP_0><len pubKeyHash: 20><20-byte-pubKeyHash>
+	redeemScript := append([]byte{0x00, 0x14}, btcutil.Hash160(pubKey)...)
+	if !bytes.Equal(btcutil.Hash160(redeemScript), pkScript[2:22]) {
+		return nil
+	}
+	return redeemScript
+}
+
+// readPsbtMap reads the key-value pairs of one psbt map up to the separator
+func readPsbtMap(r io.Reader, onKV func(key []byte, value []byte) error) error {
+	for {
+		key, err := wire.ReadVarBytes(r, 0, psbtMaxKeyValueSize, "psbt key")
+		if err != nil {
+			return err
+		}
+		if len(key) == 0 {
+			return nil
+		}
+		value, err := wire.ReadVarBytes(r, 0, psbtMaxKeyValueSize, "psbt value")
+		if err != nil {
+			return err
+		}
+		if err = onKV(key, value); err != nil {
+			return err
+		}
+	}
+}
+
+func unpackBip32Derivation(pubKey []byte, value []byte) (*Bip32Derivation, error) {
+	if len(value) < 4 || len(value)%4 != 0 {
+		return nil, errors.New("Invalid bip32 derivation")
+	}
+	d := Bip32Derivation{
+		PubKey:      pubKey,
+		Fingerprint: value[:4],
+		Path:        make([]uint32, len(value)/4-1),
+	}
+	for i := range d.Path {
+		d.Path[i] = binary.LittleEndian.Uint32(value[4+4*i:])
+	}
+	return &d, nil
+}
+
+func readWitness(b []byte) ([][]byte, error) {
+	r := bytes.NewReader(b)
+	n, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(b)) {
+		return nil, errors.New("Invalid witness")
+	}
+	witness := make([][]byte, n)
+	for i := range witness {
+		if witness[i], err = wire.ReadVarBytes(r, 0, psbtMaxKeyValueSize, "witness item"); err != nil {
+			return nil, err
+		}
+	}
+	return witness, nil
+}
+
+func (in *PsbtInput) setKV(key []byte, value []byte) error {
+	switch key[0] {
+	case psbtInNonWitnessUtxo:
+		in.NonWitnessUtxo = value
+	case psbtInWitnessUtxo:
+		if len(value) < 9 {
+			return errors.New("Invalid witness utxo")
+		}
+		script, err := wire.ReadVarBytes(bytes.NewReader(value[8:]), 0, psbtMaxKeyValueSize, "witness utxo script")
+		if err != nil {
+			return err
+		}
+		in.WitnessUtxo = wire.NewTxOut(int64(binary.LittleEndian.Uint64(value)), script)
+	case psbtInPartialSig:
+		in.PartialSigs = append(in.PartialSigs, PartialSig{PubKey: key[1:], Signature: value})
+	case psbtInSighashType:
+		if len(value) != 4 {
+			return errors.New("Invalid sighash type")
+		}
+		in.SighashType = binary.LittleEndian.Uint32(value)
+	case psbtInRedeemScript:
+		in.RedeemScript = value
+	case psbtInWitnessScript:
+		in.WitnessScript = value
+	case psbtInBip32Derivation:
+		d, err := unpackBip32Derivation(key[1:], value)
+		if err != nil {
+			return err
+		}
+		in.Bip32Derivation = append(in.Bip32Derivation, *d)
+	case psbtInFinalScriptSig:
+		in.FinalScriptSig = value
+	case psbtInFinalScriptWitness:
+		witness, err := readWitness(value)
+		if err != nil {
+			return err
+		}
+		in.FinalScriptWitness = witness
+	case psbtInTapKeySig:
+		in.TapKeySig = value
+	default:
+		in.Unknowns = append(in.Unknowns, PsbtUnknown{Key: key, Value: value})
+	}
+	return nil
+}
+
+func (out *PsbtOutput) setKV(key []byte, value []byte) error {
+	switch key[0] {
+	case psbtOutRedeemScript:
+		out.RedeemScript = value
+	case psbtOutWitnessScript:
+		out.WitnessScript = value
+	case psbtOutBip32Derivation:
+		d, err := unpackBip32Derivation(key[1:], value)
+		if err != nil {
+			return err
+		}
+		out.Bip32Derivation = append(out.Bip32Derivation, *d)
+	default:
+		out.Unknowns = append(out.Unknowns, PsbtUnknown{Key: key, Value: value})
+	}
+	return nil
+}
+
+// DeserializePsbt parses the binary form of the psbt
+func DeserializePsbt(b []byte) (*Psbt, error) {
+	if !bytes.HasPrefix(b, psbtMagic) {
+		return nil, errors.New("Invalid psbt magic bytes")
+	}
+	r := bytes.NewReader(b[len(psbtMagic):])
+	p := &Psbt{}
+	err := readPsbtMap(r, func(key []byte, value []byte) error {
+		if key[0] == psbtGlobalUnsignedTx && len(key) == 1 {
+			if p.UnsignedTx != nil {
+				return errors.New("Duplicate unsigned transaction")
+			}
+			tx := wire.MsgTx{}
+			if err := tx.DeserializeNoWitness(bytes.NewReader(value)); err != nil {
+				return errors.Annotatef(err, "unsigned transaction")
+			}
+			p.UnsignedTx = &tx
+			return nil
+		}
+		p.Unknowns = append(p.Unknowns, PsbtUnknown{Key: key, Value: value})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if p.UnsignedTx == nil {
+		return nil, errors.New("Missing unsigned transaction")
+	}
+	for _, in := range p.UnsignedTx.TxIn {
+		if len(in.SignatureScript) > 0 || len(in.Witness) > 0 {
+			return nil, errors.New("Unsigned transaction contains signatures")
+		}
+	}
+	p.Inputs = make([]PsbtInput, len(p.UnsignedTx.TxIn))
+	for i := range p.Inputs {
+		if err = readPsbtMap(r, p.Inputs[i].setKV); err != nil {
+			return nil, errors.Annotatef(err, "input %d", i)
+		}
+	}
+	p.Outputs = make([]PsbtOutput, len(p.UnsignedTx.TxOut))
+	for i := range p.Outputs {
+		if err = readPsbtMap(r, p.Outputs[i].setKV); err != nil {
+			return nil, errors.Annotatef(err, "output %d", i)
+		}
+	}
+	return p, nil
+}
+
+// B64DecodePsbt decodes the psbt in base64, as returned by B64Encode
+func B64DecodePsbt(s string) (*Psbt, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+	return DeserializePsbt(b)
+}
+
+// InputUtxo returns the output spent by the input i, as stored in the psbt
+func (p *Psbt) InputUtxo(i int) (*wire.TxOut, error) {
+	in := &p.Inputs[i]
+	if in.WitnessUtxo != nil {
+		return in.WitnessUtxo, nil
+	}
+	if in.NonWitnessUtxo != nil {
+		prev := &p.UnsignedTx.TxIn[i].PreviousOutPoint
+		tx := wire.MsgTx{}
+		if err := tx.Deserialize(bytes.NewReader(in.NonWitnessUtxo)); err != nil {
+			return nil, err
+		}
+		if tx.TxHash() != prev.Hash || int(prev.Index) >= len(tx.TxOut) {
+			return nil, errors.New("Non witness utxo does not match the input")
+		}
+		return tx.TxOut[prev.Index], nil
+	}
+	return nil, nil
+}
+
+func isP2PKH(script []byte) bool {
+	return len(script) == 25 && script[0] == txscript.OP_DUP && script[1] == txscript.OP_HASH160 && script[2] == 0x14 &&
+		script[23] == txscript.OP_EQUALVERIFY && script[24] == txscript.OP_CHECKSIG
+}
+
+func isP2PK(script []byte) bool {
+	return (len(script) == 35 && script[0] == 0x21 || len(script) == 67 && script[0] == 0x41) && script[len(script)-1] == txscript.OP_CHECKSIG
+}
+
+func isP2SH(script []byte) bool {
+	return len(script) == 23 && script[0] == txscript.OP_HASH160 && script[1] == 0x14 && script[22] == txscript.OP_EQUAL
+}
+
+func isP2WPKH(script []byte) bool {
+	return len(script) == 22 && script[0] == txscript.OP_0 && script[1] == 0x14
+}
+
+func isP2WSH(script []byte) bool {
+	return len(script) == 34 && script[0] == txscript.OP_0 && script[1] == 0x20
+}
+
+func isP2TR(script []byte) bool {
+	return len(script) == 34 && script[0] == txscript.OP_1 && script[1] == 0x20
+}
+
+// parseMultisig returns the number of required signatures and the public keys of the script OP_m <pubkeys> OP_n OP_CHECKMULTISIG
+// the pay-to-pubkey script is handled as 1 of 1 multisig
+func parseMultisig(script []byte) (int, [][]byte, bool) {
+	if (len(script) == 35 || len(script) == 67) && int(script[0]) == len(script)-2 && script[len(script)-1] == txscript.OP_CHECKSIG {
+		return 1, [][]byte{script[1 : len(script)-1]}, true
+	}
+	if len(script) < 3 || script[len(script)-1] != txscript.OP_CHECKMULTISIG ||
+		script[0] < txscript.OP_1 || script[0] > txscript.OP_16 {
+		return 0, nil, false
+	}
+	m := int(script[0] - txscript.OP_1 + 1)
+	var pubKeys [][]byte
+	i := 1
+	for i < len(script)-2 && (script[i] == 33 || script[i] == 65) {
+		l := int(script[i])
+		if i+1+l > len(script)-2 {
+			return 0, nil, false
+		}
+		pubKeys = append(pubKeys, script[i+1:i+1+l])
+		i += 1 + l
+	}
+	if i != len(script)-2 || int(script[i]-txscript.OP_1+1) != len(pubKeys) || m > len(pubKeys) {
+		return 0, nil, false
+	}
+	return m, pubKeys, true
+}
+
+func (in *PsbtInput) signature(pubKey []byte) []byte {
+	for i := range in.PartialSigs {
+		if bytes.Equal(in.PartialSigs[i].PubKey, pubKey) {
+			return in.PartialSigs[i].Signature
+		}
+	}
+	return nil
+}
+
+// keyHashPubKeys returns the known public keys of the input (from the signatures and derivations) with the hash
+func (in *PsbtInput) keyHashPubKeys(hash []byte) [][]byte {
+	var r [][]byte
+	add := func(pubKey []byte) {
+		if !bytes.Equal(btcutil.Hash160(pubKey), hash) {
+			return
+		}
+		for _, k := range r {
+			if bytes.Equal(k, pubKey) {
+				return
+			}
+		}
+		r = append(r, pubKey)
+	}
+	for i := range in.PartialSigs {
+		add(in.PartialSigs[i].PubKey)
+	}
+	for i := range in.Bip32Derivation {
+		add(in.Bip32Derivation[i].PubKey)
+	}
+	return r
+}
+
+// inputScript resolves the script which must be satisfied by the signatures of the input spending pkScript,
+// returns the script, the number of required signatures and the public keys which can sign it
+func (in *PsbtInput) inputScript(pkScript []byte) (script []byte, required int, pubKeys [][]byte, err error) {
+	script = pkScript
+	if isP2SH(script) {
+		if in.RedeemScript == nil {
+			return nil, 0, nil, errors.New("Missing redeem script")
+		}
+		if !bytes.Equal(btcutil.Hash160(in.RedeemScript), script[2:22]) {
+			return nil, 0, nil, errors.New("Redeem script does not match the spent output")
+		}
+		script = in.RedeemScript
+	}
+	if isP2WSH(script) {
+		if in.WitnessScript == nil {
+			return nil, 0, nil, errors.New("Missing witness script")
+		}
+		h := sha256.Sum256(in.WitnessScript)
+		if !bytes.Equal(h[:], script[2:]) {
+			return nil, 0, nil, errors.New("Witness script does not match the spent output")
+		}
+		script = in.WitnessScript
+	}
+	switch {
+	case isP2PK(script):
+		return script, 1, [][]byte{script[1 : len(script)-1]}, nil
+	case isP2PKH(script):
+		return script, 1, in.keyHashPubKeys(script[3:23]), nil
+	case isP2WPKH(script):
+		return script, 1, in.keyHashPubKeys(script[2:]), nil
+	case isP2TR(script):
+		return script, 1, [][]byte{script[2:]}, nil
+	}
+	if m, keys, ok := parseMultisig(script); ok {
+		return script, m, keys, nil
+	}
+	return nil, 0, nil, errors.Errorf("Unsupported script %v", hex.EncodeToString(script))
+}
+
+// Signatures returns the state of signing of the input i spending the output script pkScript
+func (p *Psbt) Signatures(i int, pkScript []byte) (*InputSignatures, error) {
+	in := &p.Inputs[i]
+	if in.FinalScriptSig != nil || in.FinalScriptWitness != nil {
+		return &InputSignatures{Finalized: true}, nil
+	}
+	script, required, pubKeys, err := in.inputScript(pkScript)
+	if err != nil {
+		return nil, err
+	}
+	s := InputSignatures{Required: required}
+	if isP2TR(script) {
+		if in.TapKeySig != nil {
+			s.Signed = 1
+		} else {
+			s.Missing = pubKeys
+		}
+		return &s, nil
+	}
+	for _, k := range pubKeys {
+		if in.signature(k) != nil {
+			s.Signed++
+		} else {
+			s.Missing = append(s.Missing, k)
+		}
+	}
+	if s.Signed >= s.Required {
+		s.Missing = nil
+	}
+	return &s, nil
+}
+
+// FinalizeInput creates the final scriptSig and witness of the input i spending the output script pkScript from the signatures
+// and removes the data which are not necessary anymore, as the finalizer role of BIP174
+func (p *Psbt) FinalizeInput(i int, pkScript []byte) error {
+	in := &p.Inputs[i]
+	if in.FinalScriptSig != nil || in.FinalScriptWitness != nil {
+		return nil
+	}
+	script, required, pubKeys, err := in.inputScript(pkScript)
+	if err != nil {
+		return err
+	}
+	// items are the data satisfying the script, they go to the witness if the script is a witness script
+	var items, witness [][]byte
+	switch {
+	case isP2TR(script):
+		if in.TapKeySig == nil {
+			return errors.New("Missing signature")
+		}
+		witness = [][]byte{in.TapKeySig}
+	case isP2PK(script):
+		sig := in.signature(pubKeys[0])
+		if sig == nil {
+			return errors.New("Missing signature")
+		}
+		items = [][]byte{sig}
+	case isP2PKH(script) || isP2WPKH(script):
+		var sig, pubKey []byte
+		for _, k := range pubKeys {
+			if sig = in.signature(k); sig != nil {
+				pubKey = k
+				break
+			}
+		}
+		if sig == nil {
+			return errors.New("Missing signature")
+		}
+		if isP2WPKH(script) {
+			witness = [][]byte{sig, pubKey}
+		} else {
+			items = [][]byte{sig, pubKey}
+		}
+	default:
+		// multisig, the signatures must be in the order of the public keys in the script
+		sigs := make([][]byte, 0, required)
+		for _, k := range pubKeys {
+			if sig := in.signature(k); sig != nil && len(sigs) < required {
+				sigs = append(sigs, sig)
+			}
+		}
+		if len(sigs) < required {
+			return errors.Errorf("Missing signatures, %d of %d present", len(sigs), required)
+		}
+		if script[len(script)-1] == txscript.OP_CHECKMULTISIG {
+			// the extra item consumed by OP_CHECKMULTISIG
+			items = append(items, []byte{})
+		}
+		items = append(items, sigs...)
+	}
+	b := txscript.NewScriptBuilder()
+	if in.WitnessScript != nil && bytes.Equal(script, in.WitnessScript) {
+		witness = append(items, in.WitnessScript)
+	} else {
+		// the empty item is pushed as OP_0
+		for _, item := range items {
+			b.AddData(item)
+		}
+	}
+	if isP2SH(pkScript) {
+		b.AddData(in.RedeemScript)
+	}
+	scriptSig, err := b.Script()
+	if err != nil {
+		return err
+	}
+	if len(scriptSig) > 0 {
+		in.FinalScriptSig = scriptSig
+	}
+	in.FinalScriptWitness = witness
+	in.PartialSigs = nil
+	in.SighashType = 0
+	in.RedeemScript = nil
+	in.WitnessScript = nil
+	in.Bip32Derivation = nil
+	in.TapKeySig = nil
+	return nil
+}
+
+// Extract returns the signed transaction, all inputs must be finalized
+func (p *Psbt) Extract() (*wire.MsgTx, error) {
+	tx := p.UnsignedTx.Copy()
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		if in.FinalScriptSig == nil && in.FinalScriptWitness == nil {
+			return nil, errors.Errorf("Input %d is not finalized", i)
+		}
+		tx.TxIn[i].SignatureScript = in.FinalScriptSig
+		tx.TxIn[i].Witness = in.FinalScriptWitness
+	}
+	return tx, nil
+}
@@ -0,0 +1,230 @@
+// +build unittest
+
+package btc
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+	"github.com/martinboehm/btcd/wire"
+)
+
+func TestPsbt_Serialize(t *testing.T) {
+	script, _ := hex.DecodeString("0014d85c2b71d0060b09c9886aeb815e50991dda124d")
+	pubKey, _ := hex.DecodeString("0330d54fd0dd420a6e5f8d3624f5f3482cae350f79d5f0753bf5beef9c2d91af3c")
+	fingerprint, _ := hex.DecodeString("d90c6a4f")
+	hash, err := chainhash.NewHashFromStr("75ddabb27b8845f5247975c8a5ba7c6f336c4570708ebe230caf6db5217ae858")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := wire.NewMsgTx(2)
+	in := wire.NewTxIn(wire.NewOutPoint(hash, 0), nil, nil)
+	in.Sequence = wire.MaxTxInSequenceNum - 2
+	tx.AddTxIn(in)
+	tx.AddTxOut(wire.NewTxOut(99999699, script))
+	p := NewPsbt(tx)
+	p.Inputs[0].WitnessUtxo = wire.NewTxOut(100000000, script)
+	p.Inputs[0].Bip32Derivation = []Bip32Derivation{{PubKey: pubKey, Fingerprint: fingerprint, Path: []uint32{0x80000054, 0x80000000, 0x80000000, 0, 1}}}
+	p.Outputs[0].Bip32Derivation = []Bip32Derivation{{PubKey: pubKey, Fingerprint: fingerprint, Path: []uint32{0x80000054, 0x80000000, 0x80000000, 1, 0}}}
+	got, err := p.B64Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "cHNidP8BAFICAAAAAVjoeiG1ba8MI76OcHBFbDNvfLqlyHV5JPVFiHuyq911AAAAAAD9////AdPf9QUAAAAAFgAU2FwrcdAGCwnJiGrrgV5QmR3aEk0AAAAAAAEBHwDh9QUAAAAAFgAU2FwrcdAGCwnJiGrrgV5QmR3aEk0iBgMw1U/Q3UIKbl+NNiT180gsrjUPedXwdTv1vu+cLZGvPBjZDGpPVAAAgAAAAIAAAACAAAAAAAEAAAAAIgIDMNVP0N1CCm5fjTYk9fNILK41D3nV8HU79b7vnC2RrzwY2QxqT1QAAIAAAACAAAAAgAEAAAAAAAAAAA=="
+	if got != want {
+		t.Errorf("B64Encode() = %v, want %v", got, want)
+	}
+}
+
+func TestParseBip32Path(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    []uint32
+		wantErr bool
+	}{
+		{path: "m/49'/1'/33'/0/5", want: []uint32{0x80000031, 0x80000001, 0x80000021, 0, 5}},
+		{path: "84h/0h/0h/1/0", want: []uint32{0x80000054, 0x80000000, 0x80000000, 1, 0}},
+		{path: "m", want: []uint32{}},
+		{path: "unknown/0'/0/1", wantErr: true},
+		{path: "m/2147483648", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := ParseBip32Path(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBip32Path() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBip32Path() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestP2shP2wpkhRedeemScript(t *testing.T) {
+	// the first address of the BIP49 test vector, 2Mww8dCYPUpKHofjgcXcBCEGmniw9CoaiD2
+	pubKey, _ := hex.DecodeString("03a1af804ac108a8a51782198c2d034b28bf90c8803f5a53f76276fa69a4eae77f")
+	pkScript, _ := hex.DecodeString("a914336caa13e08b96080a32b5d818d59b4ab3b3674287")
+	want := "001438971f73930f6c141d977ac4fd4a727c854935b3"
+	if got := hex.EncodeToString(P2shP2wpkhRedeemScript(pubKey, pkScript)); got != want {
+		t.Errorf("P2shP2wpkhRedeemScript() = %v, want %v", got, want)
+	}
+	if got := P2shP2wpkhRedeemScript(pubKey, pkScript[:22]); got != nil {
+		t.Errorf("P2shP2wpkhRedeemScript() = %v, want nil", got)
+	}
+}
+
+func TestB64DecodePsbt(t *testing.T) {
+	s := "cHNidP8BAFICAAAAAVjoeiG1ba8MI76OcHBFbDNvfLqlyHV5JPVFiHuyq911AAAAAAD9////AdPf9QUAAAAAFgAU2FwrcdAGCwnJiGrrgV5QmR3aEk0AAAAAAAEBHwDh9QUAAAAAFgAU2FwrcdAGCwnJiGrrgV5QmR3aEk0iBgMw1U/Q3UIKbl+NNiT180gsrjUPedXwdTv1vu+cLZGvPBjZDGpPVAAAgAAAAIAAAACAAAAAAAEAAAAAIgIDMNVP0N1CCm5fjTYk9fNILK41D3nV8HU79b7vnC2RrzwY2QxqT1QAAIAAAACAAAAAgAEAAAAAAAAAAA=="
+	p, err := B64DecodePsbt(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.UnsignedTx.TxIn[0].PreviousOutPoint.Hash.String(); got != "75ddabb27b8845f5247975c8a5ba7c6f336c4570708ebe230caf6db5217ae858" {
+		t.Errorf("B64DecodePsbt() input txid = %v", got)
+	}
+	if p.Inputs[0].WitnessUtxo == nil || p.Inputs[0].WitnessUtxo.Value != 100000000 {
+		t.Errorf("B64DecodePsbt() WitnessUtxo = %+v", p.Inputs[0].WitnessUtxo)
+	}
+	if len(p.Outputs[0].Bip32Derivation) != 1 || !reflect.DeepEqual(p.Outputs[0].Bip32Derivation[0].Path, []uint32{0x80000054, 0x80000000, 0x80000000, 1, 0}) {
+		t.Errorf("B64DecodePsbt() output Bip32Derivation = %+v", p.Outputs[0].Bip32Derivation)
+	}
+	got, err := p.B64Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != s {
+		t.Errorf("B64Encode() = %v, want %v", got, s)
+	}
+	for _, invalid := range []string{"", "cHNidA==", s[:40]} {
+		if _, err := B64DecodePsbt(invalid); err == nil {
+			t.Errorf("B64DecodePsbt(%v) expected error", invalid)
+		}
+	}
+}
+
+func TestPsbt_FinalizeInput(t *testing.T) {
+	pubKey1, _ := hex.DecodeString("0330d54fd0dd420a6e5f8d3624f5f3482cae350f79d5f0753bf5beef9c2d91af3c")
+	pubKey2, _ := hex.DecodeString("03e775fd51f0dfb8cd865d9ff1cca2a158cf651fe997fdc9fee9c1d3b5e995ea77")
+	p2pk, _ := hex.DecodeString("210330d54fd0dd420a6e5f8d3624f5f3482cae350f79d5f0753bf5beef9c2d91af3cac")
+	p2wpkh, _ := hex.DecodeString("0014c0cebcd6c3d3ca8c75dc5ec62ebe55330ef910e2")
+	p2sh, _ := hex.DecodeString("a914a6b5888fddc8fa193dd353d10e5cd5a8eeab064e87")
+	multisig, _ := hex.DecodeString("52210330d54fd0dd420a6e5f8d3624f5f3482cae350f79d5f0753bf5beef9c2d91af3c2103e775fd51f0dfb8cd865d9ff1cca2a158cf651fe997fdc9fee9c1d3b5e995ea7752ae")
+	p2wsh, _ := hex.DecodeString("0020b8e2342310b2f2114d0604a4b382f03b277d7504e0458d8d36edc9a9a6c4945f")
+	p2pkh, _ := hex.DecodeString("76a914c0cebcd6c3d3ca8c75dc5ec62ebe55330ef910e288ac")
+	p2wshP2pk, _ := hex.DecodeString("00202a1b4c8d626a3c93f60bff871dafd924e0827206150516bcd2a66c84ea30bfcf")
+	p2wshP2pkh, _ := hex.DecodeString("0020d5235c05c4fae02187dfe85bfc3f4c8e4d1f347ece4234e36d1396578e21e8bb")
+	sig1 := []byte{0x30, 0x01, 0x01}
+	sig2 := []byte{0x30, 0x02, 0x01}
+	tests := []struct {
+		name           string
+		in             PsbtInput
+		pkScript       []byte
+		wantSignatures InputSignatures
+		wantErr        bool
+		wantScriptSig  string
+		wantWitness    [][]byte
+	}{
+		{
+			name:           "P2PK",
+			in:             PsbtInput{PartialSigs: []PartialSig{{PubKey: pubKey1, Signature: sig1}}},
+			pkScript:       p2pk,
+			wantSignatures: InputSignatures{Required: 1, Signed: 1},
+			wantScriptSig:  "03" + hex.EncodeToString(sig1),
+		},
+		{
+			name:           "P2PK unsigned",
+			in:             PsbtInput{PartialSigs: []PartialSig{{PubKey: pubKey2, Signature: sig2}}},
+			pkScript:       p2pk,
+			wantSignatures: InputSignatures{Required: 1, Missing: [][]byte{pubKey1}},
+			wantErr:        true,
+		},
+		{
+			name:           "P2WPKH",
+			in:             PsbtInput{PartialSigs: []PartialSig{{PubKey: pubKey1, Signature: sig1}}},
+			pkScript:       p2wpkh,
+			wantSignatures: InputSignatures{Required: 1, Signed: 1},
+			wantWitness:    [][]byte{sig1, pubKey1},
+		},
+		{
+			name:           "P2WPKH unsigned",
+			in:             PsbtInput{Bip32Derivation: []Bip32Derivation{{PubKey: pubKey1, Fingerprint: []byte{1, 2, 3, 4}}, {PubKey: pubKey2, Fingerprint: []byte{1, 2, 3, 4}}}},
+			pkScript:       p2wpkh,
+			wantSignatures: InputSignatures{Required: 1, Missing: [][]byte{pubKey1}},
+			wantErr:        true,
+		},
+		{
+			name:           "P2SH-P2WPKH",
+			in:             PsbtInput{PartialSigs: []PartialSig{{PubKey: pubKey1, Signature: sig1}}, RedeemScript: p2wpkh},
+			pkScript:       p2sh,
+			wantSignatures: InputSignatures{Required: 1, Signed: 1},
+			wantScriptSig:  "16" + hex.EncodeToString(p2wpkh),
+			wantWitness:    [][]byte{sig1, pubKey1},
+		},
+		{
+			name:           "P2WSH multisig one signature",
+			in:             PsbtInput{PartialSigs: []PartialSig{{PubKey: pubKey2, Signature: sig2}}, WitnessScript: multisig},
+			pkScript:       p2wsh,
+			wantSignatures: InputSignatures{Required: 2, Signed: 1, Missing: [][]byte{pubKey1}},
+			wantErr:        true,
+		},
+		{
+			name:           "P2WSH multisig",
+			in:             PsbtInput{PartialSigs: []PartialSig{{PubKey: pubKey2, Signature: sig2}, {PubKey: pubKey1, Signature: sig1}}, WitnessScript: multisig},
+			pkScript:       p2wsh,
+			wantSignatures: InputSignatures{Required: 2, Signed: 2},
+			wantWitness:    [][]byte{{}, sig1, sig2, multisig},
+		},
+		{
+			name:           "P2WSH-P2PK",
+			in:             PsbtInput{PartialSigs: []PartialSig{{PubKey: pubKey1, Signature: sig1}}, WitnessScript: p2pk},
+			pkScript:       p2wshP2pk,
+			wantSignatures: InputSignatures{Required: 1, Signed: 1},
+			wantWitness:    [][]byte{sig1, p2pk},
+		},
+		{
+			name:           "P2WSH-P2PKH",
+			in:             PsbtInput{PartialSigs: []PartialSig{{PubKey: pubKey1, Signature: sig1}}, WitnessScript: p2pkh},
+			pkScript:       p2wshP2pkh,
+			wantSignatures: InputSignatures{Required: 1, Signed: 1},
+			wantWitness:    [][]byte{sig1, pubKey1, p2pkh},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := wire.NewMsgTx(2)
+			tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, 0), nil, nil))
+			tx.AddTxOut(wire.NewTxOut(1000, p2wpkh))
+			p := NewPsbt(tx)
+			p.Inputs[0] = tt.in
+			s, err := p.Signatures(0, tt.pkScript)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*s, tt.wantSignatures) {
+				t.Errorf("Signatures() = %+v, want %+v", *s, tt.wantSignatures)
+			}
+			err = p.FinalizeInput(0, tt.pkScript)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FinalizeInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := hex.EncodeToString(p.Inputs[0].FinalScriptSig); got != tt.wantScriptSig {
+				t.Errorf("FinalScriptSig = %v, want %v", got, tt.wantScriptSig)
+			}
+			if !reflect.DeepEqual(p.Inputs[0].FinalScriptWitness, tt.wantWitness) {
+				t.Errorf("FinalScriptWitness = %x, want %x", p.Inputs[0].FinalScriptWitness, tt.wantWitness)
+			}
+			if p.Inputs[0].PartialSigs != nil || p.Inputs[0].RedeemScript != nil || p.Inputs[0].WitnessScript != nil {
+				t.Errorf("FinalizeInput() did not clear the input data")
+			}
+			if _, err = p.Extract(); err != nil {
+				t.Errorf("Extract() error = %v", err)
+			}
+		})
+	}
+}
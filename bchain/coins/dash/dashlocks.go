@@ -0,0 +1,158 @@
+package dash
+
+import (
+	"blockbook/bchain"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// instantLocks are kept only until the transactions are safely ChainLocked
+const instantLockKeepPeriod = 2 * time.Hour
+
+// getbestchainlock
+
+type cmdGetBestChainLock struct {
+	Method string `json:"method"`
+}
+
+type resGetBestChainLock struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result struct {
+		Blockhash string `json:"blockhash"`
+		Height    uint32 `json:"height"`
+	} `json:"result"`
+}
+
+// txLockStatus is the InstantSend and ChainLock status returned by getrawtransaction
+type txLockStatus struct {
+	InstantLock bool `json:"instantlock"`
+	ChainLock   bool `json:"chainlock"`
+}
+
+// InitializeLocks sets the handlers of InstantSend and ChainLock notifications and loads the best ChainLock
+func (b *DashRPC) InitializeLocks(onNewInstantLock bchain.OnNewInstantLockFunc, onNewChainLock bchain.OnNewChainLockFunc) error {
+	b.lockMux.Lock()
+	b.onNewInstantLock = onNewInstantLock
+	b.onNewChainLock = onNewChainLock
+	b.lockMux.Unlock()
+	hash, height, err := b.getBestChainLock()
+	if err != nil {
+		// older backends do not support ChainLocks, it is not a fatal error
+		glog.Warning("rpc: getbestchainlock ", err)
+		return nil
+	}
+	b.setChainLock(hash, height, false)
+	return nil
+}
+
+func (b *DashRPC) getBestChainLock() (string, uint32, error) {
+	glog.V(1).Info("rpc: getbestchainlock")
+
+	res := resGetBestChainLock{}
+	req := cmdGetBestChainLock{Method: "getbestchainlock"}
+	err := b.Call(&req, &res)
+
+	if err != nil {
+		return "", 0, err
+	}
+	if res.Error != nil {
+		return "", 0, res.Error
+	}
+	return res.Result.Blockhash, res.Result.Height, nil
+}
+
+// IsInstantLocked returns true if the transaction is known to be locked by InstantSend
+func (b *DashRPC) IsInstantLocked(txid string) bool {
+	b.lockMux.Lock()
+	defer b.lockMux.Unlock()
+	_, found := b.instantLocks[txid]
+	return found
+}
+
+// GetBestChainLockHeight returns the height of the last ChainLocked block, all blocks below it are ChainLocked too
+func (b *DashRPC) GetBestChainLockHeight() uint32 {
+	b.lockMux.Lock()
+	defer b.lockMux.Unlock()
+	return b.chainLockHeight
+}
+
+// setInstantLock stores InstantSend lock of the transaction and sends notification if the lock is new
+func (b *DashRPC) setInstantLock(txid string) {
+	b.lockMux.Lock()
+	_, found := b.instantLocks[txid]
+	if !found {
+		b.instantLocks[txid] = time.Now()
+	}
+	onNewInstantLock := b.onNewInstantLock
+	b.lockMux.Unlock()
+	if !found && onNewInstantLock != nil {
+		onNewInstantLock(txid)
+	}
+}
+
+// setChainLock stores ChainLock of the block, removes expired InstantSend locks and optionally sends notification
+func (b *DashRPC) setChainLock(hash string, height uint32, notify bool) {
+	b.lockMux.Lock()
+	if height <= b.chainLockHeight {
+		b.lockMux.Unlock()
+		return
+	}
+	b.chainLockHeight = height
+	expired := time.Now().Add(-instantLockKeepPeriod)
+	for txid, t := range b.instantLocks {
+		if t.Before(expired) {
+			delete(b.instantLocks, txid)
+		}
+	}
+	onNewChainLock := b.onNewChainLock
+	b.lockMux.Unlock()
+	glog.V(1).Info("rpc: chainlock ", height, " ", hash)
+	if notify && onNewChainLock != nil {
+		onNewChainLock(hash, height)
+	}
+}
+
+func (b *DashRPC) onHashTxLock(body []byte) {
+	if len(body) != 32 {
+		glog.Error("MQ: hashtxlock invalid message length ", len(body))
+		return
+	}
+	b.setInstantLock(hex.EncodeToString(body))
+}
+
+func (b *DashRPC) onHashChainLock(body []byte) {
+	if len(body) != 32 {
+		glog.Error("MQ: hashchainlock invalid message length ", len(body))
+		return
+	}
+	hash := hex.EncodeToString(body)
+	header, err := b.GetBlockHeader(hash)
+	if err != nil {
+		glog.Error("MQ: hashchainlock ", hash, ": ", err)
+		return
+	}
+	b.setChainLock(hash, header.Height, true)
+}
+
+// GetTransaction returns a transaction by the transaction ID, the InstantSend lock status is read from the returned data
+func (b *DashRPC) GetTransaction(txid string) (*bchain.Tx, error) {
+	tx, err := b.BitcoinRPC.GetTransaction(txid)
+	if err != nil {
+		return nil, err
+	}
+	if csd, ok := tx.CoinSpecificData.(json.RawMessage); ok {
+		var ls txLockStatus
+		if err = json.Unmarshal(csd, &ls); err != nil {
+			return nil, errors.Annotatef(err, "txid %v", txid)
+		}
+		// transaction in a ChainLocked block does not need the InstantSend lock
+		if ls.InstantLock && !ls.ChainLock {
+			b.setInstantLock(txid)
+		}
+	}
+	return tx, nil
+}
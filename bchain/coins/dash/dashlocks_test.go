@@ -0,0 +1,51 @@
+// +build unittest
+
+package dash
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDashRPC_Locks(t *testing.T) {
+	b := &DashRPC{instantLocks: make(map[string]time.Time)}
+	var instantLocks []string
+	var chainLocks []uint32
+	b.onNewInstantLock = func(txid string) {
+		instantLocks = append(instantLocks, txid)
+	}
+	b.onNewChainLock = func(hash string, height uint32) {
+		chainLocks = append(chainLocks, height)
+	}
+
+	txid := "8a6e1bde7e2f0b35f2e1a1ee84aaa4b8a04fcb1ec8c4d7beb6fa2b8ef47bfd1b"
+	if b.IsInstantLocked(txid) {
+		t.Errorf("IsInstantLocked() = true before lock")
+	}
+	b.setInstantLock(txid)
+	b.setInstantLock(txid)
+	if !b.IsInstantLocked(txid) {
+		t.Errorf("IsInstantLocked() = false after lock")
+	}
+	if len(instantLocks) != 1 || instantLocks[0] != txid {
+		t.Errorf("instant lock notifications = %v, want [%v]", instantLocks, txid)
+	}
+
+	b.setChainLock("hash1", 1000, true)
+	// older chainlock must not lower the height
+	b.setChainLock("hash0", 999, true)
+	b.setChainLock("hash2", 1001, false)
+	if got := b.GetBestChainLockHeight(); got != 1001 {
+		t.Errorf("GetBestChainLockHeight() = %v, want 1001", got)
+	}
+	if len(chainLocks) != 1 || chainLocks[0] != 1000 {
+		t.Errorf("chain lock notifications = %v, want [1000]", chainLocks)
+	}
+
+	// expired instant locks are removed on a new chainlock
+	b.instantLocks[txid] = time.Now().Add(-instantLockKeepPeriod - time.Minute)
+	b.setChainLock("hash3", 1002, true)
+	if b.IsInstantLocked(txid) {
+		t.Errorf("IsInstantLocked() = true after expiration")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"blockbook/bchain"
 	"blockbook/bchain/coins/btc"
 	"encoding/json"
+	"sync"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/juju/errors"
@@ -14,6 +16,11 @@ const firstBlockWithSpecialTransactions = 1028160
 // DashRPC is an interface to JSON-RPC bitcoind service
 type DashRPC struct {
 	*btc.BitcoinRPC
+	lockMux          sync.Mutex
+	instantLocks     map[string]time.Time
+	chainLockHeight  uint32
+	onNewInstantLock bchain.OnNewInstantLockFunc
+	onNewChainLock   bchain.OnNewChainLockFunc
 }
 
 // NewDashRPC returns new DashRPC instance
@@ -24,10 +31,15 @@ func NewDashRPC(config json.RawMessage, pushHandler func(bchain.NotificationType
 	}
 
 	s := &DashRPC{
-		b.(*btc.BitcoinRPC),
+		BitcoinRPC:   b.(*btc.BitcoinRPC),
+		instantLocks: make(map[string]time.Time),
 	}
 	s.RPCMarshaler = btc.JSONMarshalerV1{}
 	s.ChainConfig.SupportsEstimateSmartFee = false
+	s.MQTopicHandlers = map[string]bchain.MQTopicHandler{
+		"hashtxlock":    s.onHashTxLock,
+		"hashchainlock": s.onHashChainLock,
+	}
 
 	return s, nil
 }
@@ -782,6 +782,11 @@ func (d *DecredRPC) EstimateFee(blocks int) (big.Int, error) {
 	return r, nil
 }
 
+// TestMempoolAccept is not supported by the Decred backend
+func (d *DecredRPC) TestMempoolAccept(tx string) (*bchain.MempoolAcceptResult, error) {
+	return nil, bchain.ErrNotSupported
+}
+
 func (d *DecredRPC) SendRawTransaction(tx string) (string, error) {
 	sendRawTxRequest := &GenericCmd{
 		ID:     1,
@@ -41,7 +41,7 @@ const erc20BalanceOf = "0x70a08231"
 var cachedContracts = make(map[string]*bchain.Erc20Contract)
 var cachedContractsMux sync.Mutex
 
-func addressFromPaddedHex(s string) (string, error) {
+func ethAddressFromPaddedHex(s string) (ethcommon.Address, error) {
 	var t big.Int
 	var ok bool
 	if has0xPrefix(s) {
@@ -50,34 +50,66 @@ func addressFromPaddedHex(s string) (string, error) {
 		_, ok = t.SetString(s, 16)
 	}
 	if !ok {
-		return "", errors.New("Data is not a number")
+		return ethcommon.Address{}, errors.New("Data is not a number")
+	}
+	return ethcommon.BigToAddress(&t), nil
+}
+
+func addressFromPaddedHex(s string) (string, error) {
+	a, err := ethAddressFromPaddedHex(s)
+	if err != nil {
+		return "", err
 	}
-	a := ethcommon.BigToAddress(&t)
 	return a.String(), nil
 }
 
+// Erc20TransferLog contains ERC20 Transfer event decoded from log
+type Erc20TransferLog struct {
+	From   ethcommon.Address
+	To     ethcommon.Address
+	Tokens big.Int
+}
+
+// ParseErc20TransferLog decodes ERC20 Transfer event from log topics and data
+// the values can be with or without the 0x prefix (Qtum type coins do not use it)
+// nil is returned if the log is not ERC20 Transfer event
+func ParseErc20TransferLog(topics []string, data string) (*Erc20TransferLog, error) {
+	if len(topics) != 3 || strings.TrimPrefix(topics[0], "0x") != erc20TransferEventSignature[2:] {
+		return nil, nil
+	}
+	var r Erc20TransferLog
+	var ok bool
+	if has0xPrefix(data) {
+		_, ok = r.Tokens.SetString(data[2:], 16)
+	} else {
+		_, ok = r.Tokens.SetString(data, 16)
+	}
+	if !ok {
+		return nil, errors.New("Data is not a number")
+	}
+	var err error
+	if r.From, err = ethAddressFromPaddedHex(topics[1]); err != nil {
+		return nil, err
+	}
+	if r.To, err = ethAddressFromPaddedHex(topics[2]); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
 func erc20GetTransfersFromLog(logs []*rpcLog) ([]bchain.Erc20Transfer, error) {
 	var r []bchain.Erc20Transfer
 	for _, l := range logs {
-		if len(l.Topics) == 3 && l.Topics[0] == erc20TransferEventSignature {
-			var t big.Int
-			_, ok := t.SetString(l.Data, 0)
-			if !ok {
-				return nil, errors.New("Data is not a number")
-			}
-			from, err := addressFromPaddedHex(l.Topics[1])
-			if err != nil {
-				return nil, err
-			}
-			to, err := addressFromPaddedHex(l.Topics[2])
-			if err != nil {
-				return nil, err
-			}
+		t, err := ParseErc20TransferLog(l.Topics, l.Data)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
 			r = append(r, bchain.Erc20Transfer{
 				Contract: strings.ToLower(l.Address),
-				From:     strings.ToLower(from),
-				To:       strings.ToLower(to),
-				Tokens:   t,
+				From:     strings.ToLower(t.From.String()),
+				To:       strings.ToLower(t.To.String()),
+				Tokens:   t.Tokens,
 			})
 		}
 	}
@@ -120,7 +152,8 @@ func (b *EthereumRPC) ethCall(data, to string) (string, error) {
 	return r, nil
 }
 
-func parseErc20NumericProperty(contractDesc bchain.AddressDescriptor, data string) *big.Int {
+// ParseErc20NumericProperty parses uint256 value returned by a contract call
+func ParseErc20NumericProperty(contractDesc bchain.AddressDescriptor, data string) *big.Int {
 	if has0xPrefix(data) {
 		data = data[2:]
 	}
@@ -137,12 +170,13 @@ func parseErc20NumericProperty(contractDesc bchain.AddressDescriptor, data strin
 	return nil
 }
 
-func parseErc20StringProperty(contractDesc bchain.AddressDescriptor, data string) string {
+// ParseErc20StringProperty parses string value returned by a contract call
+func ParseErc20StringProperty(contractDesc bchain.AddressDescriptor, data string) string {
 	if has0xPrefix(data) {
 		data = data[2:]
 	}
 	if len(data) > 128 {
-		n := parseErc20NumericProperty(contractDesc, data[64:128])
+		n := ParseErc20NumericProperty(contractDesc, data[64:128])
 		if n != nil {
 			l := n.Uint64()
 			if 2*int(l) <= len(data)-128 {
@@ -183,13 +217,13 @@ func (b *EthereumRPC) EthereumTypeGetErc20ContractInfo(contractDesc bchain.Addre
 		if err != nil {
 			return nil, err
 		}
-		name := parseErc20StringProperty(contractDesc, data)
+		name := ParseErc20StringProperty(contractDesc, data)
 		if name != "" {
 			data, err = b.ethCall(erc20SymbolSignature, address)
 			if err != nil {
 				return nil, err
 			}
-			symbol := parseErc20StringProperty(contractDesc, data)
+			symbol := ParseErc20StringProperty(contractDesc, data)
 			data, err = b.ethCall(erc20DecimalsSignature, address)
 			if err != nil {
 				return nil, err
@@ -199,7 +233,7 @@ func (b *EthereumRPC) EthereumTypeGetErc20ContractInfo(contractDesc bchain.Addre
 				Name:     name,
 				Symbol:   symbol,
 			}
-			d := parseErc20NumericProperty(contractDesc, data)
+			d := ParseErc20NumericProperty(contractDesc, data)
 			if d != nil {
 				contract.Decimals = int(uint8(d.Uint64()))
 			} else {
@@ -224,7 +258,7 @@ func (b *EthereumRPC) EthereumTypeGetErc20ContractBalance(addrDesc, contractDesc
 	if err != nil {
 		return nil, err
 	}
-	r := parseErc20NumericProperty(contractDesc, data)
+	r := ParseErc20NumericProperty(contractDesc, data)
 	if r == nil {
 		return nil, errors.New("Invalid balance")
 	}
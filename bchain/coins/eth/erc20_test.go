@@ -112,7 +112,7 @@ func TestErc20_erc20GetTransfersFromLog(t *testing.T) {
 	}
 }
 
-func TestErc20_parseErc20StringProperty(t *testing.T) {
+func TestErc20_ParseErc20StringProperty(t *testing.T) {
 	tests := []struct {
 		name string
 		args string
@@ -141,10 +141,10 @@ func TestErc20_parseErc20StringProperty(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := parseErc20StringProperty(nil, tt.args)
+			got := ParseErc20StringProperty(nil, tt.args)
 			// the addresses could have different case
 			if got != tt.want {
-				t.Errorf("parseErc20StringProperty = %v, want %v", got, tt.want)
+				t.Errorf("ParseErc20StringProperty = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -30,11 +30,15 @@ func init() {
 // NamecoinParser handle
 type NamecoinParser struct {
 	*btc.BitcoinParser
+	BitcoinOutputScriptToAddressesFunc btc.OutputScriptToAddressesFunc
 }
 
 // NewNamecoinParser returns new NamecoinParser instance
 func NewNamecoinParser(params *chaincfg.Params, c *btc.Configuration) *NamecoinParser {
-	return &NamecoinParser{BitcoinParser: btc.NewBitcoinParser(params, c)}
+	p := &NamecoinParser{BitcoinParser: btc.NewBitcoinParser(params, c)}
+	p.BitcoinOutputScriptToAddressesFunc = p.OutputScriptToAddressesFunc
+	p.OutputScriptToAddressesFunc = p.outputScriptToAddresses
+	return p
 }
 
 // GetChainParams contains network parameters for the main Namecoin network,
@@ -0,0 +1,132 @@
+package namecoin
+
+import (
+	"blockbook/bchain"
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/martinboehm/btcutil/txscript"
+)
+
+// the names expire after this number of blocks from their last update
+const nameExpirationDepth = 36000
+
+// the name operations are prefixed to the standard locking script
+// name_new: OP_1 <hash> OP_2DROP <script>
+// name_firstupdate: OP_2 <name> <rand> <value> OP_2DROP OP_2DROP <script>
+// name_update: OP_3 <name> <value> OP_2DROP OP_DROP <script>
+const (
+	opNameNew         = txscript.OP_1
+	opNameFirstUpdate = txscript.OP_2
+	opNameUpdate      = txscript.OP_3
+)
+
+// nameData returns the data pushed at the beginning of the script and the length of the push
+func nameData(script []byte) ([]byte, int, bool) {
+	if len(script) == 0 {
+		return nil, 0, false
+	}
+	op := script[0]
+	var l, s int
+	switch {
+	case op == txscript.OP_0:
+		return []byte{}, 1, true
+	case op < txscript.OP_PUSHDATA1:
+		l, s = int(op), 1
+	case op == txscript.OP_PUSHDATA1 && len(script) > 1:
+		l, s = int(script[1]), 2
+	case op == txscript.OP_PUSHDATA2 && len(script) > 2:
+		l, s = int(binary.LittleEndian.Uint16(script[1:])), 3
+	case op == txscript.OP_PUSHDATA4 && len(script) > 4:
+		l, s = int(binary.LittleEndian.Uint32(script[1:])), 5
+	default:
+		return nil, 0, false
+	}
+	if l < 0 || len(script) < s+l {
+		return nil, 0, false
+	}
+	return script[s : s+l], s + l, true
+}
+
+// parseNameScript splits the script to the name operation and the standard locking script
+// if the script is not a valid name script, nil operation and unchanged script are returned
+func parseNameScript(script []byte) (*bchain.NameOperation, []byte) {
+	if len(script) == 0 {
+		return nil, script
+	}
+	var pushes int
+	var drops []byte
+	switch script[0] {
+	case opNameNew:
+		pushes, drops = 1, []byte{txscript.OP_2DROP}
+	case opNameFirstUpdate:
+		pushes, drops = 3, []byte{txscript.OP_2DROP, txscript.OP_2DROP}
+	case opNameUpdate:
+		pushes, drops = 2, []byte{txscript.OP_2DROP, txscript.OP_DROP}
+	default:
+		return nil, script
+	}
+	data := make([][]byte, pushes)
+	i := 1
+	for j := range data {
+		d, l, ok := nameData(script[i:])
+		if !ok {
+			return nil, script
+		}
+		data[j] = d
+		i += l
+	}
+	for _, d := range drops {
+		if i >= len(script) || script[i] != d {
+			return nil, script
+		}
+		i++
+	}
+	no := bchain.NameOperation{Op: bchain.NameOp(script[0] - txscript.OP_1 + 1)}
+	switch script[0] {
+	case opNameNew:
+		if len(data[0]) != 20 {
+			return nil, script
+		}
+		no.Hash = data[0]
+	case opNameFirstUpdate:
+		no.Name, no.Rand, no.Value = data[0], data[1], data[2]
+	case opNameUpdate:
+		no.Name, no.Value = data[0], data[1]
+	}
+	return &no, script[i:]
+}
+
+// GetAddrDescFromVout returns internal address representation of given transaction output
+// the name operation is not part of the address descriptor
+func (p *NamecoinParser) GetAddrDescFromVout(output *bchain.Vout) (bchain.AddressDescriptor, error) {
+	ad, err := hex.DecodeString(output.ScriptPubKey.Hex)
+	if err != nil {
+		return ad, err
+	}
+	_, ad = parseNameScript(ad)
+	// convert possible P2PK script to P2PKH
+	// so that all transactions by given public key are indexed together
+	return txscript.ConvertP2PKtoP2PKH(p.Params.Base58CksumHasher, ad)
+}
+
+// outputScriptToAddresses converts ScriptPubKey to addresses, the name operation is ignored
+func (p *NamecoinParser) outputScriptToAddresses(script []byte) ([]string, bool, error) {
+	_, script = parseNameScript(script)
+	return p.BitcoinOutputScriptToAddressesFunc(script)
+}
+
+// GetNameExpirationDepth returns the number of blocks after which the name expires if it is not updated
+func (p *NamecoinParser) GetNameExpirationDepth() uint32 {
+	return nameExpirationDepth
+}
+
+// GetNameOperationFromVout returns the name operation of the output or nil if the output is not a name output
+func (p *NamecoinParser) GetNameOperationFromVout(output *bchain.Vout) (*bchain.NameOperation, error) {
+	script, err := hex.DecodeString(output.ScriptPubKey.Hex)
+	if err != nil {
+		return nil, err
+	}
+	no, _ := parseNameScript(script)
+	return no, nil
+}
@@ -0,0 +1,101 @@
+// +build unittest
+
+package namecoin
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/btc"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func Test_parseNameScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   *bchain.NameOperation
+		rest   string
+	}{
+		{
+			name:   "P2PKH",
+			script: "76a91427a1f12771de5cc3b73941664b2537c15316be4388ac",
+			rest:   "76a91427a1f12771de5cc3b73941664b2537c15316be4388ac",
+		},
+		{
+			name:   "name_new",
+			script: "511400112233445566778899aabbccddeeff001122336d76a91427a1f12771de5cc3b73941664b2537c15316be4388ac",
+			want: &bchain.NameOperation{
+				Op:   bchain.NameNew,
+				Hash: []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff, 0x00, 0x11, 0x22, 0x33},
+			},
+			rest: "76a91427a1f12771de5cc3b73941664b2537c15316be4388ac",
+		},
+		{
+			name:   "name_firstupdate with empty value",
+			script: "5206642f74657374080102030405060708006d6d76a91427a1f12771de5cc3b73941664b2537c15316be4388ac",
+			want: &bchain.NameOperation{
+				Op:    bchain.NameFirstUpdate,
+				Name:  []byte("d/test"),
+				Rand:  []byte{1, 2, 3, 4, 5, 6, 7, 8},
+				Value: []byte{},
+			},
+			rest: "76a91427a1f12771de5cc3b73941664b2537c15316be4388ac",
+		},
+		{
+			name:   "name_update",
+			script: "5306642f74657374027b7d6d7576a91427a1f12771de5cc3b73941664b2537c15316be4388ac",
+			want: &bchain.NameOperation{
+				Op:    bchain.NameUpdate,
+				Name:  []byte("d/test"),
+				Value: []byte("{}"),
+			},
+			rest: "76a91427a1f12771de5cc3b73941664b2537c15316be4388ac",
+		},
+		{
+			name:   "name_update missing drop",
+			script: "5306642f74657374027b7d6d76a91427a1f12771de5cc3b73941664b2537c15316be4388ac",
+			rest:   "5306642f74657374027b7d6d76a91427a1f12771de5cc3b73941664b2537c15316be4388ac",
+		},
+		{
+			name:   "truncated push",
+			script: "5306642f7465",
+			rest:   "5306642f7465",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, _ := hex.DecodeString(tt.script)
+			got, rest := parseNameScript(script)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseNameScript() = %+v, want %+v", got, tt.want)
+			}
+			if h := hex.EncodeToString(rest); h != tt.rest {
+				t.Errorf("parseNameScript() script = %v, want %v", h, tt.rest)
+			}
+		})
+	}
+}
+
+func Test_NameOutputAddresses(t *testing.T) {
+	parser := NewNamecoinParser(GetChainParams("main"), &btc.Configuration{})
+	vout := bchain.Vout{
+		ScriptPubKey: bchain.ScriptPubKey{
+			Hex: "5306642f74657374027b7d6d7576a91427a1f12771de5cc3b73941664b2537c15316be4388ac",
+		},
+	}
+	ad, err := parser.GetAddrDescFromVout(&vout)
+	if err != nil {
+		t.Fatalf("GetAddrDescFromVout() error = %v", err)
+	}
+	if h := hex.EncodeToString(ad); h != "76a91427a1f12771de5cc3b73941664b2537c15316be4388ac" {
+		t.Errorf("GetAddrDescFromVout() = %v", h)
+	}
+	a, _, err := parser.GetAddressesFromAddrDesc(ad)
+	if err != nil {
+		t.Fatalf("GetAddressesFromAddrDesc() error = %v", err)
+	}
+	if !reflect.DeepEqual(a, []string{"MzBvZ4F759X6wHTjzwkMEbKh12am3PHT6F"}) {
+		t.Errorf("GetAddressesFromAddrDesc() = %v", a)
+	}
+}
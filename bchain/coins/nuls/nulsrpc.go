@@ -472,6 +472,11 @@ func (n *NulsRPC) EstimateFee(blocks int) (big.Int, error) {
 	return *big.NewInt(100000), nil
 }
 
+// TestMempoolAccept is not supported by the Nuls backend
+func (n *NulsRPC) TestMempoolAccept(tx string) (*bchain.MempoolAcceptResult, error) {
+	return nil, bchain.ErrNotSupported
+}
+
 func (n *NulsRPC) SendRawTransaction(tx string) (string, error) {
 	broadcast := CmdTxBroadcast{}
 	req := struct {
@@ -0,0 +1,257 @@
+package qtum
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/btc"
+	"blockbook/bchain/coins/eth"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcutil"
+	"github.com/martinboehm/btcutil/chaincfg"
+)
+
+// Qrc20TokenType is the type of the contract tokens of Qtum type coins
+const Qrc20TokenType = "QRC20"
+
+// the contract calls of Qtum are without the 0x prefix
+const qrc20NameSignature = "06fdde03"
+const qrc20SymbolSignature = "95d89b41"
+const qrc20DecimalsSignature = "313ce567"
+const qrc20BalanceOf = "70a08231"
+
+// the last opcode of the contract outputs
+const opCreate = "c1"
+const opCall = "c2"
+
+var cachedQrc20Contracts = make(map[string]*bchain.Erc20Contract)
+var cachedQrc20ContractsMux sync.Mutex
+
+// Qrc20Log is a log entry of a contract execution
+type Qrc20Log struct {
+	Address string   `json:"address"`
+	Topics  []string `json:"topics"`
+	Data    string   `json:"data"`
+}
+
+// Qrc20Receipt is a receipt of a contract execution as returned by gettransactionreceipt
+type Qrc20Receipt struct {
+	ContractAddress string     `json:"contractAddress"`
+	Excepted        string     `json:"excepted"`
+	Log             []Qrc20Log `json:"log"`
+}
+
+// gettransactionreceipt
+
+type cmdGetTransactionReceipt struct {
+	Method string   `json:"method"`
+	Params []string `json:"params"`
+}
+
+type resGetTransactionReceipt struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result []Qrc20Receipt   `json:"result"`
+}
+
+// callcontract
+
+type cmdCallContract struct {
+	Method string   `json:"method"`
+	Params []string `json:"params"`
+}
+
+type resCallContract struct {
+	Error  *bchain.RPCError `json:"error"`
+	Result struct {
+		ExecutionResult struct {
+			Excepted string `json:"excepted"`
+			Output   string `json:"output"`
+		} `json:"executionResult"`
+	} `json:"result"`
+}
+
+// isContractTx returns true if the transaction has an output creating or calling a contract
+func isContractTx(tx *bchain.Tx) bool {
+	for i := range tx.Vout {
+		h := strings.ToLower(tx.Vout[i].ScriptPubKey.Hex)
+		if strings.HasSuffix(h, opCall) || strings.HasSuffix(h, opCreate) {
+			return true
+		}
+	}
+	return false
+}
+
+func getTransactionReceipt(b *btc.BitcoinRPC, txid string) ([]Qrc20Receipt, error) {
+	glog.V(1).Info("rpc: gettransactionreceipt ", txid)
+
+	res := resGetTransactionReceipt{}
+	req := cmdGetTransactionReceipt{Method: "gettransactionreceipt", Params: []string{txid}}
+	err := b.Call(&req, &res)
+
+	if err != nil {
+		return nil, errors.Annotatef(err, "txid %v", txid)
+	}
+	if res.Error != nil {
+		return nil, errors.Annotatef(res.Error, "txid %v", txid)
+	}
+	return res.Result, nil
+}
+
+// GetQrc20BlockReceipts stores the receipts of the contract transactions of the block to their CoinSpecificData
+func GetQrc20BlockReceipts(b *btc.BitcoinRPC, block *bchain.Block) error {
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		if !isContractTx(tx) {
+			continue
+		}
+		r, err := getTransactionReceipt(b, tx.Txid)
+		if err != nil {
+			return err
+		}
+		tx.CoinSpecificData = r
+	}
+	return nil
+}
+
+// GetQrc20TransfersFromTx returns QRC20 transfers from the receipts stored in the CoinSpecificData of the transaction
+// the contract is returned as hex encoded contract address, from and to addresses are P2PKH addresses
+func GetQrc20TransfersFromTx(tx *bchain.Tx, params *chaincfg.Params) ([]bchain.Erc20Transfer, error) {
+	receipts, ok := tx.CoinSpecificData.([]Qrc20Receipt)
+	if !ok {
+		return nil, nil
+	}
+	var r []bchain.Erc20Transfer
+	for i := range receipts {
+		if receipts[i].Excepted != "" && receipts[i].Excepted != "None" {
+			continue
+		}
+		for _, l := range receipts[i].Log {
+			t, err := eth.ParseErc20TransferLog(l.Topics, l.Data)
+			if err != nil {
+				return nil, errors.Annotatef(err, "txid %v", tx.Txid)
+			}
+			if t == nil {
+				continue
+			}
+			from, err := qrc20AddressFromHash(t.From.Bytes(), params)
+			if err != nil {
+				return nil, err
+			}
+			to, err := qrc20AddressFromHash(t.To.Bytes(), params)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, bchain.Erc20Transfer{
+				Contract: strings.ToLower(l.Address),
+				From:     from,
+				To:       to,
+				Tokens:   t.Tokens,
+			})
+		}
+	}
+	return r, nil
+}
+
+// qrc20AddressFromHash converts the hash160 used by the contracts to P2PKH address, zero hash (mint or burn) is returned as empty address
+func qrc20AddressFromHash(hash []byte, params *chaincfg.Params) (string, error) {
+	zero := true
+	for _, b := range hash {
+		if b != 0 {
+			zero = false
+			break
+		}
+	}
+	if zero {
+		return "", nil
+	}
+	a, err := btcutil.NewAddressPubKeyHash(hash, params)
+	if err != nil {
+		return "", err
+	}
+	return a.EncodeAddress(), nil
+}
+
+func callContract(b *btc.BitcoinRPC, contract, data string) (string, error) {
+	glog.V(1).Info("rpc: callcontract ", contract, " ", data)
+
+	res := resCallContract{}
+	req := cmdCallContract{Method: "callcontract", Params: []string{contract, data}}
+	err := b.Call(&req, &res)
+
+	if err != nil {
+		return "", errors.Annotatef(err, "contract %v", contract)
+	}
+	if res.Error != nil {
+		return "", errors.Annotatef(res.Error, "contract %v", contract)
+	}
+	if res.Result.ExecutionResult.Excepted != "None" {
+		return "", errors.Errorf("contract %v execution excepted: %v", contract, res.Result.ExecutionResult.Excepted)
+	}
+	return res.Result.ExecutionResult.Output, nil
+}
+
+// GetQrc20ContractInfo returns information about QRC20 contract, contractDesc is the 20 byte contract address
+func GetQrc20ContractInfo(b *btc.BitcoinRPC, contractDesc bchain.AddressDescriptor) (*bchain.Erc20Contract, error) {
+	cds := string(contractDesc)
+	cachedQrc20ContractsMux.Lock()
+	contract, found := cachedQrc20Contracts[cds]
+	cachedQrc20ContractsMux.Unlock()
+	if !found {
+		address := hex.EncodeToString(contractDesc)
+		data, err := callContract(b, address, qrc20NameSignature)
+		if err != nil {
+			// the contract may not implement the method, it is not a QRC20 token
+			glog.V(1).Info("callcontract ", address, ": ", err)
+			data = ""
+		}
+		name := eth.ParseErc20StringProperty(contractDesc, data)
+		if name != "" {
+			data, err = callContract(b, address, qrc20SymbolSignature)
+			if err != nil {
+				return nil, err
+			}
+			symbol := eth.ParseErc20StringProperty(contractDesc, data)
+			data, err = callContract(b, address, qrc20DecimalsSignature)
+			if err != nil {
+				return nil, err
+			}
+			contract = &bchain.Erc20Contract{
+				Contract: address,
+				Name:     name,
+				Symbol:   symbol,
+			}
+			d := eth.ParseErc20NumericProperty(contractDesc, data)
+			if d != nil {
+				contract.Decimals = int(uint8(d.Uint64()))
+			}
+		} else {
+			contract = nil
+		}
+		cachedQrc20ContractsMux.Lock()
+		cachedQrc20Contracts[cds] = contract
+		cachedQrc20ContractsMux.Unlock()
+	}
+	return contract, nil
+}
+
+// GetQrc20ContractBalance returns balance of QRC20 contract for given P2PKH address descriptor
+func GetQrc20ContractBalance(b *btc.BitcoinRPC, addrDesc, contractDesc bchain.AddressDescriptor) (*big.Int, error) {
+	// only P2PKH addresses can hold QRC20 tokens, OP_DUP OP_HASH160 <hash> OP_EQUALVERIFY OP_CHECKSIG
+	if len(addrDesc) != 25 || addrDesc[0] != 0x76 || addrDesc[1] != 0xa9 || addrDesc[2] != 20 {
+		return nil, errors.New("Not a P2PKH address")
+	}
+	req := qrc20BalanceOf + "000000000000000000000000" + hex.EncodeToString(addrDesc[3:23])
+	data, err := callContract(b, hex.EncodeToString(contractDesc), req)
+	if err != nil {
+		return nil, err
+	}
+	r := eth.ParseErc20NumericProperty(contractDesc, data)
+	if r == nil {
+		return nil, errors.New("Invalid balance")
+	}
+	return r, nil
+}
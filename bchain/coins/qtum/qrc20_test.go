@@ -0,0 +1,163 @@
+// +build unittest
+
+package qtum
+
+import (
+	"blockbook/bchain"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestGetQrc20TransfersFromTx(t *testing.T) {
+	params := GetChainParams("main")
+	tests := []struct {
+		name    string
+		tx      bchain.Tx
+		want    []bchain.Erc20Transfer
+		wantErr bool
+	}{
+		{
+			name: "no receipts",
+			tx:   bchain.Tx{Txid: "1"},
+		},
+		{
+			name: "transfer, mint and other event",
+			tx: bchain.Tx{
+				Txid: "2",
+				CoinSpecificData: []Qrc20Receipt{
+					{
+						ContractAddress: "f2033ede578e17fa6231047265010445bca8cf1c",
+						Excepted:        "None",
+						Log: []Qrc20Log{
+							{
+								Address: "f2033ede578e17fa6231047265010445bca8cf1c",
+								Topics: []string{
+									"ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+									"0000000000000000000000006f44cceb49b4a5812d54b6f494fc2febf25511ed",
+									"0000000000000000000000004bda106325c335df99eab7fe363cac8a0ba2a24d",
+								},
+								Data: "0000000000000000000000000000000000000000000000000000000000000123",
+							},
+							{
+								Address: "f2033ede578e17fa6231047265010445bca8cf1c",
+								Topics: []string{
+									"ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+									"0000000000000000000000000000000000000000000000000000000000000000",
+									"0000000000000000000000004bda106325c335df99eab7fe363cac8a0ba2a24d",
+								},
+								Data: "00000000000000000000000000000000000000000000000000000000000f4240",
+							},
+							{
+								Address: "f2033ede578e17fa6231047265010445bca8cf1c",
+								Topics: []string{
+									"8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925",
+									"0000000000000000000000006f44cceb49b4a5812d54b6f494fc2febf25511ed",
+									"0000000000000000000000004bda106325c335df99eab7fe363cac8a0ba2a24d",
+								},
+								Data: "0000000000000000000000000000000000000000000000000000000000000001",
+							},
+						},
+					},
+				},
+			},
+			want: []bchain.Erc20Transfer{
+				{
+					Contract: "f2033ede578e17fa6231047265010445bca8cf1c",
+					From:     "QWkKXw29SfkQVnu4uYt7M5mypY2zrQCQMd",
+					To:       "QTX423EotzL2yScUAD1aScfqJM6bBBQwSk",
+					Tokens:   *big.NewInt(0x123),
+				},
+				{
+					Contract: "f2033ede578e17fa6231047265010445bca8cf1c",
+					To:       "QTX423EotzL2yScUAD1aScfqJM6bBBQwSk",
+					Tokens:   *big.NewInt(1000000),
+				},
+			},
+		},
+		{
+			name: "excepted execution",
+			tx: bchain.Tx{
+				Txid: "3",
+				CoinSpecificData: []Qrc20Receipt{
+					{
+						Excepted: "OutOfGas",
+						Log: []Qrc20Log{
+							{
+								Address: "f2033ede578e17fa6231047265010445bca8cf1c",
+								Topics: []string{
+									"ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+									"0000000000000000000000006f44cceb49b4a5812d54b6f494fc2febf25511ed",
+									"0000000000000000000000004bda106325c335df99eab7fe363cac8a0ba2a24d",
+								},
+								Data: "0000000000000000000000000000000000000000000000000000000000000123",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid data",
+			tx: bchain.Tx{
+				Txid: "4",
+				CoinSpecificData: []Qrc20Receipt{
+					{
+						Excepted: "None",
+						Log: []Qrc20Log{
+							{
+								Address: "f2033ede578e17fa6231047265010445bca8cf1c",
+								Topics: []string{
+									"ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+									"0000000000000000000000006f44cceb49b4a5812d54b6f494fc2febf25511ed",
+									"0000000000000000000000004bda106325c335df99eab7fe363cac8a0ba2a24d",
+								},
+								Data: "",
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetQrc20TransfersFromTx(&tt.tx, params)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetQrc20TransfersFromTx() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetQrc20TransfersFromTx() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isContractTx(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+		want bool
+	}{
+		{
+			name: "P2PKH",
+			hex:  "76a914f0e2aff6730b53b9986a5db8ca17c59426134a0988ac",
+			want: false,
+		},
+		{
+			name: "OP_CALL",
+			hex:  "0104032dc6c00128044a1d4d5a14f2033ede578e17fa6231047265010445bca8cf1cc2",
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := bchain.Tx{Vout: []bchain.Vout{{ScriptPubKey: bchain.ScriptPubKey{Hex: tt.hex}}}}
+			if got := isContractTx(&tx); got != tt.want {
+				t.Errorf("isContractTx() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -153,3 +153,13 @@ func (p *QtumParser) ParseTxFromJson(msg json.RawMessage) (*bchain.Tx, error) {
 
 	return &tx, nil
 }
+
+// GetContractTokenType returns the type of the contract tokens
+func (p *QtumParser) GetContractTokenType() string {
+	return Qrc20TokenType
+}
+
+// EthereumTypeGetErc20FromTx returns QRC20 transfers from the receipts of the transaction
+func (p *QtumParser) EthereumTypeGetErc20FromTx(tx *bchain.Tx) ([]bchain.Erc20Transfer, error) {
+	return GetQrc20TransfersFromTx(tx, p.Params)
+}
@@ -75,3 +75,25 @@ func (b *QtumRPC) EstimateSmartFee(blocks int, conservative bool) (big.Int, erro
 	}
 	return feeRate, err
 }
+
+// GetBlock returns block with given hash, the receipts of the contract transactions are stored in the transactions
+func (b *QtumRPC) GetBlock(hash string, height uint32) (*bchain.Block, error) {
+	block, err := b.BitcoinRPC.GetBlock(hash, height)
+	if err != nil {
+		return nil, err
+	}
+	if err = GetQrc20BlockReceipts(b.BitcoinRPC, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// EthereumTypeGetErc20ContractInfo returns information about QRC20 contract
+func (b *QtumRPC) EthereumTypeGetErc20ContractInfo(contractDesc bchain.AddressDescriptor) (*bchain.Erc20Contract, error) {
+	return GetQrc20ContractInfo(b.BitcoinRPC, contractDesc)
+}
+
+// EthereumTypeGetErc20ContractBalance returns balance of QRC20 contract for given address
+func (b *QtumRPC) EthereumTypeGetErc20ContractBalance(addrDesc, contractDesc bchain.AddressDescriptor) (*big.Int, error) {
+	return GetQrc20ContractBalance(b.BitcoinRPC, addrDesc, contractDesc)
+}
@@ -0,0 +1,156 @@
+package ravencoin
+
+import (
+	"blockbook/bchain"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcd/wire"
+	"github.com/martinboehm/btcutil/txscript"
+)
+
+// RavencoinAssetTokenType is the type of Ravencoin assets
+const RavencoinAssetTokenType = "RavencoinAsset"
+
+// the asset amounts are in the units of 1e-8 as the amounts of RVN
+const assetAmountDecimals = 8
+
+// owner asset is always issued in the amount of 1
+const ownerAssetAmount = 100000000
+
+const (
+	opRvnAsset = 0xc0
+
+	assetTransfer = 't'
+	assetNew      = 'q'
+	assetOwner    = 'o'
+	assetReissue  = 'r'
+)
+
+var assetPrefix = []byte("rvn")
+
+var errInvalidAssetScript = errors.New("Invalid asset script")
+
+// assetScriptIndex returns the index of OP_RVN_ASSET in the script or -1 if the script is not an asset script
+// the asset data are appended to standard P2PKH or P2SH script
+func assetScriptIndex(script []byte) int {
+	// OP_DUP OP_HASH160 <20 bytes> OP_EQUALVERIFY OP_CHECKSIG OP_RVN_ASSET
+	if len(script) > 25 && script[0] == txscript.OP_DUP && script[1] == txscript.OP_HASH160 && script[2] == txscript.OP_DATA_20 &&
+		script[23] == txscript.OP_EQUALVERIFY && script[24] == txscript.OP_CHECKSIG && script[25] == opRvnAsset {
+		return 25
+	}
+	// OP_HASH160 <20 bytes> OP_EQUAL OP_RVN_ASSET
+	if len(script) > 23 && script[0] == txscript.OP_HASH160 && script[1] == txscript.OP_DATA_20 &&
+		script[22] == txscript.OP_EQUAL && script[23] == opRvnAsset {
+		return 23
+	}
+	return -1
+}
+
+// assetData returns the data pushed after OP_RVN_ASSET
+func assetData(script []byte) ([]byte, error) {
+	if len(script) < 2 {
+		return nil, errInvalidAssetScript
+	}
+	op := script[0]
+	var l, s int
+	switch {
+	case op < txscript.OP_PUSHDATA1:
+		l, s = int(op), 1
+	case op == txscript.OP_PUSHDATA1:
+		l, s = int(script[1]), 2
+	case op == txscript.OP_PUSHDATA2 && len(script) > 2:
+		l, s = int(binary.LittleEndian.Uint16(script[1:])), 3
+	default:
+		return nil, errInvalidAssetScript
+	}
+	if len(script) < s+l {
+		return nil, errInvalidAssetScript
+	}
+	return script[s : s+l], nil
+}
+
+// parseAssetScript splits the script to the standard locking script and the asset
+// if the script is not a valid asset script, nil asset and unchanged script are returned
+func parseAssetScript(script []byte) (*bchain.UtxoToken, []byte) {
+	i := assetScriptIndex(script)
+	if i < 0 {
+		return nil, script
+	}
+	t, err := unpackAsset(script[i+1:])
+	if err != nil {
+		return nil, script
+	}
+	return t, script[:i]
+}
+
+func unpackAsset(script []byte) (*bchain.UtxoToken, error) {
+	data, err := assetData(script)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(assetPrefix)+1 || !bytes.Equal(data[:len(assetPrefix)], assetPrefix) {
+		return nil, errInvalidAssetScript
+	}
+	assetType := data[len(assetPrefix)]
+	r := bytes.NewReader(data[len(assetPrefix)+1:])
+	name, err := wire.ReadVarString(r, 0)
+	if err != nil || len(name) == 0 {
+		return nil, errInvalidAssetScript
+	}
+	t := bchain.UtxoToken{ID: name}
+	switch assetType {
+	case assetTransfer, assetNew, assetReissue:
+		var amount int64
+		if err = binary.Read(r, binary.LittleEndian, &amount); err != nil || amount < 0 {
+			return nil, errInvalidAssetScript
+		}
+		t.Amount.SetInt64(amount)
+	case assetOwner:
+		t.Amount.SetInt64(ownerAssetAmount)
+	default:
+		return nil, errInvalidAssetScript
+	}
+	return &t, nil
+}
+
+// GetAddrDescFromVout returns internal address representation of given transaction output
+// the asset data are not part of the address descriptor
+func (p *RavencoinParser) GetAddrDescFromVout(output *bchain.Vout) (bchain.AddressDescriptor, error) {
+	ad, err := hex.DecodeString(output.ScriptPubKey.Hex)
+	if err != nil {
+		return ad, err
+	}
+	_, ad = parseAssetScript(ad)
+	// convert possible P2PK script to P2PKH
+	// so that all transactions by given public key are indexed together
+	return txscript.ConvertP2PKtoP2PKH(p.Params.Base58CksumHasher, ad)
+}
+
+// outputScriptToAddresses converts ScriptPubKey to addresses, the asset data are ignored
+func (p *RavencoinParser) outputScriptToAddresses(script []byte) ([]string, bool, error) {
+	_, script = parseAssetScript(script)
+	return p.BitcoinOutputScriptToAddressesFunc(script)
+}
+
+// GetUtxoTokenType returns the type of the UTXO tokens
+func (p *RavencoinParser) GetUtxoTokenType() string {
+	return RavencoinAssetTokenType
+}
+
+// GetUtxoTokenDecimals returns the number of decimal places of the amounts of the assets
+func (p *RavencoinParser) GetUtxoTokenDecimals() int {
+	return assetAmountDecimals
+}
+
+// GetUtxoTokenFromVout returns the asset carried by the output or nil if the output does not carry any asset
+func (p *RavencoinParser) GetUtxoTokenFromVout(output *bchain.Vout) (*bchain.UtxoToken, error) {
+	script, err := hex.DecodeString(output.ScriptPubKey.Hex)
+	if err != nil {
+		return nil, err
+	}
+	t, _ := parseAssetScript(script)
+	return t, nil
+}
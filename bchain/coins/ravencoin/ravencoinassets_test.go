@@ -0,0 +1,99 @@
+// +build unittest
+
+package ravencoin
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/btc"
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func Test_parseAssetScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   *bchain.UtxoToken
+		rest   string
+	}{
+		{
+			name:   "P2PKH",
+			script: "76a91410a8805f1a6af1a5927088544b0b6ec7d6f0ab8b88ac",
+			rest:   "76a91410a8805f1a6af1a5927088544b0b6ec7d6f0ab8b88ac",
+		},
+		{
+			name:   "P2PKH transfer",
+			script: "76a91410a8805f1a6af1a5927088544b0b6ec7d6f0ab8b88acc01272766e7405415353455400e1f5050000000075",
+			want:   &bchain.UtxoToken{ID: "ASSET", Amount: *big.NewInt(100000000)},
+			rest:   "76a91410a8805f1a6af1a5927088544b0b6ec7d6f0ab8b88ac",
+		},
+		{
+			name:   "P2PKH new asset",
+			script: "76a91410a8805f1a6af1a5927088544b0b6ec7d6f0ab8b88acc01572766e7105415353455400a0724e18090000000100",
+			want:   &bchain.UtxoToken{ID: "ASSET", Amount: *big.NewInt(10000000000000)},
+			rest:   "76a91410a8805f1a6af1a5927088544b0b6ec7d6f0ab8b88ac",
+		},
+		{
+			name:   "P2SH owner",
+			script: "a9144a2a40987c74578ee517d426aa2c43fc568f7e0887c00b72766e6f0641535345542175",
+			want:   &bchain.UtxoToken{ID: "ASSET!", Amount: *big.NewInt(100000000)},
+			rest:   "a9144a2a40987c74578ee517d426aa2c43fc568f7e0887",
+		},
+		{
+			name:   "unknown asset type",
+			script: "76a91410a8805f1a6af1a5927088544b0b6ec7d6f0ab8b88acc01272766e7805415353455400e1f5050000000075",
+			rest:   "76a91410a8805f1a6af1a5927088544b0b6ec7d6f0ab8b88acc01272766e7805415353455400e1f5050000000075",
+		},
+		{
+			name:   "truncated data",
+			script: "76a91410a8805f1a6af1a5927088544b0b6ec7d6f0ab8b88acc01272766e74054153",
+			rest:   "76a91410a8805f1a6af1a5927088544b0b6ec7d6f0ab8b88acc01272766e74054153",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, _ := hex.DecodeString(tt.script)
+			got, rest := parseAssetScript(script)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAssetScript() = %+v, want %+v", got, tt.want)
+			}
+			if h := hex.EncodeToString(rest); h != tt.rest {
+				t.Errorf("parseAssetScript() script = %v, want %v", h, tt.rest)
+			}
+		})
+	}
+}
+
+func Test_AssetOutputAddresses(t *testing.T) {
+	parser := NewRavencoinParser(GetChainParams("main"), &btc.Configuration{})
+	vout := bchain.Vout{
+		ScriptPubKey: bchain.ScriptPubKey{
+			Hex: "76a91410a8805f1a6af1a5927088544b0b6ec7d6f0ab8b88acc01272766e7405415353455400e1f5050000000075",
+		},
+	}
+	ad, err := parser.GetAddrDescFromVout(&vout)
+	if err != nil {
+		t.Fatalf("GetAddrDescFromVout() error = %v", err)
+	}
+	if h := hex.EncodeToString(ad); h != "76a91410a8805f1a6af1a5927088544b0b6ec7d6f0ab8b88ac" {
+		t.Errorf("GetAddrDescFromVout() = %v", h)
+	}
+	script, _ := hex.DecodeString(vout.ScriptPubKey.Hex)
+	addresses, searchable, err := parser.GetAddressesFromAddrDesc(script)
+	if err != nil {
+		t.Fatalf("GetAddressesFromAddrDesc() error = %v", err)
+	}
+	if !searchable || !reflect.DeepEqual(addresses, []string{"RAoGkGhKwzxLnstApumYPD2eTrAJ849cga"}) {
+		t.Errorf("GetAddressesFromAddrDesc() = %v, %v", addresses, searchable)
+	}
+	token, err := parser.GetUtxoTokenFromVout(&vout)
+	if err != nil {
+		t.Fatalf("GetUtxoTokenFromVout() error = %v", err)
+	}
+	want := &bchain.UtxoToken{ID: "ASSET", Amount: *big.NewInt(100000000)}
+	if !reflect.DeepEqual(token, want) {
+		t.Errorf("GetUtxoTokenFromVout() = %+v, want %+v", token, want)
+	}
+}
@@ -37,11 +37,15 @@ func init() {
 // RavencoinParser handle
 type RavencoinParser struct {
 	*btc.BitcoinParser
+	BitcoinOutputScriptToAddressesFunc btc.OutputScriptToAddressesFunc
 }
 
 // NewRavencoinParser returns new RavencoinParser instance
 func NewRavencoinParser(params *chaincfg.Params, c *btc.Configuration) *RavencoinParser {
-	return &RavencoinParser{BitcoinParser: btc.NewBitcoinParser(params, c)}
+	p := &RavencoinParser{BitcoinParser: btc.NewBitcoinParser(params, c)}
+	p.BitcoinOutputScriptToAddressesFunc = p.OutputScriptToAddressesFunc
+	p.OutputScriptToAddressesFunc = p.outputScriptToAddresses
+	return p
 }
 
 // GetChainParams contains network parameters
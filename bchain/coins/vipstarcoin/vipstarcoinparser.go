@@ -3,6 +3,7 @@ package vipstarcoin
 import (
 	"blockbook/bchain"
 	"blockbook/bchain/coins/btc"
+	"blockbook/bchain/coins/qtum"
 	"blockbook/bchain/coins/utils"
 	"bytes"
 	"encoding/json"
@@ -151,3 +152,13 @@ func (p *VIPSTARCOINParser) ParseTxFromJson(msg json.RawMessage) (*bchain.Tx, er
 
 	return &tx, nil
 }
+
+// GetContractTokenType returns the type of the contract tokens
+func (p *VIPSTARCOINParser) GetContractTokenType() string {
+	return qtum.Qrc20TokenType
+}
+
+// EthereumTypeGetErc20FromTx returns QRC20 transfers from the receipts of the transaction
+func (p *VIPSTARCOINParser) EthereumTypeGetErc20FromTx(tx *bchain.Tx) ([]bchain.Erc20Transfer, error) {
+	return qtum.GetQrc20TransfersFromTx(tx, p.Params)
+}
@@ -3,7 +3,9 @@ package vipstarcoin
 import (
 	"blockbook/bchain"
 	"blockbook/bchain/coins/btc"
+	"blockbook/bchain/coins/qtum"
 	"encoding/json"
+	"math/big"
 
 	"github.com/golang/glog"
 )
@@ -62,3 +64,25 @@ func (b *VIPSTARCOINRPC) Initialize() error {
 func (b *VIPSTARCOINRPC) GetTransactionForMempool(txid string) (*bchain.Tx, error) {
 	return b.GetTransaction(txid)
 }
+
+// GetBlock returns block with given hash, the receipts of the contract transactions are stored in the transactions
+func (b *VIPSTARCOINRPC) GetBlock(hash string, height uint32) (*bchain.Block, error) {
+	block, err := b.BitcoinRPC.GetBlock(hash, height)
+	if err != nil {
+		return nil, err
+	}
+	if err = qtum.GetQrc20BlockReceipts(b.BitcoinRPC, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// EthereumTypeGetErc20ContractInfo returns information about QRC20 contract
+func (b *VIPSTARCOINRPC) EthereumTypeGetErc20ContractInfo(contractDesc bchain.AddressDescriptor) (*bchain.Erc20Contract, error) {
+	return qtum.GetQrc20ContractInfo(b.BitcoinRPC, contractDesc)
+}
+
+// EthereumTypeGetErc20ContractBalance returns balance of QRC20 contract for given address
+func (b *VIPSTARCOINRPC) EthereumTypeGetErc20ContractBalance(addrDesc, contractDesc bchain.AddressDescriptor) (*big.Int, error) {
+	return qtum.GetQrc20ContractBalance(b.BitcoinRPC, addrDesc, contractDesc)
+}
@@ -11,13 +11,17 @@ import (
 
 // MQ is message queue listener handle
 type MQ struct {
-	context   *zmq.Context
-	socket    *zmq.Socket
-	isRunning bool
-	finished  chan error
-	binding   string
+	context       *zmq.Context
+	socket        *zmq.Socket
+	isRunning     bool
+	finished      chan error
+	binding       string
+	topicHandlers map[string]MQTopicHandler
 }
 
+// MQTopicHandler receives body of a message of an additional (coin specific) topic
+type MQTopicHandler func(body []byte)
+
 // NotificationType is type of notification
 type NotificationType int
 
@@ -33,6 +37,12 @@ const (
 // NewMQ creates new Bitcoind ZeroMQ listener
 // callback function receives messages
 func NewMQ(binding string, callback func(NotificationType)) (*MQ, error) {
+	return NewMQWithTopics(binding, callback, nil)
+}
+
+// NewMQWithTopics creates new Bitcoind ZeroMQ listener subscribed also to the additional topics
+// callback function receives hashblock and hashtx messages, messages of the additional topics are passed to their topic handlers
+func NewMQWithTopics(binding string, callback func(NotificationType), topicHandlers map[string]MQTopicHandler) (*MQ, error) {
 	context, err := zmq.NewContext()
 	if err != nil {
 		return nil, err
@@ -49,6 +59,12 @@ func NewMQ(binding string, callback func(NotificationType)) (*MQ, error) {
 	if err != nil {
 		return nil, err
 	}
+	for topic := range topicHandlers {
+		err = socket.SetSubscribe(topic)
+		if err != nil {
+			return nil, err
+		}
+	}
 	// for now do not use raw subscriptions - we would have to handle skipped/lost notifications from zeromq
 	// on each notification we do sync or syncmempool respectively
 	// socket.SetSubscribe("rawblock")
@@ -58,7 +74,7 @@ func NewMQ(binding string, callback func(NotificationType)) (*MQ, error) {
 		return nil, err
 	}
 	glog.Info("MQ listening to ", binding)
-	mq := &MQ{context, socket, true, make(chan error), binding}
+	mq := &MQ{context, socket, true, make(chan error), binding, topicHandlers}
 	go mq.run(callback)
 	return mq, nil
 }
@@ -83,6 +99,11 @@ func (mq *MQ) run(callback func(NotificationType)) {
 			time.Sleep(100 * time.Millisecond)
 		}
 		if msg != nil && len(msg) >= 3 {
+			if h, ok := mq.topicHandlers[string(msg[0])]; ok {
+				glog.V(2).Infof("MQ: topic %s", string(msg[0]))
+				h(msg[1])
+				continue
+			}
 			var nt NotificationType
 			switch string(msg[0]) {
 			case "hashblock":
@@ -113,6 +134,12 @@ func (mq *MQ) Shutdown(ctx context.Context) error {
 	if mq.isRunning {
 		go func() {
 			// if errors in the closing sequence, let it close ungracefully
+			for topic := range mq.topicHandlers {
+				if err := mq.socket.SetUnsubscribe(topic); err != nil {
+					mq.finished <- err
+					return
+				}
+			}
 			if err := mq.socket.SetUnsubscribe("hashtx"); err != nil {
 				mq.finished <- err
 				return
@@ -33,6 +33,8 @@ var (
 	ErrTxidMissing = errors.New("Txid missing")
 	// ErrTxNotFound is returned if transaction was not found
 	ErrTxNotFound = errors.New("Tx not found")
+	// ErrNotSupported is returned if the operation is not supported by the backend
+	ErrNotSupported = errors.New("Not supported")
 )
 
 // Outpoint is txid together with output (or input) index
@@ -149,6 +151,18 @@ type ChainInfo struct {
 	ProtocolVersion string  `json:"protocolversion"`
 	Timeoffset      float64 `json:"timeoffset"`
 	Warnings        string  `json:"warnings"`
+	// RelayFee is the minimum relay fee per kilobyte in the coin units, empty if not known
+	RelayFee string `json:"relayfee,omitempty"`
+}
+
+// MempoolAcceptResult is the result of the check if the transaction would be accepted to the mempool
+type MempoolAcceptResult struct {
+	Txid         string
+	Allowed      bool
+	RejectReason string
+	// Vsize and FeeSat are returned only by newer backends and only for allowed transactions
+	Vsize  int
+	FeeSat *big.Int
 }
 
 // RPCError defines rpc error returned by backend
@@ -186,6 +200,35 @@ type Erc20Transfer struct {
 	Tokens   big.Int
 }
 
+// UtxoToken contains a token carried by an output of a Bitcoin type transaction, for example CashTokens
+type UtxoToken struct {
+	ID            string
+	Amount        big.Int
+	Nft           bool
+	NftCapability string
+	NftCommitment []byte
+}
+
+// NameOp is the type of the name operation
+type NameOp uint8
+
+// name operations, the values are the same as the opcodes OP_1, OP_2, OP_3 minus 0x50 used in the name scripts
+const (
+	NameNew         NameOp = 1
+	NameFirstUpdate NameOp = 2
+	NameUpdate      NameOp = 3
+)
+
+// NameOperation contains a name operation carried by an output of a Bitcoin type transaction, for example Namecoin name_update
+// name_new operation contains only the Hash of the name, the Name is revealed by the following name_firstupdate operation
+type NameOperation struct {
+	Op    NameOp
+	Name  []byte
+	Value []byte
+	Rand  []byte
+	Hash  []byte
+}
+
 // MempoolTxidEntry contains mempool txid with first seen time
 type MempoolTxidEntry struct {
 	Txid string
@@ -201,6 +244,12 @@ type OnNewBlockFunc func(hash string, height uint32)
 // OnNewTxAddrFunc is used to send notification about a new transaction/address
 type OnNewTxAddrFunc func(tx *Tx, desc AddressDescriptor)
 
+// OnNewInstantLockFunc is used to send notification about a new InstantSend lock of a transaction
+type OnNewInstantLockFunc func(txid string)
+
+// OnNewChainLockFunc is used to send notification about a new ChainLock of a block
+type OnNewChainLockFunc func(hash string, height uint32)
+
 // AddrDescForOutpointFunc defines function that returns address descriptorfor given outpoint or nil if outpoint not found
 type AddrDescForOutpointFunc func(outpoint Outpoint) AddressDescriptor
 
@@ -213,6 +262,8 @@ type BlockChain interface {
 	CreateMempool(BlockChain) (Mempool, error)
 	// initialize mempool, create ZeroMQ (or other) subscription
 	InitializeMempool(AddrDescForOutpointFunc, OnNewTxAddrFunc) error
+	// set handlers of InstantSend and ChainLock notifications, must be called before InitializeMempool
+	InitializeLocks(OnNewInstantLockFunc, OnNewChainLockFunc) error
 	// shutdown mempool, ZeroMQ and block chain connections
 	Shutdown(ctx context.Context) error
 	// chain info
@@ -235,6 +286,7 @@ type BlockChain interface {
 	EstimateSmartFee(blocks int, conservative bool) (big.Int, error)
 	EstimateFee(blocks int) (big.Int, error)
 	SendRawTransaction(tx string) (string, error)
+	TestMempoolAccept(tx string) (*MempoolAcceptResult, error)
 	GetMempoolEntry(txid string) (*MempoolEntry, error)
 	// parser
 	GetChainParser() BlockChainParser
@@ -244,6 +296,9 @@ type BlockChain interface {
 	EthereumTypeEstimateGas(params map[string]interface{}) (uint64, error)
 	EthereumTypeGetErc20ContractInfo(contractDesc AddressDescriptor) (*Erc20Contract, error)
 	EthereumTypeGetErc20ContractBalance(addrDesc, contractDesc AddressDescriptor) (*big.Int, error)
+	// InstantSend and ChainLock specific
+	IsInstantLocked(txid string) bool
+	GetBestChainLockHeight() uint32
 }
 
 // BlockChainParser defines common interface to parsing and conversions of block chain data
@@ -285,8 +340,33 @@ type BlockChainParser interface {
 	DerivationBasePath(xpub string) (string, error)
 	DeriveAddressDescriptors(xpub string, change uint32, indexes []uint32) ([]AddressDescriptor, error)
 	DeriveAddressDescriptorsFromTo(xpub string, change uint32, fromIndex uint32, toIndex uint32) ([]AddressDescriptor, error)
+	DerivePublicKeys(xpub string, change uint32, indexes []uint32) ([]byte, [][]byte, error)
 	// EthereumType specific
 	EthereumTypeGetErc20FromTx(tx *Tx) ([]Erc20Transfer, error)
+	// UTXO tokens specific, GetUtxoTokenType returns empty string if the tokens are not supported
+	GetUtxoTokenType() string
+	GetUtxoTokenFromVout(output *Vout) (*UtxoToken, error)
+	GetUtxoTokenDecimals() int
+	// Bitcoin type coins with ERC20 compatible contracts, the transfers are returned by EthereumTypeGetErc20FromTx
+	// GetContractTokenType returns empty string if the contracts are not supported
+	GetContractTokenType() string
+	// name operations specific, GetNameExpirationDepth returns 0 if the names are not supported
+	GetNameExpirationDepth() uint32
+	GetNameOperationFromVout(output *Vout) (*NameOperation, error)
+}
+
+// TracedBlockChain is implemented by the block chain which can trace its calls as spans of a request
+type TracedBlockChain interface {
+	// WithContext returns the block chain creating the spans of the calls as children of the span in ctx
+	WithContext(ctx context.Context) BlockChain
+}
+
+// BlockChainWithContext returns the block chain tracing its calls in ctx if it supports tracing, otherwise the chain itself
+func BlockChainWithContext(chain BlockChain, ctx context.Context) BlockChain {
+	if t, ok := chain.(TracedBlockChain); ok {
+		return t.WithContext(ctx)
+	}
+	return chain
 }
 
 // Mempool defines common interface to mempool
@@ -63,6 +63,21 @@ var (
 
 	publicBinding = flag.String("public", "", "public http server binding [address]:port[/path] (default no public server)")
 
+	addressesLimit = flag.Int("addresseslimit", 1000, "max number of addresses in one request of the addresses api and websocket getAddresses")
+
+	accessConfig = flag.String("accesscfg", "", "path to json file with api keys, rate limits and allowed origins of the public interfaces (default unlimited access)")
+
+	adminKeysPath = flag.String("adminkeys", "", "path to file with the keys authorizing the admin api of the internal server, one key per line (default admin api disabled)")
+
+	accessLogPath = flag.String("accesslog", "", "path to the file of the JSON access log of the REST API and of the explorer (default no access log)")
+
+	otlpEndpoint    = flag.String("otlp", "", "OTLP/HTTP collector [address]:port or url of its traces endpoint to export the tracing spans to (default no tracing)")
+	traceSampleRate = flag.Float64("tracesample", 1, "fraction of the requests which are traced, used with -otlp")
+
+	electrumBinding = flag.String("electrum", "", "electrum protocol server binding [address]:port, uses SSL if certfile is specified (default no electrum server)")
+
+	grpcBinding = flag.String("grpc", "", "grpc server binding [address]:port, uses TLS if certfile is specified (default no grpc server)")
+
 	certFiles = flag.String("certfile", "", "to enable SSL specify path to certificate files without extension, expecting <certfile>.crt and <certfile>.key (default no SSL)")
 
 	explorerURL = flag.String("explorer", "", "address of blockchain explorer")
@@ -96,6 +111,9 @@ var (
 	internalState              *common.InternalState
 	callbacksOnNewBlock        []bchain.OnNewBlockFunc
 	callbacksOnNewTxAddr       []bchain.OnNewTxAddrFunc
+	callbacksOnNewInstantLock  []bchain.OnNewInstantLockFunc
+	callbacksOnNewChainLock    []bchain.OnNewChainLockFunc
+	callbacksOnReorg           []db.OnReorgFunc
 	chanOsSignal               chan os.Signal
 	inShutdown                 int32
 )
@@ -162,6 +180,21 @@ func mainWithExitCode() int {
 		return exitCodeFatal
 	}
 
+	if *otlpEndpoint != "" {
+		shutdownTracing, err := common.InitTracing(*otlpEndpoint, coin, *traceSampleRate)
+		if err != nil {
+			glog.Error("tracing: ", err)
+			return exitCodeFatal
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				glog.Error("tracing: ", err)
+			}
+		}()
+	}
+
 	if chain, mempool, err = getBlockChainWithRetry(coin, *blockchain, pushSynchronizationHandler, metrics, 120); err != nil {
 		glog.Error("rpc: ", err)
 		return exitCodeFatal
@@ -259,10 +292,28 @@ func mainWithExitCode() int {
 		}
 	}
 
+	var electrumServer *server.ElectrumServer
+	if *electrumBinding != "" {
+		electrumServer, err = startElectrumServer()
+		if err != nil {
+			glog.Error("electrum server: ", err)
+			return exitCodeFatal
+		}
+	}
+
+	var grpcServer *server.GrpcServer
+	if *grpcBinding != "" {
+		grpcServer, err = startGrpcServer()
+		if err != nil {
+			glog.Error("grpc server: ", err)
+			return exitCodeFatal
+		}
+	}
+
 	if *synchronize {
 		internalState.SyncMode = true
 		internalState.InitialSync = true
-		if err := syncWorker.ResyncIndex(nil, true); err != nil {
+		if err := syncWorker.ResyncIndex(nil, nil, true); err != nil {
 			if err != db.ErrOperationInterrupted {
 				glog.Error("resyncIndex ", err)
 				return exitCodeFatal
@@ -274,6 +325,11 @@ func mainWithExitCode() int {
 		if chain.GetChainParser().GetChainType() == bchain.ChainBitcoinType {
 			addrDescForOutpoint = index.AddrDescForOutpoint
 		}
+		err = chain.InitializeLocks(onNewInstantLock, onNewChainLock)
+		if err != nil {
+			glog.Error("initializeLocks ", err)
+			return exitCodeFatal
+		}
 		err = chain.InitializeMempool(addrDescForOutpoint, onNewTxAddr)
 		if err != nil {
 			glog.Error("initializeMempool ", err)
@@ -291,13 +347,31 @@ func mainWithExitCode() int {
 	}
 	go storeInternalStateLoop()
 
+	if internalServer != nil {
+		callbacksOnNewBlock = append(callbacksOnNewBlock, internalServer.OnNewBlock)
+		callbacksOnNewTxAddr = append(callbacksOnNewTxAddr, internalServer.OnNewTxAddr)
+	}
+
 	if publicServer != nil {
 		// start full public interface
 		callbacksOnNewBlock = append(callbacksOnNewBlock, publicServer.OnNewBlock)
 		callbacksOnNewTxAddr = append(callbacksOnNewTxAddr, publicServer.OnNewTxAddr)
+		callbacksOnNewInstantLock = append(callbacksOnNewInstantLock, publicServer.OnNewInstantLock)
+		callbacksOnNewChainLock = append(callbacksOnNewChainLock, publicServer.OnNewChainLock)
+		callbacksOnReorg = append(callbacksOnReorg, publicServer.OnReorg)
 		publicServer.ConnectFullPublicInterface()
 	}
 
+	if electrumServer != nil {
+		callbacksOnNewBlock = append(callbacksOnNewBlock, electrumServer.OnNewBlock)
+		callbacksOnNewTxAddr = append(callbacksOnNewTxAddr, electrumServer.OnNewTxAddr)
+	}
+
+	if grpcServer != nil {
+		callbacksOnNewBlock = append(callbacksOnNewBlock, grpcServer.OnNewBlock)
+		callbacksOnNewTxAddr = append(callbacksOnNewTxAddr, grpcServer.OnNewTxAddr)
+	}
+
 	if *blockFrom >= 0 {
 		if *blockUntil < 0 {
 			*blockUntil = *blockFrom
@@ -306,7 +380,11 @@ func mainWithExitCode() int {
 		until := uint32(*blockUntil)
 
 		if !*synchronize {
-			if err = syncWorker.ConnectBlocksParallel(height, until); err != nil {
+			// the admin jobs of the internal server must not run during the import of the blocks
+			err = syncWorker.RunExclusive(func() error {
+				return syncWorker.ConnectBlocksParallel(height, until)
+			})
+			if err != nil {
 				if err != db.ErrOperationInterrupted {
 					glog.Error("connectBlocksParallel ", err)
 					return exitCodeFatal
@@ -316,8 +394,8 @@ func mainWithExitCode() int {
 		}
 	}
 
-	if internalServer != nil || publicServer != nil || chain != nil {
-		waitForSignalAndShutdown(internalServer, publicServer, chain, 10*time.Second)
+	if internalServer != nil || publicServer != nil || electrumServer != nil || grpcServer != nil || chain != nil {
+		waitForSignalAndShutdown(internalServer, publicServer, electrumServer, grpcServer, chain, 10*time.Second)
 	}
 
 	if *synchronize {
@@ -356,7 +434,14 @@ func getBlockChainWithRetry(coin string, configfile string, pushHandler func(bch
 }
 
 func startInternalServer() (*server.InternalServer, error) {
-	internalServer, err := server.NewInternalServer(*internalBinding, *certFiles, index, chain, mempool, txCache, internalState)
+	var adminKeys []string
+	if *adminKeysPath != "" {
+		var err error
+		if adminKeys, err = server.LoadAdminKeys(*adminKeysPath); err != nil {
+			return nil, err
+		}
+	}
+	internalServer, err := server.NewInternalServer(*internalBinding, *certFiles, index, chain, mempool, txCache, metrics, internalState, syncWorker, pushSynchronizationHandler, onReorg, adminKeys)
 	if err != nil {
 		return nil, err
 	}
@@ -375,8 +460,22 @@ func startInternalServer() (*server.InternalServer, error) {
 }
 
 func startPublicServer() (*server.PublicServer, error) {
+	var ac *server.AccessConfig
+	if *accessConfig != "" {
+		var err error
+		if ac, err = server.LoadAccessConfig(*accessConfig); err != nil {
+			return nil, err
+		}
+	}
+	var al *server.AccessLog
+	if *accessLogPath != "" {
+		var err error
+		if al, err = server.NewAccessLog(*accessLogPath); err != nil {
+			return nil, err
+		}
+	}
 	// start public server in limited functionality, extend it after sync is finished by calling ConnectFullPublicInterface
-	publicServer, err := server.NewPublicServer(*publicBinding, *certFiles, index, chain, mempool, txCache, *explorerURL, metrics, internalState, *debugMode)
+	publicServer, err := server.NewPublicServer(*publicBinding, *certFiles, index, chain, mempool, txCache, *explorerURL, metrics, internalState, *debugMode, *addressesLimit, ac, al)
 	if err != nil {
 		return nil, err
 	}
@@ -394,6 +493,36 @@ func startPublicServer() (*server.PublicServer, error) {
 	return publicServer, err
 }
 
+func startElectrumServer() (*server.ElectrumServer, error) {
+	electrumServer, err := server.NewElectrumServer(*electrumBinding, *certFiles, index, chain, mempool, txCache, metrics, internalState)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := electrumServer.Run(); err != nil {
+			glog.Error("electrum server: ", err)
+			return
+		}
+		glog.Info("electrum server: closed")
+	}()
+	return electrumServer, nil
+}
+
+func startGrpcServer() (*server.GrpcServer, error) {
+	grpcServer, err := server.NewGrpcServer(*grpcBinding, *certFiles, index, chain, mempool, txCache, metrics, internalState)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := grpcServer.Run(); err != nil {
+			glog.Error("grpc server: ", err)
+			return
+		}
+		glog.Info("grpc server: closed")
+	}()
+	return grpcServer, nil
+}
+
 func performRollback() error {
 	bestHeight, bestHash, err := index.GetBestBlock()
 	if err != nil {
@@ -412,7 +541,7 @@ func performRollback() error {
 			}
 			hashes = append(hashes, hash)
 		}
-		err = syncWorker.DisconnectBlocks(uint32(*rollbackHeight), bestHeight, hashes)
+		err = syncWorker.DisconnectBlocks(uint32(*rollbackHeight), bestHeight, hashes, db.OrphanedByRollback)
 		if err != nil {
 			glog.Error("rollbackHeight: ", err)
 			return err
@@ -503,11 +632,11 @@ func syncIndexLoop() {
 	glog.Info("syncIndexLoop starting")
 	// resync index about every 15 minutes if there are no chanSyncIndex requests, with debounce 1 second
 	tickAndDebounce(time.Duration(*resyncIndexPeriodMs)*time.Millisecond, debounceResyncIndexMs*time.Millisecond, chanSyncIndex, func() {
-		if err := syncWorker.ResyncIndex(onNewBlockHash, false); err != nil {
+		if err := syncWorker.ResyncIndex(onNewBlockHash, onReorg, false); err != nil {
 			glog.Error("syncIndexLoop ", errors.ErrorStack(err), ", will retry...")
 			// retry once in case of random network error, after a slight delay
 			time.Sleep(time.Millisecond * 2500)
-			if err := syncWorker.ResyncIndex(onNewBlockHash, false); err != nil {
+			if err := syncWorker.ResyncIndex(onNewBlockHash, onReorg, false); err != nil {
 				glog.Error("syncIndexLoop ", errors.ErrorStack(err))
 			}
 		}
@@ -580,12 +709,30 @@ func storeInternalStateLoop() {
 	glog.Info("storeInternalStateLoop stopped")
 }
 
+func onReorg(blocks []*db.OrphanedBlock) {
+	for _, c := range callbacksOnReorg {
+		c(blocks)
+	}
+}
+
 func onNewTxAddr(tx *bchain.Tx, desc bchain.AddressDescriptor) {
 	for _, c := range callbacksOnNewTxAddr {
 		c(tx, desc)
 	}
 }
 
+func onNewInstantLock(txid string) {
+	for _, c := range callbacksOnNewInstantLock {
+		c(txid)
+	}
+}
+
+func onNewChainLock(hash string, height uint32) {
+	for _, c := range callbacksOnNewChainLock {
+		c(hash, height)
+	}
+}
+
 func pushSynchronizationHandler(nt bchain.NotificationType) {
 	glog.V(1).Info("MQ: notification ", nt)
 	if atomic.LoadInt32(&inShutdown) != 0 {
@@ -600,7 +747,7 @@ func pushSynchronizationHandler(nt bchain.NotificationType) {
 	}
 }
 
-func waitForSignalAndShutdown(internal *server.InternalServer, public *server.PublicServer, chain bchain.BlockChain, timeout time.Duration) {
+func waitForSignalAndShutdown(internal *server.InternalServer, public *server.PublicServer, electrum *server.ElectrumServer, grpc *server.GrpcServer, chain bchain.BlockChain, timeout time.Duration) {
 	sig := <-chanOsSignal
 	atomic.StoreInt32(&inShutdown, 1)
 	glog.Infof("shutdown: %v", sig)
@@ -620,6 +767,18 @@ func waitForSignalAndShutdown(internal *server.InternalServer, public *server.Pu
 		}
 	}
 
+	if electrum != nil {
+		if err := electrum.Shutdown(ctx); err != nil {
+			glog.Error("electrum server: shutdown error: ", err)
+		}
+	}
+
+	if grpc != nil {
+		if err := grpc.Shutdown(ctx); err != nil {
+			glog.Error("grpc server: shutdown error: ", err)
+		}
+	}
+
 	if chain != nil {
 		if err := chain.Shutdown(ctx); err != nil {
 			glog.Error("rpc: shutdown error: ", err)
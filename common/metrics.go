@@ -16,6 +16,16 @@ type Metrics struct {
 	WebsocketSubscribes   *prometheus.CounterVec
 	WebsocketClients      prometheus.Gauge
 	WebsocketReqDuration  *prometheus.HistogramVec
+	SSEClients            prometheus.Gauge
+	HTTPRequests          *prometheus.CounterVec
+	HTTPReqDuration       *prometheus.HistogramVec
+	HTTPResponseSize      *prometheus.HistogramVec
+	ElectrumRequests      *prometheus.CounterVec
+	ElectrumClients       prometheus.Gauge
+	ElectrumReqDuration   *prometheus.HistogramVec
+	GrpcRequests          *prometheus.CounterVec
+	GrpcStreams           prometheus.Gauge
+	GrpcReqDuration       *prometheus.HistogramVec
 	IndexResyncDuration   prometheus.Histogram
 	MempoolResyncDuration prometheus.Histogram
 	TxCacheEfficiency     *prometheus.CounterVec
@@ -23,6 +33,9 @@ type Metrics struct {
 	IndexResyncErrors     *prometheus.CounterVec
 	IndexDBSize           prometheus.Gauge
 	ExplorerViews         *prometheus.CounterVec
+	AccessRejections      *prometheus.CounterVec
+	WebhookDeliveries     *prometheus.CounterVec
+	AdminJobs             *prometheus.CounterVec
 	MempoolSize           prometheus.Gauge
 	DbColumnRows          *prometheus.GaugeVec
 	DbColumnSize          *prometheus.GaugeVec
@@ -100,6 +113,87 @@ func GetMetrics(coin string) (*Metrics, error) {
 		},
 		[]string{"method"},
 	)
+	metrics.SSEClients = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "blockbook_sse_clients",
+			Help:        "Number of currently connected Server-Sent Events and long-poll clients",
+			ConstLabels: Labels{"coin": coin},
+		},
+	)
+	metrics.HTTPRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_http_requests",
+			Help:        "Total number of requests of the REST API and of the explorer by route and status code",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"route", "status"},
+	)
+	metrics.HTTPReqDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:        "blockbook_http_req_duration",
+			Help:        "Duration of the requests of the REST API and of the explorer by route (in milliseconds)",
+			Buckets:     []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000},
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"route"},
+	)
+	metrics.HTTPResponseSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:        "blockbook_http_response_size",
+			Help:        "Size of the responses of the REST API and of the explorer by route (in bytes)",
+			Buckets:     prometheus.ExponentialBuckets(256, 4, 8),
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"route"},
+	)
+	metrics.ElectrumRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_electrum_requests",
+			Help:        "Total number of electrum requests by method and status",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"method", "status"},
+	)
+	metrics.ElectrumClients = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "blockbook_electrum_clients",
+			Help:        "Number of currently connected electrum clients",
+			ConstLabels: Labels{"coin": coin},
+		},
+	)
+	metrics.ElectrumReqDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:        "blockbook_electrum_req_duration",
+			Help:        "Electrum request duration by method (in microseconds)",
+			Buckets:     []float64{1, 5, 10, 25, 50, 75, 100, 250},
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"method"},
+	)
+	metrics.GrpcRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_grpc_requests",
+			Help:        "Total number of grpc requests by method and status",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"method", "status"},
+	)
+	metrics.GrpcStreams = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "blockbook_grpc_streams",
+			Help:        "Number of currently open grpc subscription streams",
+			ConstLabels: Labels{"coin": coin},
+		},
+	)
+	metrics.GrpcReqDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:        "blockbook_grpc_req_duration",
+			Help:        "Grpc request duration by method (in microseconds)",
+			Buckets:     []float64{1, 5, 10, 25, 50, 75, 100, 250},
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"method"},
+	)
 	metrics.IndexResyncDuration = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Name:        "blockbook_index_resync_duration",
@@ -156,6 +250,30 @@ func GetMetrics(coin string) (*Metrics, error) {
 		},
 		[]string{"action"},
 	)
+	metrics.AccessRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_access_rejections",
+			Help:        "Number of requests rejected by the access limits by interface and reason",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"interface", "reason"},
+	)
+	metrics.WebhookDeliveries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_webhook_deliveries",
+			Help:        "Number of attempts to deliver webhook notifications by event and result",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"event", "result"},
+	)
+	metrics.AdminJobs = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_admin_jobs",
+			Help:        "Number of jobs run by the admin api by type and state",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"type", "state"},
+	)
 	metrics.MempoolSize = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Name:        "blockbook_mempool_size",
@@ -0,0 +1,365 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+const (
+	// tracingQueueSize is the number of the ended spans waiting for the export, the spans over it are dropped
+	tracingQueueSize = 4096
+	// tracingBatchSize is the maximum number of the spans sent in one export request
+	tracingBatchSize = 512
+	// tracingFlushInterval is the maximum time the ended span waits for the export
+	tracingFlushInterval = 5 * time.Second
+)
+
+const (
+	// otlpStatusError is the status code of the OTLP span of the failed operation
+	otlpStatusError = 2
+	// otlpKindInternal is the kind of all spans, the spans do not represent the remote calls
+	otlpKindInternal = 1
+)
+
+type spanContextKey struct{}
+
+// SpanAttr is an attribute of the span, the value is a string, int or bool
+type SpanAttr struct {
+	Key   string
+	Value interface{}
+}
+
+// StringAttr returns the attribute with the string value
+func StringAttr(key, value string) SpanAttr {
+	return SpanAttr{Key: key, Value: value}
+}
+
+// IntAttr returns the attribute with the int value
+func IntAttr(key string, value int) SpanAttr {
+	return SpanAttr{Key: key, Value: value}
+}
+
+// BoolAttr returns the attribute with the bool value
+func BoolAttr(key string, value bool) SpanAttr {
+	return SpanAttr{Key: key, Value: value}
+}
+
+// Span is a traced operation, nil Span is an operation which is not traced
+type Span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    []SpanAttr
+	err      string
+}
+
+// SetAttr sets the attribute of the span
+func (s *Span) SetAttr(attr SpanAttr) {
+	if s != nil {
+		s.attrs = append(s.attrs, attr)
+	}
+}
+
+// tracer exports the ended spans in batches by OTLP/HTTP in json encoding
+type tracer struct {
+	url         string
+	resource    []SpanAttr
+	sampleRatio float64
+	client      *http.Client
+	spans       chan *Span
+	flush       chan chan error
+	dropped     sync.Once
+}
+
+// activeTracer is set by InitTracing, until it is called the tracing is off
+var activeTracer *tracer
+
+// otlpTracesURL returns the url of the OTLP/HTTP traces endpoint, endpoint [address]:port is completed to http://[address]:port/v1/traces
+func otlpTracesURL(endpoint string) string {
+	if strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://") {
+		return endpoint
+	}
+	return "http://" + endpoint + "/v1/traces"
+}
+
+// InitTracing exports the tracing spans by OTLP over HTTP to the collector at endpoint ([address]:port or the url of the traces endpoint),
+// sampleRatio is the fraction of the traced requests; the returned function flushes the pending spans and stops the export
+func InitTracing(endpoint string, coin string, sampleRatio float64) (func(context.Context) error, error) {
+	if endpoint == "" {
+		return nil, errors.New("Missing OTLP endpoint")
+	}
+	t := &tracer{
+		url: otlpTracesURL(endpoint),
+		resource: []SpanAttr{
+			StringAttr("service.name", "blockbook"),
+			StringAttr("service.version", GetVersionInfo().Version),
+			StringAttr("coin", coin),
+		},
+		sampleRatio: sampleRatio,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		spans:       make(chan *Span, tracingQueueSize),
+		flush:       make(chan chan error),
+	}
+	go t.exportLoop()
+	activeTracer = t
+	glog.Info("tracing: exporting spans to ", t.url, ", sample ratio ", sampleRatio)
+	return t.shutdown, nil
+}
+
+// TracingEnabled returns true if the tracing spans are exported
+func TracingEnabled() bool {
+	return activeTracer != nil
+}
+
+func randomBytes(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		glog.Error("tracing: random ", err)
+	}
+}
+
+// sampled decides about the tracing of the new trace by its id, the same way as the OpenTelemetry TraceIDRatioBased sampler
+func (t *tracer) sampled(traceID [16]byte) bool {
+	if t.sampleRatio >= 1 {
+		return true
+	}
+	bound := uint64(t.sampleRatio * (1 << 63))
+	var x uint64
+	for _, b := range traceID[8:] {
+		x = x<<8 | uint64(b)
+	}
+	return x>>1 < bound
+}
+
+// StartSpan starts a span as a child of the span in ctx, nil ctx means that the operation is not traced;
+// the operations of the traces which are not sampled are not traced either
+func StartSpan(ctx context.Context, name string, attrs ...SpanAttr) (context.Context, *Span) {
+	t := activeTracer
+	if ctx == nil || t == nil {
+		return ctx, nil
+	}
+	s := &Span{name: name, start: time.Now(), attrs: attrs}
+	if v := ctx.Value(spanContextKey{}); v != nil {
+		parent := v.(*Span)
+		if parent == nil {
+			// the trace is not sampled
+			return ctx, nil
+		}
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		randomBytes(s.traceID[:])
+		if !t.sampled(s.traceID) {
+			return context.WithValue(ctx, spanContextKey{}, (*Span)(nil)), nil
+		}
+	}
+	randomBytes(s.spanID[:])
+	return context.WithValue(ctx, spanContextKey{}, s), s
+}
+
+// EndSpan records the error of the operation, if any, and ends the span
+func EndSpan(span *Span, err error) {
+	t := activeTracer
+	if span == nil || t == nil {
+		return
+	}
+	span.end = time.Now()
+	if err != nil {
+		span.err = err.Error()
+	}
+	select {
+	case t.spans <- span:
+	default:
+		t.dropped.Do(func() {
+			glog.Warning("tracing: export queue full, dropping the spans")
+		})
+	}
+}
+
+func (t *tracer) exportLoop() {
+	ticker := time.NewTicker(tracingFlushInterval)
+	defer ticker.Stop()
+	batch := make([]*Span, 0, tracingBatchSize)
+	export := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := t.export(batch)
+		batch = batch[:0]
+		return err
+	}
+	for {
+		select {
+		case s := <-t.spans:
+			batch = append(batch, s)
+			if len(batch) >= tracingBatchSize {
+				if err := export(); err != nil {
+					glog.Error("tracing: ", err)
+				}
+			}
+		case <-ticker.C:
+			if err := export(); err != nil {
+				glog.Error("tracing: ", err)
+			}
+		case done := <-t.flush:
+			// export all the spans ended before the shutdown
+			for {
+				for len(t.spans) > 0 && len(batch) < tracingBatchSize {
+					batch = append(batch, <-t.spans)
+				}
+				if err := export(); err != nil || len(t.spans) == 0 {
+					done <- err
+					return
+				}
+			}
+		}
+	}
+}
+
+// shutdown stops the tracing and exports the pending spans
+func (t *tracer) shutdown(ctx context.Context) error {
+	if activeTracer == t {
+		activeTracer = nil
+	}
+	done := make(chan error, 1)
+	select {
+	case t.flush <- done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+type otlpAttr struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []otlpAttr `json:"attributes,omitempty"`
+	Status            otlpStatus `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttr `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+// otlpTraces is the ExportTraceServiceRequest of OTLP in the json encoding
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+func otlpAttrs(attrs []SpanAttr) []otlpAttr {
+	rv := make([]otlpAttr, 0, len(attrs))
+	for _, a := range attrs {
+		var v otlpValue
+		switch x := a.Value.(type) {
+		case string:
+			v.StringValue = &x
+		case int:
+			i := strconv.Itoa(x)
+			v.IntValue = &i
+		case bool:
+			v.BoolValue = &x
+		default:
+			continue
+		}
+		rv = append(rv, otlpAttr{Key: a.Key, Value: v})
+	}
+	return rv
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func (t *tracer) otlpTraces(spans []*Span) *otlpTraces {
+	var rs otlpResourceSpans
+	rs.Resource.Attributes = otlpAttrs(t.resource)
+	var ss otlpScopeSpans
+	ss.Scope.Name = "blockbook"
+	ss.Spans = make([]otlpSpan, len(spans))
+	for i, s := range spans {
+		o := &ss.Spans[i]
+		o.TraceID = hex.EncodeToString(s.traceID[:])
+		o.SpanID = hex.EncodeToString(s.spanID[:])
+		if s.parentID != [8]byte{} {
+			o.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		o.Name = s.name
+		o.Kind = otlpKindInternal
+		o.StartTimeUnixNano = unixNano(s.start)
+		o.EndTimeUnixNano = unixNano(s.end)
+		o.Attributes = otlpAttrs(s.attrs)
+		if s.err != "" {
+			o.Status = otlpStatus{Code: otlpStatusError, Message: s.err}
+		}
+	}
+	rs.ScopeSpans = []otlpScopeSpans{ss}
+	return &otlpTraces{ResourceSpans: []otlpResourceSpans{rs}}
+}
+
+func (t *tracer) export(spans []*Span) error {
+	b, err := json.Marshal(t.otlpTraces(spans))
+	if err != nil {
+		return errors.Annotatef(err, "Marshal")
+	}
+	resp, err := t.client.Post(t.url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return errors.Annotatef(err, "OTLP export %v", t.url)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("OTLP export %v: %v, %d spans dropped", t.url, resp.Status, len(spans))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// +build unittest
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_otlpTracesURL(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"127.0.0.1:4318", "http://127.0.0.1:4318/v1/traces"},
+		{"collector:4318", "http://collector:4318/v1/traces"},
+		{"https://collector/otlp/v1/traces", "https://collector/otlp/v1/traces"},
+	}
+	for _, tt := range tests {
+		if got := otlpTracesURL(tt.endpoint); got != tt.want {
+			t.Errorf("otlpTracesURL(%v) = %v, want %v", tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+func TestStartSpan_disabled(t *testing.T) {
+	ctx := context.Background()
+	c, span := StartSpan(ctx, "test")
+	if c != ctx || span != nil {
+		t.Error("StartSpan() started span with disabled tracing")
+	}
+	span.SetAttr(BoolAttr("hit", true))
+	EndSpan(span, errors.New("test error"))
+	if c, _ := StartSpan(nil, "test"); c != nil {
+		t.Error("StartSpan() returned context for nil context")
+	}
+}
+
+func TestStartSpan_notSampled(t *testing.T) {
+	activeTracer = &tracer{sampleRatio: 0}
+	defer func() { activeTracer = nil }()
+	ctx, span := StartSpan(context.Background(), "http apiTx")
+	if span != nil {
+		t.Fatal("StartSpan() started span of not sampled trace")
+	}
+	// the nested operations of the trace are not sampled again
+	activeTracer.sampleRatio = 1
+	if _, child := StartSpan(ctx, "db.GetTx"); child != nil {
+		t.Error("StartSpan() started child span of not sampled trace")
+	}
+}
+
+func TestStartSpan_enabled(t *testing.T) {
+	requests := make(chan *otlpTraces, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %v", ct)
+		}
+		var req otlpTraces
+		if err = json.Unmarshal(b, &req); err != nil {
+			t.Error(err)
+		}
+		requests <- &req
+	}))
+	defer ts.Close()
+	shutdown, err := InitTracing(ts.URL, "Bitcoin", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, parent := StartSpan(context.Background(), "http apiTx", StringAttr("http.method", "GET"))
+	_, child := StartSpan(ctx, "db.GetTx", IntAttr("page", 2))
+	child.SetAttr(BoolAttr("hit", false))
+	EndSpan(child, errors.New("not found"))
+	EndSpan(parent, nil)
+	c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = shutdown(c); err != nil {
+		t.Fatal(err)
+	}
+	if TracingEnabled() {
+		t.Error("TracingEnabled() after shutdown")
+	}
+
+	var req *otlpTraces
+	select {
+	case req = <-requests:
+	default:
+		t.Fatal("no export request")
+	}
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("export request %+v", req)
+	}
+	rs := req.ResourceSpans[0]
+	if a := rs.Resource.Attributes; len(a) != 3 || a[0].Key != "service.name" || *a[0].Value.StringValue != "blockbook" || *a[2].Value.StringValue != "Bitcoin" {
+		t.Errorf("resource attributes %+v", a)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("exported spans = %d, want 2", len(spans))
+	}
+	cs, ps := spans[0], spans[1]
+	if cs.Name != "db.GetTx" || ps.Name != "http apiTx" {
+		t.Errorf("span names = %v, %v", cs.Name, ps.Name)
+	}
+	if len(cs.TraceID) != 32 || cs.TraceID != ps.TraceID || len(ps.SpanID) != 16 || cs.ParentSpanID != ps.SpanID || ps.ParentSpanID != "" {
+		t.Errorf("db.GetTx %+v is not a child of http apiTx %+v", cs, ps)
+	}
+	if cs.Status.Code != otlpStatusError || cs.Status.Message != "not found" || ps.Status.Code != 0 {
+		t.Errorf("statuses = %+v, %+v", cs.Status, ps.Status)
+	}
+	if a := cs.Attributes; len(a) != 2 || a[0].Key != "page" || *a[0].Value.IntValue != "2" || a[1].Key != "hit" || *a[1].Value.BoolValue {
+		t.Errorf("db.GetTx attributes %+v", a)
+	}
+	if cs.StartTimeUnixNano == "" || cs.EndTimeUnixNano < cs.StartTimeUnixNano {
+		t.Errorf("db.GetTx times %v-%v", cs.StartTimeUnixNano, cs.EndTimeUnixNano)
+	}
+}
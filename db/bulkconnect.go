@@ -27,6 +27,15 @@ type BulkConnect struct {
 	bulkAddressesCount int
 	txAddressesMap     map[string]*TxAddresses
 	balances           map[string]*AddrBalance
+	utxoTokens         utxoTokensMap
+	tokenTransfers     tokenTransfersMap
+	tokenContracts     tokenContractsMap
+	nameHistory        nameHistoryMap
+	txNames            txNamesMap
+	spentBy            spentByMap
+	outputScripts      outputScriptsMap
+	blockFilters       []*BlockFilter
+	lastFilterHeader   []byte
 	addressContracts   map[string]*AddrContracts
 	height             uint32
 }
@@ -48,6 +57,13 @@ func (d *RocksDB) InitBulkConnect() (*BulkConnect, error) {
 		chainType:        d.chainParser.GetChainType(),
 		txAddressesMap:   make(map[string]*TxAddresses),
 		balances:         make(map[string]*AddrBalance),
+		utxoTokens:       make(utxoTokensMap),
+		tokenTransfers:   make(tokenTransfersMap),
+		tokenContracts:   make(tokenContractsMap),
+		nameHistory:      make(nameHistoryMap),
+		txNames:          make(txNamesMap),
+		spentBy:          make(spentByMap),
+		outputScripts:    make(outputScriptsMap),
 		addressContracts: make(map[string]*AddrContracts),
 	}
 	if err := d.SetInconsistentState(true); err != nil {
@@ -169,11 +185,96 @@ func (b *BulkConnect) storeBulkAddresses(wb *gorocksdb.WriteBatch) error {
 	return nil
 }
 
+func (b *BulkConnect) storeUtxoTokens(wb *gorocksdb.WriteBatch) error {
+	if len(b.utxoTokens) == 0 {
+		return nil
+	}
+	if err := b.d.storeUtxoTokens(wb, b.utxoTokens); err != nil {
+		return err
+	}
+	b.utxoTokens = make(utxoTokensMap)
+	return nil
+}
+
+func (b *BulkConnect) storeTokenTransfers(wb *gorocksdb.WriteBatch) error {
+	if len(b.tokenTransfers) == 0 && len(b.tokenContracts) == 0 {
+		return nil
+	}
+	if err := b.d.storeTokenTransfers(wb, b.tokenTransfers); err != nil {
+		return err
+	}
+	if err := b.d.storeTokenContracts(wb, b.tokenContracts); err != nil {
+		return err
+	}
+	b.tokenTransfers = make(tokenTransfersMap)
+	b.tokenContracts = make(tokenContractsMap)
+	return nil
+}
+
+func (b *BulkConnect) storeNames(wb *gorocksdb.WriteBatch) error {
+	if len(b.nameHistory) == 0 {
+		return nil
+	}
+	if err := b.d.storeNames(wb, b.nameHistory, b.txNames); err != nil {
+		return err
+	}
+	b.nameHistory = make(nameHistoryMap)
+	b.txNames = make(txNamesMap)
+	return nil
+}
+
+func (b *BulkConnect) storeSpentBy(wb *gorocksdb.WriteBatch) error {
+	if len(b.spentBy) == 0 {
+		return nil
+	}
+	if err := b.d.storeSpentBy(wb, b.spentBy); err != nil {
+		return err
+	}
+	b.spentBy = make(spentByMap)
+	return nil
+}
+
+func (b *BulkConnect) storeBlockFilters(wb *gorocksdb.WriteBatch) {
+	b.d.storeOutputScripts(wb, b.outputScripts)
+	b.outputScripts = make(outputScriptsMap)
+	for _, bf := range b.blockFilters {
+		b.d.storeBlockFilter(wb, bf)
+	}
+	b.blockFilters = b.blockFilters[:0]
+}
+
 func (b *BulkConnect) connectBlockBitcoinType(block *bchain.Block, storeBlockTxs bool) error {
 	addresses := make(addressesMap)
 	if err := b.d.processAddressesBitcoinType(block, addresses, b.txAddressesMap, b.balances); err != nil {
 		return err
 	}
+	if b.lastFilterHeader == nil {
+		var err error
+		if b.lastFilterHeader, err = b.d.getPrevFilterHeader(block.Height); err != nil {
+			return err
+		}
+	}
+	if err := b.d.processOutputScripts(block, b.outputScripts); err != nil {
+		return err
+	}
+	bf, err := b.d.processBlockFilter(block, b.txAddressesMap, b.outputScripts, b.lastFilterHeader)
+	if err != nil {
+		return err
+	}
+	b.blockFilters = append(b.blockFilters, bf)
+	b.lastFilterHeader = bf.Header
+	if err := b.d.processUtxoTokens(block, b.utxoTokens); err != nil {
+		return err
+	}
+	if err := b.d.processTokenTransfers(block, b.tokenTransfers, b.tokenContracts); err != nil {
+		return err
+	}
+	if err := b.d.processNames(block, b.nameHistory, b.txNames); err != nil {
+		return err
+	}
+	if err := b.d.processSpentBy(block, b.spentBy); err != nil {
+		return err
+	}
 	var storeAddressesChan, storeBalancesChan chan error
 	var sa bool
 	if len(b.txAddressesMap) > maxBulkTxAddresses || len(b.balances) > maxBulkBalances {
@@ -209,6 +310,19 @@ func (b *BulkConnect) connectBlockBitcoinType(block *bchain.Block, storeBlockTxs
 				return err
 			}
 		}
+		if err := b.storeUtxoTokens(wb); err != nil {
+			return err
+		}
+		if err := b.storeTokenTransfers(wb); err != nil {
+			return err
+		}
+		if err := b.storeNames(wb); err != nil {
+			return err
+		}
+		if err := b.storeSpentBy(wb); err != nil {
+			return err
+		}
+		b.storeBlockFilters(wb)
 		if storeBlockTxs {
 			if err := b.d.storeAndCleanupBlockTxs(wb, block); err != nil {
 				return err
@@ -362,6 +476,19 @@ func (b *BulkConnect) Close() error {
 	if err := b.storeBulkAddresses(wb); err != nil {
 		return err
 	}
+	if err := b.storeUtxoTokens(wb); err != nil {
+		return err
+	}
+	if err := b.storeTokenTransfers(wb); err != nil {
+		return err
+	}
+	if err := b.storeNames(wb); err != nil {
+		return err
+	}
+	if err := b.storeSpentBy(wb); err != nil {
+		return err
+	}
+	b.storeBlockFilters(wb)
 	if err := b.d.db.Write(b.d.wo, wb); err != nil {
 		return err
 	}
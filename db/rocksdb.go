@@ -21,7 +21,12 @@ import (
 	"github.com/tecbot/gorocksdb"
 )
 
-const dbVersion = 5
+// dbVersion is the version of the data format, the version 6 added columns of Bitcoin type coins which must be filled from the first block,
+// the version 7 added the position of the transaction in the block to txAddresses
+const dbVersion = 7
+
+// dbVersionEthereumType is the version of the data format of Ethereum type coins, which were not changed by the versions 6 and 7
+const dbVersionEthereumType = 5
 
 const packedHeightBytes = 4
 const maxAddrDescLen = 1024
@@ -77,19 +82,32 @@ const (
 	cfAddresses
 	cfBlockTxs
 	cfTransactions
+	cfOrphanedBlocks
+	cfWebhooks
+	cfWebhookDeliveries
+	cfWebhookTxs
 	// BitcoinType
 	cfAddressBalance
 	cfTxAddresses
+	cfUtxoTokens
+	cfTokenContracts
+	cfTokenTransfers
+	cfNameHistory
+	cfTxNames
+	cfSpentBy
+	cfBlockFilters
+	cfScriptHashes
+	cfOutputScripts
 	// EthereumType
 	cfAddressContracts = cfAddressBalance
 )
 
 // common columns
 var cfNames []string
-var cfBaseNames = []string{"default", "height", "addresses", "blockTxs", "transactions"}
+var cfBaseNames = []string{"default", "height", "addresses", "blockTxs", "transactions", "orphanedBlocks", "webhooks", "webhookDeliveries", "webhookTxs"}
 
 // type specific columns
-var cfNamesBitcoinType = []string{"addressBalance", "txAddresses"}
+var cfNamesBitcoinType = []string{"addressBalance", "txAddresses", "utxoTokens", "tokenContracts", "tokenTransfers", "nameHistory", "txNames", "spentBy", "blockFilters", "scriptHashes", "outputScripts"}
 var cfNamesEthereumType = []string{"addressContracts"}
 
 func openDB(path string, c *gorocksdb.Cache, openFiles int) (*gorocksdb.DB, []*gorocksdb.ColumnFamilyHandle, error) {
@@ -98,8 +116,8 @@ func openDB(path string, c *gorocksdb.Cache, openFiles int) (*gorocksdb.DB, []*g
 	// opts for addresses without bloom filter
 	// from documentation: if most of your queries are executed using iterators, you shouldn't set bloom filter
 	optsAddresses := createAndSetDBOptions(0, c, openFiles)
-	// default, height, addresses, blockTxids, transactions
-	cfOptions := []*gorocksdb.Options{opts, opts, optsAddresses, opts, opts}
+	// default, height, addresses, blockTxids, transactions, orphanedBlocks, webhooks, webhookDeliveries, webhookTxs
+	cfOptions := []*gorocksdb.Options{opts, opts, optsAddresses, opts, opts, opts, opts, opts, opts}
 	// append type specific options
 	count := len(cfNames) - len(cfOptions)
 	for i := 0; i < count; i++ {
@@ -115,7 +133,7 @@ func openDB(path string, c *gorocksdb.Cache, openFiles int) (*gorocksdb.DB, []*g
 // NewRocksDB opens an internal handle to RocksDB environment.  Close
 // needs to be called to release it.
 func NewRocksDB(path string, cacheSize, maxOpenFiles int, parser bchain.BlockChainParser, metrics *common.Metrics) (d *RocksDB, err error) {
-	glog.Infof("rocksdb: opening %s, required data version %v, cache size %v, max open files %v", path, dbVersion, cacheSize, maxOpenFiles)
+	glog.Infof("rocksdb: opening %s, required data version %v, cache size %v, max open files %v", path, requiredDbVersion(parser.GetChainType()), cacheSize, maxOpenFiles)
 
 	cfNames = append([]string{}, cfBaseNames...)
 	chainType := parser.GetChainType()
@@ -137,6 +155,14 @@ func NewRocksDB(path string, cacheSize, maxOpenFiles int, parser bchain.BlockCha
 	return &RocksDB{path, db, wo, ro, cfh, parser, nil, metrics, c, maxOpenFiles, connectBlockStats{}}, nil
 }
 
+// requiredDbVersion returns the version of the data format of the chain type
+func requiredDbVersion(chainType bchain.ChainType) uint32 {
+	if chainType == bchain.ChainEthereumType {
+		return dbVersionEthereumType
+	}
+	return dbVersion
+}
+
 func (d *RocksDB) closeDB() error {
 	for _, h := range d.cfh {
 		h.Destroy()
@@ -318,12 +344,59 @@ func (d *RocksDB) ConnectBlock(block *bchain.Block) error {
 	if chainType == bchain.ChainBitcoinType {
 		txAddressesMap := make(map[string]*TxAddresses)
 		balances := make(map[string]*AddrBalance)
+		utxoTokens := make(utxoTokensMap)
 		if err := d.processAddressesBitcoinType(block, addresses, txAddressesMap, balances); err != nil {
 			return err
 		}
+		if err := d.processUtxoTokens(block, utxoTokens); err != nil {
+			return err
+		}
+		tokenTransfers := make(tokenTransfersMap)
+		tokenContracts := make(tokenContractsMap)
+		if err := d.processTokenTransfers(block, tokenTransfers, tokenContracts); err != nil {
+			return err
+		}
+		outputScripts := make(outputScriptsMap)
+		if err := d.processOutputScripts(block, outputScripts); err != nil {
+			return err
+		}
+		prevFilterHeader, err := d.getPrevFilterHeader(block.Height)
+		if err != nil {
+			return err
+		}
+		blockFilter, err := d.processBlockFilter(block, txAddressesMap, outputScripts, prevFilterHeader)
+		if err != nil {
+			return err
+		}
+		spentBy := make(spentByMap)
+		if err := d.processSpentBy(block, spentBy); err != nil {
+			return err
+		}
+		nameHistory := make(nameHistoryMap)
+		txNames := make(txNamesMap)
+		if err := d.processNames(block, nameHistory, txNames); err != nil {
+			return err
+		}
 		if err := d.storeTxAddresses(wb, txAddressesMap); err != nil {
 			return err
 		}
+		if err := d.storeUtxoTokens(wb, utxoTokens); err != nil {
+			return err
+		}
+		if err := d.storeTokenTransfers(wb, tokenTransfers); err != nil {
+			return err
+		}
+		if err := d.storeTokenContracts(wb, tokenContracts); err != nil {
+			return err
+		}
+		if err := d.storeNames(wb, nameHistory, txNames); err != nil {
+			return err
+		}
+		if err := d.storeSpentBy(wb, spentBy); err != nil {
+			return err
+		}
+		d.storeOutputScripts(wb, outputScripts)
+		d.storeBlockFilter(wb, blockFilter)
 		if err := d.storeBalances(wb, balances); err != nil {
 			return err
 		}
@@ -394,9 +467,10 @@ func (to *TxOutput) Addresses(p bchain.BlockChainParser) ([]string, bool, error)
 
 // TxAddresses stores transaction inputs and outputs with amounts
 type TxAddresses struct {
-	Height  uint32
-	Inputs  []TxInput
-	Outputs []TxOutput
+	Height   uint32
+	Position uint32
+	Inputs   []TxInput
+	Outputs  []TxOutput
 }
 
 // Utxo holds information about unspent transaction output
@@ -510,7 +584,7 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 			return err
 		}
 		blockTxIDs[txi] = btxID
-		ta := TxAddresses{Height: block.Height}
+		ta := TxAddresses{Height: block.Height, Position: uint32(txi)}
 		ta.Outputs = make([]TxOutput, len(tx.Vout))
 		txAddressesMap[string(btxID)] = &ta
 		blockTxAddresses[txi] = &ta
@@ -696,9 +770,11 @@ func (d *RocksDB) storeBalances(wb *gorocksdb.WriteBatch, abm map[string]*AddrBa
 		// balance with 0 transactions is removed from db - happens on disconnect
 		if ab == nil || ab.Txs <= 0 {
 			wb.DeleteCF(d.cfh[cfAddressBalance], bchain.AddressDescriptor(addrDesc))
+			d.deleteScriptHash(wb, bchain.AddressDescriptor(addrDesc))
 		} else {
 			buf = packAddrBalance(ab, buf, varBuf)
 			wb.PutCF(d.cfh[cfAddressBalance], bchain.AddressDescriptor(addrDesc), buf)
+			d.storeScriptHash(wb, bchain.AddressDescriptor(addrDesc))
 		}
 	}
 	return nil
@@ -791,6 +867,25 @@ func (d *RocksDB) getBlockTxs(height uint32) ([]blockTxs, error) {
 	return bt, nil
 }
 
+// GetBlockTxids returns txids of the transactions in the block of Bitcoin type coin from the blockTxs column
+// the column contains only the last blocks, nil is returned if the block is not there
+func (d *RocksDB) GetBlockTxids(height uint32) ([]string, error) {
+	bt, err := d.getBlockTxs(height)
+	if err != nil {
+		return nil, err
+	}
+	if len(bt) == 0 {
+		return nil, nil
+	}
+	txids := make([]string, len(bt))
+	for i := range bt {
+		if txids[i], err = d.chainParser.UnpackTxid(bt[i].btxID); err != nil {
+			return nil, err
+		}
+	}
+	return txids, nil
+}
+
 // GetAddrDescBalance returns AddrBalance for given addrDesc
 func (d *RocksDB) GetAddrDescBalance(addrDesc bchain.AddressDescriptor, detail AddressBalanceDetail) (*AddrBalance, error) {
 	val, err := d.db.GetCF(d.ro, d.cfh[cfAddressBalance], addrDesc)
@@ -806,6 +901,33 @@ func (d *RocksDB) GetAddrDescBalance(addrDesc bchain.AddressDescriptor, detail A
 	return unpackAddrBalance(buf, d.chainParser.PackedTxidLen(), detail)
 }
 
+// GetAddrDescBalances returns balances of multiple address descriptors, read from the db in one batch
+// the balance is nil if the address descriptor is not found
+func (d *RocksDB) GetAddrDescBalances(addrDescs []bchain.AddressDescriptor, detail AddressBalanceDetail) ([]*AddrBalance, error) {
+	keys := make([][]byte, len(addrDescs))
+	for i := range addrDescs {
+		keys[i] = addrDescs[i]
+	}
+	vals, err := d.db.MultiGetCF(d.ro, d.cfh[cfAddressBalance], keys...)
+	if err != nil {
+		return nil, err
+	}
+	defer vals.Destroy()
+	rv := make([]*AddrBalance, len(addrDescs))
+	for i, val := range vals {
+		buf := val.Data()
+		// 3 is minimum length of addrBalance - 1 byte txs, 1 byte sent, 1 byte balance
+		if len(buf) < 3 {
+			continue
+		}
+		rv[i], err = unpackAddrBalance(buf, d.chainParser.PackedTxidLen(), detail)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rv, nil
+}
+
 // GetAddressBalance returns address balance for an address or nil if address not found
 func (d *RocksDB) GetAddressBalance(address string, detail AddressBalanceDetail) (*AddrBalance, error) {
 	addrDesc, err := d.chainParser.GetAddrDescFromAddress(address)
@@ -861,6 +983,8 @@ func packTxAddresses(ta *TxAddresses, buf []byte, varBuf []byte) []byte {
 	buf = buf[:0]
 	l := packVaruint(uint(ta.Height), varBuf)
 	buf = append(buf, varBuf[:l]...)
+	l = packVaruint(uint(ta.Position), varBuf)
+	buf = append(buf, varBuf[:l]...)
 	l = packVaruint(uint(len(ta.Inputs)), varBuf)
 	buf = append(buf, varBuf[:l]...)
 	for i := range ta.Inputs {
@@ -963,6 +1087,9 @@ func unpackTxAddresses(buf []byte) (*TxAddresses, error) {
 	ta := TxAddresses{}
 	height, l := unpackVaruint(buf)
 	ta.Height = uint32(height)
+	position, ll := unpackVaruint(buf[l:])
+	l += ll
+	ta.Position = uint32(position)
 	inputs, ll := unpackVaruint(buf[l:])
 	l += ll
 	ta.Inputs = make([]TxInput, inputs)
@@ -1281,7 +1408,8 @@ func (d *RocksDB) disconnectTxAddresses(wb *gorocksdb.WriteBatch, height uint32,
 
 // DisconnectBlockRangeBitcoinType removes all data belonging to blocks in range lower-higher
 // it is able to disconnect only blocks for which there are data in the blockTxs column
-func (d *RocksDB) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) error {
+// the disconnected blocks are stored to the orphanedBlocks column with the cause of the disconnection
+func (d *RocksDB) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32, cause OrphanedCause) error {
 	blocks := make([][]blockTxs, higher-lower+1)
 	for height := lower; height <= higher; height++ {
 		blockTxs, err := d.getBlockTxs(height)
@@ -1301,11 +1429,14 @@ func (d *RocksDB) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) e
 	for height := higher; height >= lower; height-- {
 		blockTxs := blocks[height-lower]
 		glog.Info("Disconnecting block ", height, " containing ", len(blockTxs), " transactions")
+		btxIDs := make([][]byte, len(blockTxs))
+		addrDescs := make(orphanedBlockAddrDescs)
 		// go backwards to avoid interim negative balance
 		// when connecting block, amount is first in tx on the output side, then in another tx on the input side
 		// when disconnecting, it must be done backwards
 		for i := len(blockTxs) - 1; i >= 0; i-- {
 			btxID := blockTxs[i].btxID
+			btxIDs[i] = btxID
 			s := string(btxID)
 			txsToDelete[s] = struct{}{}
 			txa, err := d.getTxAddresses(btxID)
@@ -1317,21 +1448,52 @@ func (d *RocksDB) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) e
 				glog.Warning("TxAddress for txid ", ut, " not found")
 				continue
 			}
+			for j := range txa.Inputs {
+				addrDescs.add(txa.Inputs[j].AddrDesc)
+			}
+			for j := range txa.Outputs {
+				addrDescs.add(txa.Outputs[j].AddrDesc)
+			}
 			if err := d.disconnectTxAddresses(wb, height, btxID, blockTxs[i].inputs, txa, txAddressesToUpdate, balances); err != nil {
 				return err
 			}
+			d.disconnectSpentBy(wb, blockTxs[i].inputs)
+			d.disconnectOutputScripts(wb, btxID, len(txa.Outputs))
+		}
+		if err := d.storeOrphanedBlock(wb, height, cause, btxIDs, addrDescs); err != nil {
+			return err
 		}
 		key := packUint(height)
 		wb.DeleteCF(d.cfh[cfBlockTxs], key)
 		wb.DeleteCF(d.cfh[cfHeight], key)
+		wb.DeleteCF(d.cfh[cfBlockFilters], key)
 	}
+	d.pruneOrphanedBlocks(wb, int(higher-lower+1))
 	d.storeTxAddresses(wb, txAddressesToUpdate)
 	d.storeBalancesDisconnect(wb, balances)
+	utxoTokens := d.chainParser.GetUtxoTokenType() != ""
+	contractTokens := d.chainParser.GetContractTokenType() != ""
+	names := d.chainParser.GetNameExpirationDepth() != 0
+	tokenContracts := make(tokenContractsMap)
 	for s := range txsToDelete {
 		b := []byte(s)
 		wb.DeleteCF(d.cfh[cfTransactions], b)
 		wb.DeleteCF(d.cfh[cfTxAddresses], b)
+		if utxoTokens {
+			wb.DeleteCF(d.cfh[cfUtxoTokens], b)
+		}
+		if contractTokens {
+			if err := d.disconnectTokenTransfers(wb, b, tokenContracts); err != nil {
+				return err
+			}
+		}
+		if names {
+			if err := d.disconnectNames(wb, b); err != nil {
+				return err
+			}
+		}
 	}
+	d.storeTokenContracts(wb, tokenContracts)
 	err := d.db.Write(d.wo, wb)
 	if err == nil {
 		glog.Infof("rocksdb: blocks %d-%d disconnected", lower, higher)
@@ -1475,14 +1637,15 @@ func (d *RocksDB) LoadInternalState(rpcCoin string) (*common.InternalState, erro
 	// make sure that column stats match the columns
 	sc := is.DbColumns
 	nc := make([]common.InternalStateColumn, len(cfNames))
+	version := requiredDbVersion(d.chainParser.GetChainType())
 	for i := 0; i < len(nc); i++ {
 		nc[i].Name = cfNames[i]
-		nc[i].Version = dbVersion
+		nc[i].Version = version
 		for j := 0; j < len(sc); j++ {
 			if sc[j].Name == nc[i].Name {
 				// check the version of the column, if it does not match, the db is not compatible
-				if sc[j].Version != dbVersion {
-					return nil, errors.Errorf("DB version %v of column '%v' does not match the required version %v. DB is not compatible.", sc[j].Version, sc[j].Name, dbVersion)
+				if sc[j].Version != version {
+					return nil, errors.Errorf("DB version %v of column '%v' does not match the required version %v. DB is not compatible, it is necessary to rebuild index.", sc[j].Version, sc[j].Name, version)
 				}
 				nc[i].Rows = sc[j].Rows
 				nc[i].KeyBytes = sc[j].KeyBytes
@@ -1585,17 +1748,44 @@ func (d *RocksDB) ComputeInternalStateColumnStats(stopCompute chan os.Signal) er
 	start := time.Now()
 	glog.Info("db: ComputeInternalStateColumnStats start")
 	for c := 0; c < len(cfNames); c++ {
-		rows, keysSum, valuesSum, err := d.computeColumnSize(c, stopCompute)
-		if err != nil {
+		if err := d.ComputeColumnStats(c, stopCompute); err != nil {
 			return err
 		}
-		d.is.SetDBColumnStats(c, rows, keysSum, valuesSum)
-		glog.Info("db: Column ", cfNames[c], ": rows ", rows, ", key bytes ", keysSum, ", value bytes ", valuesSum)
 	}
 	glog.Info("db: ComputeInternalStateColumnStats finished in ", time.Since(start))
 	return nil
 }
 
+// ComputeColumnStats computes stats of the db column with index c and sets them to internal state
+func (d *RocksDB) ComputeColumnStats(c int, stopCompute chan os.Signal) error {
+	rows, keysSum, valuesSum, err := d.computeColumnSize(c, stopCompute)
+	if err != nil {
+		return err
+	}
+	d.is.SetDBColumnStats(c, rows, keysSum, valuesSum)
+	glog.Info("db: Column ", cfNames[c], ": rows ", rows, ", key bytes ", keysSum, ", value bytes ", valuesSum)
+	return nil
+}
+
+// ColumnNames returns the names of the db columns, the index of the name is the index of the column
+func (d *RocksDB) ColumnNames() []string {
+	return cfNames
+}
+
+// CompactColumn compacts the whole db column with the given name
+func (d *RocksDB) CompactColumn(name string) error {
+	for c := range cfNames {
+		if cfNames[c] == name {
+			start := time.Now()
+			glog.Info("db: compacting column ", name)
+			d.db.CompactRangeCF(d.cfh[c], gorocksdb.Range{})
+			glog.Info("db: column ", name, " compacted in ", time.Since(start))
+			return nil
+		}
+	}
+	return errors.Errorf("Unknown column %v", name)
+}
+
 // Helpers
 
 func packAddressKey(addrDesc bchain.AddressDescriptor, height uint32) []byte {
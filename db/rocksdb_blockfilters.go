@@ -0,0 +1,285 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math/bits"
+	"sort"
+
+	vlq "github.com/bsm/go-vlq"
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// parameters of BIP158 basic filter
+const (
+	basicFilterP = 19
+	basicFilterM = 784931
+)
+
+const filterHeaderLen = sha256.Size
+
+// BlockFilter is a BIP158 basic filter of a block with the filter header
+type BlockFilter struct {
+	Height uint32
+	Filter []byte
+	Header []byte
+}
+
+func rotl64(x uint64, b uint) uint64 {
+	return (x << b) | (x >> (64 - b))
+}
+
+// sipHash24 computes SipHash-2-4 of the message with the key k0, k1
+func sipHash24(k0, k1 uint64, m []byte) uint64 {
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+	round := func() {
+		v0 += v1
+		v1 = rotl64(v1, 13)
+		v1 ^= v0
+		v0 = rotl64(v0, 32)
+		v2 += v3
+		v3 = rotl64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = rotl64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = rotl64(v1, 17)
+		v1 ^= v2
+		v2 = rotl64(v2, 32)
+	}
+	l := len(m)
+	i := 0
+	for ; i+8 <= l; i += 8 {
+		mi := binary.LittleEndian.Uint64(m[i:])
+		v3 ^= mi
+		round()
+		round()
+		v0 ^= mi
+	}
+	t := uint64(l&0xff) << 56
+	for j := 0; i+j < l; j++ {
+		t |= uint64(m[i+j]) << (8 * uint(j))
+	}
+	v3 ^= t
+	round()
+	round()
+	v0 ^= t
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	round()
+	return v0 ^ v1 ^ v2 ^ v3
+}
+
+type bitWriter struct {
+	buf  []byte
+	used uint
+}
+
+func (w *bitWriter) writeBit(b bool) {
+	if w.used == 0 {
+		w.buf = append(w.buf, 0)
+		w.used = 8
+	}
+	w.used--
+	if b {
+		w.buf[len(w.buf)-1] |= 1 << w.used
+	}
+}
+
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	for n > 0 {
+		n--
+		w.writeBit(v&(1<<n) != 0)
+	}
+}
+
+// packCompactSize packs the number in the bitcoin CompactSize format
+func packCompactSize(n uint64, buf []byte) []byte {
+	switch {
+	case n < 0xfd:
+		return append(buf, byte(n))
+	case n <= 0xffff:
+		buf = append(buf, 0xfd, 0, 0)
+		binary.LittleEndian.PutUint16(buf[len(buf)-2:], uint16(n))
+	case n <= 0xffffffff:
+		buf = append(buf, 0xfe, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(buf[len(buf)-4:], uint32(n))
+	default:
+		buf = append(buf, 0xff, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint64(buf[len(buf)-8:], n)
+	}
+	return buf
+}
+
+// buildBasicFilter builds Golomb-Rice coded set of the items as specified by BIP158
+// the key is derived from the block hash in the internal byte order
+func buildBasicFilter(blockHash []byte, items [][]byte) []byte {
+	unique := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		unique[string(item)] = struct{}{}
+	}
+	n := uint64(len(unique))
+	if n == 0 {
+		return []byte{0}
+	}
+	k0 := binary.LittleEndian.Uint64(blockHash[0:8])
+	k1 := binary.LittleEndian.Uint64(blockHash[8:16])
+	f := n * basicFilterM
+	values := make([]uint64, 0, n)
+	for item := range unique {
+		// map the hash uniformly to the range [0, f)
+		v, _ := bits.Mul64(sipHash24(k0, k1, []byte(item)), f)
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	w := bitWriter{}
+	var last uint64
+	for _, v := range values {
+		d := v - last
+		last = v
+		for q := d >> basicFilterP; q > 0; q-- {
+			w.writeBit(true)
+		}
+		w.writeBit(false)
+		w.writeBits(d, basicFilterP)
+	}
+	return append(packCompactSize(n, make([]byte, 0, 9+len(w.buf))), w.buf...)
+}
+
+func doubleSha256(b []byte) []byte {
+	h := sha256.Sum256(b)
+	h = sha256.Sum256(h[:])
+	return h[:]
+}
+
+// basicFilterHeader computes the filter header from the filter and the header of the previous block
+func basicFilterHeader(filter, prevHeader []byte) []byte {
+	return doubleSha256(append(doubleSha256(filter), prevHeader...))
+}
+
+// processBlockFilter computes basic filter of the block and its filter header
+// the filter contains the output scripts of the block except OP_RETURN and the scripts spent by the inputs,
+// the spent scripts are the address descriptors from txAddressesMap unless the real script is stored in outputScripts
+func (d *RocksDB) processBlockFilter(block *bchain.Block, txAddressesMap map[string]*TxAddresses, outputScripts outputScriptsMap, prevHeader []byte) (*BlockFilter, error) {
+	blockHash, err := hex.DecodeString(block.Hash)
+	if err != nil || len(blockHash) < 16 {
+		return nil, errors.Errorf("Invalid block hash %v", block.Hash)
+	}
+	// the hash is displayed in reversed byte order
+	for i, j := 0, len(blockHash)-1; i < j; i, j = i+1, j-1 {
+		blockHash[i], blockHash[j] = blockHash[j], blockHash[i]
+	}
+	varBuf := make([]byte, vlq.MaxLen64)
+	items := make([][]byte, 0, 4*len(block.Txs))
+	for txi := range block.Txs {
+		tx := &block.Txs[txi]
+		for i := range tx.Vout {
+			script, err := hex.DecodeString(tx.Vout[i].ScriptPubKey.Hex)
+			if err != nil {
+				glog.Warningf("rocksdb: block filter: height %d, tx %v, vout %v, error %v", block.Height, tx.Txid, i, err)
+				continue
+			}
+			// OP_RETURN outputs are not part of the filter
+			if len(script) == 0 || script[0] == 0x6a {
+				continue
+			}
+			items = append(items, script)
+		}
+		btxID, err := d.chainParser.PackTxid(tx.Txid)
+		if err != nil {
+			return nil, err
+		}
+		ta, found := txAddressesMap[string(btxID)]
+		if !found {
+			continue
+		}
+		for i := range tx.Vin {
+			if i >= len(ta.Inputs) {
+				break
+			}
+			input := &tx.Vin[i]
+			ibtxID, err := d.chainParser.PackTxid(input.Txid)
+			if err != nil {
+				// coinbase inputs do not spend any script
+				if err == bchain.ErrTxidMissing {
+					continue
+				}
+				return nil, err
+			}
+			script, err := d.getSpentScript(ibtxID, input.Vout, ta.Inputs[i].AddrDesc, outputScripts, varBuf)
+			if err != nil {
+				return nil, err
+			}
+			if len(script) > 0 {
+				items = append(items, script)
+			}
+		}
+	}
+	filter := buildBasicFilter(blockHash, items)
+	return &BlockFilter{
+		Height: block.Height,
+		Filter: filter,
+		Header: basicFilterHeader(filter, prevHeader),
+	}, nil
+}
+
+// getPrevFilterHeader returns the filter header of the block preceding the block with given height
+// the header chain starts with zero header at the first block of the index, the filters cannot be added
+// to an index containing blocks without filters
+func (d *RocksDB) getPrevFilterHeader(height uint32) ([]byte, error) {
+	if height > 0 {
+		bf, err := d.GetBlockFilter(height - 1)
+		if err != nil {
+			return nil, err
+		}
+		if bf != nil {
+			return bf.Header, nil
+		}
+		hash, err := d.GetBlockHash(height - 1)
+		if err != nil {
+			return nil, err
+		}
+		if hash != "" {
+			return nil, errors.Errorf("Block %v does not have block filter. It is necessary to rebuild index.", height-1)
+		}
+	}
+	return make([]byte, filterHeaderLen), nil
+}
+
+func (d *RocksDB) storeBlockFilter(wb *gorocksdb.WriteBatch, bf *BlockFilter) {
+	buf := make([]byte, 0, filterHeaderLen+len(bf.Filter))
+	buf = append(buf, bf.Header...)
+	buf = append(buf, bf.Filter...)
+	wb.PutCF(d.cfh[cfBlockFilters], packUint(bf.Height), buf)
+}
+
+// GetBlockFilter returns the basic filter of the block with the filter header, nil if the filter is not stored
+func (d *RocksDB) GetBlockFilter(height uint32) (*BlockFilter, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfBlockFilters], packUint(height))
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	if len(buf) <= filterHeaderLen {
+		return nil, errors.New("Invalid block filter data")
+	}
+	return &BlockFilter{
+		Height: height,
+		Header: append([]byte(nil), buf[:filterHeaderLen]...),
+		Filter: append([]byte(nil), buf[filterHeaderLen:]...),
+	}, nil
+}
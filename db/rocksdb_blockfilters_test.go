@@ -0,0 +1,168 @@
+// +build unittest
+
+package db
+
+import (
+	"blockbook/bchain"
+	"blockbook/tests/dbtestdata"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	vlq "github.com/bsm/go-vlq"
+	"github.com/tecbot/gorocksdb"
+)
+
+func Test_sipHash24(t *testing.T) {
+	// reference vector of SipHash-2-4, key 00..0f, message 00..0e
+	key := make([]byte, 16)
+	m := make([]byte, 15)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	for i := range m {
+		m[i] = byte(i)
+	}
+	got := sipHash24(binary.LittleEndian.Uint64(key), binary.LittleEndian.Uint64(key[8:]), m)
+	if got != 0xa129ca6149be45e5 {
+		t.Errorf("sipHash24() = %x", got)
+	}
+}
+
+func reversedHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b
+}
+
+func Test_buildBasicFilter(t *testing.T) {
+	// BIP158 test vector, testnet genesis block
+	blockHash := reversedHex(t, "000000000933ea01ad0ee984209779baaec3ced90fa3f408719526f8d77f4943")
+	script, _ := hex.DecodeString("4104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac")
+	filter := buildBasicFilter(blockHash, [][]byte{script, script})
+	if h := hex.EncodeToString(filter); h != "019dfca8" {
+		t.Errorf("buildBasicFilter() = %v, want 019dfca8", h)
+	}
+	header := reversedHex(t, hex.EncodeToString(basicFilterHeader(filter, make([]byte, filterHeaderLen))))
+	if h := hex.EncodeToString(header); h != "21584579b7eb08997773e5aeff3a7f932700042d0ed2a6129012b7d7ae81b750" {
+		t.Errorf("basicFilterHeader() = %v", h)
+	}
+	p2pkh, _ := hex.DecodeString("76a91427a1f12771de5cc3b73941664b2537c15316be4388ac")
+	if h := hex.EncodeToString(buildBasicFilter(blockHash, [][]byte{script, p2pkh, {1, 2, 3}})); h != "030db272256ef03e2c" {
+		t.Errorf("buildBasicFilter() = %v, want 030db272256ef03e2c", h)
+	}
+	if h := hex.EncodeToString(buildBasicFilter(blockHash, nil)); h != "00" {
+		t.Errorf("buildBasicFilter() = %v, want 00", h)
+	}
+}
+
+func Test_processBlockFilter_spentP2PK(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{BitcoinParser: bitcoinTestnetParser()})
+	defer closeAndDestroyRocksDB(t, d)
+	p2pk := hexToBytes("4104678afdb0fe5548271967f1a67130b7105cd6a828e03909a67962e0ea1f61deb649f6bc3f4cef38c4f35504e51ec112de5c384df7ba0b8d578a4c702b6bf11d5fac")
+	p2pkh1 := hexToBytes(dbtestdata.AddressToPubKeyHex(dbtestdata.Addr1, d.chainParser))
+	p2pkh2 := hexToBytes(dbtestdata.AddressToPubKeyHex(dbtestdata.Addr2, d.chainParser))
+	txid1 := "1111111111111111111111111111111111111111111111111111111111111111"
+	txid2 := "2222222222222222222222222222222222222222222222222222222222222222"
+	block1 := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{Height: 100, Hash: "00000000000000000000000000000000000000000000000000000000000000a1"},
+		Txs: []bchain.Tx{{
+			Txid: txid1,
+			Vout: []bchain.Vout{
+				{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: hex.EncodeToString(p2pk)}, ValueSat: *big.NewInt(1000)},
+				{N: 1, ScriptPubKey: bchain.ScriptPubKey{Hex: hex.EncodeToString(p2pkh1)}, ValueSat: *big.NewInt(2000)},
+			},
+		}},
+	}
+	block2 := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{Height: 101, Hash: "00000000000000000000000000000000000000000000000000000000000000a2"},
+		Txs: []bchain.Tx{{
+			Txid: txid2,
+			Vin:  []bchain.Vin{{Txid: txid1, Vout: 0}, {Txid: txid1, Vout: 1}},
+			Vout: []bchain.Vout{
+				{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: hex.EncodeToString(p2pkh2)}, ValueSat: *big.NewInt(2500)},
+				{N: 1, ScriptPubKey: bchain.ScriptPubKey{Hex: hex.EncodeToString(p2pk)}, ValueSat: *big.NewInt(400)},
+			},
+		}},
+	}
+	for _, b := range []*bchain.Block{block1, block2} {
+		if err := d.ConnectBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bf1, err := d.GetBlockFilter(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bf2, err := d.GetBlockFilter(101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bf1 == nil || bf2 == nil {
+		t.Fatal("GetBlockFilter() returned nil")
+	}
+	// the spent P2PK output is in the filter as P2PK script, not as the P2PKH address descriptor from txAddresses
+	want := buildBasicFilter(reversedHex(t, block2.Hash), [][]byte{p2pkh2, p2pk, p2pk, p2pkh1})
+	if !bytes.Equal(bf2.Filter, want) {
+		t.Errorf("filter = %x, want %x", bf2.Filter, want)
+	}
+	if !bytes.Equal(bf1.Header, basicFilterHeader(bf1.Filter, make([]byte, filterHeaderLen))) || !bytes.Equal(bf2.Header, basicFilterHeader(bf2.Filter, bf1.Header)) {
+		t.Error("filter headers do not form a chain")
+	}
+	btxID1, _ := d.chainParser.PackTxid(txid1)
+	btxID2, _ := d.chainParser.PackTxid(txid2)
+	varBuf := make([]byte, vlq.MaxLen64)
+	if err := checkColumn(d, cfOutputScripts, []keyPair{
+		{hex.EncodeToString(packOutpointKey(btxID1, 0, varBuf)), hex.EncodeToString(p2pk), nil},
+		{hex.EncodeToString(packOutpointKey(btxID2, 1, varBuf)), hex.EncodeToString(p2pk), nil},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// the Electrum script hash of the P2PK script is mapped to the P2PKH address descriptor
+	ad, err := d.chainParser.GetAddrDescFromVout(&block1.Txs[0].Vout[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := d.GetAddrDescForScriptHash(ScriptHash(p2pk)); err != nil || !bytes.Equal(got, ad) {
+		t.Errorf("GetAddrDescForScriptHash() = %x, %v, want %x", got, err, ad)
+	}
+
+	if err := d.DisconnectBlockRangeBitcoinType(101, 101, OrphanedByReorg); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkColumn(d, cfOutputScripts, []keyPair{
+		{hex.EncodeToString(packOutpointKey(btxID1, 0, varBuf)), hex.EncodeToString(p2pk), nil},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if bf, err := d.GetBlockFilter(101); err != nil || bf != nil {
+		t.Errorf("GetBlockFilter(101) = %+v, %v, want nil", bf, err)
+	}
+}
+
+func Test_getPrevFilterHeader_missingFilter(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{BitcoinParser: bitcoinTestnetParser()})
+	defer closeAndDestroyRocksDB(t, d)
+	// block indexed without filter, for example by an older version
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	if err := d.writeHeight(wb, 100, &BlockInfo{Hash: "00000000000000000000000000000000000000000000000000000000000000a1", Height: 100}, opInsert); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.db.Write(d.wo, wb); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.getPrevFilterHeader(101); err == nil {
+		t.Error("getPrevFilterHeader() expected error for block without filter")
+	}
+	if h, err := d.getPrevFilterHeader(100); err != nil || !bytes.Equal(h, make([]byte, filterHeaderLen)) {
+		t.Errorf("getPrevFilterHeader(100) = %x, %v, want zero header", h, err)
+	}
+}
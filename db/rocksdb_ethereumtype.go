@@ -428,7 +428,8 @@ func (d *RocksDB) disconnectBlockTxsEthereumType(wb *gorocksdb.WriteBatch, heigh
 
 // DisconnectBlockRangeEthereumType removes all data belonging to blocks in range lower-higher
 // it is able to disconnect only blocks for which there are data in the blockTxs column
-func (d *RocksDB) DisconnectBlockRangeEthereumType(lower uint32, higher uint32) error {
+// the disconnected blocks are stored to the orphanedBlocks column with the cause of the disconnection
+func (d *RocksDB) DisconnectBlockRangeEthereumType(lower uint32, higher uint32, cause OrphanedCause) error {
 	blocks := make([][]ethBlockTx, higher-lower+1)
 	for height := lower; height <= higher; height++ {
 		blockTxs, err := d.getBlockTxsEthereumType(height)
@@ -445,13 +446,28 @@ func (d *RocksDB) DisconnectBlockRangeEthereumType(lower uint32, higher uint32)
 	defer wb.Destroy()
 	contracts := make(map[string]*AddrContracts)
 	for height := higher; height >= lower; height-- {
-		if err := d.disconnectBlockTxsEthereumType(wb, height, blocks[height-lower], contracts); err != nil {
+		blockTxs := blocks[height-lower]
+		if err := d.disconnectBlockTxsEthereumType(wb, height, blockTxs, contracts); err != nil {
+			return err
+		}
+		btxIDs := make([][]byte, len(blockTxs))
+		addrDescs := make(orphanedBlockAddrDescs)
+		for i := range blockTxs {
+			btxIDs[i] = blockTxs[i].btxID
+			addrDescs.add(blockTxs[i].from)
+			addrDescs.add(blockTxs[i].to)
+			for _, c := range blockTxs[i].contracts {
+				addrDescs.add(c.addr)
+			}
+		}
+		if err := d.storeOrphanedBlock(wb, height, cause, btxIDs, addrDescs); err != nil {
 			return err
 		}
 		key := packUint(height)
 		wb.DeleteCF(d.cfh[cfBlockTxs], key)
 		wb.DeleteCF(d.cfh[cfHeight], key)
 	}
+	d.pruneOrphanedBlocks(wb, int(higher-lower+1))
 	d.storeAddressContracts(wb, contracts)
 	err := d.db.Write(d.wo, wb)
 	if err == nil {
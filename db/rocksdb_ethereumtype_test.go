@@ -256,7 +256,7 @@ func TestRocksDB_Index_EthereumType(t *testing.T) {
 		}
 	}
 	// try to disconnect both blocks, however only the last one is kept, it is not possible
-	err = d.DisconnectBlockRangeEthereumType(4321000, 4321001)
+	err = d.DisconnectBlockRangeEthereumType(4321000, 4321001, OrphanedByReorg)
 	if err == nil || err.Error() != "Cannot disconnect blocks with height 4321000 and lower. It is necessary to rebuild index." {
 		t.Fatal(err)
 	}
@@ -264,7 +264,7 @@ func TestRocksDB_Index_EthereumType(t *testing.T) {
 
 	// disconnect the 2nd block, verify that the db contains only data from the 1st block with restored unspentTxs
 	// and that the cached tx is removed
-	err = d.DisconnectBlockRangeEthereumType(4321001, 4321001)
+	err = d.DisconnectBlockRangeEthereumType(4321001, 4321001, OrphanedByReorg)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,246 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"bytes"
+
+	vlq "github.com/bsm/go-vlq"
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcutil"
+	"github.com/tecbot/gorocksdb"
+)
+
+// NameRecord is a name operation stored in the nameHistory column
+// the name_new operation is stored under the hash of the name and copied to the name history by the name_firstupdate operation
+type NameRecord struct {
+	Height   uint32
+	BtxID    []byte
+	Vout     int32
+	Op       bchain.NameOp
+	Value    []byte
+	AddrDesc bchain.AddressDescriptor
+}
+
+// nameHistoryMap maps packed name key to the name operations done in the block
+type nameHistoryMap map[string][]NameRecord
+
+// txNamesMap maps packed txid to the name keys written by the transaction
+type txNamesMap map[string][][]byte
+
+func packNameKeyPrefix(name []byte) []byte {
+	varBuf := make([]byte, vlq.MaxLen64)
+	l := packVaruint(uint(len(name)), varBuf)
+	buf := make([]byte, 0, l+len(name)+packedHeightBytes)
+	buf = append(buf, varBuf[:l]...)
+	return append(buf, name...)
+}
+
+// packNameKey packs the name with its length so that the keys of one name do not share prefix with other names
+func packNameKey(name []byte, height uint32) []byte {
+	return packAddressKey(packNameKeyPrefix(name), height)
+}
+
+// processNames collects name operations of the block
+func (d *RocksDB) processNames(block *bchain.Block, history nameHistoryMap, txNames txNamesMap) error {
+	if d.chainParser.GetNameExpirationDepth() == 0 {
+		return nil
+	}
+	for txi := range block.Txs {
+		tx := &block.Txs[txi]
+		var btxID []byte
+		for i := range tx.Vout {
+			no, err := d.chainParser.GetNameOperationFromVout(&tx.Vout[i])
+			if err != nil {
+				glog.Warningf("rocksdb: name operation: height %d, tx %v, vout %v, error %v", block.Height, tx.Txid, i, err)
+				continue
+			}
+			if no == nil {
+				continue
+			}
+			if btxID == nil {
+				if btxID, err = d.chainParser.PackTxid(tx.Txid); err != nil {
+					return err
+				}
+			}
+			addrDesc, err := d.chainParser.GetAddrDescFromVout(&tx.Vout[i])
+			if err != nil {
+				glog.Warningf("rocksdb: name operation: height %d, tx %v, vout %v, error %v", block.Height, tx.Txid, i, err)
+			}
+			r := NameRecord{
+				Height:   block.Height,
+				BtxID:    btxID,
+				Vout:     int32(i),
+				Op:       no.Op,
+				Value:    no.Value,
+				AddrDesc: addrDesc,
+			}
+			name := no.Name
+			if no.Op == bchain.NameNew {
+				name = no.Hash
+			}
+			key := packNameKey(name, block.Height)
+			history[string(key)] = append(history[string(key)], r)
+			txNames[string(btxID)] = append(txNames[string(btxID)], key)
+			if no.Op == bchain.NameFirstUpdate {
+				// the name_new operation is identified by the hash of the rand value followed by the name
+				hash := btcutil.Hash160(append(append([]byte(nil), no.Rand...), no.Name...))
+				nr, err := d.getNameNew(hash, block.Height, history)
+				if err != nil {
+					return err
+				}
+				if nr != nil {
+					key := packNameKey(name, nr.Height)
+					history[string(key)] = append(history[string(key)], *nr)
+					txNames[string(btxID)] = append(txNames[string(btxID)], key)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// getNameNew returns the newest name_new operation with given hash, first from the not yet stored operations, then from db
+func (d *RocksDB) getNameNew(hash []byte, height uint32, history nameHistoryMap) (*NameRecord, error) {
+	prefix := packNameKeyPrefix(hash)
+	var nr *NameRecord
+	for key, rs := range history {
+		if len(key) != len(prefix)+packedHeightBytes || !bytes.HasPrefix([]byte(key), prefix) {
+			continue
+		}
+		for i := range rs {
+			if rs[i].Op == bchain.NameNew && rs[i].Height < height && (nr == nil || rs[i].Height > nr.Height) {
+				nr = &rs[i]
+			}
+		}
+	}
+	if nr != nil {
+		return nr, nil
+	}
+	rs, err := d.GetNameHistory(hash)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rs {
+		if rs[i].Op == bchain.NameNew && rs[i].Height < height {
+			return &rs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (d *RocksDB) storeNames(wb *gorocksdb.WriteBatch, history nameHistoryMap, txNames txNamesMap) error {
+	pl := d.chainParser.PackedTxidLen()
+	buf := make([]byte, 0, 256)
+	varBuf := make([]byte, vlq.MaxLen64)
+	for key, rs := range history {
+		buf = packNameRecords(rs, pl, buf, varBuf)
+		wb.PutCF(d.cfh[cfNameHistory], []byte(key), buf)
+	}
+	for btxID, keys := range txNames {
+		buf = buf[:0]
+		for _, key := range keys {
+			buf = appendPackedBytes(key, buf, varBuf)
+		}
+		wb.PutCF(d.cfh[cfTxNames], []byte(btxID), buf)
+	}
+	return nil
+}
+
+// GetNameHistory returns the operations with the name ordered from the newest to the oldest
+func (d *RocksDB) GetNameHistory(name []byte) ([]NameRecord, error) {
+	pl := d.chainParser.PackedTxidLen()
+	prefix := packNameKeyPrefix(name)
+	var rs []NameRecord
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfNameHistory])
+	defer it.Close()
+	for it.Seek(prefix); it.Valid(); it.Next() {
+		key := it.Key().Data()
+		if len(key) != len(prefix)+packedHeightBytes || !bytes.HasPrefix(key, prefix) {
+			break
+		}
+		_, height, err := unpackAddressKey(key)
+		if err != nil {
+			return nil, err
+		}
+		krs, err := unpackNameRecords(it.Value().Data(), pl)
+		if err != nil {
+			return nil, errors.Annotatef(err, "name %q", name)
+		}
+		// the operations in one block are stored in the order of the transactions
+		for i := len(krs) - 1; i >= 0; i-- {
+			krs[i].Height = height
+			rs = append(rs, krs[i])
+		}
+	}
+	return rs, nil
+}
+
+// disconnectNames removes the name operations done by the transaction
+func (d *RocksDB) disconnectNames(wb *gorocksdb.WriteBatch, btxID []byte) error {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfTxNames], btxID)
+	if err != nil {
+		return err
+	}
+	defer val.Free()
+	buf := val.Data()
+	for len(buf) > 0 {
+		key, l, err := unpackBytes(buf)
+		if err != nil {
+			return err
+		}
+		wb.DeleteCF(d.cfh[cfNameHistory], key)
+		buf = buf[l:]
+	}
+	wb.DeleteCF(d.cfh[cfTxNames], btxID)
+	return nil
+}
+
+func packNameRecords(rs []NameRecord, packedTxidLen int, buf, varBuf []byte) []byte {
+	buf = buf[:0]
+	for i := range rs {
+		r := &rs[i]
+		buf = append(buf, r.BtxID[:packedTxidLen]...)
+		l := packVaruint(uint(r.Vout), varBuf)
+		buf = append(buf, varBuf[:l]...)
+		buf = append(buf, byte(r.Op))
+		buf = appendPackedBytes(r.Value, buf, varBuf)
+		buf = appendPackedBytes(r.AddrDesc, buf, varBuf)
+	}
+	return buf
+}
+
+func unpackNameRecords(buf []byte, packedTxidLen int) ([]NameRecord, error) {
+	var rs []NameRecord
+	for len(buf) > 0 {
+		if len(buf) < packedTxidLen+2 {
+			return nil, errors.New("Invalid name records data")
+		}
+		r := NameRecord{
+			BtxID: append([]byte(nil), buf[:packedTxidLen]...),
+		}
+		buf = buf[packedTxidLen:]
+		vout, l := unpackVaruint(buf)
+		r.Vout = int32(vout)
+		buf = buf[l:]
+		if len(buf) == 0 {
+			return nil, errors.New("Invalid name records data")
+		}
+		r.Op = bchain.NameOp(buf[0])
+		buf = buf[1:]
+		value, l, err := unpackBytes(buf)
+		if err != nil {
+			return nil, err
+		}
+		r.Value = value
+		buf = buf[l:]
+		addrDesc, l, err := unpackBytes(buf)
+		if err != nil {
+			return nil, err
+		}
+		r.AddrDesc = addrDesc
+		buf = buf[l:]
+		rs = append(rs, r)
+	}
+	return rs, nil
+}
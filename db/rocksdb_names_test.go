@@ -0,0 +1,52 @@
+// +build unittest
+
+package db
+
+import (
+	"blockbook/bchain"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	vlq "github.com/bsm/go-vlq"
+)
+
+func Test_packNameKey(t *testing.T) {
+	if h := hex.EncodeToString(packNameKey([]byte("d/test"), 100)); h != "06642f74657374ffffff9b" {
+		t.Errorf("packNameKey() = %v", h)
+	}
+}
+
+func Test_packNameRecords_unpackNameRecords(t *testing.T) {
+	txid := "00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840"
+	btxID, _ := hex.DecodeString(txid)
+	addrDesc, _ := hex.DecodeString("76a91427a1f12771de5cc3b73941664b2537c15316be4388ac")
+	rs := []NameRecord{
+		{
+			BtxID:    btxID,
+			Vout:     1,
+			Op:       bchain.NameUpdate,
+			Value:    []byte("{}"),
+			AddrDesc: addrDesc,
+		},
+		{
+			BtxID: btxID,
+			Op:    bchain.NameNew,
+		},
+	}
+	want := txid + "0103027b7d1976a91427a1f12771de5cc3b73941664b2537c15316be4388ac" + txid + "00010000"
+	b := packNameRecords(rs, len(btxID), make([]byte, 0, 128), make([]byte, vlq.MaxLen64))
+	if h := hex.EncodeToString(b); h != want {
+		t.Errorf("packNameRecords() = %v, want %v", h, want)
+	}
+	got, err := unpackNameRecords(b, len(btxID))
+	if err != nil {
+		t.Fatalf("unpackNameRecords() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, rs) {
+		t.Errorf("unpackNameRecords() = %+v, want %+v", got, rs)
+	}
+	if _, err := unpackNameRecords(b[:len(b)-3], len(btxID)); err == nil {
+		t.Error("unpackNameRecords() of truncated data expected error")
+	}
+}
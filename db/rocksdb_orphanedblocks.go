@@ -0,0 +1,299 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"math"
+	"time"
+
+	vlq "github.com/bsm/go-vlq"
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// MaxOrphanedBlocks is the maximum number of the orphaned blocks kept in the orphanedBlocks column,
+// the blocks with the lowest height are pruned when the column grows over the limit
+const MaxOrphanedBlocks = 1000
+
+// OrphanedCause is the cause of the disconnection of the orphaned block
+type OrphanedCause uint8
+
+const (
+	// OrphanedByReorg marks the blocks disconnected by a fork found during the synchronization
+	OrphanedByReorg OrphanedCause = iota
+	// OrphanedByRollback marks the blocks disconnected by the rollback requested by the operator
+	OrphanedByRollback
+)
+
+// OrphanedBlock is a block disconnected from the chain by a reorg or a rollback, stored in the orphanedBlocks column
+// together with the txids and the address descriptors of its transactions
+// the fate of the transactions is known only after the index is synchronized again following the disconnection
+type OrphanedBlock struct {
+	Hash         string
+	Height       uint32
+	Time         int64
+	Size         uint32
+	Disconnected int64
+	Cause        OrphanedCause
+	Txids        []string
+	AddrDescs    []bchain.AddressDescriptor
+	FateKnown    bool
+	Reconfirmed  uint32
+	InMempool    uint32
+	Dropped      uint32
+}
+
+// OnReorgFunc is used to send notification about the blocks disconnected by a reorg
+type OnReorgFunc func(blocks []*OrphanedBlock)
+
+// orphanedBlockAddrDescs collects the unique address descriptors of the transactions of the disconnected block
+type orphanedBlockAddrDescs map[string]struct{}
+
+func (m orphanedBlockAddrDescs) add(addrDesc bchain.AddressDescriptor) {
+	if len(addrDesc) > 0 {
+		m[string(addrDesc)] = struct{}{}
+	}
+}
+
+func (d *RocksDB) packOrphanedBlockKey(height uint32, hash string) ([]byte, error) {
+	b, err := d.chainParser.PackBlockHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	return append(packUint(height), b...), nil
+}
+
+func (d *RocksDB) packOrphanedBlock(ob *OrphanedBlock, btxIDs [][]byte) []byte {
+	varBuf := make([]byte, vlq.MaxLen64)
+	buf := make([]byte, 0, 32+len(btxIDs)*d.chainParser.PackedTxidLen()+len(ob.AddrDescs)*26)
+	buf = append(buf, packUint(uint32(ob.Time))...)
+	buf = append(buf, packUint(uint32(ob.Disconnected))...)
+	buf = append(buf, byte(ob.Cause))
+	l := packVaruint(uint(ob.Size), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	l = packVaruint(uint(len(btxIDs)), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	for _, btxID := range btxIDs {
+		buf = append(buf, btxID...)
+	}
+	l = packVaruint(uint(len(ob.AddrDescs)), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	for _, ad := range ob.AddrDescs {
+		l = packVaruint(uint(len(ad)), varBuf)
+		buf = append(buf, varBuf[:l]...)
+		buf = append(buf, ad...)
+	}
+	// the fate of the transactions is appended only when it is known
+	if ob.FateKnown {
+		for _, n := range []uint32{ob.Reconfirmed, ob.InMempool, ob.Dropped} {
+			l = packVaruint(uint(n), varBuf)
+			buf = append(buf, varBuf[:l]...)
+		}
+	}
+	return buf
+}
+
+func (d *RocksDB) unpackOrphanedBlock(key, buf []byte) (*OrphanedBlock, error) {
+	if len(key) < 4 || len(buf) < 9 {
+		return nil, errors.New("Invalid orphaned block")
+	}
+	hash, err := d.chainParser.UnpackBlockHash(key[4:])
+	if err != nil {
+		return nil, err
+	}
+	ob := &OrphanedBlock{
+		Hash:         hash,
+		Height:       unpackUint(key),
+		Time:         int64(unpackUint(buf)),
+		Disconnected: int64(unpackUint(buf[4:])),
+		Cause:        OrphanedCause(buf[8]),
+	}
+	buf = buf[9:]
+	size, l := unpackVaruint(buf)
+	ob.Size = uint32(size)
+	buf = buf[l:]
+	txs, l := unpackVaruint(buf)
+	buf = buf[l:]
+	pl := d.chainParser.PackedTxidLen()
+	if len(buf) < int(txs)*pl {
+		return nil, errors.Errorf("Invalid orphaned block %v", hash)
+	}
+	ob.Txids = make([]string, txs)
+	for i := range ob.Txids {
+		if ob.Txids[i], err = d.chainParser.UnpackTxid(buf[:pl]); err != nil {
+			return nil, err
+		}
+		buf = buf[pl:]
+	}
+	ads, l := unpackVaruint(buf)
+	buf = buf[l:]
+	ob.AddrDescs = make([]bchain.AddressDescriptor, 0, ads)
+	for i := uint(0); i < ads; i++ {
+		al, l := unpackVaruint(buf)
+		if len(buf) < l+int(al) {
+			return nil, errors.Errorf("Invalid orphaned block %v", hash)
+		}
+		ob.AddrDescs = append(ob.AddrDescs, append(bchain.AddressDescriptor{}, buf[l:l+int(al)]...))
+		buf = buf[l+int(al):]
+	}
+	if len(buf) > 0 {
+		ob.FateKnown = true
+		for _, n := range []*uint32{&ob.Reconfirmed, &ob.InMempool, &ob.Dropped} {
+			v, l := unpackVaruint(buf)
+			*n = uint32(v)
+			buf = buf[l:]
+		}
+	}
+	return ob, nil
+}
+
+// storeOrphanedBlock stores the block at the height, which is being disconnected, to the orphanedBlocks column
+func (d *RocksDB) storeOrphanedBlock(wb *gorocksdb.WriteBatch, height uint32, cause OrphanedCause, btxIDs [][]byte, addrDescs orphanedBlockAddrDescs) error {
+	bi, err := d.GetBlockInfo(height)
+	if err != nil {
+		return err
+	}
+	if bi == nil {
+		return nil
+	}
+	key, err := d.packOrphanedBlockKey(height, bi.Hash)
+	if err != nil {
+		return err
+	}
+	ob := &OrphanedBlock{
+		Hash:         bi.Hash,
+		Height:       height,
+		Time:         bi.Time,
+		Size:         bi.Size,
+		Disconnected: time.Now().Unix(),
+		Cause:        cause,
+		AddrDescs:    make([]bchain.AddressDescriptor, 0, len(addrDescs)),
+	}
+	for ad := range addrDescs {
+		ob.AddrDescs = append(ob.AddrDescs, bchain.AddressDescriptor(ad))
+	}
+	wb.PutCF(d.cfh[cfOrphanedBlocks], key, d.packOrphanedBlock(ob, btxIDs))
+	return nil
+}
+
+// pruneOrphanedBlocks deletes the orphaned blocks with the lowest height so that at most MaxOrphanedBlocks remain
+// after the added blocks are stored
+func (d *RocksDB) pruneOrphanedBlocks(wb *gorocksdb.WriteBatch, added int) {
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfOrphanedBlocks])
+	defer it.Close()
+	count := added
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		count++
+	}
+	for it.SeekToFirst(); it.Valid() && count > MaxOrphanedBlocks; it.Next() {
+		wb.DeleteCF(d.cfh[cfOrphanedBlocks], append([]byte{}, it.Key().Data()...))
+		count--
+	}
+}
+
+// minedTxFunc returns a function reporting whether the transaction is in the blocks of the current chain from the height lower
+func (d *RocksDB) minedTxFunc(lower uint32) (func(btxID []byte) (bool, error), error) {
+	if d.chainParser.GetChainType() != bchain.ChainEthereumType {
+		return func(btxID []byte) (bool, error) {
+			ta, err := d.getTxAddresses(btxID)
+			return ta != nil, err
+		}, nil
+	}
+	// the ethereum type index does not map txids to blocks, collect the txids of the blocks connected after the disconnection
+	bestHeight, _, err := d.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+	mined := make(map[string]struct{})
+	for height := lower; height <= bestHeight; height++ {
+		blockTxs, err := d.getBlockTxsEthereumType(height)
+		if err != nil {
+			return nil, err
+		}
+		for i := range blockTxs {
+			mined[string(blockTxs[i].btxID)] = struct{}{}
+		}
+	}
+	return func(btxID []byte) (bool, error) {
+		_, found := mined[string(btxID)]
+		return found, nil
+	}, nil
+}
+
+// StoreOrphanedBlocksFate counts the transactions of the orphaned blocks from the height lower with unknown fate,
+// which were mined again in the current chain, are in the mempool or were dropped, and stores the counts to the orphaned blocks
+func (d *RocksDB) StoreOrphanedBlocksFate(lower uint32, inMempool func(txid string) bool) error {
+	obs, err := d.GetOrphanedBlocks(lower, math.MaxUint32)
+	if err != nil {
+		return err
+	}
+	mined, err := d.minedTxFunc(lower)
+	if err != nil {
+		return err
+	}
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	for _, ob := range obs {
+		if ob.FateKnown {
+			continue
+		}
+		btxIDs := make([][]byte, len(ob.Txids))
+		for i, txid := range ob.Txids {
+			if btxIDs[i], err = d.chainParser.PackTxid(txid); err != nil {
+				return err
+			}
+			m, err := mined(btxIDs[i])
+			if err != nil {
+				return errors.Annotatef(err, "orphaned block %v, txid %v", ob.Hash, txid)
+			}
+			switch {
+			case m:
+				ob.Reconfirmed++
+			case inMempool(txid):
+				ob.InMempool++
+			default:
+				ob.Dropped++
+			}
+		}
+		ob.FateKnown = true
+		key, err := d.packOrphanedBlockKey(ob.Height, ob.Hash)
+		if err != nil {
+			return err
+		}
+		wb.PutCF(d.cfh[cfOrphanedBlocks], key, d.packOrphanedBlock(ob, btxIDs))
+	}
+	return d.db.Write(d.wo, wb)
+}
+
+// GetOrphanedBlocks returns the orphaned blocks with the height in the range lower-higher, ordered by height
+func (d *RocksDB) GetOrphanedBlocks(lower uint32, higher uint32) ([]*OrphanedBlock, error) {
+	var obs []*OrphanedBlock
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfOrphanedBlocks])
+	defer it.Close()
+	for it.Seek(packUint(lower)); it.Valid(); it.Next() {
+		key := it.Key().Data()
+		if unpackUint(key) > higher {
+			break
+		}
+		ob, err := d.unpackOrphanedBlock(key, it.Value().Data())
+		if err != nil {
+			return nil, err
+		}
+		obs = append(obs, ob)
+	}
+	return obs, nil
+}
+
+// GetLastOrphanedBlocks returns at most max orphaned blocks with the highest height, the highest first
+func (d *RocksDB) GetLastOrphanedBlocks(max int) ([]*OrphanedBlock, error) {
+	var obs []*OrphanedBlock
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfOrphanedBlocks])
+	defer it.Close()
+	for it.SeekToLast(); it.Valid() && len(obs) < max; it.Prev() {
+		ob, err := d.unpackOrphanedBlock(it.Key().Data(), it.Value().Data())
+		if err != nil {
+			return nil, err
+		}
+		obs = append(obs, ob)
+	}
+	return obs, nil
+}
@@ -0,0 +1,44 @@
+// +build unittest
+
+package db
+
+import (
+	"testing"
+
+	"github.com/tecbot/gorocksdb"
+)
+
+func TestRocksDB_pruneOrphanedBlocks(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	const hash = "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6"
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	for height := uint32(1); height <= MaxOrphanedBlocks; height++ {
+		key, err := d.packOrphanedBlockKey(height, hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ob := &OrphanedBlock{Hash: hash, Height: height, Disconnected: 1553096617, Cause: OrphanedByRollback}
+		wb.PutCF(d.cfh[cfOrphanedBlocks], key, d.packOrphanedBlock(ob, nil))
+	}
+	if err := d.db.Write(d.wo, wb); err != nil {
+		t.Fatal(err)
+	}
+	wb.Clear()
+	// two blocks are being added, the two lowest are pruned
+	d.pruneOrphanedBlocks(wb, 2)
+	if err := d.db.Write(d.wo, wb); err != nil {
+		t.Fatal(err)
+	}
+	obs, err := d.GetOrphanedBlocks(0, MaxOrphanedBlocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(obs) != MaxOrphanedBlocks-2 || obs[0].Height != 3 || obs[0].Cause != OrphanedByRollback || obs[0].FateKnown {
+		t.Errorf("GetOrphanedBlocks() returned %d blocks, first %+v", len(obs), obs[0])
+	}
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"bytes"
+	"encoding/hex"
+
+	vlq "github.com/bsm/go-vlq"
+	"github.com/tecbot/gorocksdb"
+)
+
+type outputScript struct {
+	script   []byte
+	addrDesc bchain.AddressDescriptor
+}
+
+// outputScriptsMap maps packed outpoint to the output script which is not stored as address descriptor in txAddresses
+type outputScriptsMap map[string]*outputScript
+
+// processOutputScripts collects the scripts of the outputs of the transactions in the block which cannot be restored
+// from the address descriptors (for example P2PK scripts stored as P2PKH or the scripts without address descriptor)
+func (d *RocksDB) processOutputScripts(block *bchain.Block, outputScripts outputScriptsMap) error {
+	varBuf := make([]byte, vlq.MaxLen64)
+	for txi := range block.Txs {
+		tx := &block.Txs[txi]
+		var btxID []byte
+		for i := range tx.Vout {
+			output := &tx.Vout[i]
+			script, err := hex.DecodeString(output.ScriptPubKey.Hex)
+			if err != nil || len(script) == 0 {
+				continue
+			}
+			addrDesc, err := d.chainParser.GetAddrDescFromVout(output)
+			if err != nil || len(addrDesc) > maxAddrDescLen {
+				// the output is stored in txAddresses without address descriptor
+				addrDesc = nil
+			} else if bytes.Equal(addrDesc, script) {
+				continue
+			}
+			if btxID == nil {
+				if btxID, err = d.chainParser.PackTxid(tx.Txid); err != nil {
+					return err
+				}
+			}
+			outputScripts[string(packOutpointKey(btxID, uint32(i), varBuf))] = &outputScript{script: script, addrDesc: addrDesc}
+		}
+	}
+	return nil
+}
+
+// storeOutputScripts stores the scripts and maps their script hashes to the address descriptors, so that the Electrum protocol
+// finds for example the P2PK outputs by the hash of the P2PK script
+// the script hashes are not removed on disconnect, other outputs can have the same script; the entries without balance
+// of the address descriptor return empty history
+func (d *RocksDB) storeOutputScripts(wb *gorocksdb.WriteBatch, outputScripts outputScriptsMap) {
+	for key, o := range outputScripts {
+		wb.PutCF(d.cfh[cfOutputScripts], []byte(key), o.script)
+		if len(o.addrDesc) > 0 && d.chainParser.IsAddrDescIndexable(o.addrDesc) {
+			wb.PutCF(d.cfh[cfScriptHashes], ScriptHash(o.script), o.addrDesc)
+		}
+	}
+}
+
+// getSpentScript returns the script of the output spent by an input, addrDesc is the address descriptor of the output from txAddresses
+func (d *RocksDB) getSpentScript(btxID []byte, vout uint32, addrDesc bchain.AddressDescriptor, outputScripts outputScriptsMap, varBuf []byte) ([]byte, error) {
+	key := packOutpointKey(btxID, vout, varBuf)
+	if o, found := outputScripts[string(key)]; found {
+		return o.script, nil
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfOutputScripts], key)
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	if len(val.Data()) > 0 {
+		return append([]byte(nil), val.Data()...), nil
+	}
+	return addrDesc, nil
+}
+
+// disconnectOutputScripts removes the scripts of the outputs of a disconnected transaction
+func (d *RocksDB) disconnectOutputScripts(wb *gorocksdb.WriteBatch, btxID []byte, outputs int) {
+	varBuf := make([]byte, vlq.MaxLen64)
+	for i := 0; i < outputs; i++ {
+		wb.DeleteCF(d.cfh[cfOutputScripts], packOutpointKey(btxID, uint32(i), varBuf))
+	}
+}
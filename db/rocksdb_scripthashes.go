@@ -0,0 +1,42 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"crypto/sha256"
+
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// ScriptHash returns sha256 hash of the output script, the script hash used by the Electrum protocol (in the byte order before reversal)
+func ScriptHash(script []byte) []byte {
+	h := sha256.Sum256(script)
+	return h[:]
+}
+
+// storeScriptHash stores the script hash of the address descriptor, which is the output script for the most of the outputs,
+// the other scripts are stored by storeOutputScripts
+func (d *RocksDB) storeScriptHash(wb *gorocksdb.WriteBatch, addrDesc bchain.AddressDescriptor) {
+	wb.PutCF(d.cfh[cfScriptHashes], ScriptHash(addrDesc), addrDesc)
+}
+
+func (d *RocksDB) deleteScriptHash(wb *gorocksdb.WriteBatch, addrDesc bchain.AddressDescriptor) {
+	wb.DeleteCF(d.cfh[cfScriptHashes], ScriptHash(addrDesc))
+}
+
+// GetAddrDescForScriptHash returns address descriptor with given script hash or nil if the script hash is not indexed
+func (d *RocksDB) GetAddrDescForScriptHash(scriptHash []byte) (bchain.AddressDescriptor, error) {
+	if len(scriptHash) != sha256.Size {
+		return nil, errors.New("Invalid script hash")
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfScriptHashes], scriptHash)
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	return append(bchain.AddressDescriptor(nil), buf...), nil
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"bytes"
+
+	vlq "github.com/bsm/go-vlq"
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// SpentBy identifies the input spending an output
+type SpentBy struct {
+	Txid   string
+	Vin    int32
+	Height uint32
+}
+
+// spentByMap maps packed outpoint to packed spending input
+type spentByMap map[string][]byte
+
+func packOutpointKey(btxID []byte, vout uint32, varBuf []byte) []byte {
+	l := packVaruint(uint(vout), varBuf)
+	key := make([]byte, 0, len(btxID)+l)
+	key = append(key, btxID...)
+	return append(key, varBuf[:l]...)
+}
+
+// processSpentBy collects the outputs spent by the inputs of the transactions in the block
+func (d *RocksDB) processSpentBy(block *bchain.Block, spentBy spentByMap) error {
+	varBuf := make([]byte, vlq.MaxLen64)
+	for txi := range block.Txs {
+		tx := &block.Txs[txi]
+		var spendingBtxID []byte
+		for i := range tx.Vin {
+			input := &tx.Vin[i]
+			btxID, err := d.chainParser.PackTxid(input.Txid)
+			if err != nil {
+				// do not process inputs without input txid
+				if err == bchain.ErrTxidMissing {
+					continue
+				}
+				return err
+			}
+			if spendingBtxID == nil {
+				if spendingBtxID, err = d.chainParser.PackTxid(tx.Txid); err != nil {
+					return err
+				}
+			}
+			spentBy[string(packOutpointKey(btxID, input.Vout, varBuf))] = packSpentBy(spendingBtxID, int32(i), block.Height, varBuf)
+		}
+	}
+	return nil
+}
+
+func (d *RocksDB) storeSpentBy(wb *gorocksdb.WriteBatch, spentBy spentByMap) error {
+	for key, val := range spentBy {
+		wb.PutCF(d.cfh[cfSpentBy], []byte(key), val)
+	}
+	return nil
+}
+
+// GetSpentBy returns the input spending given output or nil if the output is not spent or the spending is not indexed
+func (d *RocksDB) GetSpentBy(txid string, vout uint32) (*SpentBy, error) {
+	btxID, err := d.chainParser.PackTxid(txid)
+	if err != nil {
+		return nil, err
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfSpentBy], packOutpointKey(btxID, vout, make([]byte, vlq.MaxLen64)))
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	return d.unpackSpentBy(buf)
+}
+
+// disconnectSpentBy removes the spending of the outputs spent by the inputs of a disconnected transaction
+func (d *RocksDB) disconnectSpentBy(wb *gorocksdb.WriteBatch, inputs []outpoint) {
+	varBuf := make([]byte, vlq.MaxLen64)
+	zeroTx := make([]byte, d.chainParser.PackedTxidLen())
+	for i := range inputs {
+		if bytes.Equal(inputs[i].btxID, zeroTx) {
+			continue
+		}
+		wb.DeleteCF(d.cfh[cfSpentBy], packOutpointKey(inputs[i].btxID, uint32(inputs[i].index), varBuf))
+	}
+}
+
+func packSpentBy(btxID []byte, vin int32, height uint32, varBuf []byte) []byte {
+	buf := make([]byte, 0, len(btxID)+2*vlq.MaxLen32)
+	buf = append(buf, btxID...)
+	l := packVaruint(uint(vin), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	l = packVaruint(uint(height), varBuf)
+	return append(buf, varBuf[:l]...)
+}
+
+func (d *RocksDB) unpackSpentBy(buf []byte) (*SpentBy, error) {
+	pl := d.chainParser.PackedTxidLen()
+	if len(buf) < pl+2 {
+		return nil, errors.New("Invalid spentBy data")
+	}
+	txid, err := d.chainParser.UnpackTxid(buf[:pl])
+	if err != nil {
+		return nil, err
+	}
+	vin, l := unpackVaruint(buf[pl:])
+	height, _ := unpackVaruint(buf[pl+l:])
+	return &SpentBy{
+		Txid:   txid,
+		Vin:    int32(vin),
+		Height: uint32(height),
+	}, nil
+}
@@ -203,7 +203,7 @@ func verifyAfterBitcoinTypeBlock1(t *testing.T, d *RocksDB, afterDisconnect bool
 	if err := checkColumn(d, cfTxAddresses, []keyPair{
 		{
 			dbtestdata.TxidB1T1,
-			varuintToHex(225493) +
+			varuintToHex(225493) + varuintToHex(0) +
 				"00" +
 				"02" +
 				addressToPubKeyHexWithLength(dbtestdata.Addr1, t, d) + bigintToHex(dbtestdata.SatB1T1A1) +
@@ -212,7 +212,7 @@ func verifyAfterBitcoinTypeBlock1(t *testing.T, d *RocksDB, afterDisconnect bool
 		},
 		{
 			dbtestdata.TxidB1T2,
-			varuintToHex(225493) +
+			varuintToHex(225493) + varuintToHex(1) +
 				"00" +
 				"03" +
 				addressToPubKeyHexWithLength(dbtestdata.Addr3, t, d) + bigintToHex(dbtestdata.SatB1T2A3) +
@@ -280,6 +280,28 @@ func verifyAfterBitcoinTypeBlock1(t *testing.T, d *RocksDB, afterDisconnect bool
 			t.Fatal(err)
 		}
 	}
+	if err := checkColumn(d, cfSpentBy, []keyPair{}); err != nil {
+		{
+			t.Fatal(err)
+		}
+	}
+	if err := checkColumn(d, cfScriptHashes, []keyPair{
+		scriptHashKeyPair(dbtestdata.Addr1, d.chainParser),
+		scriptHashKeyPair(dbtestdata.Addr2, d.chainParser),
+		scriptHashKeyPair(dbtestdata.Addr3, d.chainParser),
+		scriptHashKeyPair(dbtestdata.Addr4, d.chainParser),
+		scriptHashKeyPair(dbtestdata.Addr5, d.chainParser),
+	}); err != nil {
+		{
+			t.Fatal(err)
+		}
+	}
+}
+
+func scriptHashKeyPair(addr string, parser bchain.BlockChainParser) keyPair {
+	ad := dbtestdata.AddressToPubKeyHex(addr, parser)
+	b, _ := hex.DecodeString(ad)
+	return keyPair{hex.EncodeToString(ScriptHash(b)), ad, nil}
 }
 
 func verifyAfterBitcoinTypeBlock2(t *testing.T, d *RocksDB) {
@@ -322,7 +344,7 @@ func verifyAfterBitcoinTypeBlock2(t *testing.T, d *RocksDB) {
 	if err := checkColumn(d, cfTxAddresses, []keyPair{
 		{
 			dbtestdata.TxidB1T1,
-			varuintToHex(225493) +
+			varuintToHex(225493) + varuintToHex(0) +
 				"00" +
 				"02" +
 				addressToPubKeyHexWithLength(dbtestdata.Addr1, t, d) + bigintToHex(dbtestdata.SatB1T1A1) +
@@ -331,7 +353,7 @@ func verifyAfterBitcoinTypeBlock2(t *testing.T, d *RocksDB) {
 		},
 		{
 			dbtestdata.TxidB1T2,
-			varuintToHex(225493) +
+			varuintToHex(225493) + varuintToHex(1) +
 				"00" +
 				"03" +
 				spentAddressToPubKeyHexWithLength(dbtestdata.Addr3, t, d) + bigintToHex(dbtestdata.SatB1T2A3) +
@@ -341,7 +363,7 @@ func verifyAfterBitcoinTypeBlock2(t *testing.T, d *RocksDB) {
 		},
 		{
 			dbtestdata.TxidB2T1,
-			varuintToHex(225494) +
+			varuintToHex(225494) + varuintToHex(0) +
 				"02" +
 				inputAddressToPubKeyHexWithLength(dbtestdata.Addr3, t, d) + bigintToHex(dbtestdata.SatB1T2A3) +
 				inputAddressToPubKeyHexWithLength(dbtestdata.Addr2, t, d) + bigintToHex(dbtestdata.SatB1T1A2) +
@@ -353,7 +375,7 @@ func verifyAfterBitcoinTypeBlock2(t *testing.T, d *RocksDB) {
 		},
 		{
 			dbtestdata.TxidB2T2,
-			varuintToHex(225494) +
+			varuintToHex(225494) + varuintToHex(1) +
 				"02" +
 				inputAddressToPubKeyHexWithLength(dbtestdata.Addr6, t, d) + bigintToHex(dbtestdata.SatB2T1A6) +
 				inputAddressToPubKeyHexWithLength(dbtestdata.Addr4, t, d) + bigintToHex(dbtestdata.SatB1T2A4) +
@@ -364,7 +386,7 @@ func verifyAfterBitcoinTypeBlock2(t *testing.T, d *RocksDB) {
 		},
 		{
 			dbtestdata.TxidB2T3,
-			varuintToHex(225494) +
+			varuintToHex(225494) + varuintToHex(2) +
 				"01" +
 				inputAddressToPubKeyHexWithLength(dbtestdata.Addr5, t, d) + bigintToHex(dbtestdata.SatB1T2A5) +
 				"01" +
@@ -373,7 +395,7 @@ func verifyAfterBitcoinTypeBlock2(t *testing.T, d *RocksDB) {
 		},
 		{
 			dbtestdata.TxidB2T4,
-			varuintToHex(225494) +
+			varuintToHex(225494) + varuintToHex(3) +
 				"01" + inputAddressToPubKeyHexWithLength("", t, d) + bigintToHex(dbtestdata.SatZero) +
 				"02" +
 				addressToPubKeyHexWithLength(dbtestdata.AddrA, t, d) + bigintToHex(dbtestdata.SatB2T4AA) +
@@ -461,6 +483,27 @@ func verifyAfterBitcoinTypeBlock2(t *testing.T, d *RocksDB) {
 			t.Fatal(err)
 		}
 	}
+	if err := checkColumn(d, cfSpentBy, []keyPair{
+		{dbtestdata.TxidB1T1 + varuintToHex(1), dbtestdata.TxidB2T1 + varuintToHex(1) + varuintToHex(225494), nil},
+		{dbtestdata.TxidB1T2 + varuintToHex(0), dbtestdata.TxidB2T1 + varuintToHex(0) + varuintToHex(225494), nil},
+		{dbtestdata.TxidB1T2 + varuintToHex(1), dbtestdata.TxidB2T2 + varuintToHex(1) + varuintToHex(225494), nil},
+		{dbtestdata.TxidB1T2 + varuintToHex(2), dbtestdata.TxidB2T3 + varuintToHex(0) + varuintToHex(225494), nil},
+		{dbtestdata.TxidB2T1 + varuintToHex(0), dbtestdata.TxidB2T2 + varuintToHex(0) + varuintToHex(225494), nil},
+	}); err != nil {
+		{
+			t.Fatal(err)
+		}
+	}
+	scriptHashesKp := make([]keyPair, 0, 10)
+	for _, a := range []string{dbtestdata.Addr1, dbtestdata.Addr2, dbtestdata.Addr3, dbtestdata.Addr4, dbtestdata.Addr5,
+		dbtestdata.Addr6, dbtestdata.Addr7, dbtestdata.Addr8, dbtestdata.Addr9, dbtestdata.AddrA} {
+		scriptHashesKp = append(scriptHashesKp, scriptHashKeyPair(a, d.chainParser))
+	}
+	if err := checkColumn(d, cfScriptHashes, scriptHashesKp); err != nil {
+		{
+			t.Fatal(err)
+		}
+	}
 }
 
 type txidIndex struct {
@@ -613,6 +656,33 @@ func TestRocksDB_Index_BitcoinType(t *testing.T) {
 		t.Errorf("GetBlockInfo() = %+v, want %+v", info, iw)
 	}
 
+	// GetAddrDescBalances must return the same balances as GetAddrDescBalance
+	ads := make([]bchain.AddressDescriptor, 0, 3)
+	for _, a := range []string{
+		dbtestdata.AddressToPubKeyHex(dbtestdata.Addr1, d.chainParser),
+		"76a914000000000000000000000000000000000000000088ac",
+		dbtestdata.AddressToPubKeyHex(dbtestdata.Addr6, d.chainParser),
+	} {
+		ad, _ := hex.DecodeString(a)
+		ads = append(ads, ad)
+	}
+	bas, err := d.GetAddrDescBalances(ads, AddressBalanceDetailUTXO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, ad := range ads {
+		ba, err := d.GetAddrDescBalance(ad, AddressBalanceDetailUTXO)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(bas[i], ba) {
+			t.Errorf("GetAddrDescBalances()[%d] = %+v, want %+v", i, bas[i], ba)
+		}
+	}
+	if bas[1] != nil {
+		t.Errorf("GetAddrDescBalances()[1] = %+v, want nil", bas[1])
+	}
+
 	// Test tx caching functionality, leave one tx in db to test cleanup in DisconnectBlock
 	testTxCache(t, d, block1, &block1.Txs[0])
 	testTxCache(t, d, block2, &block2.Txs[0])
@@ -630,7 +700,7 @@ func TestRocksDB_Index_BitcoinType(t *testing.T) {
 	}
 
 	// try to disconnect both blocks, however only the last one is kept, it is not possible
-	err = d.DisconnectBlockRangeBitcoinType(225493, 225494)
+	err = d.DisconnectBlockRangeBitcoinType(225493, 225494, OrphanedByReorg)
 	if err == nil || err.Error() != "Cannot disconnect blocks with height 225493 and lower. It is necessary to rebuild index." {
 		t.Fatal(err)
 	}
@@ -638,7 +708,7 @@ func TestRocksDB_Index_BitcoinType(t *testing.T) {
 
 	// disconnect the 2nd block, verify that the db contains only data from the 1st block with restored unspentTxs
 	// and that the cached tx is removed
-	err = d.DisconnectBlockRangeBitcoinType(225494, 225494)
+	err = d.DisconnectBlockRangeBitcoinType(225494, 225494, OrphanedByReorg)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -648,6 +718,40 @@ func TestRocksDB_Index_BitcoinType(t *testing.T) {
 			t.Fatal(err)
 		}
 	}
+	// the disconnected block is stored in the orphanedBlocks column
+	obs, err := d.GetOrphanedBlocks(225493, 225494)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(obs) != 1 || obs[0].Hash != "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6" || obs[0].Height != 225494 || obs[0].Disconnected == 0 || obs[0].Cause != OrphanedByReorg {
+		t.Fatalf("GetOrphanedBlocks() = %+v", obs)
+	}
+	if want := []string{dbtestdata.TxidB2T1, dbtestdata.TxidB2T2, dbtestdata.TxidB2T3, dbtestdata.TxidB2T4}; !reflect.DeepEqual(obs[0].Txids, want) {
+		t.Errorf("GetOrphanedBlocks() txids = %v, want %v", obs[0].Txids, want)
+	}
+	addr6 := dbtestdata.AddressToPubKeyHex(dbtestdata.Addr6, d.chainParser)
+	found := false
+	for _, ad := range obs[0].AddrDescs {
+		if hex.EncodeToString(ad) == addr6 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("GetOrphanedBlocks() addrDescs %v do not contain %v", obs[0].AddrDescs, dbtestdata.Addr6)
+	}
+	if last, err := d.GetLastOrphanedBlocks(10); err != nil || !reflect.DeepEqual(last, obs) {
+		t.Errorf("GetLastOrphanedBlocks() = %+v, %v", last, err)
+	}
+	if obs[0].FateKnown {
+		t.Errorf("GetOrphanedBlocks() fate known before the synchronization")
+	}
+	// none of the transactions is mined again, one returned to the mempool
+	if err := d.StoreOrphanedBlocksFate(225494, func(txid string) bool { return txid == dbtestdata.TxidB2T2 }); err != nil {
+		t.Fatal(err)
+	}
+	if obs, err = d.GetOrphanedBlocks(225494, 225494); err != nil || len(obs) != 1 || !obs[0].FateKnown || obs[0].Reconfirmed != 0 || obs[0].InMempool != 1 || obs[0].Dropped != 3 || len(obs[0].Txids) != 4 {
+		t.Errorf("GetOrphanedBlocks() after StoreOrphanedBlocksFate = %+v, %v", obs, err)
+	}
 
 	// connect block again and verify the state of db
 	if err := d.ConnectBlock(block2); err != nil {
@@ -875,7 +979,7 @@ func Test_packTxAddresses_unpackTxAddresses(t *testing.T) {
 	}{
 		{
 			name: "1",
-			hex:  "7b0216001443aac20a116e09ea4f7914be1c55e4c17aa600b70016001454633aa8bd2e552bd4e89c01e73c1b7905eb58460811207cb68a199872012d001443aac20a116e09ea4f7914be1c55e4c17aa600b70101",
+			hex:  "7b000216001443aac20a116e09ea4f7914be1c55e4c17aa600b70016001454633aa8bd2e552bd4e89c01e73c1b7905eb58460811207cb68a199872012d001443aac20a116e09ea4f7914be1c55e4c17aa600b70101",
 			data: &TxAddresses{
 				Height: 123,
 				Inputs: []TxInput{
@@ -899,9 +1003,10 @@ func Test_packTxAddresses_unpackTxAddresses(t *testing.T) {
 		},
 		{
 			name: "2",
-			hex:  "e0390317a9149eb21980dc9d413d8eac27314938b9da920ee53e8705021918f2c017a91409f70b896169c37981d2b54b371df0d81a136a2c870501dd7e28c017a914e371782582a4addb541362c55565d2cdf56f6498870501a1e35ec0052fa9141d9ca71efa36d814424ea6ca1437e67287aebe348705012aadcac02ea91424fbc77cdc62702ade74dcf989c15e5d3f9240bc870501664894c02fa914afbfb74ee994c7d45f6698738bc4226d065266f7870501a1e35ec03276a914d2a37ce20ac9ec4f15dd05a7c6e8e9fbdb99850e88ac043b9943603376a9146b2044146a4438e6e5bfbc65f147afeb64d14fbb88ac05012a05f200",
+			hex:  "e039050317a9149eb21980dc9d413d8eac27314938b9da920ee53e8705021918f2c017a91409f70b896169c37981d2b54b371df0d81a136a2c870501dd7e28c017a914e371782582a4addb541362c55565d2cdf56f6498870501a1e35ec0052fa9141d9ca71efa36d814424ea6ca1437e67287aebe348705012aadcac02ea91424fbc77cdc62702ade74dcf989c15e5d3f9240bc870501664894c02fa914afbfb74ee994c7d45f6698738bc4226d065266f7870501a1e35ec03276a914d2a37ce20ac9ec4f15dd05a7c6e8e9fbdb99850e88ac043b9943603376a9146b2044146a4438e6e5bfbc65f147afeb64d14fbb88ac05012a05f200",
 			data: &TxAddresses{
-				Height: 12345,
+				Height:   12345,
+				Position: 5,
 				Inputs: []TxInput{
 					{
 						AddrDesc: addressToAddrDesc("2N7iL7AvS4LViugwsdjTB13uN4T7XhV1bCP", parser),
@@ -945,7 +1050,7 @@ func Test_packTxAddresses_unpackTxAddresses(t *testing.T) {
 		},
 		{
 			name: "empty address",
-			hex:  "baef9a1501000204d2020002162e010162",
+			hex:  "baef9a150001000204d2020002162e010162",
 			data: &TxAddresses{
 				Height: 123456789,
 				Inputs: []TxInput{
@@ -969,7 +1074,7 @@ func Test_packTxAddresses_unpackTxAddresses(t *testing.T) {
 		},
 		{
 			name: "empty",
-			hex:  "000000",
+			hex:  "00000000",
 			data: &TxAddresses{
 				Inputs:  []TxInput{},
 				Outputs: []TxOutput{},
@@ -0,0 +1,263 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/eth"
+	"bytes"
+	"encoding/hex"
+	"math/big"
+
+	vlq "github.com/bsm/go-vlq"
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// TokenTransfer is a transfer of a contract token of Bitcoin type coin (QRC20)
+type TokenTransfer struct {
+	Contract bchain.AddressDescriptor
+	From     bchain.AddressDescriptor
+	To       bchain.AddressDescriptor
+	Value    big.Int
+}
+
+// tokenTransfersMap maps packed txid to token transfers done in the transaction
+type tokenTransfersMap map[string][]TokenTransfer
+
+// tokenContractsMap maps addrDesc to token contracts with number of transfers of the address
+type tokenContractsMap map[string][]AddrContract
+
+// processTokenTransfers collects token transfers of the block and updates the token contracts of the addresses
+func (d *RocksDB) processTokenTransfers(block *bchain.Block, transfers tokenTransfersMap, contracts tokenContractsMap) error {
+	if d.chainParser.GetContractTokenType() == "" {
+		return nil
+	}
+	for txi := range block.Txs {
+		tx := &block.Txs[txi]
+		ets, err := d.chainParser.EthereumTypeGetErc20FromTx(tx)
+		if err != nil {
+			glog.Warningf("rocksdb: token transfers: height %d, tx %v, error %v", block.Height, tx.Txid, err)
+			continue
+		}
+		if len(ets) == 0 {
+			continue
+		}
+		tts := make([]TokenTransfer, 0, len(ets))
+		for i := range ets {
+			e := &ets[i]
+			// the contract is hex encoded contract address
+			contract, err := hex.DecodeString(e.Contract)
+			if err != nil || len(contract) != eth.EthereumTypeAddressDescriptorLen {
+				glog.Warningf("rocksdb: token transfers: height %d, tx %v, invalid contract %v", block.Height, tx.Txid, e.Contract)
+				continue
+			}
+			tt := TokenTransfer{
+				Contract: contract,
+				Value:    e.Tokens,
+			}
+			// mint and burn have empty from or to address
+			if e.From != "" {
+				if tt.From, err = d.chainParser.GetAddrDescFromAddress(e.From); err != nil {
+					glog.Warningf("rocksdb: token transfers: height %d, tx %v, invalid address %v", block.Height, tx.Txid, e.From)
+					continue
+				}
+			}
+			if e.To != "" {
+				if tt.To, err = d.chainParser.GetAddrDescFromAddress(e.To); err != nil {
+					glog.Warningf("rocksdb: token transfers: height %d, tx %v, invalid address %v", block.Height, tx.Txid, e.To)
+					continue
+				}
+			}
+			if err = d.addToTokenContracts(tt.From, tt.Contract, contracts, 1); err != nil {
+				return err
+			}
+			// transfer to self is counted only once
+			if !bytes.Equal(tt.From, tt.To) {
+				if err = d.addToTokenContracts(tt.To, tt.Contract, contracts, 1); err != nil {
+					return err
+				}
+			}
+			tts = append(tts, tt)
+		}
+		if len(tts) > 0 {
+			btxID, err := d.chainParser.PackTxid(tx.Txid)
+			if err != nil {
+				return err
+			}
+			transfers[string(btxID)] = tts
+		}
+	}
+	return nil
+}
+
+// addToTokenContracts adds (or removes in case of negative delta) transfers of the contract to the address
+func (d *RocksDB) addToTokenContracts(addrDesc, contract bchain.AddressDescriptor, contracts tokenContractsMap, delta int) error {
+	if len(addrDesc) == 0 {
+		return nil
+	}
+	s := string(addrDesc)
+	acs, found := contracts[s]
+	if !found {
+		var err error
+		acs, err = d.GetAddrDescTokenContracts(addrDesc)
+		if err != nil {
+			return err
+		}
+	}
+	i, found := findContractInAddressContracts(contract, acs)
+	if delta > 0 {
+		if found {
+			acs[i].Txs++
+		} else {
+			acs = append(acs, AddrContract{
+				Contract: contract,
+				Txs:      1,
+			})
+		}
+	} else {
+		if found && acs[i].Txs > 0 {
+			acs[i].Txs--
+			if acs[i].Txs == 0 {
+				acs = append(acs[:i], acs[i+1:]...)
+			}
+		} else {
+			glog.Warning("TokenContracts ", addrDesc, ", contract ", contract, " not found")
+		}
+	}
+	contracts[s] = acs
+	return nil
+}
+
+func (d *RocksDB) storeTokenContracts(wb *gorocksdb.WriteBatch, contracts tokenContractsMap) error {
+	buf := make([]byte, 0, 64)
+	varBuf := make([]byte, vlq.MaxLen64)
+	for addrDesc, acs := range contracts {
+		// address without contracts is removed from db - happens on disconnect
+		if len(acs) == 0 {
+			wb.DeleteCF(d.cfh[cfTokenContracts], bchain.AddressDescriptor(addrDesc))
+		} else {
+			buf = buf[:0]
+			for _, ac := range acs {
+				buf = append(buf, ac.Contract...)
+				l := packVaruint(ac.Txs, varBuf)
+				buf = append(buf, varBuf[:l]...)
+			}
+			wb.PutCF(d.cfh[cfTokenContracts], bchain.AddressDescriptor(addrDesc), buf)
+		}
+	}
+	return nil
+}
+
+// GetAddrDescTokenContracts returns token contracts with number of transfers of given addrDesc
+func (d *RocksDB) GetAddrDescTokenContracts(addrDesc bchain.AddressDescriptor) ([]AddrContract, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfTokenContracts], addrDesc)
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	var c []AddrContract
+	for len(buf) > 0 {
+		if len(buf) < eth.EthereumTypeAddressDescriptorLen {
+			return nil, errors.New("Invalid data stored in cfTokenContracts for AddrDesc " + addrDesc.String())
+		}
+		txs, l := unpackVaruint(buf[eth.EthereumTypeAddressDescriptorLen:])
+		contract := append(bchain.AddressDescriptor(nil), buf[:eth.EthereumTypeAddressDescriptorLen]...)
+		c = append(c, AddrContract{
+			Contract: contract,
+			Txs:      txs,
+		})
+		buf = buf[eth.EthereumTypeAddressDescriptorLen+l:]
+	}
+	return c, nil
+}
+
+func (d *RocksDB) storeTokenTransfers(wb *gorocksdb.WriteBatch, transfers tokenTransfersMap) error {
+	buf := make([]byte, 0, 128)
+	varBuf := make([]byte, maxPackedBigintBytes)
+	for btxID, tts := range transfers {
+		buf = packTokenTransfers(tts, buf, varBuf)
+		wb.PutCF(d.cfh[cfTokenTransfers], []byte(btxID), buf)
+	}
+	return nil
+}
+
+// GetTokenTransfers returns token transfers done in the transaction
+func (d *RocksDB) GetTokenTransfers(btxID []byte) ([]TokenTransfer, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfTokenTransfers], btxID)
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	return unpackTokenTransfers(buf)
+}
+
+// disconnectTokenTransfers removes the transfers of the transaction from the token contracts of the addresses
+func (d *RocksDB) disconnectTokenTransfers(wb *gorocksdb.WriteBatch, btxID []byte, contracts tokenContractsMap) error {
+	tts, err := d.GetTokenTransfers(btxID)
+	if err != nil {
+		return err
+	}
+	for i := range tts {
+		tt := &tts[i]
+		if err = d.addToTokenContracts(tt.From, tt.Contract, contracts, -1); err != nil {
+			return err
+		}
+		if !bytes.Equal(tt.From, tt.To) {
+			if err = d.addToTokenContracts(tt.To, tt.Contract, contracts, -1); err != nil {
+				return err
+			}
+		}
+	}
+	wb.DeleteCF(d.cfh[cfTokenTransfers], btxID)
+	return nil
+}
+
+func packTokenTransfers(tts []TokenTransfer, buf, varBuf []byte) []byte {
+	buf = buf[:0]
+	for i := range tts {
+		tt := &tts[i]
+		buf = append(buf, tt.Contract...)
+		buf = appendPackedBytes(tt.From, buf, varBuf)
+		buf = appendPackedBytes(tt.To, buf, varBuf)
+		l := packBigint(&tt.Value, varBuf)
+		buf = append(buf, varBuf[:l]...)
+	}
+	return buf
+}
+
+func unpackTokenTransfers(buf []byte) ([]TokenTransfer, error) {
+	var tts []TokenTransfer
+	for len(buf) > 0 {
+		if len(buf) < eth.EthereumTypeAddressDescriptorLen {
+			return nil, errors.New("Invalid token transfers data")
+		}
+		tt := TokenTransfer{
+			Contract: append(bchain.AddressDescriptor(nil), buf[:eth.EthereumTypeAddressDescriptorLen]...),
+		}
+		buf = buf[eth.EthereumTypeAddressDescriptorLen:]
+		from, l, err := unpackBytes(buf)
+		if err != nil {
+			return nil, err
+		}
+		tt.From = from
+		buf = buf[l:]
+		to, l, err := unpackBytes(buf)
+		if err != nil {
+			return nil, err
+		}
+		tt.To = to
+		buf = buf[l:]
+		if len(buf) == 0 {
+			return nil, errors.New("Invalid token transfers data")
+		}
+		tt.Value, l = unpackBigint(buf)
+		buf = buf[l:]
+		tts = append(tts, tt)
+	}
+	return tts, nil
+}
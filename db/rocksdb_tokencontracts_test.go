@@ -0,0 +1,76 @@
+// +build unittest
+
+package db
+
+import (
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func Test_packTokenTransfers_unpackTokenTransfers(t *testing.T) {
+	contract, _ := hex.DecodeString("f2033ede578e17fa6231047265010445bca8cf1c")
+	from, _ := hex.DecodeString("76a9146f44cceb49b4a5812d54b6f494fc2febf25511ed88ac")
+	to, _ := hex.DecodeString("76a9144bda106325c335df99eab7fe363cac8a0ba2a24d88ac")
+	varBuf := make([]byte, maxPackedBigintBytes)
+	buf := make([]byte, 1024)
+	tests := []struct {
+		name string
+		hex  string
+		tts  []TokenTransfer
+	}{
+		{
+			name: "transfer",
+			hex:  "f2033ede578e17fa6231047265010445bca8cf1c1976a9146f44cceb49b4a5812d54b6f494fc2febf25511ed88ac1976a9144bda106325c335df99eab7fe363cac8a0ba2a24d88ac020123",
+			tts: []TokenTransfer{
+				{
+					Contract: contract,
+					From:     from,
+					To:       to,
+					Value:    *big.NewInt(0x123),
+				},
+			},
+		},
+		{
+			name: "transfer and mint",
+			hex:  "f2033ede578e17fa6231047265010445bca8cf1c1976a9146f44cceb49b4a5812d54b6f494fc2febf25511ed88ac1976a9144bda106325c335df99eab7fe363cac8a0ba2a24d88ac020123f2033ede578e17fa6231047265010445bca8cf1c001976a9144bda106325c335df99eab7fe363cac8a0ba2a24d88ac030f4240",
+			tts: []TokenTransfer{
+				{
+					Contract: contract,
+					From:     from,
+					To:       to,
+					Value:    *big.NewInt(0x123),
+				},
+				{
+					Contract: contract,
+					To:       to,
+					Value:    *big.NewInt(1000000),
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := packTokenTransfers(tt.tts, buf, varBuf)
+			if h := hex.EncodeToString(b); h != tt.hex {
+				t.Errorf("packTokenTransfers() = %v, want %v", h, tt.hex)
+			}
+			got, err := unpackTokenTransfers(b)
+			if err != nil {
+				t.Errorf("unpackTokenTransfers() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.tts) {
+				t.Errorf("unpackTokenTransfers() = %+v, want %+v", got, tt.tts)
+			}
+		})
+	}
+}
+
+func Test_unpackTokenTransfers_invalid(t *testing.T) {
+	b, _ := hex.DecodeString("f2033ede578e17fa6231047265010445bca8cf1c1976a9146f44cceb49b4a5")
+	if _, err := unpackTokenTransfers(b); err == nil {
+		t.Errorf("unpackTokenTransfers() expected error")
+	}
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"blockbook/common"
+	"context"
+)
+
+// TracedDB is the handle to the database tracing the reads of the request in ctx as spans,
+// it refers to the shared database, the reads which are not traced are passed to it unchanged
+type TracedDB struct {
+	*RocksDB
+	// ctx is the context of the traced request, nil if the reads are not traced
+	ctx context.Context
+}
+
+// Traced returns the handle to the database tracing the reads as spans of the request in ctx, nil ctx means no tracing
+func (d *RocksDB) Traced(ctx context.Context) *TracedDB {
+	return &TracedDB{RocksDB: d, ctx: ctx}
+}
+
+// GetAddrDescTransactions traces RocksDB.GetAddrDescTransactions
+func (d *TracedDB) GetAddrDescTransactions(addrDesc bchain.AddressDescriptor, lower uint32, higher uint32, fn GetTransactionsCallback) (err error) {
+	_, span := common.StartSpan(d.ctx, "db.GetAddrDescTransactions")
+	defer func() { common.EndSpan(span, err) }()
+	return d.RocksDB.GetAddrDescTransactions(addrDesc, lower, higher, fn)
+}
+
+// GetAddrDescBalance traces RocksDB.GetAddrDescBalance
+func (d *TracedDB) GetAddrDescBalance(addrDesc bchain.AddressDescriptor, detail AddressBalanceDetail) (ab *AddrBalance, err error) {
+	_, span := common.StartSpan(d.ctx, "db.GetAddrDescBalance")
+	defer func() { common.EndSpan(span, err) }()
+	return d.RocksDB.GetAddrDescBalance(addrDesc, detail)
+}
+
+// GetTxAddresses traces RocksDB.GetTxAddresses
+func (d *TracedDB) GetTxAddresses(txid string) (ta *TxAddresses, err error) {
+	_, span := common.StartSpan(d.ctx, "db.GetTxAddresses")
+	defer func() { common.EndSpan(span, err) }()
+	return d.RocksDB.GetTxAddresses(txid)
+}
+
+// GetBlockInfo traces RocksDB.GetBlockInfo
+func (d *TracedDB) GetBlockInfo(height uint32) (bi *BlockInfo, err error) {
+	_, span := common.StartSpan(d.ctx, "db.GetBlockInfo")
+	defer func() { common.EndSpan(span, err) }()
+	return d.RocksDB.GetBlockInfo(height)
+}
+
+// GetTx traces RocksDB.GetTx
+func (d *TracedDB) GetTx(txid string) (tx *bchain.Tx, height uint32, err error) {
+	_, span := common.StartSpan(d.ctx, "db.GetTx")
+	defer func() { common.EndSpan(span, err) }()
+	return d.RocksDB.GetTx(txid)
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"blockbook/bchain"
+	"sort"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// utxoTokensMap maps packed txid to tokens carried by the outputs of the transaction, indexed by vout
+type utxoTokensMap map[string]map[int32]*bchain.UtxoToken
+
+// processUtxoTokens collects tokens carried by the outputs of the transactions in the block
+func (d *RocksDB) processUtxoTokens(block *bchain.Block, utxoTokens utxoTokensMap) error {
+	if d.chainParser.GetUtxoTokenType() == "" {
+		return nil
+	}
+	for txi := range block.Txs {
+		tx := &block.Txs[txi]
+		var tokens map[int32]*bchain.UtxoToken
+		for i := range tx.Vout {
+			t, err := d.chainParser.GetUtxoTokenFromVout(&tx.Vout[i])
+			if err != nil {
+				glog.Warningf("rocksdb: utxo token: height %d, tx %v, vout %v, error %v", block.Height, tx.Txid, i, err)
+				continue
+			}
+			if t != nil {
+				if tokens == nil {
+					tokens = make(map[int32]*bchain.UtxoToken)
+				}
+				tokens[int32(i)] = t
+			}
+		}
+		if tokens != nil {
+			btxID, err := d.chainParser.PackTxid(tx.Txid)
+			if err != nil {
+				return err
+			}
+			utxoTokens[string(btxID)] = tokens
+		}
+	}
+	return nil
+}
+
+func (d *RocksDB) storeUtxoTokens(wb *gorocksdb.WriteBatch, utxoTokens utxoTokensMap) error {
+	buf := make([]byte, 0, 128)
+	varBuf := make([]byte, maxPackedBigintBytes)
+	for btxID, tokens := range utxoTokens {
+		buf = packUtxoTokens(tokens, buf, varBuf)
+		wb.PutCF(d.cfh[cfUtxoTokens], []byte(btxID), buf)
+	}
+	return nil
+}
+
+// GetUtxoTokens returns tokens carried by the outputs of the transaction, indexed by vout
+// nil is returned if the transaction has no outputs with tokens
+func (d *RocksDB) GetUtxoTokens(btxID []byte) (map[int32]*bchain.UtxoToken, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cfUtxoTokens], btxID)
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	return unpackUtxoTokens(buf)
+}
+
+func packUtxoTokens(tokens map[int32]*bchain.UtxoToken, buf, varBuf []byte) []byte {
+	buf = buf[:0]
+	vouts := make([]int32, 0, len(tokens))
+	for vout := range tokens {
+		vouts = append(vouts, vout)
+	}
+	sort.Slice(vouts, func(i, j int) bool { return vouts[i] < vouts[j] })
+	for _, vout := range vouts {
+		t := tokens[vout]
+		l := packVaruint(uint(vout), varBuf)
+		buf = append(buf, varBuf[:l]...)
+		buf = appendPackedBytes([]byte(t.ID), buf, varBuf)
+		l = packBigint(&t.Amount, varBuf)
+		buf = append(buf, varBuf[:l]...)
+		if t.Nft {
+			buf = append(buf, 1)
+			buf = appendPackedBytes([]byte(t.NftCapability), buf, varBuf)
+			buf = appendPackedBytes(t.NftCommitment, buf, varBuf)
+		} else {
+			buf = append(buf, 0)
+		}
+	}
+	return buf
+}
+
+func appendPackedBytes(b []byte, buf, varBuf []byte) []byte {
+	l := packVaruint(uint(len(b)), varBuf)
+	buf = append(buf, varBuf[:l]...)
+	return append(buf, b...)
+}
+
+func unpackBytes(buf []byte) ([]byte, int, error) {
+	bl, l := unpackVaruint(buf)
+	if len(buf) < l+int(bl) {
+		return nil, 0, errors.New("Invalid data length")
+	}
+	return append([]byte(nil), buf[l:l+int(bl)]...), l + int(bl), nil
+}
+
+func unpackUtxoTokens(buf []byte) (map[int32]*bchain.UtxoToken, error) {
+	tokens := make(map[int32]*bchain.UtxoToken)
+	for l := 0; l < len(buf); {
+		vout, ll := unpackVaruint(buf[l:])
+		l += ll
+		id, ll, err := unpackBytes(buf[l:])
+		if err != nil {
+			return nil, err
+		}
+		l += ll
+		t := bchain.UtxoToken{ID: string(id)}
+		t.Amount, ll = unpackBigint(buf[l:])
+		l += ll
+		if l >= len(buf) {
+			return nil, errors.New("Invalid utxo tokens data")
+		}
+		nft := buf[l]
+		l++
+		if nft != 0 {
+			t.Nft = true
+			capability, ll, err := unpackBytes(buf[l:])
+			if err != nil {
+				return nil, err
+			}
+			l += ll
+			t.NftCapability = string(capability)
+			t.NftCommitment, ll, err = unpackBytes(buf[l:])
+			if err != nil {
+				return nil, err
+			}
+			l += ll
+		}
+		tokens[int32(vout)] = &t
+	}
+	return tokens, nil
+}
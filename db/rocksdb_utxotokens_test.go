@@ -0,0 +1,66 @@
+// +build unittest
+
+package db
+
+import (
+	"blockbook/bchain"
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func Test_packUtxoTokens_unpackUtxoTokens(t *testing.T) {
+	varBuf := make([]byte, maxPackedBigintBytes)
+	buf := make([]byte, 1024)
+	tests := []struct {
+		name   string
+		hex    string
+		tokens map[int32]*bchain.UtxoToken
+	}{
+		{
+			name: "fungible",
+			hex:  "0208746f6b656e2d69640203e800",
+			tokens: map[int32]*bchain.UtxoToken{
+				2: {
+					ID:     "token-id",
+					Amount: *big.NewInt(1000),
+				},
+			},
+		},
+		{
+			name: "fungible and nft",
+			hex:  "0001610001046e6f6e6500010162017f01076d696e74696e6702cafe",
+			tokens: map[int32]*bchain.UtxoToken{
+				0: {
+					ID:            "a",
+					Nft:           true,
+					NftCapability: "none",
+				},
+				1: {
+					ID:            "b",
+					Amount:        *big.NewInt(127),
+					Nft:           true,
+					NftCapability: "minting",
+					NftCommitment: []byte{0xca, 0xfe},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := packUtxoTokens(tt.tokens, buf, varBuf)
+			if h := hex.EncodeToString(b); h != tt.hex {
+				t.Errorf("packUtxoTokens() = %v, want %v", h, tt.hex)
+			}
+			got, err := unpackUtxoTokens(b)
+			if err != nil {
+				t.Errorf("unpackUtxoTokens() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.tokens) {
+				t.Errorf("unpackUtxoTokens() = %+v, want %+v", got, tt.tokens)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/tecbot/gorocksdb"
+)
+
+// webhookIDLen is the length of the packed id of the webhook, the id is 16 random bytes in hex
+const webhookIDLen = 16
+
+// Webhook is a registration of the url notified about the transactions of the addresses and xpubs
+type Webhook struct {
+	ID            string    `json:"id"`
+	URL           string    `json:"url"`
+	Secret        string    `json:"secret"`
+	Addresses     []string  `json:"addresses,omitempty"`
+	Xpubs         []string  `json:"xpubs,omitempty"`
+	Confirmations []int     `json:"confirmations,omitempty"`
+	Created       time.Time `json:"created"`
+}
+
+// WebhookDelivery is a record of the delivery log of the webhook
+type WebhookDelivery struct {
+	ID            uint64          `json:"id"`
+	Event         string          `json:"event"`
+	Txid          string          `json:"txid"`
+	Address       string          `json:"address,omitempty"`
+	Confirmations int             `json:"confirmations,omitempty"`
+	Created       time.Time       `json:"created"`
+	Attempts      int             `json:"attempts"`
+	LastAttempt   time.Time       `json:"lastAttempt"`
+	NextAttempt   time.Time       `json:"nextAttempt"`
+	Status        int             `json:"status,omitempty"`
+	Error         string          `json:"error,omitempty"`
+	Delivered     bool            `json:"delivered"`
+	Failed        bool            `json:"failed,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// WebhookTx is a transaction notified by the webhook, kept until it reaches all confirmation thresholds of the webhook
+type WebhookTx struct {
+	Txid          string    `json:"txid"`
+	Address       string    `json:"address"`
+	Confirmations int       `json:"confirmations"`
+	Seen          time.Time `json:"seen"`
+}
+
+// packWebhookID packs the hex id of the webhook, the ids which are not 16 bytes in hex cannot be stored
+func packWebhookID(id string) ([]byte, error) {
+	b, err := hex.DecodeString(id)
+	if err != nil || len(b) != webhookIDLen {
+		return nil, errors.Errorf("Invalid webhook id %v", id)
+	}
+	return b, nil
+}
+
+func packWebhookDeliveryKey(bid []byte, id uint64) []byte {
+	key := make([]byte, webhookIDLen+8)
+	copy(key, bid)
+	binary.BigEndian.PutUint64(key[webhookIDLen:], id)
+	return key
+}
+
+func (d *RocksDB) packWebhookTxKey(webhookID string, txid string) ([]byte, error) {
+	bid, err := packWebhookID(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	btxID, err := d.chainParser.PackTxid(txid)
+	if err != nil {
+		return nil, err
+	}
+	return append(bid, btxID...), nil
+}
+
+func (d *RocksDB) putJSON(cf int, key []byte, v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return errors.Annotatef(err, "Marshal %s", hex.EncodeToString(key))
+	}
+	return d.db.PutCF(d.wo, d.cfh[cf], key, buf)
+}
+
+// getJSON unmarshals the value of the key in the column to v, returns false if the key is not found
+func (d *RocksDB) getJSON(cf int, key []byte, v interface{}) (bool, error) {
+	val, err := d.db.GetCF(d.ro, d.cfh[cf], key)
+	if err != nil {
+		return false, err
+	}
+	defer val.Free()
+	if val.Size() == 0 {
+		return false, nil
+	}
+	if err = json.Unmarshal(val.Data(), v); err != nil {
+		return false, errors.Annotatef(err, "%s", hex.EncodeToString(key))
+	}
+	return true, nil
+}
+
+// iteratePrefix calls f for all the records in the column with the key prefix, the iteration stops if f returns false
+func (d *RocksDB) iteratePrefix(cf int, prefix []byte, f func(key, val []byte) (bool, error)) error {
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cf])
+	defer it.Close()
+	for it.Seek(prefix); it.Valid(); it.Next() {
+		key := it.Key().Data()
+		if !bytes.HasPrefix(key, prefix) {
+			break
+		}
+		cont, err := f(key, it.Value().Data())
+		if err != nil {
+			return err
+		}
+		if !cont {
+			break
+		}
+	}
+	return nil
+}
+
+// StoreWebhook stores the webhook registration
+func (d *RocksDB) StoreWebhook(w *Webhook) error {
+	bid, err := packWebhookID(w.ID)
+	if err != nil {
+		return err
+	}
+	return d.putJSON(cfWebhooks, bid, w)
+}
+
+// GetWebhook returns the webhook registration or nil if it does not exist
+func (d *RocksDB) GetWebhook(id string) (*Webhook, error) {
+	bid, err := packWebhookID(id)
+	if err != nil {
+		// the webhook with invalid id cannot exist
+		return nil, nil
+	}
+	var w Webhook
+	found, err := d.getJSON(cfWebhooks, bid, &w)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// GetWebhooks returns all webhook registrations
+func (d *RocksDB) GetWebhooks() ([]*Webhook, error) {
+	var ws []*Webhook
+	err := d.iteratePrefix(cfWebhooks, nil, func(key, val []byte) (bool, error) {
+		var w Webhook
+		if err := json.Unmarshal(val, &w); err != nil {
+			return false, errors.Annotatef(err, "webhook %s", hex.EncodeToString(key))
+		}
+		ws = append(ws, &w)
+		return true, nil
+	})
+	return ws, err
+}
+
+// DeleteWebhook deletes the webhook registration together with its delivery log and transactions
+func (d *RocksDB) DeleteWebhook(id string) error {
+	bid, err := packWebhookID(id)
+	if err != nil {
+		return err
+	}
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	wb.DeleteCF(d.cfh[cfWebhooks], bid)
+	for _, cf := range []int{cfWebhookDeliveries, cfWebhookTxs} {
+		err := d.iteratePrefix(cf, bid, func(key, val []byte) (bool, error) {
+			wb.DeleteCF(d.cfh[cf], append([]byte{}, key...))
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return d.db.Write(d.wo, wb)
+}
+
+// StoreWebhookDelivery stores the record of the delivery log of the webhook
+func (d *RocksDB) StoreWebhookDelivery(webhookID string, r *WebhookDelivery) error {
+	bid, err := packWebhookID(webhookID)
+	if err != nil {
+		return err
+	}
+	return d.putJSON(cfWebhookDeliveries, packWebhookDeliveryKey(bid, r.ID), r)
+}
+
+// GetWebhookDeliveries returns the records of the delivery log of the webhook in the order of their ids
+func (d *RocksDB) GetWebhookDeliveries(webhookID string) ([]*WebhookDelivery, error) {
+	bid, err := packWebhookID(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	var rs []*WebhookDelivery
+	err = d.iteratePrefix(cfWebhookDeliveries, bid, func(key, val []byte) (bool, error) {
+		var r WebhookDelivery
+		if err := json.Unmarshal(val, &r); err != nil {
+			return false, errors.Annotatef(err, "webhook delivery %s", hex.EncodeToString(key))
+		}
+		rs = append(rs, &r)
+		return true, nil
+	})
+	return rs, err
+}
+
+// TrimWebhookDeliveries deletes the oldest delivered or failed records of the delivery log of the webhook so that at most keep records remain,
+// the records waiting for the delivery or for the retry are never deleted
+func (d *RocksDB) TrimWebhookDeliveries(webhookID string, keep int) error {
+	bid, err := packWebhookID(webhookID)
+	if err != nil {
+		return err
+	}
+	count := 0
+	err = d.iteratePrefix(cfWebhookDeliveries, bid, func(key, val []byte) (bool, error) {
+		count++
+		return true, nil
+	})
+	if err != nil || count <= keep {
+		return err
+	}
+	// the oldest records are almost always finished, only the records up to the excess must be unmarshalled
+	excess := count - keep
+	wb := gorocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	err = d.iteratePrefix(cfWebhookDeliveries, bid, func(key, val []byte) (bool, error) {
+		var r struct {
+			Delivered bool `json:"delivered"`
+			Failed    bool `json:"failed"`
+		}
+		if err := json.Unmarshal(val, &r); err != nil {
+			return false, errors.Annotatef(err, "webhook delivery %s", hex.EncodeToString(key))
+		}
+		if r.Delivered || r.Failed {
+			wb.DeleteCF(d.cfh[cfWebhookDeliveries], append([]byte{}, key...))
+			excess--
+		}
+		return excess > 0, nil
+	})
+	if err != nil {
+		return err
+	}
+	return d.db.Write(d.wo, wb)
+}
+
+// StoreWebhookTx stores the transaction notified by the webhook
+func (d *RocksDB) StoreWebhookTx(webhookID string, t *WebhookTx) error {
+	key, err := d.packWebhookTxKey(webhookID, t.Txid)
+	if err != nil {
+		return err
+	}
+	return d.putJSON(cfWebhookTxs, key, t)
+}
+
+// GetWebhookTx returns the transaction notified by the webhook or nil if it is not stored
+func (d *RocksDB) GetWebhookTx(webhookID string, txid string) (*WebhookTx, error) {
+	key, err := d.packWebhookTxKey(webhookID, txid)
+	if err != nil {
+		return nil, err
+	}
+	var t WebhookTx
+	found, err := d.getJSON(cfWebhookTxs, key, &t)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// GetWebhookTxs returns the transactions notified by the webhook
+func (d *RocksDB) GetWebhookTxs(webhookID string) ([]*WebhookTx, error) {
+	bid, err := packWebhookID(webhookID)
+	if err != nil {
+		return nil, err
+	}
+	var ts []*WebhookTx
+	err = d.iteratePrefix(cfWebhookTxs, bid, func(key, val []byte) (bool, error) {
+		var t WebhookTx
+		if err := json.Unmarshal(val, &t); err != nil {
+			return false, errors.Annotatef(err, "webhook tx %s", hex.EncodeToString(key))
+		}
+		ts = append(ts, &t)
+		return true, nil
+	})
+	return ts, err
+}
+
+// DeleteWebhookTx deletes the transaction notified by the webhook
+func (d *RocksDB) DeleteWebhookTx(webhookID string, txid string) error {
+	key, err := d.packWebhookTxKey(webhookID, txid)
+	if err != nil {
+		return err
+	}
+	return d.db.DeleteCF(d.wo, d.cfh[cfWebhookTxs], key)
+}
@@ -0,0 +1,103 @@
+// +build unittest
+
+package db
+
+import (
+	"blockbook/tests/dbtestdata"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRocksDB_Webhooks(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	const (
+		id1 = "5e2c0d6f1a7b4c3d9e8f0a1b2c3d4e5f"
+		id2 = "5e2c0d6f1a7b4c3d9e8f0a1b2c3d4e60"
+		id3 = "5e2c0d6f1a7b4c3d9e8f0a1b2c3d4e61"
+	)
+	w1 := &Webhook{ID: id1, URL: "http://localhost:8080/hook", Secret: "s1", Addresses: []string{"mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz"}, Confirmations: []int{1, 6}, Created: created}
+	w2 := &Webhook{ID: id2, URL: "https://example.com/", Secret: "s2", Xpubs: []string{"upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q"}, Created: created}
+	for _, w := range []*Webhook{w1, w2} {
+		if err := d.StoreWebhook(w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.StoreWebhook(&Webhook{ID: "a1"}); err == nil {
+		t.Error("StoreWebhook() with invalid id, expected error")
+	}
+	got, err := d.GetWebhook(id1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, w1) {
+		t.Errorf("GetWebhook() = %+v, want %+v", got, w1)
+	}
+	for _, id := range []string{id3, "a3"} {
+		if got, err = d.GetWebhook(id); err != nil || got != nil {
+			t.Errorf("GetWebhook(%v) = %+v, %v, want nil", id, got, err)
+		}
+	}
+
+	// the deliveries 1 and 3 of the first webhook wait for the retry
+	for i := uint64(1); i <= 5; i++ {
+		for _, id := range []string{id1, id2} {
+			r := &WebhookDelivery{ID: i << 8, Event: "tx", Txid: id, Created: created, Delivered: id != id1 || (i != 1 && i != 3), Payload: []byte(`{"id":1}`)}
+			if err = d.StoreWebhookDelivery(id, r); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err = d.StoreWebhookTx(id1, &WebhookTx{Txid: dbtestdata.TxidB1T1, Address: "mtGXQvBowMkBpnhLckhxhbwYK44Gs9eEtz", Seen: created}); err != nil {
+		t.Fatal(err)
+	}
+	// only the delivered records are trimmed
+	if err = d.TrimWebhookDeliveries(id1, 3); err != nil {
+		t.Fatal(err)
+	}
+	ds, err := d.GetWebhookDeliveries(id1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ds) != 3 || ds[0].ID != 1<<8 || ds[1].ID != 3<<8 || ds[2].ID != 5<<8 || ds[0].Txid != id1 {
+		t.Errorf("GetWebhookDeliveries() = %+v", ds)
+	}
+	if err = d.TrimWebhookDeliveries(id1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if ds, err = d.GetWebhookDeliveries(id1); err != nil || len(ds) != 2 || ds[0].ID != 1<<8 || ds[1].ID != 3<<8 {
+		t.Errorf("GetWebhookDeliveries() = %+v, %v", ds, err)
+	}
+	ts, err := d.GetWebhookTxs(id1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 1 || ts[0].Txid != dbtestdata.TxidB1T1 {
+		t.Errorf("GetWebhookTxs() = %+v", ts)
+	}
+
+	if err = d.DeleteWebhook(id1); err != nil {
+		t.Fatal(err)
+	}
+	ws, err := d.GetWebhooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ws, []*Webhook{w2}) {
+		t.Errorf("GetWebhooks() = %+v, want %+v", ws, []*Webhook{w2})
+	}
+	if ds, err = d.GetWebhookDeliveries(id1); err != nil || len(ds) != 0 {
+		t.Errorf("GetWebhookDeliveries() after delete = %+v, %v", ds, err)
+	}
+	if tx, err := d.GetWebhookTx(id1, dbtestdata.TxidB1T1); err != nil || tx != nil {
+		t.Errorf("GetWebhookTx() after delete = %+v, %v", tx, err)
+	}
+	if ds, err = d.GetWebhookDeliveries(id2); err != nil || len(ds) != 5 {
+		t.Errorf("GetWebhookDeliveries(id2) = %+v, %v", ds, err)
+	}
+}
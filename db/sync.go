@@ -23,6 +23,12 @@ type SyncWorker struct {
 	chanOsSignal           chan os.Signal
 	metrics                *common.Metrics
 	is                     *common.InternalState
+	// orphanedLower is the lowest height of the blocks disconnected since the last synchronization,
+	// the fate of their transactions is stored once the index is synchronized again
+	orphanedLower   uint32
+	orphanedPending bool
+	// lock serializes the synchronization of the index with the jobs run by RunExclusive
+	lock sync.Mutex
 }
 
 // NewSyncWorker creates new SyncWorker and returns its handle
@@ -50,11 +56,19 @@ var ErrOperationInterrupted = errors.New("ErrOperationInterrupted")
 
 // ResyncIndex synchronizes index to the top of the blockchain
 // onNewBlock is called when new block is connected, but not in initial parallel sync
-func (w *SyncWorker) ResyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync bool) error {
+// onReorg is called when blocks are disconnected by a reorg, before the new blocks are connected
+func (w *SyncWorker) ResyncIndex(onNewBlock bchain.OnNewBlockFunc, onReorg OnReorgFunc, initialSync bool) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
 	start := time.Now()
 	w.is.StartedSync()
 
-	err := w.resyncIndex(onNewBlock, initialSync)
+	err := w.resyncIndex(onNewBlock, onReorg, initialSync)
+	if err == nil || err == errSynced {
+		if ferr := w.storeOrphanedBlocksFate(); ferr != nil {
+			err = ferr
+		}
+	}
 
 	switch err {
 	case nil:
@@ -83,7 +97,7 @@ func (w *SyncWorker) ResyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync b
 	return err
 }
 
-func (w *SyncWorker) resyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync bool) error {
+func (w *SyncWorker) resyncIndex(onNewBlock bchain.OnNewBlockFunc, onReorg OnReorgFunc, initialSync bool) error {
 	remoteBestHash, err := w.chain.GetBestBlockHash()
 	if err != nil {
 		return err
@@ -106,7 +120,7 @@ func (w *SyncWorker) resyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync b
 		if remoteHash != localBestHash {
 			// forked - the remote hash differs from the local hash at the same height
 			glog.Info("resync: local is forked at height ", localBestHeight, ", local hash ", localBestHash, ", remote hash", remoteHash)
-			return w.handleFork(localBestHeight, localBestHash, onNewBlock, initialSync)
+			return w.handleFork(localBestHeight, localBestHash, onNewBlock, onReorg, initialSync)
 		}
 		glog.Info("resync: local at ", localBestHeight, " is behind")
 		w.startHeight = localBestHeight + 1
@@ -138,13 +152,13 @@ func (w *SyncWorker) resyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync b
 			}
 			// after parallel load finish the sync using standard way,
 			// new blocks may have been created in the meantime
-			return w.resyncIndex(onNewBlock, initialSync)
+			return w.resyncIndex(onNewBlock, onReorg, initialSync)
 		}
 	}
 	return w.connectBlocks(onNewBlock, initialSync)
 }
 
-func (w *SyncWorker) handleFork(localBestHeight uint32, localBestHash string, onNewBlock bchain.OnNewBlockFunc, initialSync bool) error {
+func (w *SyncWorker) handleFork(localBestHeight uint32, localBestHash string, onNewBlock bchain.OnNewBlockFunc, onReorg OnReorgFunc, initialSync bool) error {
 	// find forked blocks, disconnect them and then synchronize again
 	var height uint32
 	hashes := []string{localBestHash}
@@ -166,10 +180,67 @@ func (w *SyncWorker) handleFork(localBestHeight uint32, localBestHash string, on
 		}
 		hashes = append(hashes, local)
 	}
-	if err := w.DisconnectBlocks(height+1, localBestHeight, hashes); err != nil {
+	if err := w.RollbackBlocks(height+1, localBestHeight, hashes, OrphanedByReorg, onReorg); err != nil {
+		return err
+	}
+	return w.resyncIndex(onNewBlock, onReorg, initialSync)
+}
+
+// RollbackBlocks disconnects the blocks in range lower-higher with the hashes and passes them to onReorg,
+// the clients are notified the same way about the blocks disconnected by a fork and by a rollback
+func (w *SyncWorker) RollbackBlocks(lower uint32, higher uint32, hashes []string, cause OrphanedCause, onReorg OnReorgFunc) error {
+	if err := w.DisconnectBlocks(lower, higher, hashes, cause); err != nil {
 		return err
 	}
-	return w.resyncIndex(onNewBlock, initialSync)
+	if onReorg != nil {
+		w.notifyReorg(lower, higher, hashes, onReorg)
+	}
+	return nil
+}
+
+// notifyReorg passes the disconnected blocks with the hashes to onReorg
+func (w *SyncWorker) notifyReorg(lower uint32, higher uint32, hashes []string, onReorg OnReorgFunc) {
+	obs, err := w.db.GetOrphanedBlocks(lower, higher)
+	if err != nil {
+		glog.Error("GetOrphanedBlocks ", lower, "-", higher, ": ", err)
+		return
+	}
+	disconnected := make([]*OrphanedBlock, 0, len(hashes))
+	for _, ob := range obs {
+		for _, h := range hashes {
+			if ob.Hash == h {
+				disconnected = append(disconnected, ob)
+				break
+			}
+		}
+	}
+	if len(disconnected) > 0 {
+		onReorg(disconnected)
+	}
+}
+
+// storeOrphanedBlocksFate stores the fate of the transactions of the blocks disconnected since the last synchronization
+func (w *SyncWorker) storeOrphanedBlocksFate() error {
+	if !w.orphanedPending {
+		return nil
+	}
+	txids, err := w.chain.GetMempoolTransactions()
+	if err != nil {
+		return errors.Annotatef(err, "GetMempoolTransactions")
+	}
+	mempool := make(map[string]struct{}, len(txids))
+	for _, txid := range txids {
+		mempool[txid] = struct{}{}
+	}
+	err = w.db.StoreOrphanedBlocksFate(w.orphanedLower, func(txid string) bool {
+		_, found := mempool[txid]
+		return found
+	})
+	if err != nil {
+		return errors.Annotatef(err, "StoreOrphanedBlocksFate %v", w.orphanedLower)
+	}
+	w.orphanedPending = false
+	return nil
 }
 
 func (w *SyncWorker) connectBlocks(onNewBlock bchain.OnNewBlockFunc, initialSync bool) error {
@@ -400,14 +471,25 @@ func (w *SyncWorker) getBlockChain(out chan blockResult, done chan struct{}) {
 	}
 }
 
-// DisconnectBlocks removes all data belonging to blocks in range lower-higher,
-func (w *SyncWorker) DisconnectBlocks(lower uint32, higher uint32, hashes []string) error {
+// RunExclusive runs the job f when the index is not being synchronized, the synchronization waits until the job finishes
+func (w *SyncWorker) RunExclusive(f func() error) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return f()
+}
+
+// DisconnectBlocks removes all data belonging to blocks in range lower-higher, the blocks are stored as orphaned with the cause
+func (w *SyncWorker) DisconnectBlocks(lower uint32, higher uint32, hashes []string, cause OrphanedCause) error {
 	glog.Infof("sync: disconnecting blocks %d-%d", lower, higher)
+	if !w.orphanedPending || lower < w.orphanedLower {
+		w.orphanedLower = lower
+	}
+	w.orphanedPending = true
 	ct := w.chain.GetChainParser().GetChainType()
 	if ct == bchain.ChainBitcoinType {
-		return w.db.DisconnectBlockRangeBitcoinType(lower, higher)
+		return w.db.DisconnectBlockRangeBitcoinType(lower, higher, cause)
 	} else if ct == bchain.ChainEthereumType {
-		return w.db.DisconnectBlockRangeEthereumType(lower, higher)
+		return w.db.DisconnectBlockRangeEthereumType(lower, higher, cause)
 	}
 	return errors.New("Unknown chain type")
 }
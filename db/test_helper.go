@@ -15,5 +15,5 @@ func ConnectBlocks(w *SyncWorker, onNewBlock bchain.OnNewBlockFunc, initialSync
 }
 
 func HandleFork(w *SyncWorker, localBestHeight uint32, localBestHash string, onNewBlock bchain.OnNewBlockFunc, initialSync bool) error {
-	return w.handleFork(localBestHeight, localBestHash, onNewBlock, initialSync)
+	return w.handleFork(localBestHeight, localBestHash, onNewBlock, nil, initialSync)
 }
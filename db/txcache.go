@@ -4,6 +4,7 @@ import (
 	"blockbook/bchain"
 	"blockbook/bchain/coins/eth"
 	"blockbook/common"
+	"context"
 
 	"github.com/golang/glog"
 	"github.com/juju/errors"
@@ -34,18 +35,44 @@ func NewTxCache(db *RocksDB, chain bchain.BlockChain, metrics *common.Metrics, i
 	}, nil
 }
 
+// TracedTxCache is the handle to the cache tracing the reads of the request in ctx as spans, it refers to the shared cache
+type TracedTxCache struct {
+	*TxCache
+	db    *TracedDB
+	chain bchain.BlockChain
+}
+
+// Traced returns the handle to the cache reading from the db and the blockchain traced as spans of the request in ctx,
+// nil ctx means no tracing
+func (c *TxCache) Traced(ctx context.Context) *TracedTxCache {
+	if c == nil {
+		return nil
+	}
+	t := &TracedTxCache{TxCache: c, db: c.db.Traced(ctx), chain: c.chain}
+	if ctx != nil {
+		t.chain = bchain.BlockChainWithContext(c.chain, ctx)
+	}
+	return t
+}
+
 // GetTransaction returns transaction either from RocksDB or if not present from blockchain
 // it the transaction is confirmed, it is stored in the RocksDB
 func (c *TxCache) GetTransaction(txid string) (*bchain.Tx, int, error) {
-	var tx *bchain.Tx
+	return c.Traced(nil).GetTransaction(txid)
+}
+
+// GetTransaction returns the transaction the same way as TxCache.GetTransaction, traced as a span
+func (c *TracedTxCache) GetTransaction(txid string) (tx *bchain.Tx, height int, err error) {
+	_, span := common.StartSpan(c.db.ctx, "txcache.GetTransaction")
+	defer func() { common.EndSpan(span, err) }()
 	var h uint32
-	var err error
 	if c.enabled {
 		tx, h, err = c.db.GetTx(txid)
 		if err != nil {
 			return nil, 0, err
 		}
 		if tx != nil {
+			span.SetAttr(common.BoolAttr("hit", true))
 			// number of confirmations is not stored in cache, they change all the time
 			_, bestheight, _ := c.is.GetSyncState()
 			tx.Confirmations = bestheight - h + 1
@@ -57,6 +84,7 @@ func (c *TxCache) GetTransaction(txid string) (*bchain.Tx, int, error) {
 	if err != nil {
 		return nil, 0, err
 	}
+	span.SetAttr(common.BoolAttr("hit", false))
 	c.metrics.TxCacheEfficiency.With(common.Labels{"status": "miss"}).Inc()
 	// cache only confirmed transactions
 	if tx.Confirmations > 0 {
@@ -0,0 +1,352 @@
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/bchain"
+	"blockbook/common"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// error codes of the rejected requests
+const (
+	accessErrorUnauthorized      = "unauthorized"
+	accessErrorRateLimited       = "rate_limited"
+	accessErrorOrigin            = "origin_not_allowed"
+	accessErrorSubscriptionLimit = "subscription_limit"
+)
+
+// defaultEndpointCosts are the costs of the requests to the endpoints (the first part of the path after the optional api/v2/
+// or the websocket method), the endpoints which are not listed cost 1
+// the xpub requests scan many addresses, the address requests read the history of one address, the tx requests read one transaction
+var defaultEndpointCosts = map[string]float64{
+	"xpub":        10,
+	"addresses":   10,
+	"buildtx":     10,
+	"address":     3,
+	"utxo":        3,
+	"tx":          1,
+	"static":      0,
+	"favicon.ico": 0,
+}
+
+// bucketsCleanupPeriod is the period of removal of the idle token buckets
+const bucketsCleanupPeriod = time.Minute
+
+// RateLimit is the limit of the token bucket, Rate is the number of tokens (request costs) per second
+// and Burst the capacity of the bucket; zero Rate means unlimited access
+type RateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst float64 `json:"burst"`
+}
+
+// AccessConfig configures the access of the clients to the public interfaces
+type AccessConfig struct {
+	// APIKeys maps the api keys to their rate limits, the key is sent in the header X-API-Key or in the query parameter apikey
+	APIKeys map[string]*RateLimit `json:"api_keys"`
+	// RequireAPIKey rejects the requests without a valid api key
+	RequireAPIKey bool `json:"require_api_key"`
+	// Anonymous is the rate limit applied per IP address to the requests without api key
+	Anonymous RateLimit `json:"anonymous"`
+	// Costs override the default costs of the endpoints
+	Costs map[string]float64 `json:"costs"`
+	// AllowedOrigins of the websocket, socket.io and event stream connections, empty means all origins
+	AllowedOrigins []string `json:"allowed_origins"`
+	// MaxSubscribedAddresses per websocket connection or event stream, zero means unlimited
+	MaxSubscribedAddresses int `json:"max_subscribed_addresses"`
+	// TrustProxyHeaders takes the IP address of the client from the headers X-Real-Ip or X-Forwarded-For set by a reverse proxy
+	TrustProxyHeaders bool `json:"trust_proxy_headers"`
+}
+
+// LoadAccessConfig reads the access configuration from the json file
+func LoadAccessConfig(path string) (*AccessConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Annotatef(err, "ReadFile %v", path)
+	}
+	var config AccessConfig
+	if err = json.Unmarshal(data, &config); err != nil {
+		return nil, errors.Annotatef(err, "Unmarshal %v", path)
+	}
+	if config.RequireAPIKey && len(config.APIKeys) == 0 {
+		return nil, errors.New("api key is required but no api_keys are configured")
+	}
+	return &config, nil
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// accessClient is the identity of the client, its api key or its IP address, with its rate limit
+type accessClient struct {
+	id    string
+	limit *RateLimit
+}
+
+// accessLimiter checks the api keys, the rate limits and the origins of the requests to the public interfaces
+type accessLimiter struct {
+	config      *AccessConfig
+	chainParser bchain.BlockChainParser
+	metrics     *common.Metrics
+	origins     map[string]struct{}
+	buckets     map[string]*tokenBucket
+	bucketsLock sync.Mutex
+	lastCleanup time.Time
+}
+
+// newAccessLimiter creates the limiter, nil config means no limits
+func newAccessLimiter(config *AccessConfig, chainParser bchain.BlockChainParser, metrics *common.Metrics) *accessLimiter {
+	if config == nil {
+		config = &AccessConfig{}
+	}
+	l := &accessLimiter{
+		config:      config,
+		chainParser: chainParser,
+		metrics:     metrics,
+		buckets:     make(map[string]*tokenBucket),
+		lastCleanup: time.Now(),
+	}
+	if len(config.AllowedOrigins) > 0 {
+		l.origins = make(map[string]struct{})
+		for _, o := range config.AllowedOrigins {
+			l.origins[strings.TrimSuffix(o, "/")] = struct{}{}
+		}
+	}
+	return l
+}
+
+func (l *accessLimiter) reject(iface string, reason string) {
+	l.metrics.AccessRejections.With(common.Labels{"interface": iface, "reason": reason}).Inc()
+}
+
+// checkOrigin returns true if the origin of the request is allowed, the requests without origin (not from browsers) are always allowed
+func (l *accessLimiter) checkOrigin(r *http.Request) bool {
+	if l.origins == nil {
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if _, ok := l.origins["*"]; ok {
+		return true
+	}
+	_, ok := l.origins[origin]
+	return ok
+}
+
+// clientIP returns the IP address of the client without the port
+func (l *accessLimiter) clientIP(r *http.Request) string {
+	if l.config.TrustProxyHeaders {
+		if ip := r.Header.Get("X-Real-Ip"); ip != "" {
+			return ip
+		}
+		if f := r.Header.Get("X-Forwarded-For"); f != "" {
+			return strings.TrimSpace(strings.Split(f, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// client identifies the client by the api key or by the IP address, returns nil if the key is invalid or missing and it is required
+func (l *accessLimiter) client(apiKey string, ip string) *accessClient {
+	if apiKey != "" {
+		limit, ok := l.config.APIKeys[apiKey]
+		if !ok {
+			return nil
+		}
+		if limit == nil {
+			limit = &RateLimit{}
+		}
+		return &accessClient{id: "key:" + apiKey, limit: limit}
+	}
+	if l.config.RequireAPIKey {
+		return nil
+	}
+	return &accessClient{id: "ip:" + ip, limit: &l.config.Anonymous}
+}
+
+func (l *accessLimiter) requestClient(r *http.Request) *accessClient {
+	apiKey := r.Header.Get("X-API-Key")
+	if apiKey == "" {
+		apiKey = r.URL.Query().Get("apikey")
+	}
+	return l.client(apiKey, l.clientIP(r))
+}
+
+// socketioClient identifies the client of the socket.io message, the connection was already authorized by the http handler
+// the api key sent in the query parameter is not available to the messages, such clients are limited by the IP address
+func (l *accessLimiter) socketioClient(apiKey string, ip string) *accessClient {
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	if c := l.client(apiKey, ip); c != nil {
+		return c
+	}
+	return &accessClient{id: "ip:" + ip, limit: &l.config.Anonymous}
+}
+
+// take consumes the cost from the token bucket of the client and returns true if the request is allowed
+// a request costing more than the burst is allowed if the bucket is full, the bucket then gets into debt
+func (l *accessLimiter) take(c *accessClient, cost float64) bool {
+	if c.limit.Rate <= 0 || cost <= 0 {
+		return true
+	}
+	burst := c.limit.Burst
+	if burst < c.limit.Rate {
+		burst = c.limit.Rate
+	}
+	now := time.Now()
+	l.bucketsLock.Lock()
+	defer l.bucketsLock.Unlock()
+	if now.Sub(l.lastCleanup) > bucketsCleanupPeriod {
+		l.cleanupBuckets(now)
+	}
+	b, ok := l.buckets[c.id]
+	if !ok {
+		b = &tokenBucket{tokens: burst, last: now}
+		l.buckets[c.id] = b
+	} else {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*c.limit.Rate)
+		b.last = now
+	}
+	if b.tokens < math.Min(cost, burst) {
+		return false
+	}
+	b.tokens -= cost
+	return true
+}
+
+// cleanupBuckets removes the buckets idle long enough to be refilled, they would be created full again, must be called with the lock held
+func (l *accessLimiter) cleanupBuckets(now time.Time) {
+	for id, b := range l.buckets {
+		limit := &l.config.Anonymous
+		if strings.HasPrefix(id, "key:") {
+			if kl := l.config.APIKeys[id[4:]]; kl != nil {
+				limit = kl
+			}
+		}
+		burst := math.Max(limit.Burst, limit.Rate)
+		if limit.Rate <= 0 || b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= burst {
+			delete(l.buckets, id)
+		}
+	}
+	l.lastCleanup = now
+}
+
+// cost returns the cost of the request to the endpoint
+func (l *accessLimiter) cost(endpoint string) float64 {
+	if c, ok := l.config.Costs[endpoint]; ok {
+		return c
+	}
+	if c, ok := defaultEndpointCosts[endpoint]; ok {
+		return c
+	}
+	return 1
+}
+
+// descriptorCost returns the cost of the address endpoint if the descriptor is an address, otherwise the cost of the xpub endpoint
+func (l *accessLimiter) descriptorCost(descriptor string) float64 {
+	if _, err := l.chainParser.GetAddrDescFromAddress(descriptor); err == nil {
+		return l.cost("address")
+	}
+	return l.cost("xpub")
+}
+
+// requestCost returns the cost of the http request, path is the path of the request without the binding path
+func (l *accessLimiter) requestCost(path string) float64 {
+	path = strings.TrimPrefix(path, "api/")
+	if strings.HasPrefix(path, "v1/") || strings.HasPrefix(path, "v2/") {
+		path = path[3:]
+	}
+	endpoint, param := path, ""
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		endpoint, param = path[:i], path[i+1:]
+	}
+	if endpoint == "utxo" && param != "" {
+		return l.descriptorCost(param)
+	}
+	return l.cost(endpoint)
+}
+
+// websocketCost returns the cost of the websocket request
+func (l *accessLimiter) websocketCost(req *websocketReq) float64 {
+	switch req.Method {
+	case "getAccountInfo", "getAccountUtxo":
+		r := struct {
+			Descriptor string `json:"descriptor"`
+		}{}
+		if err := json.Unmarshal(req.Params, &r); err == nil && r.Descriptor != "" {
+			return l.descriptorCost(r.Descriptor)
+		}
+	case "getAddresses":
+		return l.cost("addresses")
+	case "getTransaction", "getTransactionSpecific", "getTransactionProof":
+		return l.cost("tx")
+	}
+	return l.cost(req.Method)
+}
+
+// socketioCost returns the cost of the socket.io request
+func (l *accessLimiter) socketioCost(method string) float64 {
+	switch method {
+	case "getAddressTxids", "getAddressHistory":
+		return l.cost("address")
+	case "getDetailedTransaction":
+		return l.cost("tx")
+	}
+	return l.cost(method)
+}
+
+// writeAccessError writes the json error in the same format as the api errors
+func writeAccessError(w http.ResponseWriter, status int, text string, code string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Text string `json:"error"`
+		Code string `json:"code"`
+	}{text, code})
+}
+
+// handler checks the api key, the rate limit and for the socket.io, websocket and event stream connections the origin of the http requests
+func (l *accessLimiter) handler(path string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := strings.TrimPrefix(r.URL.Path, path)
+		if (strings.HasPrefix(p, "socket.io/") || p == "websocket" || strings.HasPrefix(p, "api/v2/events/")) && !l.checkOrigin(r) {
+			l.reject("http", accessErrorOrigin)
+			writeAccessError(w, http.StatusForbidden, "Origin not allowed", accessErrorOrigin)
+			return
+		}
+		c := l.requestClient(r)
+		if c == nil {
+			l.reject("http", accessErrorUnauthorized)
+			writeAccessError(w, http.StatusUnauthorized, "Missing or invalid API key", accessErrorUnauthorized)
+			return
+		}
+		if !l.take(c, l.requestCost(p)) {
+			l.reject("http", accessErrorRateLimited)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(l.requestCost(p)/c.limit.Rate))))
+			writeAccessError(w, http.StatusTooManyRequests, "Rate limit exceeded", accessErrorRateLimited)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// errRateLimited is returned to the websocket and socket.io requests over the rate limit
+var errRateLimited = api.NewAPIErrorWithCode("Rate limit exceeded", accessErrorRateLimited)
@@ -0,0 +1,115 @@
+// +build unittest
+
+package server
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_accessLimiter_take(t *testing.T) {
+	l := newAccessLimiter(&AccessConfig{Anonymous: RateLimit{Rate: 10, Burst: 20}}, nil, nil)
+	c := l.client("", "192.0.2.1")
+	// the bucket starts full
+	for i := 0; i < 20; i++ {
+		if !l.take(c, 1) {
+			t.Fatalf("take() %d = false, want true", i)
+		}
+	}
+	if l.take(c, 1) {
+		t.Error("take() over burst = true, want false")
+	}
+	// refill 0.5s*10 tokens
+	l.buckets[c.id].last = l.buckets[c.id].last.Add(-500 * time.Millisecond)
+	if !l.take(c, 5) {
+		t.Error("take() after refill = false, want true")
+	}
+	if l.take(c, 1) {
+		t.Error("take() after refill over burst = true, want false")
+	}
+	// the request more expensive than the burst is allowed with full bucket and leaves the bucket in debt
+	l.buckets[c.id].last = l.buckets[c.id].last.Add(-10 * time.Second)
+	if !l.take(c, 30) {
+		t.Error("take() of expensive request = false, want true")
+	}
+	if got := l.buckets[c.id].tokens; got != -10 {
+		t.Errorf("tokens = %v, want -10", got)
+	}
+	// other client has its own bucket
+	if !l.take(l.client("", "192.0.2.2"), 1) {
+		t.Error("take() of other client = false, want true")
+	}
+}
+
+func Test_accessLimiter_client(t *testing.T) {
+	config := &AccessConfig{
+		APIKeys:       map[string]*RateLimit{"key1": {Rate: 100}, "key2": nil},
+		RequireAPIKey: true,
+	}
+	l := newAccessLimiter(config, nil, nil)
+	if c := l.client("key1", "192.0.2.1"); c == nil || c.id != "key:key1" || c.limit.Rate != 100 {
+		t.Errorf("client(key1) = %+v", c)
+	}
+	if c := l.client("key2", "192.0.2.1"); c == nil || c.limit.Rate != 0 {
+		t.Errorf("client(key2) = %+v", c)
+	}
+	if c := l.client("unknown", "192.0.2.1"); c != nil {
+		t.Errorf("client(unknown) = %+v, want nil", c)
+	}
+	if c := l.client("", "192.0.2.1"); c != nil {
+		t.Errorf("client() without required key = %+v, want nil", c)
+	}
+	config.RequireAPIKey = false
+	if c := l.client("", "192.0.2.1"); c == nil || c.id != "ip:192.0.2.1" {
+		t.Errorf("client() = %+v", c)
+	}
+}
+
+func Test_accessLimiter_requestCost(t *testing.T) {
+	l := newAccessLimiter(&AccessConfig{Costs: map[string]float64{"block": 2}}, nil, nil)
+	tests := []struct {
+		path string
+		want float64
+	}{
+		{"api/v2/xpub/xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj", 10},
+		{"xpub/xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj", 10},
+		{"api/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw", 3},
+		{"api/v2/tx/7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25", 1},
+		{"api/v2/block/1", 2},
+		{"static/css/main.css", 0},
+		{"api/", 1},
+		{"", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := l.requestCost(tt.path); got != tt.want {
+				t.Errorf("requestCost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_accessLimiter_checkOrigin(t *testing.T) {
+	l := newAccessLimiter(&AccessConfig{AllowedOrigins: []string{"https://wallet.example.com/"}}, nil, nil)
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://wallet.example.com", true},
+		{"https://evil.example.com", false},
+		{"", true},
+	}
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", "http://localhost/websocket", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := l.checkOrigin(r); got != tt.want {
+			t.Errorf("checkOrigin(%v) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+	if !newAccessLimiter(nil, nil, nil).checkOrigin(&http.Request{Header: http.Header{"Origin": []string{"https://any.example.com"}}}) {
+		t.Error("checkOrigin() without allowed origins = false, want true")
+	}
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"blockbook/common"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// AccessLog writes one JSON line for each request of the REST API and of the explorer
+type AccessLog struct {
+	file *os.File
+	lock sync.Mutex
+}
+
+// NewAccessLog opens the access log file, the entries are appended to the existing file
+func NewAccessLog(path string) (*AccessLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Annotatef(err, "OpenFile %v", path)
+	}
+	return &AccessLog{file: f}, nil
+}
+
+// Close closes the access log file
+func (l *AccessLog) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.file.Close()
+}
+
+// accessLogEntry is one line of the access log, the parameters of the request (addresses, xpubs)
+// are not logged, only their hash which is the same for the same request
+type accessLogEntry struct {
+	Time       string  `json:"time"`
+	IP         string  `json:"ip"`
+	Method     string  `json:"method"`
+	Route      string  `json:"route"`
+	ParamsHash string  `json:"paramsHash"`
+	Status     int     `json:"status"`
+	Size       int64   `json:"size"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+func (l *AccessLog) write(e *accessLogEntry) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		glog.Error("access log: ", err)
+		return
+	}
+	b = append(b, '\n')
+	l.lock.Lock()
+	_, err = l.file.Write(b)
+	l.lock.Unlock()
+	if err != nil {
+		glog.Error("access log: ", err)
+	}
+}
+
+// paramsHash identifies the path and the query parameters of the request except the api key
+func paramsHash(r *http.Request) string {
+	q := r.URL.Query()
+	q.Del("apikey")
+	h := sha256.Sum256([]byte(r.URL.Path + "?" + q.Encode()))
+	return hex.EncodeToString(h[:8])
+}
+
+// handlerRoute returns the name of the handler function, it is the route label of the http metrics and of the access log
+func handlerRoute(handler interface{}) string {
+	n := strings.TrimSuffix(getFunctionName(handler), "-fm")
+	if i := strings.LastIndexByte(n, '.'); i >= 0 {
+		n = n[i+1:]
+	}
+	return n
+}
+
+// responseRecorder records the status code and the size of the response
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *responseRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+// Flush sends the buffered data to the client, it is used by the csv export
+func (w *responseRecorder) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// observeHandler counts the requests served by the handler by route and status, observes their duration and response size
+// and writes them to the access log
+func (s *PublicServer) observeHandler(route string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// the request is already observed by an outer handler, e.g. the error of the csv export is returned by jsonHandler
+		if _, ok := w.(*responseRecorder); ok {
+			handler(w, r)
+			return
+		}
+		start := time.Now()
+		rw := &responseRecorder{ResponseWriter: w}
+		handler(rw, r)
+		duration := float64(time.Since(start)) / float64(time.Millisecond)
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		s.metrics.HTTPRequests.With(common.Labels{"route": route, "status": strconv.Itoa(status)}).Inc()
+		s.metrics.HTTPReqDuration.With(common.Labels{"route": route}).Observe(duration)
+		s.metrics.HTTPResponseSize.With(common.Labels{"route": route}).Observe(float64(rw.size))
+		if s.accessLog != nil {
+			s.accessLog.write(&accessLogEntry{
+				Time:       start.UTC().Format(time.RFC3339Nano),
+				IP:         s.limiter.clientIP(r),
+				Method:     r.Method,
+				Route:      route,
+				ParamsHash: paramsHash(r),
+				Status:     status,
+				Size:       rw.size,
+				DurationMs: duration,
+			})
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// +build unittest
+
+package server
+
+import (
+	"blockbook/common"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func Test_paramsHash(t *testing.T) {
+	r1 := httptest.NewRequest("GET", "/api/v2/xpub/xpub6CUGRUo?details=txs&apikey=key1", nil)
+	r2 := httptest.NewRequest("GET", "/api/v2/xpub/xpub6CUGRUo?apikey=key2&details=txs", nil)
+	r3 := httptest.NewRequest("GET", "/api/v2/xpub/xpub6CUGRUo?details=basic", nil)
+	h1 := paramsHash(r1)
+	if len(h1) != 16 {
+		t.Errorf("paramsHash() = %v, want 16 hex characters", h1)
+	}
+	if h2 := paramsHash(r2); h1 != h2 {
+		t.Errorf("paramsHash() = %v, %v, want the same hash without the api key", h1, h2)
+	}
+	if h3 := paramsHash(r3); h1 == h3 {
+		t.Errorf("paramsHash() = %v, want different hash for different parameters", h3)
+	}
+}
+
+func Test_handlerRoute(t *testing.T) {
+	s := &PublicServer{}
+	if r := handlerRoute(s.apiXpub); r != "apiXpub" {
+		t.Errorf("handlerRoute() = %v, want apiXpub", r)
+	}
+	if r := handlerRoute(paramsHash); r != "paramsHash" {
+		t.Errorf("handlerRoute() = %v, want paramsHash", r)
+	}
+}
+
+func Test_observeHandler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "accesslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	al, err := NewAccessLog(filepath.Join(dir, "access.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the collectors are not registered, the test does not interfere with the metrics of the public server tests
+	metrics := &common.Metrics{
+		HTTPRequests:     prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_requests"}, []string{"route", "status"}),
+		HTTPReqDuration:  prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_duration"}, []string{"route"}),
+		HTTPResponseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_size"}, []string{"route"}),
+	}
+	s := &PublicServer{
+		metrics:   metrics,
+		limiter:   newAccessLimiter(&AccessConfig{TrustProxyHeaders: true}, nil, metrics),
+		accessLog: al,
+	}
+	h := s.observeHandler("apiTest", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"test"}`))
+	})
+	r := httptest.NewRequest("GET", "/api/v2/test/abc?page=2", nil)
+	r.Header.Set("X-Real-Ip", "10.0.0.1")
+	w := httptest.NewRecorder()
+	h(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %v, want %v", w.Code, http.StatusBadRequest)
+	}
+	if err := al.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "access.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e accessLogEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		t.Fatal(err, string(b))
+	}
+	if e.IP != "10.0.0.1" || e.Method != "GET" || e.Route != "apiTest" || e.Status != http.StatusBadRequest || e.Size != 16 ||
+		e.ParamsHash != paramsHash(r) || e.Time == "" || e.DurationMs < 0 {
+		t.Errorf("access log entry = %+v", e)
+	}
+}
@@ -0,0 +1,552 @@
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/bchain"
+	"blockbook/common"
+	"blockbook/db"
+	"crypto/subtle"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// types of the admin jobs
+// the repair of the db is not a job, RocksDB repairs only the closed db and the db is used by all servers while Blockbook runs
+const (
+	adminJobRollback    = "rollback"
+	adminJobColumnStats = "columnstats"
+	adminJobFeeStats    = "feestats"
+	adminJobMempool     = "mempool"
+	adminJobCompact     = "compact"
+	adminJobRepair      = "repair"
+)
+
+// states of the admin jobs
+const (
+	adminJobQueued    = "queued"
+	adminJobRunning   = "running"
+	adminJobFinished  = "finished"
+	adminJobFailed    = "failed"
+	adminJobCancelled = "cancelled"
+)
+
+const (
+	adminJobsQueueSize    = 16
+	adminJobsHistorySize  = 100
+	adminMempoolTimeout   = 5 * time.Minute
+	adminMempoolCheckTime = 100 * time.Millisecond
+)
+
+// AdminJobRequest is the job sent to the admin api of the internal server
+type AdminJobRequest struct {
+	Type string `json:"type"`
+	// Height is the height to which the rollback job disconnects the blocks, the block at Height is disconnected too
+	Height *int `json:"height,omitempty"`
+	// From and To is the range of blocks of the feestats job
+	From *int `json:"from,omitempty"`
+	To   *int `json:"to,omitempty"`
+	// Column is the db column of the compact job
+	Column string `json:"column,omitempty"`
+}
+
+// AdminJob is the state of the job run by the admin api, Done of Total is its progress
+type AdminJob struct {
+	ID       string          `json:"id"`
+	Request  AdminJobRequest `json:"request"`
+	State    string          `json:"state"`
+	Done     int             `json:"done"`
+	Total    int             `json:"total"`
+	Result   string          `json:"result,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Created  time.Time       `json:"created"`
+	Started  *time.Time      `json:"started,omitempty"`
+	Finished *time.Time      `json:"finished,omitempty"`
+}
+
+type adminJob struct {
+	AdminJob
+	run func(j *adminJob) error
+	// concurrent job does not need the index and runs also during its synchronization
+	concurrent bool
+	// stop interrupts the job, nil if the job cannot be interrupted
+	stop      chan os.Signal
+	stopClose sync.Once
+}
+
+func (j *adminJob) interrupt() {
+	j.stopClose.Do(func() { close(j.stop) })
+}
+
+func (j *adminJob) interrupted() bool {
+	if j.stop == nil {
+		return false
+	}
+	select {
+	case <-j.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// adminJobs runs the maintenance jobs of the admin api one by one, never concurrently with the synchronization of the index
+// except the concurrent jobs
+type adminJobs struct {
+	db          *db.RocksDB
+	api         *api.Worker
+	is          *common.InternalState
+	syncWorker  *db.SyncWorker
+	pushHandler func(bchain.NotificationType)
+	onReorg     db.OnReorgFunc
+	metrics     *common.Metrics
+	keys        [][]byte
+	lock        sync.Mutex
+	jobs        []*adminJob
+	lastID      uint64
+	closed      bool
+	queue       chan *adminJob
+	done        chan struct{}
+}
+
+// LoadAdminKeys reads the keys authorizing the requests to the admin api, one key per line, the lines starting with # are ignored
+func LoadAdminKeys(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Annotatef(err, "ReadFile %v", path)
+	}
+	var keys []string
+	for _, l := range strings.Split(string(data), "\n") {
+		l = strings.TrimSpace(l)
+		if l != "" && !strings.HasPrefix(l, "#") {
+			keys = append(keys, l)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.Errorf("No admin keys in %v", path)
+	}
+	return keys, nil
+}
+
+// newAdminJobs creates the job scheduler, no keys mean that the admin api is disabled
+// pushHandler is used to request the synchronization of the index and of the mempool, onReorg is notified about the rolled back blocks
+func newAdminJobs(d *db.RocksDB, w *api.Worker, is *common.InternalState, syncWorker *db.SyncWorker, pushHandler func(bchain.NotificationType),
+	onReorg db.OnReorgFunc, metrics *common.Metrics, keys []string) *adminJobs {
+	a := &adminJobs{
+		db:          d,
+		api:         w,
+		is:          is,
+		syncWorker:  syncWorker,
+		pushHandler: pushHandler,
+		onReorg:     onReorg,
+		metrics:     metrics,
+		queue:       make(chan *adminJob, adminJobsQueueSize),
+		done:        make(chan struct{}),
+	}
+	for _, k := range keys {
+		a.keys = append(a.keys, []byte(k))
+	}
+	go a.runJobs()
+	return a
+}
+
+func (a *adminJobs) enabled() bool {
+	return len(a.keys) > 0 && a.syncWorker != nil
+}
+
+// authorized checks the admin key sent in the header X-API-Key
+func (a *adminJobs) authorized(r *http.Request) bool {
+	key := []byte(r.Header.Get("X-API-Key"))
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare(key, k) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// Close cancels the queued jobs, interrupts the running job if possible and waits until it finishes
+func (a *adminJobs) Close() {
+	a.lock.Lock()
+	if a.closed {
+		a.lock.Unlock()
+		return
+	}
+	a.closed = true
+	for _, j := range a.jobs {
+		if j.State == adminJobQueued {
+			a.finish(j, adminJobCancelled, "")
+		} else if j.State == adminJobRunning && j.stop != nil {
+			j.interrupt()
+		}
+	}
+	close(a.queue)
+	a.lock.Unlock()
+	<-a.done
+}
+
+func (a *adminJobs) runJobs() {
+	defer close(a.done)
+	for j := range a.queue {
+		a.runJob(j)
+	}
+}
+
+func (a *adminJobs) runJob(j *adminJob) {
+	a.lock.Lock()
+	cancelled := j.State != adminJobQueued
+	a.lock.Unlock()
+	if cancelled {
+		return
+	}
+	started := false
+	run := func() error {
+		a.lock.Lock()
+		if j.State != adminJobQueued {
+			a.lock.Unlock()
+			return nil
+		}
+		now := time.Now()
+		j.State = adminJobRunning
+		j.Started = &now
+		a.lock.Unlock()
+		started = true
+		glog.Info("admin: job ", j.ID, " ", j.Request.Type, " started")
+		return j.run(j)
+	}
+	var err error
+	if j.concurrent {
+		err = run()
+	} else {
+		err = a.syncWorker.RunExclusive(run)
+	}
+	if !started {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	switch {
+	case err == nil:
+		a.finish(j, adminJobFinished, "")
+	case j.interrupted():
+		a.finish(j, adminJobCancelled, "")
+	default:
+		a.finish(j, adminJobFailed, err.Error())
+	}
+}
+
+// finish sets the final state of the job, must be called with the lock held
+func (a *adminJobs) finish(j *adminJob, state string, e string) {
+	now := time.Now()
+	j.State = state
+	j.Error = e
+	j.Finished = &now
+	a.metrics.AdminJobs.With(common.Labels{"type": j.Request.Type, "state": state}).Inc()
+	if e != "" {
+		glog.Error("admin: job ", j.ID, " ", j.Request.Type, " ", state, ": ", e)
+	} else {
+		glog.Info("admin: job ", j.ID, " ", j.Request.Type, " ", state)
+	}
+}
+
+func (a *adminJobs) progress(j *adminJob, done, total int) {
+	a.lock.Lock()
+	j.Done, j.Total = done, total
+	a.lock.Unlock()
+}
+
+func (a *adminJobs) result(j *adminJob, result string) {
+	a.lock.Lock()
+	j.Result = result
+	a.lock.Unlock()
+}
+
+// Add validates the request and queues the job
+func (a *adminJobs) Add(req *AdminJobRequest) (*AdminJob, error) {
+	j := &adminJob{}
+	switch req.Type {
+	case adminJobRollback:
+		if req.Height == nil || *req.Height < 1 {
+			return nil, api.NewAPIError("Missing or invalid height", true)
+		}
+		j.run = a.rollback
+	case adminJobColumnStats:
+		j.run = a.columnStats
+		j.stop = make(chan os.Signal)
+	case adminJobFeeStats:
+		if req.From == nil || req.To == nil || *req.From < 0 || *req.From > *req.To {
+			return nil, api.NewAPIError("Missing or invalid range from-to", true)
+		}
+		bestHeight, _, err := a.db.GetBestBlock()
+		if err != nil {
+			return nil, err
+		}
+		if *req.To > int(bestHeight) {
+			return nil, api.NewAPIError("Block range is above the best block "+strconv.Itoa(int(bestHeight)), true)
+		}
+		j.run = a.feeStats
+		j.stop = make(chan os.Signal)
+	case adminJobMempool:
+		// the mempool is synchronized independently of the index, holding the index for the wait would only block its synchronization
+		j.run = a.mempoolResync
+		j.stop = make(chan os.Signal)
+		j.concurrent = true
+	case adminJobCompact:
+		found := false
+		for _, c := range a.db.ColumnNames() {
+			if c == req.Column {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, api.NewAPIError("Unknown column "+req.Column, true)
+		}
+		j.run = a.compact
+	case adminJobRepair:
+		return nil, api.NewAPIError("Repair of the database is possible only at the startup with the parameter -repair", true)
+	default:
+		return nil, api.NewAPIError("Unknown job type "+req.Type, true)
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.closed {
+		return nil, api.NewAPIError("Server is shutting down", true)
+	}
+	if len(a.queue) == cap(a.queue) {
+		return nil, api.NewAPIError("Too many queued jobs", true)
+	}
+	a.lastID++
+	j.ID = strconv.FormatUint(a.lastID, 10)
+	j.Request = *req
+	j.State = adminJobQueued
+	j.Created = time.Now()
+	a.addToHistory(j)
+	a.queue <- j
+	glog.Info("admin: job ", j.ID, " ", j.Request.Type, " queued")
+	rv := j.AdminJob
+	return &rv, nil
+}
+
+// addToHistory appends the job to the list of jobs and removes the oldest finished jobs over adminJobsHistorySize
+func (a *adminJobs) addToHistory(j *adminJob) {
+	a.jobs = append(a.jobs, j)
+	for i := 0; len(a.jobs) > adminJobsHistorySize && i < len(a.jobs); {
+		if a.jobs[i].Finished != nil {
+			a.jobs = append(a.jobs[:i], a.jobs[i+1:]...)
+		} else {
+			i++
+		}
+	}
+}
+
+// Jobs returns the state of the jobs in the order of their creation
+func (a *adminJobs) Jobs() []AdminJob {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	rv := make([]AdminJob, len(a.jobs))
+	for i, j := range a.jobs {
+		rv[i] = j.AdminJob
+	}
+	return rv
+}
+
+func (a *adminJobs) job(id string) (*adminJob, error) {
+	for _, j := range a.jobs {
+		if j.ID == id {
+			return j, nil
+		}
+	}
+	return nil, api.NewAPIErrorWithCode("Job not found", errorCodeNotFound)
+}
+
+// Job returns the state of the job
+func (a *adminJobs) Job(id string) (*AdminJob, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	j, err := a.job(id)
+	if err != nil {
+		return nil, err
+	}
+	rv := j.AdminJob
+	return &rv, nil
+}
+
+// Cancel cancels the queued job or interrupts the running job
+func (a *adminJobs) Cancel(id string) (*AdminJob, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	j, err := a.job(id)
+	if err != nil {
+		return nil, err
+	}
+	switch j.State {
+	case adminJobQueued:
+		a.finish(j, adminJobCancelled, "")
+	case adminJobRunning:
+		if j.stop == nil {
+			return nil, api.NewAPIError("Job "+j.Request.Type+" cannot be cancelled", true)
+		}
+		j.interrupt()
+	default:
+		return nil, api.NewAPIError("Job is not queued or running", true)
+	}
+	rv := j.AdminJob
+	return &rv, nil
+}
+
+// rollback disconnects the blocks from the requested height to the best block, the index is then synchronized again
+// the disconnected blocks are stored as orphaned and the clients are notified about them as about a reorg
+func (a *adminJobs) rollback(j *adminJob) error {
+	height := uint32(*j.Request.Height)
+	bestHeight, bestHash, err := a.db.GetBestBlock()
+	if err != nil {
+		return err
+	}
+	if height > bestHeight {
+		a.result(j, "Nothing to rollback, best height "+strconv.Itoa(int(bestHeight)))
+		return nil
+	}
+	total := int(bestHeight-height) + 1
+	a.progress(j, 0, total)
+	hashes := []string{bestHash}
+	for h := bestHeight - 1; h >= height; h-- {
+		hash, err := a.db.GetBlockHash(h)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, hash)
+	}
+	if err = a.syncWorker.RollbackBlocks(height, bestHeight, hashes, db.OrphanedByRollback, a.onReorg); err != nil {
+		return err
+	}
+	a.is.UpdateBestHeight(height - 1)
+	a.progress(j, total, total)
+	// the synchronization waits for the end of the job, request it asynchronously
+	go a.pushHandler(bchain.NotificationNewBlock)
+	return nil
+}
+
+// columnStats recomputes the stats of all db columns
+func (a *adminJobs) columnStats(j *adminJob) error {
+	columns := a.db.ColumnNames()
+	for c := range columns {
+		a.progress(j, c, len(columns))
+		if err := a.db.ComputeColumnStats(c, j.stop); err != nil {
+			return err
+		}
+	}
+	a.progress(j, len(columns), len(columns))
+	return nil
+}
+
+// feeStats recomputes the fee stats of the blocks in the range from-to
+func (a *adminJobs) feeStats(j *adminJob) error {
+	from, to := *j.Request.From, *j.Request.To
+	total := to - from + 1
+	for block := from; block <= to; block++ {
+		a.progress(j, block-from, total)
+		if err := a.api.ComputeFeeStats(block, block, j.stop); err != nil {
+			return err
+		}
+	}
+	a.progress(j, total, total)
+	return nil
+}
+
+// mempoolResync requests the resynchronization of the mempool and waits until it finishes
+func (a *adminJobs) mempoolResync(j *adminJob) error {
+	a.progress(j, 0, 1)
+	_, lastSync, _ := a.is.GetMempoolSyncState()
+	go a.pushHandler(bchain.NotificationNewTx)
+	timeout := time.After(adminMempoolTimeout)
+	tick := time.NewTicker(adminMempoolCheckTime)
+	defer tick.Stop()
+	for {
+		select {
+		case <-j.stop:
+			return db.ErrOperationInterrupted
+		case <-timeout:
+			return errors.New("Mempool resync timeout")
+		case <-tick.C:
+			if synced, last, size := a.is.GetMempoolSyncState(); synced && last.After(lastSync) {
+				a.progress(j, 1, 1)
+				a.result(j, "Mempool size "+strconv.Itoa(size))
+				return nil
+			}
+		}
+	}
+}
+
+// compact runs the manual compaction of the db column
+func (a *adminJobs) compact(j *adminJob) error {
+	a.progress(j, 0, 1)
+	if err := a.db.CompactColumn(j.Request.Column); err != nil {
+		return err
+	}
+	a.progress(j, 1, 1)
+	return nil
+}
+
+// adminHandler checks the admin key of the requests to the admin api
+func (s *InternalServer) adminHandler(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var status int
+		var text string
+		if !s.admin.enabled() {
+			status, text = http.StatusForbidden, "Admin api is disabled"
+		} else if !s.admin.authorized(r) {
+			status, text = http.StatusUnauthorized, "Unauthorized"
+		} else {
+			handler(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(internalError{Text: text}); err != nil {
+			glog.Error(err)
+		}
+	}
+}
+
+// adminJobsHandler lists the jobs (GET) or queues a new job (POST)
+func (s *InternalServer) adminJobsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, s.admin.Jobs(), nil)
+	case http.MethodPost:
+		var req AdminJobRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSON(w, nil, api.NewAPIError("Invalid request, "+err.Error(), true))
+			return
+		}
+		j, err := s.admin.Add(&req)
+		s.writeJSON(w, j, err)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// adminJobHandler returns (GET) or cancels (DELETE) the job api/admin/jobs/<id>
+func (s *InternalServer) adminJobHandler(w http.ResponseWriter, r *http.Request) {
+	i := strings.Index(r.URL.Path, "api/admin/jobs/")
+	id := r.URL.Path[i+len("api/admin/jobs/"):]
+	switch r.Method {
+	case http.MethodGet:
+		j, err := s.admin.Job(id)
+		s.writeJSON(w, j, err)
+	case http.MethodDelete:
+		j, err := s.admin.Cancel(id)
+		s.writeJSON(w, j, err)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
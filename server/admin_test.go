@@ -0,0 +1,146 @@
+// +build unittest
+
+package server
+
+import (
+	"blockbook/bchain"
+	"blockbook/common"
+	"blockbook/db"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newTestAdminJobs(t *testing.T, pushHandler func(bchain.NotificationType), keys []string) (*adminJobs, *db.SyncWorker, *common.InternalState) {
+	is := &common.InternalState{}
+	syncWorker, err := db.NewSyncWorker(nil, nil, 1, 1, 0, false, nil, nil, is)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the collector is not registered, the test does not interfere with the metrics of the public server tests
+	metrics := &common.Metrics{
+		AdminJobs: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_admin_jobs"}, []string{"type", "state"}),
+	}
+	return newAdminJobs(nil, nil, is, syncWorker, pushHandler, nil, metrics, keys), syncWorker, is
+}
+
+func waitForAdminJob(t *testing.T, a *adminJobs, id string, state string) *AdminJob {
+	for i := 0; i < 100; i++ {
+		j, err := a.Job(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if j.State == state {
+			return j
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("job %v did not reach state %v", id, state)
+	return nil
+}
+
+func Test_adminJobs_Add(t *testing.T) {
+	a, _, _ := newTestAdminJobs(t, func(bchain.NotificationType) {}, nil)
+	defer a.Close()
+	height := 0
+	for _, req := range []AdminJobRequest{
+		{Type: "unknown"},
+		{Type: adminJobRollback},
+		{Type: adminJobRollback, Height: &height},
+		{Type: adminJobFeeStats},
+		{Type: adminJobFeeStats, From: &height},
+		{Type: adminJobRepair},
+	} {
+		if _, err := a.Add(&req); err == nil {
+			t.Errorf("Add(%+v) expected error", req)
+		}
+	}
+	if j := a.Jobs(); len(j) != 0 {
+		t.Errorf("Jobs() = %+v, want no jobs", j)
+	}
+}
+
+func Test_adminJobs_mempool(t *testing.T) {
+	pushed := make(chan bchain.NotificationType, 1)
+	a, syncWorker, is := newTestAdminJobs(t, func(nt bchain.NotificationType) { pushed <- nt }, nil)
+	defer a.Close()
+
+	// the job does not wait until the synchronization of the index finishes
+	release := make(chan struct{})
+	locked := make(chan struct{})
+	go syncWorker.RunExclusive(func() error {
+		close(locked)
+		<-release
+		return nil
+	})
+	<-locked
+	j, err := a.Add(&AdminJobRequest{Type: adminJobMempool})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForAdminJob(t, a, j.ID, adminJobRunning)
+	if nt := <-pushed; nt != bchain.NotificationNewTx {
+		t.Errorf("pushed notification %v, want NotificationNewTx", nt)
+	}
+	is.StartedMempoolSync()
+	is.FinishedMempoolSync(5)
+	j = waitForAdminJob(t, a, j.ID, adminJobFinished)
+	close(release)
+	if j.Done != 1 || j.Total != 1 || j.Result != "Mempool size 5" || j.Started == nil || j.Finished == nil {
+		t.Errorf("job = %+v", j)
+	}
+
+	// cancel the running job
+	j, err = a.Add(&AdminJobRequest{Type: adminJobMempool})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForAdminJob(t, a, j.ID, adminJobRunning)
+	if _, err = a.Cancel(j.ID); err != nil {
+		t.Fatal(err)
+	}
+	waitForAdminJob(t, a, j.ID, adminJobCancelled)
+	if _, err = a.Cancel(j.ID); err == nil {
+		t.Error("Cancel() of cancelled job expected error")
+	}
+	if _, err = a.Job("unknown"); err == nil {
+		t.Error("Job() expected error for unknown id")
+	}
+	if n := len(a.Jobs()); n != 2 {
+		t.Errorf("len(Jobs()) = %v, want 2", n)
+	}
+}
+
+func Test_adminHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   []string
+		key    string
+		status int
+	}{
+		{name: "disabled", key: "key", status: http.StatusForbidden},
+		{name: "missing key", keys: []string{"key"}, status: http.StatusUnauthorized},
+		{name: "wrong key", keys: []string{"key"}, key: "other", status: http.StatusUnauthorized},
+		{name: "authorized", keys: []string{"other", "key"}, key: "key", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _, _ := newTestAdminJobs(t, nil, tt.keys)
+			defer a.Close()
+			s := &InternalServer{admin: a}
+			h := s.adminHandler(s.adminJobsHandler)
+			r := httptest.NewRequest("GET", "/api/admin/jobs", nil)
+			if tt.key != "" {
+				r.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			h(w, r)
+			if w.Code != tt.status {
+				t.Errorf("status = %v, want %v, body %v", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
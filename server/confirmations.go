@@ -0,0 +1,243 @@
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/bchain"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// maxConfirmationThresholds is the maximum number of thresholds of one subscription
+	maxConfirmationThresholds = 16
+	// maxConfirmationThreshold is the maximum confirmation threshold
+	maxConfirmationThreshold = 1000
+	// maxTrackedTxs is the maximum number of transactions tracked for one websocket channel
+	maxTrackedTxs = 1000
+	// trackedTxMissingBlocks is the number of blocks after which a transaction found neither in the chain nor in the mempool is dropped
+	trackedTxMissingBlocks = 10
+)
+
+// trackedTx is a transaction of the subscribed addresses waiting for the confirmation thresholds
+type trackedTx struct {
+	address       string
+	confirmations int
+	notified      int
+	missing       int
+}
+
+// confirmationSubscription holds the confirmation thresholds of the address subscription of a websocket channel
+type confirmationSubscription struct {
+	id         string
+	thresholds []int
+	txs        map[string]*trackedTx
+}
+
+// confirmationNotification is sent to the subscribers of addresses when their transaction reaches a threshold
+// or when it becomes unconfirmed by a reorg
+type confirmationNotification struct {
+	Address       string `json:"address"`
+	Txid          string `json:"txid"`
+	Confirmations int    `json:"confirmations"`
+	Threshold     int    `json:"threshold,omitempty"`
+	BlockHeight   uint32 `json:"blockHeight,omitempty"`
+	Unconfirmed   bool   `json:"unconfirmed,omitempty"`
+}
+
+// unmarshalConfirmations returns the sorted unique confirmation thresholds of the subscribeAddresses request
+func unmarshalConfirmations(params []byte) ([]int, error) {
+	r := struct {
+		Confirmations []int `json:"confirmations"`
+	}{}
+	if err := json.Unmarshal(params, &r); err != nil {
+		return nil, err
+	}
+	if len(r.Confirmations) > maxConfirmationThresholds {
+		return nil, api.NewAPIError("Too many confirmation thresholds, the limit is "+strconv.Itoa(maxConfirmationThresholds), true)
+	}
+	rv := make([]int, 0, len(r.Confirmations))
+	for _, c := range r.Confirmations {
+		if c < 1 || c > maxConfirmationThreshold {
+			return nil, api.NewAPIError("Invalid confirmations "+strconv.Itoa(c), true)
+		}
+		if !containsInt(rv, c) {
+			rv = append(rv, c)
+		}
+	}
+	sort.Ints(rv)
+	return rv, nil
+}
+
+// update sets the current confirmations of the transaction and returns the newly reached thresholds
+// and whether the transaction became unconfirmed; found is false if the transaction is neither in the chain nor in the mempool
+func (t *trackedTx) update(confirmations int, found bool, thresholds []int) (reached []int, unconfirmed bool) {
+	if found {
+		t.missing = 0
+	} else {
+		t.missing++
+	}
+	if confirmations == 0 && t.confirmations > 0 {
+		unconfirmed = true
+	}
+	if confirmations < t.notified {
+		// the transaction was moved by a reorg to a later block, the thresholds above the current confirmations will be notified again
+		t.notified = 0
+		for _, c := range thresholds {
+			if c <= confirmations {
+				t.notified = c
+			}
+		}
+	}
+	for _, c := range thresholds {
+		if c > t.notified && c <= confirmations {
+			reached = append(reached, c)
+			t.notified = c
+		}
+	}
+	t.confirmations = confirmations
+	return reached, unconfirmed
+}
+
+// done returns true if the transaction does not have to be tracked anymore
+func (t *trackedTx) done(thresholds []int) bool {
+	return t.notified >= thresholds[len(thresholds)-1] || t.missing > trackedTxMissingBlocks
+}
+
+// subscribeConfirmations sets the confirmation thresholds of the address subscription of the channel
+// and starts tracking the mempool transactions of the addresses
+func (s *WebsocketServer) subscribeConfirmations(c *websocketChannel, addrDesc []bchain.AddressDescriptor, thresholds []int, id string) {
+	cs := &confirmationSubscription{
+		id:         id,
+		thresholds: thresholds,
+		txs:        make(map[string]*trackedTx),
+	}
+	for _, ad := range addrDesc {
+		outpoints, err := s.mempool.GetAddrDescTransactions(ad)
+		if err != nil || len(outpoints) == 0 {
+			continue
+		}
+		addr, _, err := s.chainParser.GetAddressesFromAddrDesc(ad)
+		if err != nil || len(addr) != 1 {
+			continue
+		}
+		for _, o := range outpoints {
+			if len(cs.txs) >= maxTrackedTxs {
+				break
+			}
+			if _, found := cs.txs[o.Txid]; !found {
+				cs.txs[o.Txid] = &trackedTx{address: addr[0]}
+			}
+		}
+	}
+	s.confirmationSubscriptionsLock.Lock()
+	defer s.confirmationSubscriptionsLock.Unlock()
+	s.confirmationSubscriptions[c] = cs
+}
+
+func (s *WebsocketServer) unsubscribeConfirmations(c *websocketChannel) {
+	s.confirmationSubscriptionsLock.Lock()
+	defer s.confirmationSubscriptionsLock.Unlock()
+	delete(s.confirmationSubscriptions, c)
+}
+
+// trackTx starts tracking the confirmations of a new transaction of the address subscribed by the channel
+func (s *WebsocketServer) trackTx(c *websocketChannel, txid string, address string) {
+	s.confirmationSubscriptionsLock.Lock()
+	defer s.confirmationSubscriptionsLock.Unlock()
+	cs, ok := s.confirmationSubscriptions[c]
+	if !ok || len(cs.txs) >= maxTrackedTxs {
+		return
+	}
+	if _, found := cs.txs[txid]; !found {
+		cs.txs[txid] = &trackedTx{address: address}
+	}
+}
+
+// txHeight returns the height of the block of the transaction, 0 for a mempool transaction;
+// found is false if the transaction is neither in the chain nor in the mempool
+func (s *WebsocketServer) txHeight(txid string) (height uint32, found bool, err error) {
+	if s.chainParser.GetChainType() == bchain.ChainBitcoinType {
+		ta, err := s.db.GetTxAddresses(txid)
+		if err != nil {
+			return 0, false, err
+		}
+		if ta != nil {
+			return ta.Height, true, nil
+		}
+		return 0, s.mempool.GetTransactionTime(txid) != 0, nil
+	}
+	tx, h, err := s.txCache.GetTransaction(txid)
+	if err != nil {
+		if err == bchain.ErrTxNotFound {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	if tx.Confirmations == 0 {
+		return 0, true, nil
+	}
+	return uint32(h), true, nil
+}
+
+// notifyConfirmations notifies the subscribers of addresses about their transactions which crossed a confirmation threshold
+// or which were unconfirmed by a reorg; it is called on each connected block with the best height
+func (s *WebsocketServer) notifyConfirmations(bestHeight uint32) {
+	s.confirmationSubscriptionsLock.Lock()
+	defer s.confirmationSubscriptionsLock.Unlock()
+	type txState struct {
+		height uint32
+		found  bool
+	}
+	states := make(map[string]*txState)
+	notifications := 0
+	for c, cs := range s.confirmationSubscriptions {
+		for txid, t := range cs.txs {
+			st, ok := states[txid]
+			if !ok {
+				height, found, err := s.txHeight(txid)
+				if err != nil {
+					glog.Error("txHeight error ", err, " for ", txid)
+					continue
+				}
+				st = &txState{height, found}
+				states[txid] = st
+			}
+			confirmations := 0
+			if st.height > 0 && st.height <= bestHeight {
+				confirmations = int(bestHeight - st.height + 1)
+			}
+			reached, unconfirmed := t.update(confirmations, st.found, cs.thresholds)
+			if c.IsAlive() {
+				if unconfirmed {
+					c.out <- &websocketRes{
+						ID:   cs.id,
+						Data: &confirmationNotification{Address: t.address, Txid: txid, Unconfirmed: true},
+					}
+					notifications++
+				}
+				for _, th := range reached {
+					c.out <- &websocketRes{
+						ID: cs.id,
+						Data: &confirmationNotification{
+							Address:       t.address,
+							Txid:          txid,
+							Confirmations: confirmations,
+							Threshold:     th,
+							BlockHeight:   st.height,
+						},
+					}
+					notifications++
+				}
+			}
+			if t.done(cs.thresholds) {
+				delete(cs.txs, txid)
+			}
+		}
+	}
+	if notifications > 0 {
+		glog.Info("broadcasting ", notifications, " confirmation notifications of ", len(states), " txs")
+	}
+}
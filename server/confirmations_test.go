@@ -0,0 +1,65 @@
+// +build unittest
+
+package server
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_unmarshalConfirmations(t *testing.T) {
+	got, err := unmarshalConfirmations([]byte(`{"addresses":["a"],"confirmations":[6,1,3,1]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 3, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("unmarshalConfirmations() = %v, want %v", got, want)
+	}
+	if got, err = unmarshalConfirmations([]byte(`{"addresses":["a"]}`)); err != nil || len(got) != 0 {
+		t.Errorf("unmarshalConfirmations() without thresholds = %v, %v", got, err)
+	}
+	for _, p := range []string{`{"confirmations":[0]}`, `{"confirmations":[1001]}`, `{"confirmations":[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17]}`, `{"confirmations":"1"}`} {
+		if _, err = unmarshalConfirmations([]byte(p)); err == nil {
+			t.Errorf("unmarshalConfirmations(%v) expected error", p)
+		}
+	}
+}
+
+func Test_trackedTx_update(t *testing.T) {
+	thresholds := []int{1, 3, 6}
+	tx := &trackedTx{address: "addr"}
+	steps := []struct {
+		name            string
+		confirmations   int
+		found           bool
+		wantReached     []int
+		wantUnconfirmed bool
+		wantDone        bool
+	}{
+		{"mempool", 0, true, nil, false, false},
+		{"mined", 1, true, []int{1}, false, false},
+		{"skipped blocks", 4, true, []int{3}, false, false},
+		{"reorg to mempool", 0, true, nil, true, false},
+		{"reorg missing", 0, false, nil, false, false},
+		{"mined again", 2, true, []int{1}, false, false},
+		{"next block", 3, true, []int{3}, false, false},
+		{"reorg to later block", 2, true, nil, false, false},
+		{"final", 7, true, []int{3, 6}, false, true},
+	}
+	for _, s := range steps {
+		reached, unconfirmed := tx.update(s.confirmations, s.found, thresholds)
+		if !reflect.DeepEqual(reached, s.wantReached) || unconfirmed != s.wantUnconfirmed {
+			t.Errorf("%s: update() = %v, %v, want %v, %v", s.name, reached, unconfirmed, s.wantReached, s.wantUnconfirmed)
+		}
+		if done := tx.done(thresholds); done != s.wantDone {
+			t.Errorf("%s: done() = %v, want %v", s.name, done, s.wantDone)
+		}
+	}
+	missing := &trackedTx{address: "addr"}
+	for i := 0; i <= trackedTxMissingBlocks; i++ {
+		missing.update(0, false, thresholds)
+	}
+	if !missing.done(thresholds) {
+		t.Error("done() of missing tx = false, want true")
+	}
+}
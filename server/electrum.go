@@ -0,0 +1,1157 @@
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/bchain"
+	"blockbook/common"
+	"blockbook/db"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+const electrumProtocolVersion = "1.4"
+const electrumMaxRequestSize = 4 * 1024 * 1024
+const electrumIdleTimeout = 10 * time.Minute
+const electrumMaxHeaders = 2016
+
+// electrumMaxPendingRequests is the maximum number of requests of one client processed concurrently,
+// the reading of further requests waits until some of them finishes
+const electrumMaxPendingRequests = 16
+
+// JSON-RPC error codes used by the Electrum protocol
+const (
+	electrumErrorBadRequest     = 1
+	electrumErrorParse          = -32700
+	electrumErrorMethodNotFound = -32601
+	electrumErrorInvalidParams  = -32602
+)
+
+type electrumReq struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type electrumError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type electrumResult struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+}
+
+type electrumErrorResult struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   electrumError   `json:"error"`
+}
+
+type electrumNotification struct {
+	JSONRPC string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type electrumParamError struct {
+	msg string
+}
+
+func (e *electrumParamError) Error() string {
+	return e.msg
+}
+
+type electrumChannel struct {
+	id        uint64
+	conn      net.Conn
+	out       chan interface{}
+	pending   chan struct{}
+	ip        string
+	alive     bool
+	aliveLock sync.Mutex
+}
+
+type electrumHistoryItem struct {
+	Txid   string      `json:"tx_hash"`
+	Height int         `json:"height"`
+	Fee    json.Number `json:"fee,omitempty"`
+}
+
+type electrumUnspent struct {
+	Txid   string      `json:"tx_hash"`
+	Vout   int32       `json:"tx_pos"`
+	Height int         `json:"height"`
+	Value  json.Number `json:"value"`
+}
+
+type electrumBalance struct {
+	Confirmed   json.Number `json:"confirmed"`
+	Unconfirmed json.Number `json:"unconfirmed"`
+}
+
+type electrumHeader struct {
+	Hex    string `json:"hex"`
+	Height uint32 `json:"height"`
+}
+
+// ElectrumServer is a handle to the server implementing Electrum protocol over TCP or TLS
+type ElectrumServer struct {
+	binding                     string
+	listener                    net.Listener
+	closed                      int32
+	db                          *db.RocksDB
+	txCache                     *db.TxCache
+	chain                       bchain.BlockChain
+	chainParser                 bchain.BlockChainParser
+	mempool                     bchain.Mempool
+	metrics                     *common.Metrics
+	is                          *common.InternalState
+	api                         *api.Worker
+	channels                    map[*electrumChannel]struct{}
+	channelsLock                sync.Mutex
+	headersSubscriptions        map[*electrumChannel]struct{}
+	scriptHashSubscriptions     map[string]map[*electrumChannel]string
+	subscriptionsLock           sync.Mutex
+	mempoolScriptHashes         map[string]bchain.AddressDescriptor
+	mempoolScriptHashesLock     sync.Mutex
+	scriptHashNotificationsLock sync.Mutex
+}
+
+// NewElectrumServer creates new Electrum protocol interface to blockbook and returns its handle
+// if certFiles is set, the server accepts only TLS connections
+func NewElectrumServer(binding string, certFiles string, db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState) (*ElectrumServer, error) {
+	if chain.GetChainParser().GetChainType() != bchain.ChainBitcoinType {
+		return nil, errors.New("Electrum server is supported only for Bitcoin type coins")
+	}
+	api, err := api.NewWorker(db, chain, mempool, txCache, is)
+	if err != nil {
+		return nil, err
+	}
+	var listener net.Listener
+	if certFiles == "" {
+		listener, err = net.Listen("tcp", binding)
+	} else {
+		var cert tls.Certificate
+		cert, err = tls.LoadX509KeyPair(fmt.Sprint(certFiles, ".crt"), fmt.Sprint(certFiles, ".key"))
+		if err != nil {
+			return nil, err
+		}
+		listener, err = tls.Listen("tcp", binding, &tls.Config{Certificates: []tls.Certificate{cert}})
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &ElectrumServer{
+		binding:                 binding,
+		listener:                listener,
+		db:                      db,
+		txCache:                 txCache,
+		chain:                   chain,
+		chainParser:             chain.GetChainParser(),
+		mempool:                 mempool,
+		metrics:                 metrics,
+		is:                      is,
+		api:                     api,
+		channels:                make(map[*electrumChannel]struct{}),
+		headersSubscriptions:    make(map[*electrumChannel]struct{}),
+		scriptHashSubscriptions: make(map[string]map[*electrumChannel]string),
+		mempoolScriptHashes:     make(map[string]bchain.AddressDescriptor),
+	}
+	return s, nil
+}
+
+// Run accepts the connections until the server is closed
+func (s *ElectrumServer) Run() error {
+	glog.Info("electrum server: starting to listen on ", s.binding)
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if atomic.LoadInt32(&s.closed) != 0 {
+				return nil
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				glog.Error("electrum server: accept error ", err)
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		c := &electrumChannel{
+			id:      atomic.AddUint64(&connectionCounter, 1),
+			conn:    conn,
+			out:     make(chan interface{}, outChannelSize),
+			pending: make(chan struct{}, electrumMaxPendingRequests),
+			ip:      conn.RemoteAddr().String(),
+			alive:   true,
+		}
+		s.onConnect(c)
+		go s.inputLoop(c)
+		go s.outputLoop(c)
+	}
+}
+
+// Close closes the listener and all client connections
+func (s *ElectrumServer) Close() error {
+	glog.Infof("electrum server: closing")
+	atomic.StoreInt32(&s.closed, 1)
+	err := s.listener.Close()
+	s.channelsLock.Lock()
+	channels := make([]*electrumChannel, 0, len(s.channels))
+	for c := range s.channels {
+		channels = append(channels, c)
+	}
+	s.channelsLock.Unlock()
+	for _, c := range channels {
+		s.closeChannel(c)
+	}
+	return err
+}
+
+// Shutdown shuts down the server, there are no requests worth waiting for, therefore the connections are closed immediately
+func (s *ElectrumServer) Shutdown(ctx context.Context) error {
+	glog.Infof("electrum server: shutdown")
+	return s.Close()
+}
+
+func (s *ElectrumServer) closeChannel(c *electrumChannel) {
+	c.aliveLock.Lock()
+	alive := c.alive
+	if alive {
+		c.conn.Close()
+		c.alive = false
+		//clean out
+		close(c.out)
+		for len(c.out) > 0 {
+			<-c.out
+		}
+	}
+	c.aliveLock.Unlock()
+	// onDisconnect must be called without aliveLock, the notifications lock the subscriptions first and then aliveLock
+	if alive {
+		s.onDisconnect(c)
+	}
+}
+
+func (c *electrumChannel) IsAlive() bool {
+	c.aliveLock.Lock()
+	defer c.aliveLock.Unlock()
+	return c.alive
+}
+
+// send puts the message to the output queue of the channel, the message is dropped if the channel is closed
+func (c *electrumChannel) send(m interface{}) {
+	c.aliveLock.Lock()
+	defer c.aliveLock.Unlock()
+	if c.alive {
+		select {
+		case c.out <- m:
+		default:
+			glog.Error("Electrum client ", c.id, " output queue full, dropping message")
+		}
+	}
+}
+
+func (s *ElectrumServer) inputLoop(c *electrumChannel) {
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("recovered from panic: ", r, ", ", c.id)
+			debug.PrintStack()
+		}
+		s.closeChannel(c)
+	}()
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), electrumMaxRequestSize)
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(electrumIdleTimeout))
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				glog.V(1).Info("Electrum client ", c.id, " read error ", err)
+			}
+			return
+		}
+		d := bytes.TrimSpace(scanner.Bytes())
+		if len(d) == 0 {
+			continue
+		}
+		if d[0] == '[' {
+			var reqs []electrumReq
+			if err := json.Unmarshal(d, &reqs); err != nil {
+				glog.Error("Error parsing message from ", c.id, ", ", string(d), ", ", err)
+				c.send(electrumErrorResponse(nil, electrumErrorParse, "Parse error"))
+				return
+			}
+			c.pending <- struct{}{}
+			go func() {
+				defer func() { <-c.pending }()
+				s.onBatchRequest(c, reqs)
+			}()
+		} else {
+			var req electrumReq
+			if err := json.Unmarshal(d, &req); err != nil {
+				glog.Error("Error parsing message from ", c.id, ", ", string(d), ", ", err)
+				c.send(electrumErrorResponse(nil, electrumErrorParse, "Parse error"))
+				return
+			}
+			c.pending <- struct{}{}
+			go func() {
+				defer func() { <-c.pending }()
+				if res := s.onRequest(c, &req); res != nil {
+					c.send(res)
+				}
+			}()
+		}
+	}
+}
+
+func (s *ElectrumServer) outputLoop(c *electrumChannel) {
+	enc := json.NewEncoder(c.conn)
+	for m := range c.out {
+		c.conn.SetWriteDeadline(time.Now().Add(defaultTimeout))
+		// Encode terminates each message by newline as required by the protocol
+		if err := enc.Encode(m); err != nil {
+			glog.Error("Error sending message to ", c.id, ", ", err)
+			s.closeChannel(c)
+		}
+	}
+}
+
+func (s *ElectrumServer) onConnect(c *electrumChannel) {
+	s.channelsLock.Lock()
+	s.channels[c] = struct{}{}
+	s.channelsLock.Unlock()
+	glog.Info("Electrum client connected ", c.id, ", ", c.ip)
+	s.metrics.ElectrumClients.Inc()
+}
+
+func (s *ElectrumServer) onDisconnect(c *electrumChannel) {
+	s.channelsLock.Lock()
+	delete(s.channels, c)
+	s.channelsLock.Unlock()
+	s.unsubscribeAll(c)
+	glog.Info("Electrum client disconnected ", c.id, ", ", c.ip)
+	s.metrics.ElectrumClients.Dec()
+}
+
+func electrumErrorResponse(id json.RawMessage, code int, message string) *electrumErrorResult {
+	return &electrumErrorResult{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error: electrumError{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+var electrumHandlers = map[string]func(*ElectrumServer, *electrumChannel, []json.RawMessage) (interface{}, error){
+	"server.version": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (interface{}, error) {
+		return []string{"Blockbook " + common.GetVersionInfo().Version, electrumProtocolVersion}, nil
+	},
+	"server.banner": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (interface{}, error) {
+		return "Blockbook " + s.is.Coin, nil
+	},
+	"server.donation_address": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (interface{}, error) {
+		return "", nil
+	},
+	"server.peers.subscribe": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (interface{}, error) {
+		return []interface{}{}, nil
+	},
+	"server.ping": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (interface{}, error) {
+		return nil, nil
+	},
+	"blockchain.scripthash.get_history": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (rv interface{}, err error) {
+		sh, err := electrumParamString(params, 0)
+		if err == nil {
+			rv, err = s.getHistory(sh, false)
+		}
+		return
+	},
+	"blockchain.scripthash.get_mempool": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (rv interface{}, err error) {
+		sh, err := electrumParamString(params, 0)
+		if err == nil {
+			rv, err = s.getHistory(sh, true)
+		}
+		return
+	},
+	"blockchain.scripthash.get_balance": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (rv interface{}, err error) {
+		sh, err := electrumParamString(params, 0)
+		if err == nil {
+			rv, err = s.getBalance(sh)
+		}
+		return
+	},
+	"blockchain.scripthash.listunspent": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (rv interface{}, err error) {
+		sh, err := electrumParamString(params, 0)
+		if err == nil {
+			rv, err = s.listUnspent(sh)
+		}
+		return
+	},
+	"blockchain.scripthash.subscribe": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (rv interface{}, err error) {
+		sh, err := electrumParamString(params, 0)
+		if err == nil {
+			rv, err = s.subscribeScriptHash(c, sh)
+		}
+		return
+	},
+	"blockchain.scripthash.unsubscribe": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (rv interface{}, err error) {
+		sh, err := electrumParamString(params, 0)
+		if err == nil {
+			rv = s.unsubscribeScriptHash(c, sh)
+		}
+		return
+	},
+	"blockchain.headers.subscribe": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (interface{}, error) {
+		return s.subscribeHeaders(c)
+	},
+	"blockchain.block.header": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (rv interface{}, err error) {
+		height, err := electrumParamInt(params, 0, -1)
+		if err == nil {
+			if height < 0 {
+				return nil, &electrumParamError{"Invalid height"}
+			}
+			rv, err = s.getHeaderHex(uint32(height))
+		}
+		return
+	},
+	"blockchain.block.headers": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (rv interface{}, err error) {
+		start, err := electrumParamInt(params, 0, -1)
+		if err != nil {
+			return nil, err
+		}
+		count, err := electrumParamInt(params, 1, -1)
+		if err != nil {
+			return nil, err
+		}
+		if start < 0 || count < 0 {
+			return nil, &electrumParamError{"Invalid start height or count"}
+		}
+		return s.getHeaders(uint32(start), count)
+	},
+	"blockchain.transaction.get": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (rv interface{}, err error) {
+		txid, err := electrumParamString(params, 0)
+		if err != nil {
+			return nil, err
+		}
+		verbose, err := electrumParamBool(params, 1)
+		if err == nil {
+			rv, err = s.getTransaction(txid, verbose)
+		}
+		return
+	},
+	"blockchain.transaction.get_merkle": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (rv interface{}, err error) {
+		txid, err := electrumParamString(params, 0)
+		if err == nil {
+			rv, err = s.api.GetTransactionProof(txid)
+		}
+		return
+	},
+	"blockchain.transaction.broadcast": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (rv interface{}, err error) {
+		tx, err := electrumParamString(params, 0)
+		if err == nil {
+			rv, err = s.chain.SendRawTransaction(tx)
+		}
+		return
+	},
+	"blockchain.estimatefee": func(s *ElectrumServer, c *electrumChannel, params []json.RawMessage) (rv interface{}, err error) {
+		blocks, err := electrumParamInt(params, 0, -1)
+		if err == nil {
+			if blocks <= 0 {
+				return nil, &electrumParamError{"Invalid number of blocks"}
+			}
+			rv, err = s.estimateFee(blocks)
+		}
+		return
+	},
+}
+
+func (s *ElectrumServer) onBatchRequest(c *electrumChannel, reqs []electrumReq) {
+	res := make([]interface{}, 0, len(reqs))
+	for i := range reqs {
+		if r := s.onRequest(c, &reqs[i]); r != nil {
+			res = append(res, r)
+		}
+	}
+	if len(res) > 0 {
+		c.send(res)
+	}
+}
+
+// onRequest processes the request and returns the response, nil response means that the request was a notification
+func (s *ElectrumServer) onRequest(c *electrumChannel, req *electrumReq) (res interface{}) {
+	var err error
+	var data interface{}
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("Electrum client ", c.id, ", onRequest ", req.Method, " recovered from panic: ", r)
+			debug.PrintStack()
+			res = electrumErrorResponse(req.ID, electrumErrorBadRequest, "Internal error")
+		}
+		// requests without id are notifications and do not get any response
+		if len(req.ID) == 0 || bytes.Equal(req.ID, []byte("null")) {
+			res = nil
+		}
+	}()
+	t := time.Now()
+	defer func() {
+		s.metrics.ElectrumReqDuration.With(common.Labels{"method": req.Method}).Observe(float64(time.Since(t)) / 1e3) // in microseconds
+	}()
+	f, ok := electrumHandlers[req.Method]
+	if !ok {
+		glog.V(1).Info("Electrum client ", c.id, " unknown method ", req.Method)
+		s.metrics.ElectrumRequests.With(common.Labels{"method": "unknown", "status": "failure"}).Inc()
+		return electrumErrorResponse(req.ID, electrumErrorMethodNotFound, "unknown method "+req.Method)
+	}
+	var params []json.RawMessage
+	if len(req.Params) > 0 && !bytes.Equal(req.Params, []byte("null")) {
+		err = json.Unmarshal(req.Params, &params)
+		if err != nil {
+			err = &electrumParamError{"params must be an array"}
+		}
+	}
+	if err == nil {
+		data, err = f(s, c, params)
+	}
+	if err != nil {
+		glog.Error("Electrum client ", c.id, " onRequest ", req.Method, ": ", errors.ErrorStack(err), ", params ", string(req.Params))
+		s.metrics.ElectrumRequests.With(common.Labels{"method": req.Method, "status": "failure"}).Inc()
+		code := electrumErrorBadRequest
+		if _, ok := err.(*electrumParamError); ok {
+			code = electrumErrorInvalidParams
+		}
+		return electrumErrorResponse(req.ID, code, err.Error())
+	}
+	glog.V(1).Info("Electrum client ", c.id, " onRequest ", req.Method, " success")
+	s.metrics.ElectrumRequests.With(common.Labels{"method": req.Method, "status": "success"}).Inc()
+	return &electrumResult{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  data,
+	}
+}
+
+func electrumParamString(params []json.RawMessage, i int) (string, error) {
+	if i >= len(params) {
+		return "", &electrumParamError{fmt.Sprintf("Missing parameter %d", i)}
+	}
+	var s string
+	if err := json.Unmarshal(params[i], &s); err != nil {
+		return "", &electrumParamError{fmt.Sprintf("Parameter %d must be a string", i)}
+	}
+	return s, nil
+}
+
+func electrumParamInt(params []json.RawMessage, i int, def int) (int, error) {
+	if i >= len(params) {
+		if def >= 0 {
+			return def, nil
+		}
+		return 0, &electrumParamError{fmt.Sprintf("Missing parameter %d", i)}
+	}
+	var n int
+	if err := json.Unmarshal(params[i], &n); err != nil {
+		return 0, &electrumParamError{fmt.Sprintf("Parameter %d must be an integer", i)}
+	}
+	return n, nil
+}
+
+func electrumParamBool(params []json.RawMessage, i int) (bool, error) {
+	if i >= len(params) {
+		return false, nil
+	}
+	var b bool
+	if err := json.Unmarshal(params[i], &b); err != nil {
+		return false, &electrumParamError{fmt.Sprintf("Parameter %d must be a boolean", i)}
+	}
+	return b, nil
+}
+
+// electrumScriptHash returns the script hash of the output script in the form used by Electrum protocol (reversed hex)
+func electrumScriptHash(script []byte) string {
+	h := db.ScriptHash(script)
+	for i, j := 0, len(h)-1; i < j; i, j = i+1, j-1 {
+		h[i], h[j] = h[j], h[i]
+	}
+	return hex.EncodeToString(h)
+}
+
+// parseElectrumScriptHash converts the script hash in the Electrum form to the byte order used by the index
+func parseElectrumScriptHash(scriptHash string) ([]byte, error) {
+	h, err := hex.DecodeString(scriptHash)
+	if err != nil || len(h) != sha256.Size {
+		return nil, &electrumParamError{fmt.Sprintf("Invalid script hash %v", scriptHash)}
+	}
+	for i, j := 0, len(h)-1; i < j; i, j = i+1, j-1 {
+		h[i], h[j] = h[j], h[i]
+	}
+	return h, nil
+}
+
+// electrumStatus computes the status of the script hash from its history, the status of empty history is null
+func electrumStatus(history []electrumHistoryItem) interface{} {
+	if len(history) == 0 {
+		return nil
+	}
+	h := sha256.New()
+	for i := range history {
+		fmt.Fprintf(h, "%s:%d:", history[i].Txid, history[i].Height)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// serializeBlockHeader returns the 80 bytes header of bitcoin-like block
+func serializeBlockHeader(bi *bchain.BlockInfo) ([]byte, error) {
+	version, err := bi.Version.Int64()
+	if err != nil {
+		return nil, errors.Annotatef(err, "version %v", bi.Version)
+	}
+	nonce, err := bi.Nonce.Int64()
+	if err != nil {
+		return nil, errors.Annotatef(err, "nonce %v", bi.Nonce)
+	}
+	bits, err := strconv.ParseUint(bi.Bits, 16, 32)
+	if err != nil {
+		return nil, errors.Annotatef(err, "bits %v", bi.Bits)
+	}
+	b := make([]byte, 80)
+	binary.LittleEndian.PutUint32(b, uint32(version))
+	// previous block hash and merkle root are stored in the reversed byte order
+	for i, h := range []string{bi.Prev, bi.MerkleRoot} {
+		if h == "" {
+			continue
+		}
+		hb, err := hex.DecodeString(h)
+		if err != nil || len(hb) != 32 {
+			return nil, errors.Errorf("Invalid hash %v", h)
+		}
+		for j := range hb {
+			b[4+i*32+j] = hb[31-j]
+		}
+	}
+	binary.LittleEndian.PutUint32(b[68:], uint32(bi.Time))
+	binary.LittleEndian.PutUint32(b[72:], uint32(bits))
+	binary.LittleEndian.PutUint32(b[76:], uint32(nonce))
+	return b, nil
+}
+
+// getAddrDesc returns the address descriptor of the script hash, nil if the script hash is not known
+func (s *ElectrumServer) getAddrDesc(scriptHash string) (bchain.AddressDescriptor, error) {
+	sh, err := parseElectrumScriptHash(scriptHash)
+	if err != nil {
+		return nil, err
+	}
+	ad, err := s.db.GetAddrDescForScriptHash(sh)
+	if err != nil {
+		return nil, err
+	}
+	if ad == nil {
+		// the address can be only in mempool
+		s.mempoolScriptHashesLock.Lock()
+		ad = s.mempoolScriptHashes[scriptHash]
+		s.mempoolScriptHashesLock.Unlock()
+	}
+	return ad, nil
+}
+
+func (s *ElectrumServer) getAddrDescHistory(addrDesc bchain.AddressDescriptor, onlyMempool bool) ([]electrumHistoryItem, error) {
+	history := make([]electrumHistoryItem, 0, 8)
+	if !onlyMempool {
+		err := s.db.GetAddrDescTransactions(addrDesc, 0, ^uint32(0), func(txid string, height uint32, indexes []int32) error {
+			history = append(history, electrumHistoryItem{Txid: txid, Height: int(height)})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		// the index returns the newest blocks first, Electrum protocol requires the oldest first
+		for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+			history[i], history[j] = history[j], history[i]
+		}
+		if err = sortBlockHistory(history, s.txPosition); err != nil {
+			return nil, err
+		}
+	}
+	o, err := s.mempool.GetAddrDescTransactions(addrDesc)
+	if err != nil {
+		return nil, err
+	}
+	mempoolTxs := make([]electrumHistoryItem, 0, len(o))
+	unique := make(map[string]struct{}, len(o))
+	for _, m := range o {
+		if _, found := unique[m.Txid]; found {
+			continue
+		}
+		unique[m.Txid] = struct{}{}
+		tx, err := s.api.GetTransaction(m.Txid, false, false)
+		if err != nil {
+			// mempool transaction may disappear
+			glog.Error("GetTransaction in mempool ", m.Txid, ": ", err)
+			continue
+		}
+		h := electrumHistoryItem{Txid: m.Txid}
+		if tx.FeesSat != nil {
+			h.Fee = json.Number((*big.Int)(tx.FeesSat).String())
+		}
+		// height -1 marks mempool transaction with unconfirmed inputs
+		for i := range tx.Vin {
+			if tx.Vin[i].Txid == "" {
+				continue
+			}
+			ta, err := s.db.GetTxAddresses(tx.Vin[i].Txid)
+			if err != nil {
+				return nil, err
+			}
+			if ta == nil {
+				h.Height = -1
+				break
+			}
+		}
+		mempoolTxs = append(mempoolTxs, h)
+	}
+	// keep the order of mempool transactions stable so that the status does not change without reason
+	sort.Slice(mempoolTxs, func(i, j int) bool {
+		if mempoolTxs[i].Height != mempoolTxs[j].Height {
+			return mempoolTxs[i].Height > mempoolTxs[j].Height
+		}
+		return mempoolTxs[i].Txid < mempoolTxs[j].Txid
+	})
+	return append(history, mempoolTxs...), nil
+}
+
+// sortBlockHistory orders the transactions in the same block by their position in the block, the index does not keep this order
+// (the outputs of all transactions of the block are indexed before the inputs)
+func sortBlockHistory(history []electrumHistoryItem, txPosition func(txid string) (uint32, error)) error {
+	for i := 0; i < len(history); {
+		j := i + 1
+		for j < len(history) && history[j].Height == history[i].Height {
+			j++
+		}
+		if j-i > 1 {
+			b := history[i:j]
+			positions := make(map[string]uint32, len(b))
+			for k := range b {
+				p, err := txPosition(b[k].Txid)
+				if err != nil {
+					return err
+				}
+				positions[b[k].Txid] = p
+			}
+			sort.SliceStable(b, func(k, l int) bool {
+				return positions[b[k].Txid] < positions[b[l].Txid]
+			})
+		}
+		i = j
+	}
+	return nil
+}
+
+// txPosition returns the position of the transaction in its block, stored in the txAddresses column
+func (s *ElectrumServer) txPosition(txid string) (uint32, error) {
+	ta, err := s.db.GetTxAddresses(txid)
+	if err != nil {
+		return 0, err
+	}
+	if ta == nil {
+		return 0, errors.Errorf("Transaction %v not found in txAddresses", txid)
+	}
+	return ta.Position, nil
+}
+
+func (s *ElectrumServer) getHistory(scriptHash string, onlyMempool bool) ([]electrumHistoryItem, error) {
+	ad, err := s.getAddrDesc(scriptHash)
+	if err != nil {
+		return nil, err
+	}
+	if ad == nil {
+		return []electrumHistoryItem{}, nil
+	}
+	return s.getAddrDescHistory(ad, onlyMempool)
+}
+
+func (s *ElectrumServer) getBalance(scriptHash string) (*electrumBalance, error) {
+	ad, err := s.getAddrDesc(scriptHash)
+	if err != nil {
+		return nil, err
+	}
+	var confirmed, total big.Int
+	if ad != nil {
+		ba, err := s.db.GetAddrDescBalance(ad, db.AddressBalanceDetailNoUTXO)
+		if err != nil {
+			return nil, err
+		}
+		if ba != nil {
+			confirmed.Set(&ba.BalanceSat)
+		}
+		// the unspent outputs include the mempool outputs and exclude the outputs spent in mempool
+		utxos, err := s.api.GetAddrDescUtxo(ad, false)
+		if err != nil {
+			return nil, err
+		}
+		for i := range utxos {
+			total.Add(&total, (*big.Int)(utxos[i].AmountSat))
+		}
+	}
+	return &electrumBalance{
+		Confirmed:   json.Number(confirmed.String()),
+		Unconfirmed: json.Number(total.Sub(&total, &confirmed).String()),
+	}, nil
+}
+
+func (s *ElectrumServer) listUnspent(scriptHash string) ([]electrumUnspent, error) {
+	ad, err := s.getAddrDesc(scriptHash)
+	if err != nil {
+		return nil, err
+	}
+	rv := []electrumUnspent{}
+	if ad == nil {
+		return rv, nil
+	}
+	utxos, err := s.api.GetAddrDescUtxo(ad, false)
+	if err != nil {
+		return nil, err
+	}
+	for i := range utxos {
+		u := &utxos[i]
+		rv = append(rv, electrumUnspent{
+			Txid:   u.Txid,
+			Vout:   u.Vout,
+			Height: u.Height,
+			Value:  json.Number((*big.Int)(u.AmountSat).String()),
+		})
+	}
+	return rv, nil
+}
+
+func (s *ElectrumServer) getHeaderHex(height uint32) (string, error) {
+	hash, err := s.db.GetBlockHash(height)
+	if err != nil {
+		return "", err
+	}
+	if hash == "" {
+		return "", &electrumParamError{fmt.Sprintf("Block %d not found", height)}
+	}
+	bi, err := s.chain.GetBlockInfo(hash)
+	if err != nil {
+		return "", err
+	}
+	b, err := serializeBlockHeader(bi)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *ElectrumServer) getHeaders(start uint32, count int) (interface{}, error) {
+	if count > electrumMaxHeaders {
+		count = electrumMaxHeaders
+	}
+	bestHeight, _, err := s.db.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	n := 0
+	for h := start; n < count && h <= bestHeight; h++ {
+		hh, err := s.getHeaderHex(h)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(hh)
+		n++
+	}
+	return struct {
+		Count int    `json:"count"`
+		Hex   string `json:"hex"`
+		Max   int    `json:"max"`
+	}{
+		Count: n,
+		Hex:   buf.String(),
+		Max:   electrumMaxHeaders,
+	}, nil
+}
+
+func (s *ElectrumServer) getTransaction(txid string, verbose bool) (interface{}, error) {
+	if verbose {
+		return s.chain.GetTransactionSpecific(&bchain.Tx{Txid: txid})
+	}
+	tx, err := s.api.GetTransaction(txid, false, false)
+	if err != nil {
+		return nil, err
+	}
+	return tx.Hex, nil
+}
+
+// estimateFee returns the fee per kilobyte in coin units, -1 if the fee cannot be estimated
+func (s *ElectrumServer) estimateFee(blocks int) (interface{}, error) {
+	fee, err := s.chain.EstimateSmartFee(blocks, true)
+	if err != nil {
+		return nil, err
+	}
+	if fee.Sign() <= 0 {
+		return -1, nil
+	}
+	return json.Number(s.chainParser.AmountToDecimalString(&fee)), nil
+}
+
+func (s *ElectrumServer) subscribeHeaders(c *electrumChannel) (interface{}, error) {
+	height, _, err := s.db.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+	h, err := s.getHeaderHex(height)
+	if err != nil {
+		return nil, err
+	}
+	s.subscriptionsLock.Lock()
+	s.headersSubscriptions[c] = struct{}{}
+	s.subscriptionsLock.Unlock()
+	return &electrumHeader{Hex: h, Height: height}, nil
+}
+
+func (s *ElectrumServer) subscribeScriptHash(c *electrumChannel, scriptHash string) (interface{}, error) {
+	history, err := s.getHistory(scriptHash, false)
+	if err != nil {
+		return nil, err
+	}
+	status := electrumStatus(history)
+	st, _ := status.(string)
+	s.subscriptionsLock.Lock()
+	defer s.subscriptionsLock.Unlock()
+	cs, ok := s.scriptHashSubscriptions[scriptHash]
+	if !ok {
+		cs = make(map[*electrumChannel]string)
+		s.scriptHashSubscriptions[scriptHash] = cs
+	}
+	cs[c] = st
+	return status, nil
+}
+
+func (s *ElectrumServer) unsubscribeScriptHash(c *electrumChannel, scriptHash string) bool {
+	s.subscriptionsLock.Lock()
+	defer s.subscriptionsLock.Unlock()
+	cs, ok := s.scriptHashSubscriptions[scriptHash]
+	if !ok {
+		return false
+	}
+	_, ok = cs[c]
+	delete(cs, c)
+	if len(cs) == 0 {
+		delete(s.scriptHashSubscriptions, scriptHash)
+	}
+	return ok
+}
+
+func (s *ElectrumServer) unsubscribeAll(c *electrumChannel) {
+	s.subscriptionsLock.Lock()
+	defer s.subscriptionsLock.Unlock()
+	delete(s.headersSubscriptions, c)
+	for sh, cs := range s.scriptHashSubscriptions {
+		delete(cs, c)
+		if len(cs) == 0 {
+			delete(s.scriptHashSubscriptions, sh)
+		}
+	}
+}
+
+// notifyScriptHashes recomputes the status of the subscribed script hashes and notifies the channels if the status changed
+// if scriptHashes is nil, all subscribed script hashes are processed
+func (s *ElectrumServer) notifyScriptHashes(scriptHashes []string) {
+	// serialize the notifications so that the older status cannot overwrite the newer one
+	s.scriptHashNotificationsLock.Lock()
+	defer s.scriptHashNotificationsLock.Unlock()
+	if scriptHashes == nil {
+		s.subscriptionsLock.Lock()
+		scriptHashes = make([]string, 0, len(s.scriptHashSubscriptions))
+		for sh := range s.scriptHashSubscriptions {
+			scriptHashes = append(scriptHashes, sh)
+		}
+		s.subscriptionsLock.Unlock()
+	}
+	for _, sh := range scriptHashes {
+		history, err := s.getHistory(sh, false)
+		if err != nil {
+			glog.Error("Electrum getHistory ", sh, ": ", err)
+			continue
+		}
+		status := electrumStatus(history)
+		st, _ := status.(string)
+		s.subscriptionsLock.Lock()
+		for c, last := range s.scriptHashSubscriptions[sh] {
+			if last != st {
+				s.scriptHashSubscriptions[sh][c] = st
+				c.send(&electrumNotification{
+					JSONRPC: "2.0",
+					Method:  "blockchain.scripthash.subscribe",
+					Params:  []interface{}{sh, status},
+				})
+			}
+		}
+		s.subscriptionsLock.Unlock()
+	}
+}
+
+// pruneMempoolScriptHashes removes the script hashes which are already in the index or which do not have mempool transactions
+// and returns the removed script hashes
+func (s *ElectrumServer) pruneMempoolScriptHashes() map[string]struct{} {
+	s.mempoolScriptHashesLock.Lock()
+	defer s.mempoolScriptHashesLock.Unlock()
+	pruned := make(map[string]struct{})
+	for sh, ad := range s.mempoolScriptHashes {
+		o, err := s.mempool.GetAddrDescTransactions(ad)
+		if err == nil && len(o) > 0 {
+			h, err := parseElectrumScriptHash(sh)
+			if err != nil {
+				continue
+			}
+			if a, err := s.db.GetAddrDescForScriptHash(h); err != nil || a == nil {
+				continue
+			}
+		}
+		delete(s.mempoolScriptHashes, sh)
+		pruned[sh] = struct{}{}
+	}
+	return pruned
+}
+
+// blockAddrDescs returns the address descriptors of the inputs and outputs of the transactions in the block
+// the transactions are taken from the blockTxs column, nil is returned if the block is not there
+func (s *ElectrumServer) blockAddrDescs(height uint32) (map[string]struct{}, error) {
+	txids, err := s.db.GetBlockTxids(height)
+	if err != nil || txids == nil {
+		return nil, err
+	}
+	ads := make(map[string]struct{})
+	for _, txid := range txids {
+		ta, err := s.db.GetTxAddresses(txid)
+		if err != nil {
+			return nil, err
+		}
+		if ta == nil {
+			continue
+		}
+		for i := range ta.Inputs {
+			ads[string(ta.Inputs[i].AddrDesc)] = struct{}{}
+		}
+		for i := range ta.Outputs {
+			ads[string(ta.Outputs[i].AddrDesc)] = struct{}{}
+		}
+	}
+	return ads, nil
+}
+
+// blockScriptHashes returns the subscribed script hashes whose address is in the block with given height
+// or whose mempool transactions were pruned, nil means that all subscribed script hashes must be processed
+func (s *ElectrumServer) blockScriptHashes(height uint32, pruned map[string]struct{}) []string {
+	ads, err := s.blockAddrDescs(height)
+	if err != nil {
+		glog.Error("Electrum blockAddrDescs ", height, ": ", err)
+		return nil
+	}
+	if ads == nil {
+		return nil
+	}
+	s.subscriptionsLock.Lock()
+	subscribed := make([]string, 0, len(s.scriptHashSubscriptions))
+	for sh := range s.scriptHashSubscriptions {
+		subscribed = append(subscribed, sh)
+	}
+	s.subscriptionsLock.Unlock()
+	scriptHashes := make([]string, 0)
+	for _, sh := range subscribed {
+		if _, found := pruned[sh]; found {
+			scriptHashes = append(scriptHashes, sh)
+			continue
+		}
+		ad, err := s.getAddrDesc(sh)
+		if err != nil || ad == nil {
+			continue
+		}
+		if _, found := ads[string(ad)]; found {
+			scriptHashes = append(scriptHashes, sh)
+		}
+	}
+	return scriptHashes
+}
+
+// OnNewBlock is a callback that notifies subscribed clients about new header and about changes of statuses of script hashes
+func (s *ElectrumServer) OnNewBlock(hash string, height uint32) {
+	s.subscriptionsLock.Lock()
+	headersSubscribers := len(s.headersSubscriptions)
+	s.subscriptionsLock.Unlock()
+	if headersSubscribers > 0 {
+		h, err := s.getHeaderHex(height)
+		if err != nil {
+			glog.Error("Electrum getHeaderHex ", height, ": ", err)
+		} else {
+			n := &electrumNotification{
+				JSONRPC: "2.0",
+				Method:  "blockchain.headers.subscribe",
+				Params:  []interface{}{&electrumHeader{Hex: h, Height: height}},
+			}
+			s.subscriptionsLock.Lock()
+			for c := range s.headersSubscriptions {
+				c.send(n)
+			}
+			s.subscriptionsLock.Unlock()
+		}
+	}
+	go func() {
+		pruned := s.pruneMempoolScriptHashes()
+		// only the script hashes affected by the block can change the status
+		scriptHashes := s.blockScriptHashes(height, pruned)
+		if scriptHashes == nil || len(scriptHashes) > 0 {
+			s.notifyScriptHashes(scriptHashes)
+		}
+	}()
+}
+
+// OnNewTxAddr is a callback that notifies clients subscribed to the script hash of the address about the change of its status
+func (s *ElectrumServer) OnNewTxAddr(tx *bchain.Tx, desc bchain.AddressDescriptor) {
+	shs := []string{electrumScriptHash(desc)}
+	// the outputs with script different from the address descriptor (P2PK) have their own script hash
+	for i := range tx.Vout {
+		script, err := hex.DecodeString(tx.Vout[i].ScriptPubKey.Hex)
+		if err != nil || bytes.Equal(script, desc) {
+			continue
+		}
+		if ad, err := s.chainParser.GetAddrDescFromVout(&tx.Vout[i]); err == nil && bytes.Equal(ad, desc) {
+			shs = append(shs, electrumScriptHash(script))
+		}
+	}
+	s.mempoolScriptHashesLock.Lock()
+	for _, sh := range shs {
+		s.mempoolScriptHashes[sh] = desc
+	}
+	s.mempoolScriptHashesLock.Unlock()
+	var subscribed []string
+	s.subscriptionsLock.Lock()
+	for _, sh := range shs {
+		if _, found := s.scriptHashSubscriptions[sh]; found {
+			subscribed = append(subscribed, sh)
+		}
+	}
+	s.subscriptionsLock.Unlock()
+	if len(subscribed) > 0 {
+		s.notifyScriptHashes(subscribed)
+	}
+}
@@ -0,0 +1,110 @@
+// +build unittest
+
+package server
+
+import (
+	"blockbook/bchain"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func Test_electrumScriptHash(t *testing.T) {
+	// example from the Electrum protocol documentation, P2PKH script of 1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa
+	ad, _ := hex.DecodeString("76a91462e907b15cbf27d5425399ebf6f0fb50ebb88f1888ac")
+	sh := electrumScriptHash(ad)
+	if sh != "8b01df4e368ea28f8dc0423bcf7a4923e3a12d307c875e47a0cfbf90b5c39161" {
+		t.Errorf("electrumScriptHash() = %v", sh)
+	}
+	b, err := parseElectrumScriptHash(sh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := hex.EncodeToString(b); h != "6191c3b590bfcfa0475e877c302da1e323497acf3b42c08d8fa28e364edf018b" {
+		t.Errorf("parseElectrumScriptHash() = %v", h)
+	}
+	if _, err := parseElectrumScriptHash("8b01df4e"); err == nil {
+		t.Error("parseElectrumScriptHash() expected error for short script hash")
+	}
+}
+
+func Test_electrumStatus(t *testing.T) {
+	if s := electrumStatus(nil); s != nil {
+		t.Errorf("electrumStatus() = %v, want nil", s)
+	}
+	s := electrumStatus([]electrumHistoryItem{
+		{Txid: "8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87", Height: 100000},
+		{Txid: "fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4", Height: 0, Fee: "1000"},
+	})
+	if s != "1cff44293e3b16db477b3bfedf2f21c931a4b355d55466187052d3095fdc7f1f" {
+		t.Errorf("electrumStatus() = %v", s)
+	}
+}
+
+func Test_sortBlockHistory(t *testing.T) {
+	positions := map[string]uint32{
+		"tx1": 1, "tx2": 2, "tx3": 3,
+		"tx0": 7,
+		"tx4": 1, "tx5": 2,
+	}
+	var queried []string
+	txPosition := func(txid string) (uint32, error) {
+		queried = append(queried, txid)
+		return positions[txid], nil
+	}
+	// the index puts the transactions receiving to the address before the spending ones
+	history := []electrumHistoryItem{
+		{Txid: "tx3", Height: 100},
+		{Txid: "tx1", Height: 100},
+		{Txid: "tx2", Height: 100},
+		{Txid: "tx0", Height: 101},
+		{Txid: "tx5", Height: 102},
+		{Txid: "tx4", Height: 102},
+	}
+	if err := sortBlockHistory(history, txPosition); err != nil {
+		t.Fatal(err)
+	}
+	want := []electrumHistoryItem{
+		{Txid: "tx1", Height: 100},
+		{Txid: "tx2", Height: 100},
+		{Txid: "tx3", Height: 100},
+		{Txid: "tx0", Height: 101},
+		{Txid: "tx4", Height: 102},
+		{Txid: "tx5", Height: 102},
+	}
+	if !reflect.DeepEqual(history, want) {
+		t.Errorf("sortBlockHistory() = %+v, want %+v", history, want)
+	}
+	// the positions are not needed for the blocks with one transaction of the address
+	if want := []string{"tx3", "tx1", "tx2", "tx5", "tx4"}; !reflect.DeepEqual(queried, want) {
+		t.Errorf("sortBlockHistory() queried transactions %v, want %v", queried, want)
+	}
+}
+
+func Test_serializeBlockHeader(t *testing.T) {
+	// block 100000 of Bitcoin mainnet
+	bi := &bchain.BlockInfo{
+		BlockHeader: bchain.BlockHeader{
+			Hash: "000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506",
+			Prev: "000000000002d01c1fccc21636b607dfd930d31d01c3a62104612a1719011250",
+			Time: 1293623863,
+		},
+		Version:    json.Number("1"),
+		MerkleRoot: "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766",
+		Nonce:      json.Number("274148111"),
+		Bits:       "1b04864c",
+	}
+	b, err := serializeBlockHeader(bi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "0100000050120119172a610421a6c3011dd330d9df07b63616c2cc1f1cd00200000000006657a9252aacd5c0b2940996ecff952228c3067cc38d4885efb5a4ac4247e9f337221b4d4c86041b0f2b5710"
+	if h := hex.EncodeToString(b); h != want {
+		t.Errorf("serializeBlockHeader() = %v, want %v", h, want)
+	}
+	bi.Bits = "xyz"
+	if _, err := serializeBlockHeader(bi); err == nil {
+		t.Error("serializeBlockHeader() expected error for invalid bits")
+	}
+}
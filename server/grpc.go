@@ -0,0 +1,578 @@
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/bchain"
+	"blockbook/common"
+	"blockbook/db"
+	"blockbook/server/grpcapi"
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcErrorCodeKey is the trailer of the failed call with the code of the classified error, e.g. fee_too_low
+const grpcErrorCodeKey = "blockbook-error-code"
+
+// grpcSubscription is a stream subscribed to new blocks or to the transactions of addresses,
+// out is closed if the stream does not keep up with the notifications
+type grpcSubscription struct {
+	out    chan interface{}
+	closed bool
+}
+
+// GrpcServer is a handle to the gRPC interface to blockbook
+type GrpcServer struct {
+	binding                   string
+	listener                  net.Listener
+	server                    *grpc.Server
+	closed                    int32
+	db                        *db.RocksDB
+	txCache                   *db.TxCache
+	chain                     bchain.BlockChain
+	chainParser               bchain.BlockChainParser
+	mempool                   bchain.Mempool
+	metrics                   *common.Metrics
+	is                        *common.InternalState
+	api                       *api.Worker
+	newBlockSubscriptions     map[*grpcSubscription]struct{}
+	newBlockSubscriptionsLock sync.Mutex
+	addressSubscriptions      map[string]map[*grpcSubscription]struct{}
+	addressSubscriptionsLock  sync.Mutex
+}
+
+// NewGrpcServer creates new gRPC interface to blockbook and returns its handle
+// if certFiles is set, the server accepts only TLS connections
+func NewGrpcServer(binding string, certFiles string, db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState) (*GrpcServer, error) {
+	api, err := api.NewWorker(db, chain, mempool, txCache, is)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", binding)
+	if err != nil {
+		return nil, err
+	}
+	s := &GrpcServer{
+		binding:               binding,
+		listener:              listener,
+		db:                    db,
+		txCache:               txCache,
+		chain:                 chain,
+		chainParser:           chain.GetChainParser(),
+		mempool:               mempool,
+		metrics:               metrics,
+		is:                    is,
+		api:                   api,
+		newBlockSubscriptions: make(map[*grpcSubscription]struct{}),
+		addressSubscriptions:  make(map[string]map[*grpcSubscription]struct{}),
+	}
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(s.unaryInterceptor),
+		grpc.StreamInterceptor(s.streamInterceptor),
+	}
+	if certFiles != "" {
+		creds, err := credentials.NewServerTLSFromFile(fmt.Sprint(certFiles, ".crt"), fmt.Sprint(certFiles, ".key"))
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	s.server = grpc.NewServer(opts...)
+	grpcapi.RegisterBlockbookServer(s.server, s)
+	return s, nil
+}
+
+// Run serves the connections until the server is closed
+func (s *GrpcServer) Run() error {
+	glog.Info("grpc server: starting to listen on ", s.binding)
+	err := s.server.Serve(s.listener)
+	if atomic.LoadInt32(&s.closed) != 0 {
+		return nil
+	}
+	return err
+}
+
+// Close closes the listener and all client connections
+func (s *GrpcServer) Close() error {
+	glog.Infof("grpc server: closing")
+	atomic.StoreInt32(&s.closed, 1)
+	s.server.Stop()
+	return nil
+}
+
+// Shutdown waits for the running calls to finish, the subscription streams are closed when the context expires
+func (s *GrpcServer) Shutdown(ctx context.Context) error {
+	glog.Infof("grpc server: shutdown")
+	atomic.StoreInt32(&s.closed, 1)
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.server.Stop()
+	}
+	return nil
+}
+
+func (s *GrpcServer) observe(method string, start time.Time, err error) {
+	s.metrics.GrpcReqDuration.With(common.Labels{"method": method}).Observe(float64(time.Since(start)) / 1e3) // in microseconds
+	st := "success"
+	if err != nil {
+		st = "failure"
+	}
+	s.metrics.GrpcRequests.With(common.Labels{"method": method, "status": st}).Inc()
+}
+
+func (s *GrpcServer) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.observe(info.FullMethod, start, err)
+	return resp, err
+}
+
+func (s *GrpcServer) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	s.metrics.GrpcStreams.Inc()
+	defer s.metrics.GrpcStreams.Dec()
+	s.metrics.GrpcRequests.With(common.Labels{"method": info.FullMethod, "status": "stream"}).Inc()
+	return handler(srv, ss)
+}
+
+// grpcError converts the error to the gRPC status, the public api errors are returned as invalid arguments
+// with their code in the trailer, other errors are logged and hidden from the client
+func grpcError(ctx context.Context, err error) error {
+	if apiErr, ok := err.(*api.APIError); ok {
+		if apiErr.Code != "" {
+			grpc.SetTrailer(ctx, metadata.Pairs(grpcErrorCodeKey, apiErr.Code))
+		}
+		if apiErr.Public {
+			return status.Error(codes.InvalidArgument, apiErr.Text)
+		}
+		return status.Error(codes.Internal, apiErr.Text)
+	}
+	glog.Error("grpc error: ", err)
+	return status.Error(codes.Internal, "Internal server error")
+}
+
+func grpcVin(vin []api.Vin) []*grpcapi.Vin {
+	rv := make([]*grpcapi.Vin, len(vin))
+	for i := range vin {
+		v := &vin[i]
+		rv[i] = &grpcapi.Vin{
+			Txid:      v.Txid,
+			Vout:      v.Vout,
+			Sequence:  v.Sequence,
+			N:         int32(v.N),
+			Addresses: v.Addresses,
+			IsAddress: v.IsAddress,
+			Value:     v.ValueSat.String(),
+			Hex:       v.Hex,
+			Coinbase:  v.Coinbase,
+		}
+	}
+	return rv
+}
+
+func grpcVout(vout []api.Vout) []*grpcapi.Vout {
+	rv := make([]*grpcapi.Vout, len(vout))
+	for i := range vout {
+		v := &vout[i]
+		rv[i] = &grpcapi.Vout{
+			Value:       v.ValueSat.String(),
+			N:           int32(v.N),
+			Spent:       v.Spent,
+			SpentTxId:   v.SpentTxID,
+			SpentIndex:  int32(v.SpentIndex),
+			SpentHeight: int32(v.SpentHeight),
+			Hex:         v.Hex,
+			Addresses:   v.Addresses,
+			IsAddress:   v.IsAddress,
+			Type:        v.Type,
+		}
+	}
+	return rv
+}
+
+func grpcTx(tx *api.Tx) *grpcapi.Tx {
+	return &grpcapi.Tx{
+		Txid:          tx.Txid,
+		Version:       tx.Version,
+		LockTime:      tx.Locktime,
+		Vin:           grpcVin(tx.Vin),
+		Vout:          grpcVout(tx.Vout),
+		BlockHash:     tx.Blockhash,
+		BlockHeight:   int32(tx.Blockheight),
+		Confirmations: tx.Confirmations,
+		BlockTime:     tx.Blocktime,
+		Size:          int32(tx.Size),
+		Value:         tx.ValueOutSat.String(),
+		ValueIn:       tx.ValueInSat.String(),
+		Fees:          tx.FeesSat.String(),
+		Hex:           tx.Hex,
+		Rbf:           tx.Rbf,
+	}
+}
+
+func grpcTxs(txs []*api.Tx) []*grpcapi.Tx {
+	rv := make([]*grpcapi.Tx, len(txs))
+	for i, tx := range txs {
+		rv[i] = grpcTx(tx)
+	}
+	return rv
+}
+
+func grpcAddress(a *api.Address) *grpcapi.Address {
+	rv := &grpcapi.Address{
+		Page:               int32(a.Page),
+		TotalPages:         int32(a.TotalPages),
+		ItemsOnPage:        int32(a.ItemsOnPage),
+		Address:            a.AddrStr,
+		Balance:            a.BalanceSat.String(),
+		TotalReceived:      a.TotalReceivedSat.String(),
+		TotalSent:          a.TotalSentSat.String(),
+		UnconfirmedBalance: a.UnconfirmedBalanceSat.String(),
+		UnconfirmedTxs:     int32(a.UnconfirmedTxs),
+		Txs:                int32(a.Txs),
+		Transactions:       grpcTxs(a.Transactions),
+		Txids:              a.Txids,
+		UsedTokens:         int32(a.UsedTokens),
+		Tokens:             make([]*grpcapi.Token, len(a.Tokens)),
+		NextCursor:         a.NextCursor,
+		Nonce:              a.Nonce,
+		NonTokenTxs:        int32(a.NonTokenTxs),
+	}
+	for i := range a.Tokens {
+		t := &a.Tokens[i]
+		rv.Tokens[i] = &grpcapi.Token{
+			Type:          string(t.Type),
+			Name:          t.Name,
+			Path:          t.Path,
+			Contract:      t.Contract,
+			Transfers:     int32(t.Transfers),
+			Symbol:        t.Symbol,
+			Decimals:      int32(t.Decimals),
+			Balance:       t.BalanceSat.String(),
+			TotalReceived: t.TotalReceivedSat.String(),
+			TotalSent:     t.TotalSentSat.String(),
+		}
+	}
+	return rv
+}
+
+func grpcAccountDetails(d grpcapi.AccountDetails) api.AccountDetails {
+	switch d {
+	case grpcapi.AccountDetails_BASIC:
+		return api.AccountDetailsBasic
+	case grpcapi.AccountDetails_TOKENS:
+		return api.AccountDetailsTokens
+	case grpcapi.AccountDetails_TOKEN_BALANCES:
+		return api.AccountDetailsTokenBalances
+	case grpcapi.AccountDetails_TXS:
+		return api.AccountDetailsTxHistory
+	}
+	return api.AccountDetailsTxidHistory
+}
+
+func grpcPageSize(pageSize int32) int {
+	if pageSize <= 0 || pageSize > txsInAPI {
+		return txsInAPI
+	}
+	return int(pageSize)
+}
+
+// GetTransaction returns the transaction
+func (s *GrpcServer) GetTransaction(ctx context.Context, req *grpcapi.GetTransactionRequest) (*grpcapi.Tx, error) {
+	if req.Txid == "" {
+		return nil, status.Error(codes.InvalidArgument, "Missing txid")
+	}
+	tx, err := s.api.GetTransaction(req.Txid, req.Spending, false)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return grpcTx(tx), nil
+}
+
+// GetAddress returns the balances and the transactions of the address
+func (s *GrpcServer) GetAddress(ctx context.Context, req *grpcapi.GetAddressRequest) (*grpcapi.Address, error) {
+	if req.Address == "" {
+		return nil, status.Error(codes.InvalidArgument, "Missing address")
+	}
+	a, err := s.api.GetAddress(req.Address, int(req.Page), grpcPageSize(req.PageSize), grpcAccountDetails(req.Details), &api.AddressFilter{
+		Vout:           api.AddressFilterVoutOff,
+		TokensToReturn: api.TokensToReturnNonzeroBalance,
+		FromHeight:     req.FromHeight,
+		ToHeight:       req.ToHeight,
+		Cursor:         req.Cursor,
+	})
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return grpcAddress(a), nil
+}
+
+// GetXpub returns the balances and the transactions of the xpub
+func (s *GrpcServer) GetXpub(ctx context.Context, req *grpcapi.GetXpubRequest) (*grpcapi.Address, error) {
+	if req.Xpub == "" {
+		return nil, status.Error(codes.InvalidArgument, "Missing xpub")
+	}
+	a, err := s.api.GetXpubAddress(req.Xpub, int(req.Page), grpcPageSize(req.PageSize), grpcAccountDetails(req.Details), &api.AddressFilter{
+		Vout:           api.AddressFilterVoutOff,
+		TokensToReturn: api.TokensToReturn(req.Tokens),
+		FromHeight:     req.FromHeight,
+		ToHeight:       req.ToHeight,
+	}, int(req.Gap))
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return grpcAddress(a), nil
+}
+
+// GetUtxo returns the unspent outputs of the xpub or of the address
+func (s *GrpcServer) GetUtxo(ctx context.Context, req *grpcapi.GetUtxoRequest) (*grpcapi.UtxoList, error) {
+	if req.Descriptor_ == "" {
+		return nil, status.Error(codes.InvalidArgument, "Missing descriptor")
+	}
+	utxo, err := s.api.GetXpubUtxo(req.Descriptor_, req.Confirmed, int(req.Gap))
+	if err != nil {
+		utxo, err = s.api.GetAddressUtxo(req.Descriptor_, req.Confirmed)
+		if err != nil {
+			return nil, grpcError(ctx, err)
+		}
+	}
+	rv := &grpcapi.UtxoList{Utxos: make([]*grpcapi.Utxo, len(utxo))}
+	for i := range utxo {
+		u := &utxo[i]
+		rv.Utxos[i] = &grpcapi.Utxo{
+			Txid:          u.Txid,
+			Vout:          u.Vout,
+			Value:         u.AmountSat.String(),
+			Height:        int32(u.Height),
+			Confirmations: int32(u.Confirmations),
+			Address:       u.Address,
+			Path:          u.Path,
+			LockTime:      u.Locktime,
+			Coinbase:      u.Coinbase,
+		}
+	}
+	return rv, nil
+}
+
+// GetBlock returns the block with its transactions
+func (s *GrpcServer) GetBlock(ctx context.Context, req *grpcapi.GetBlockRequest) (*grpcapi.Block, error) {
+	if req.Block == "" {
+		return nil, status.Error(codes.InvalidArgument, "Missing block")
+	}
+	b, err := s.api.GetBlock(req.Block, int(req.Page), txsInAPI)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+	return &grpcapi.Block{
+		Page:              int32(b.Page),
+		TotalPages:        int32(b.TotalPages),
+		ItemsOnPage:       int32(b.ItemsOnPage),
+		Hash:              b.Hash,
+		PreviousBlockHash: b.Prev,
+		NextBlockHash:     b.Next,
+		Height:            b.Height,
+		Confirmations:     int32(b.Confirmations),
+		Size:              int32(b.Size),
+		Time:              b.Time,
+		Version:           b.Version.String(),
+		MerkleRoot:        b.MerkleRoot,
+		Nonce:             b.Nonce,
+		Bits:              b.Bits,
+		Difficulty:        b.Difficulty,
+		TxCount:           int32(b.TxCount),
+		Txs:               grpcTxs(b.Transactions),
+	}, nil
+}
+
+// EstimateFee returns the fee per kilobyte in the base units of the coin for each requested number of blocks
+func (s *GrpcServer) EstimateFee(ctx context.Context, req *grpcapi.EstimateFeeRequest) (*grpcapi.EstimateFeeResponse, error) {
+	if len(req.Blocks) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Missing blocks")
+	}
+	rv := &grpcapi.EstimateFeeResponse{FeePerKb: make([]string, len(req.Blocks))}
+	for i, b := range req.Blocks {
+		fee, err := s.chain.EstimateSmartFee(int(b), req.Conservative)
+		if err != nil {
+			fee, err = s.chain.EstimateFee(int(b))
+			if err != nil {
+				return nil, grpcError(ctx, err)
+			}
+		}
+		rv.FeePerKb[i] = fee.String()
+	}
+	return rv, nil
+}
+
+// SendTransaction sends the transaction to the backend or only validates it
+func (s *GrpcServer) SendTransaction(ctx context.Context, req *grpcapi.SendTransactionRequest) (*grpcapi.SendTransactionResponse, error) {
+	if req.Hex == "" {
+		return nil, status.Error(codes.InvalidArgument, "Missing tx blob")
+	}
+	if req.ValidateOnly {
+		v, err := s.api.ValidateTransaction(req.Hex)
+		if err != nil {
+			return nil, grpcError(ctx, err)
+		}
+		return &grpcapi.SendTransactionResponse{
+			Txid:        v.Txid,
+			Vsize:       int32(v.Vsize),
+			Fee:         v.FeeSat.String(),
+			FeeRate:     v.FeeRate,
+			ValidatedBy: v.ValidatedBy,
+		}, nil
+	}
+	txid, err := s.chain.SendRawTransaction(req.Hex)
+	if err != nil {
+		return nil, grpcError(ctx, api.SendTxError(err))
+	}
+	return &grpcapi.SendTransactionResponse{Txid: txid}, nil
+}
+
+// serveSubscription sends the notifications of the subscription to the stream until the client cancels it
+func serveSubscription(ctx context.Context, sub *grpcSubscription, send func(m interface{}) error) error {
+	for {
+		select {
+		case m, ok := <-sub.out:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "Subscriber does not keep up with the notifications")
+			}
+			if err := send(m); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// notify sends the notification to the subscription, the subscription which does not keep up is closed;
+// it must be called with the lock of the subscriptions held and returns false if the subscription was closed
+func (sub *grpcSubscription) notify(m interface{}) bool {
+	if sub.closed {
+		return false
+	}
+	select {
+	case sub.out <- m:
+		return true
+	default:
+		sub.closed = true
+		close(sub.out)
+		return false
+	}
+}
+
+// SubscribeNewBlock streams the new blocks
+func (s *GrpcServer) SubscribeNewBlock(req *grpcapi.SubscribeNewBlockRequest, stream grpcapi.Blockbook_SubscribeNewBlockServer) error {
+	sub := &grpcSubscription{out: make(chan interface{}, outChannelSize)}
+	s.newBlockSubscriptionsLock.Lock()
+	s.newBlockSubscriptions[sub] = struct{}{}
+	s.newBlockSubscriptionsLock.Unlock()
+	defer func() {
+		s.newBlockSubscriptionsLock.Lock()
+		delete(s.newBlockSubscriptions, sub)
+		s.newBlockSubscriptionsLock.Unlock()
+	}()
+	return serveSubscription(stream.Context(), sub, func(m interface{}) error {
+		return stream.Send(m.(*grpcapi.NewBlock))
+	})
+}
+
+// SubscribeAddresses streams the new transactions of the addresses
+func (s *GrpcServer) SubscribeAddresses(req *grpcapi.SubscribeAddressesRequest, stream grpcapi.Blockbook_SubscribeAddressesServer) error {
+	if len(req.Addresses) == 0 {
+		return status.Error(codes.InvalidArgument, "Missing addresses")
+	}
+	addrDescs := make([]string, len(req.Addresses))
+	for i, a := range req.Addresses {
+		ad, err := s.chainParser.GetAddrDescFromAddress(a)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, "Invalid address "+a+", "+err.Error())
+		}
+		addrDescs[i] = string(ad)
+	}
+	sub := &grpcSubscription{out: make(chan interface{}, outChannelSize)}
+	s.addressSubscriptionsLock.Lock()
+	for _, ad := range addrDescs {
+		as, ok := s.addressSubscriptions[ad]
+		if !ok {
+			as = make(map[*grpcSubscription]struct{})
+			s.addressSubscriptions[ad] = as
+		}
+		as[sub] = struct{}{}
+	}
+	s.addressSubscriptionsLock.Unlock()
+	defer func() {
+		s.addressSubscriptionsLock.Lock()
+		for _, ad := range addrDescs {
+			if as, ok := s.addressSubscriptions[ad]; ok {
+				delete(as, sub)
+				if len(as) == 0 {
+					delete(s.addressSubscriptions, ad)
+				}
+			}
+		}
+		s.addressSubscriptionsLock.Unlock()
+	}()
+	return serveSubscription(stream.Context(), sub, func(m interface{}) error {
+		return stream.Send(m.(*grpcapi.AddressTx))
+	})
+}
+
+// OnNewBlock sends the new block to the subscribed streams
+func (s *GrpcServer) OnNewBlock(hash string, height uint32) {
+	s.newBlockSubscriptionsLock.Lock()
+	defer s.newBlockSubscriptionsLock.Unlock()
+	m := &grpcapi.NewBlock{Height: height, Hash: hash}
+	for sub := range s.newBlockSubscriptions {
+		if !sub.notify(m) {
+			delete(s.newBlockSubscriptions, sub)
+		}
+	}
+	glog.Info("grpc server: broadcasting new block ", height, " ", hash, " to ", len(s.newBlockSubscriptions), " streams")
+}
+
+// OnNewTxAddr sends the new transaction to the streams subscribed to the address
+func (s *GrpcServer) OnNewTxAddr(tx *bchain.Tx, addrDesc bchain.AddressDescriptor) {
+	// check if there is any subscription but release the lock immediately, GetTransactionFromBchainTx may take some time
+	s.addressSubscriptionsLock.Lock()
+	as, ok := s.addressSubscriptions[string(addrDesc)]
+	subscribed := ok && len(as) > 0
+	s.addressSubscriptionsLock.Unlock()
+	if !subscribed {
+		return
+	}
+	addr, _, err := s.chainParser.GetAddressesFromAddrDesc(addrDesc)
+	if err != nil || len(addr) != 1 {
+		return
+	}
+	atx, err := s.api.GetTransactionFromBchainTx(tx, 0, false, false)
+	if err != nil {
+		glog.Error("GetTransactionFromBchainTx error ", err, " for ", tx.Txid)
+		return
+	}
+	m := &grpcapi.AddressTx{Address: addr[0], Tx: grpcTx(atx)}
+	s.addressSubscriptionsLock.Lock()
+	defer s.addressSubscriptionsLock.Unlock()
+	as = s.addressSubscriptions[string(addrDesc)]
+	for sub := range as {
+		// the closed subscription is removed from all its addresses by its stream
+		sub.notify(m)
+	}
+	glog.Info("grpc server: broadcasting new tx ", tx.Txid, " for addr ", addr[0], " to ", len(as), " streams")
+}
@@ -0,0 +1,164 @@
+// +build unittest
+
+package server
+
+import (
+	"blockbook/server/grpcapi"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcTestsBitcoinType runs the gRPC server on top of the same index and fake chain as the public server
+func grpcTestsBitcoinType(t *testing.T, ps *PublicServer) {
+	s, err := NewGrpcServer("127.0.0.1:0", "", ps.db, ps.chain, ps.mempool, ps.txCache, ps.metrics, ps.is)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Run()
+	defer s.Close()
+
+	conn, err := grpc.Dial(s.listener.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	c := grpcapi.NewBlockbookClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	t.Run("GetTransaction", func(t *testing.T) {
+		tx, err := c.GetTransaction(ctx, &grpcapi.GetTransactionRequest{Txid: "05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Value != "9000" || tx.ValueIn != "9876" || tx.Fees != "876" || tx.BlockHeight != 225494 {
+			t.Errorf("GetTransaction() = %+v", tx)
+		}
+	})
+
+	t.Run("GetTransaction not found", func(t *testing.T) {
+		_, err := c.GetTransaction(ctx, &grpcapi.GetTransactionRequest{Txid: "0000000000000000000000000000000000000000000000000000000000000000"})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("GetTransaction() error = %v, want InvalidArgument", err)
+		}
+	})
+
+	t.Run("GetAddress", func(t *testing.T) {
+		a, err := c.GetAddress(ctx, &grpcapi.GetAddressRequest{Address: "mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Txs != 2 || a.TotalReceived != "1234567890123" || a.Balance != "0" {
+			t.Errorf("GetAddress() = %+v", a)
+		}
+		want := []string{
+			"7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25",
+			"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75",
+		}
+		if !reflect.DeepEqual(a.Txids, want) {
+			t.Errorf("GetAddress().Txids = %v, want %v", a.Txids, want)
+		}
+	})
+
+	t.Run("GetUtxo", func(t *testing.T) {
+		u, err := c.GetUtxo(ctx, &grpcapi.GetUtxoRequest{Descriptor_: "mtR97eM2HPWVM6c8FGLGcukgaHHQv7THoL"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []*grpcapi.Utxo{{
+			Txid:          "7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25",
+			Vout:          1,
+			Value:         "917283951061",
+			Height:        225494,
+			Confirmations: 1,
+		}}
+		if !reflect.DeepEqual(u.Utxos, want) {
+			t.Errorf("GetUtxo() = %+v, want %+v", u.Utxos, want)
+		}
+	})
+
+	t.Run("GetBlock", func(t *testing.T) {
+		b, err := c.GetBlock(ctx, &grpcapi.GetBlockRequest{Block: "225493"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Hash != "0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997" ||
+			b.NextBlockHash != "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6" ||
+			b.Confirmations != 2 || b.TxCount != 2 || len(b.Txs) != 2 {
+			t.Fatalf("GetBlock() = %+v", b)
+		}
+		if b.Txs[0].Txid != "00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840" {
+			t.Errorf("GetBlock().Txs[0].Txid = %v", b.Txs[0].Txid)
+		}
+	})
+
+	t.Run("EstimateFee", func(t *testing.T) {
+		f, err := c.EstimateFee(ctx, &grpcapi.EstimateFeeRequest{Blocks: []int32{1, 123}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"99", "12299"}
+		if !reflect.DeepEqual(f.FeePerKb, want) {
+			t.Errorf("EstimateFee() = %v, want %v", f.FeePerKb, want)
+		}
+	})
+
+	t.Run("SendTransaction invalid", func(t *testing.T) {
+		var trailer metadata.MD
+		_, err := c.SendTransaction(ctx, &grpcapi.SendTransactionRequest{Hex: "123456", ValidateOnly: true}, grpc.Trailer(&trailer))
+		if status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("SendTransaction() error = %v, want InvalidArgument", err)
+		}
+		if code := trailer.Get(grpcErrorCodeKey); len(code) != 1 || code[0] != "invalid_tx" {
+			t.Errorf("SendTransaction() trailer %v = %v, want invalid_tx", grpcErrorCodeKey, code)
+		}
+	})
+
+	t.Run("SubscribeNewBlock", func(t *testing.T) {
+		sctx, scancel := context.WithCancel(ctx)
+		defer scancel()
+		stream, err := c.SubscribeNewBlock(sctx, &grpcapi.SubscribeNewBlockRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// wait until the stream is registered by the server
+		for i := 0; ; i++ {
+			s.newBlockSubscriptionsLock.Lock()
+			n := len(s.newBlockSubscriptions)
+			s.newBlockSubscriptionsLock.Unlock()
+			if n > 0 {
+				break
+			}
+			if i == 100 {
+				t.Fatal("Timeout while waiting for the subscription")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		s.OnNewBlock("00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6", 225494)
+		b, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Height != 225494 || b.Hash != "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6" {
+			t.Errorf("SubscribeNewBlock() = %+v", b)
+		}
+	})
+
+	t.Run("SubscribeAddresses invalid", func(t *testing.T) {
+		stream, err := c.SubscribeAddresses(ctx, &grpcapi.SubscribeAddressesRequest{Addresses: []string{"invalid"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("SubscribeAddresses() error = %v, want InvalidArgument", err)
+		}
+	})
+}
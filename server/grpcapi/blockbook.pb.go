@@ -0,0 +1,1820 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: blockbook.proto
+
+/*
+Package grpcapi is a generated protocol buffer package.
+
+It is generated from these files:
+
+	blockbook.proto
+
+It has these top-level messages:
+
+	GetTransactionRequest
+	GetAddressRequest
+	GetXpubRequest
+	GetUtxoRequest
+	GetBlockRequest
+	EstimateFeeRequest
+	EstimateFeeResponse
+	SendTransactionRequest
+	SendTransactionResponse
+	SubscribeNewBlockRequest
+	SubscribeAddressesRequest
+	Vin
+	Vout
+	Tx
+	Token
+	Address
+	Utxo
+	UtxoList
+	Block
+	NewBlock
+	AddressTx
+*/
+package grpcapi
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+// AccountDetails specifies the level of details of the address and xpub, TXIDS is the default as in the REST API
+type AccountDetails int32
+
+const (
+	AccountDetails_TXIDS          AccountDetails = 0
+	AccountDetails_BASIC          AccountDetails = 1
+	AccountDetails_TOKENS         AccountDetails = 2
+	AccountDetails_TOKEN_BALANCES AccountDetails = 3
+	AccountDetails_TXS            AccountDetails = 4
+)
+
+var AccountDetails_name = map[int32]string{
+	0: "TXIDS",
+	1: "BASIC",
+	2: "TOKENS",
+	3: "TOKEN_BALANCES",
+	4: "TXS",
+}
+var AccountDetails_value = map[string]int32{
+	"TXIDS":          0,
+	"BASIC":          1,
+	"TOKENS":         2,
+	"TOKEN_BALANCES": 3,
+	"TXS":            4,
+}
+
+func (x AccountDetails) String() string {
+	return proto.EnumName(AccountDetails_name, int32(x))
+}
+func (AccountDetails) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+// TokensToReturn specifies which addresses derived from the xpub are returned
+type TokensToReturn int32
+
+const (
+	TokensToReturn_NONZERO TokensToReturn = 0
+	TokensToReturn_USED    TokensToReturn = 1
+	TokensToReturn_DERIVED TokensToReturn = 2
+)
+
+var TokensToReturn_name = map[int32]string{
+	0: "NONZERO",
+	1: "USED",
+	2: "DERIVED",
+}
+var TokensToReturn_value = map[string]int32{
+	"NONZERO": 0,
+	"USED":    1,
+	"DERIVED": 2,
+}
+
+func (x TokensToReturn) String() string {
+	return proto.EnumName(TokensToReturn_name, int32(x))
+}
+func (TokensToReturn) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+type GetTransactionRequest struct {
+	Txid     string `protobuf:"bytes,1,opt,name=txid" json:"txid,omitempty"`
+	Spending bool   `protobuf:"varint,2,opt,name=spending" json:"spending,omitempty"`
+}
+
+func (m *GetTransactionRequest) Reset()                    { *m = GetTransactionRequest{} }
+func (m *GetTransactionRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetTransactionRequest) ProtoMessage()               {}
+func (*GetTransactionRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *GetTransactionRequest) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *GetTransactionRequest) GetSpending() bool {
+	if m != nil {
+		return m.Spending
+	}
+	return false
+}
+
+type GetAddressRequest struct {
+	Address    string         `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	Page       int32          `protobuf:"varint,2,opt,name=page" json:"page,omitempty"`
+	PageSize   int32          `protobuf:"varint,3,opt,name=page_size,json=pageSize" json:"page_size,omitempty"`
+	Details    AccountDetails `protobuf:"varint,4,opt,name=details,enum=blockbook.AccountDetails" json:"details,omitempty"`
+	FromHeight uint32         `protobuf:"varint,5,opt,name=from_height,json=fromHeight" json:"from_height,omitempty"`
+	ToHeight   uint32         `protobuf:"varint,6,opt,name=to_height,json=toHeight" json:"to_height,omitempty"`
+	Cursor     string         `protobuf:"bytes,7,opt,name=cursor" json:"cursor,omitempty"`
+}
+
+func (m *GetAddressRequest) Reset()                    { *m = GetAddressRequest{} }
+func (m *GetAddressRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetAddressRequest) ProtoMessage()               {}
+func (*GetAddressRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *GetAddressRequest) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *GetAddressRequest) GetPage() int32 {
+	if m != nil {
+		return m.Page
+	}
+	return 0
+}
+
+func (m *GetAddressRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *GetAddressRequest) GetDetails() AccountDetails {
+	if m != nil {
+		return m.Details
+	}
+	return AccountDetails_TXIDS
+}
+
+func (m *GetAddressRequest) GetFromHeight() uint32 {
+	if m != nil {
+		return m.FromHeight
+	}
+	return 0
+}
+
+func (m *GetAddressRequest) GetToHeight() uint32 {
+	if m != nil {
+		return m.ToHeight
+	}
+	return 0
+}
+
+func (m *GetAddressRequest) GetCursor() string {
+	if m != nil {
+		return m.Cursor
+	}
+	return ""
+}
+
+type GetXpubRequest struct {
+	Xpub       string         `protobuf:"bytes,1,opt,name=xpub" json:"xpub,omitempty"`
+	Page       int32          `protobuf:"varint,2,opt,name=page" json:"page,omitempty"`
+	PageSize   int32          `protobuf:"varint,3,opt,name=page_size,json=pageSize" json:"page_size,omitempty"`
+	Details    AccountDetails `protobuf:"varint,4,opt,name=details,enum=blockbook.AccountDetails" json:"details,omitempty"`
+	FromHeight uint32         `protobuf:"varint,5,opt,name=from_height,json=fromHeight" json:"from_height,omitempty"`
+	ToHeight   uint32         `protobuf:"varint,6,opt,name=to_height,json=toHeight" json:"to_height,omitempty"`
+	Tokens     TokensToReturn `protobuf:"varint,7,opt,name=tokens,enum=blockbook.TokensToReturn" json:"tokens,omitempty"`
+	Gap        int32          `protobuf:"varint,8,opt,name=gap" json:"gap,omitempty"`
+}
+
+func (m *GetXpubRequest) Reset()                    { *m = GetXpubRequest{} }
+func (m *GetXpubRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetXpubRequest) ProtoMessage()               {}
+func (*GetXpubRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *GetXpubRequest) GetXpub() string {
+	if m != nil {
+		return m.Xpub
+	}
+	return ""
+}
+
+func (m *GetXpubRequest) GetPage() int32 {
+	if m != nil {
+		return m.Page
+	}
+	return 0
+}
+
+func (m *GetXpubRequest) GetPageSize() int32 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
+}
+
+func (m *GetXpubRequest) GetDetails() AccountDetails {
+	if m != nil {
+		return m.Details
+	}
+	return AccountDetails_TXIDS
+}
+
+func (m *GetXpubRequest) GetFromHeight() uint32 {
+	if m != nil {
+		return m.FromHeight
+	}
+	return 0
+}
+
+func (m *GetXpubRequest) GetToHeight() uint32 {
+	if m != nil {
+		return m.ToHeight
+	}
+	return 0
+}
+
+func (m *GetXpubRequest) GetTokens() TokensToReturn {
+	if m != nil {
+		return m.Tokens
+	}
+	return TokensToReturn_NONZERO
+}
+
+func (m *GetXpubRequest) GetGap() int32 {
+	if m != nil {
+		return m.Gap
+	}
+	return 0
+}
+
+// GetUtxoRequest returns the utxos of an address or of an xpub
+type GetUtxoRequest struct {
+	Descriptor_ string `protobuf:"bytes,1,opt,name=descriptor" json:"descriptor,omitempty"`
+	Confirmed   bool   `protobuf:"varint,2,opt,name=confirmed" json:"confirmed,omitempty"`
+	Gap         int32  `protobuf:"varint,3,opt,name=gap" json:"gap,omitempty"`
+}
+
+func (m *GetUtxoRequest) Reset()                    { *m = GetUtxoRequest{} }
+func (m *GetUtxoRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetUtxoRequest) ProtoMessage()               {}
+func (*GetUtxoRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *GetUtxoRequest) GetDescriptor_() string {
+	if m != nil {
+		return m.Descriptor_
+	}
+	return ""
+}
+
+func (m *GetUtxoRequest) GetConfirmed() bool {
+	if m != nil {
+		return m.Confirmed
+	}
+	return false
+}
+
+func (m *GetUtxoRequest) GetGap() int32 {
+	if m != nil {
+		return m.Gap
+	}
+	return 0
+}
+
+// GetBlockRequest returns the block by its hash or height
+type GetBlockRequest struct {
+	Block string `protobuf:"bytes,1,opt,name=block" json:"block,omitempty"`
+	Page  int32  `protobuf:"varint,2,opt,name=page" json:"page,omitempty"`
+}
+
+func (m *GetBlockRequest) Reset()                    { *m = GetBlockRequest{} }
+func (m *GetBlockRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetBlockRequest) ProtoMessage()               {}
+func (*GetBlockRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *GetBlockRequest) GetBlock() string {
+	if m != nil {
+		return m.Block
+	}
+	return ""
+}
+
+func (m *GetBlockRequest) GetPage() int32 {
+	if m != nil {
+		return m.Page
+	}
+	return 0
+}
+
+type EstimateFeeRequest struct {
+	Blocks       []int32 `protobuf:"varint,1,rep,packed,name=blocks" json:"blocks,omitempty"`
+	Conservative bool    `protobuf:"varint,2,opt,name=conservative" json:"conservative,omitempty"`
+}
+
+func (m *EstimateFeeRequest) Reset()                    { *m = EstimateFeeRequest{} }
+func (m *EstimateFeeRequest) String() string            { return proto.CompactTextString(m) }
+func (*EstimateFeeRequest) ProtoMessage()               {}
+func (*EstimateFeeRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *EstimateFeeRequest) GetBlocks() []int32 {
+	if m != nil {
+		return m.Blocks
+	}
+	return nil
+}
+
+func (m *EstimateFeeRequest) GetConservative() bool {
+	if m != nil {
+		return m.Conservative
+	}
+	return false
+}
+
+// EstimateFeeResponse contains the fee per kilobyte for each number of blocks of the request
+type EstimateFeeResponse struct {
+	FeePerKb []string `protobuf:"bytes,1,rep,name=fee_per_kb,json=feePerKb" json:"fee_per_kb,omitempty"`
+}
+
+func (m *EstimateFeeResponse) Reset()                    { *m = EstimateFeeResponse{} }
+func (m *EstimateFeeResponse) String() string            { return proto.CompactTextString(m) }
+func (*EstimateFeeResponse) ProtoMessage()               {}
+func (*EstimateFeeResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *EstimateFeeResponse) GetFeePerKb() []string {
+	if m != nil {
+		return m.FeePerKb
+	}
+	return nil
+}
+
+type SendTransactionRequest struct {
+	Hex          string `protobuf:"bytes,1,opt,name=hex" json:"hex,omitempty"`
+	ValidateOnly bool   `protobuf:"varint,2,opt,name=validate_only,json=validateOnly" json:"validate_only,omitempty"`
+}
+
+func (m *SendTransactionRequest) Reset()                    { *m = SendTransactionRequest{} }
+func (m *SendTransactionRequest) String() string            { return proto.CompactTextString(m) }
+func (*SendTransactionRequest) ProtoMessage()               {}
+func (*SendTransactionRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *SendTransactionRequest) GetHex() string {
+	if m != nil {
+		return m.Hex
+	}
+	return ""
+}
+
+func (m *SendTransactionRequest) GetValidateOnly() bool {
+	if m != nil {
+		return m.ValidateOnly
+	}
+	return false
+}
+
+// SendTransactionResponse contains the txid of the sent transaction, the other fields are set only by the validation
+type SendTransactionResponse struct {
+	Txid        string  `protobuf:"bytes,1,opt,name=txid" json:"txid,omitempty"`
+	Vsize       int32   `protobuf:"varint,2,opt,name=vsize" json:"vsize,omitempty"`
+	Fee         string  `protobuf:"bytes,3,opt,name=fee" json:"fee,omitempty"`
+	FeeRate     float64 `protobuf:"fixed64,4,opt,name=fee_rate,json=feeRate" json:"fee_rate,omitempty"`
+	ValidatedBy string  `protobuf:"bytes,5,opt,name=validated_by,json=validatedBy" json:"validated_by,omitempty"`
+}
+
+func (m *SendTransactionResponse) Reset()                    { *m = SendTransactionResponse{} }
+func (m *SendTransactionResponse) String() string            { return proto.CompactTextString(m) }
+func (*SendTransactionResponse) ProtoMessage()               {}
+func (*SendTransactionResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *SendTransactionResponse) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *SendTransactionResponse) GetVsize() int32 {
+	if m != nil {
+		return m.Vsize
+	}
+	return 0
+}
+
+func (m *SendTransactionResponse) GetFee() string {
+	if m != nil {
+		return m.Fee
+	}
+	return ""
+}
+
+func (m *SendTransactionResponse) GetFeeRate() float64 {
+	if m != nil {
+		return m.FeeRate
+	}
+	return 0
+}
+
+func (m *SendTransactionResponse) GetValidatedBy() string {
+	if m != nil {
+		return m.ValidatedBy
+	}
+	return ""
+}
+
+type SubscribeNewBlockRequest struct {
+}
+
+func (m *SubscribeNewBlockRequest) Reset()                    { *m = SubscribeNewBlockRequest{} }
+func (m *SubscribeNewBlockRequest) String() string            { return proto.CompactTextString(m) }
+func (*SubscribeNewBlockRequest) ProtoMessage()               {}
+func (*SubscribeNewBlockRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+type SubscribeAddressesRequest struct {
+	Addresses []string `protobuf:"bytes,1,rep,name=addresses" json:"addresses,omitempty"`
+}
+
+func (m *SubscribeAddressesRequest) Reset()                    { *m = SubscribeAddressesRequest{} }
+func (m *SubscribeAddressesRequest) String() string            { return proto.CompactTextString(m) }
+func (*SubscribeAddressesRequest) ProtoMessage()               {}
+func (*SubscribeAddressesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *SubscribeAddressesRequest) GetAddresses() []string {
+	if m != nil {
+		return m.Addresses
+	}
+	return nil
+}
+
+type Vin struct {
+	Txid      string   `protobuf:"bytes,1,opt,name=txid" json:"txid,omitempty"`
+	Vout      uint32   `protobuf:"varint,2,opt,name=vout" json:"vout,omitempty"`
+	Sequence  int64    `protobuf:"varint,3,opt,name=sequence" json:"sequence,omitempty"`
+	N         int32    `protobuf:"varint,4,opt,name=n" json:"n,omitempty"`
+	Addresses []string `protobuf:"bytes,5,rep,name=addresses" json:"addresses,omitempty"`
+	IsAddress bool     `protobuf:"varint,6,opt,name=is_address,json=isAddress" json:"is_address,omitempty"`
+	Value     string   `protobuf:"bytes,7,opt,name=value" json:"value,omitempty"`
+	Hex       string   `protobuf:"bytes,8,opt,name=hex" json:"hex,omitempty"`
+	Coinbase  string   `protobuf:"bytes,9,opt,name=coinbase" json:"coinbase,omitempty"`
+}
+
+func (m *Vin) Reset()                    { *m = Vin{} }
+func (m *Vin) String() string            { return proto.CompactTextString(m) }
+func (*Vin) ProtoMessage()               {}
+func (*Vin) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *Vin) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *Vin) GetVout() uint32 {
+	if m != nil {
+		return m.Vout
+	}
+	return 0
+}
+
+func (m *Vin) GetSequence() int64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *Vin) GetN() int32 {
+	if m != nil {
+		return m.N
+	}
+	return 0
+}
+
+func (m *Vin) GetAddresses() []string {
+	if m != nil {
+		return m.Addresses
+	}
+	return nil
+}
+
+func (m *Vin) GetIsAddress() bool {
+	if m != nil {
+		return m.IsAddress
+	}
+	return false
+}
+
+func (m *Vin) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *Vin) GetHex() string {
+	if m != nil {
+		return m.Hex
+	}
+	return ""
+}
+
+func (m *Vin) GetCoinbase() string {
+	if m != nil {
+		return m.Coinbase
+	}
+	return ""
+}
+
+type Vout struct {
+	Value       string   `protobuf:"bytes,1,opt,name=value" json:"value,omitempty"`
+	N           int32    `protobuf:"varint,2,opt,name=n" json:"n,omitempty"`
+	Spent       bool     `protobuf:"varint,3,opt,name=spent" json:"spent,omitempty"`
+	SpentTxId   string   `protobuf:"bytes,4,opt,name=spent_tx_id,json=spentTxId" json:"spent_tx_id,omitempty"`
+	SpentIndex  int32    `protobuf:"varint,5,opt,name=spent_index,json=spentIndex" json:"spent_index,omitempty"`
+	SpentHeight int32    `protobuf:"varint,6,opt,name=spent_height,json=spentHeight" json:"spent_height,omitempty"`
+	Hex         string   `protobuf:"bytes,7,opt,name=hex" json:"hex,omitempty"`
+	Addresses   []string `protobuf:"bytes,8,rep,name=addresses" json:"addresses,omitempty"`
+	IsAddress   bool     `protobuf:"varint,9,opt,name=is_address,json=isAddress" json:"is_address,omitempty"`
+	Type        string   `protobuf:"bytes,10,opt,name=type" json:"type,omitempty"`
+}
+
+func (m *Vout) Reset()                    { *m = Vout{} }
+func (m *Vout) String() string            { return proto.CompactTextString(m) }
+func (*Vout) ProtoMessage()               {}
+func (*Vout) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *Vout) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *Vout) GetN() int32 {
+	if m != nil {
+		return m.N
+	}
+	return 0
+}
+
+func (m *Vout) GetSpent() bool {
+	if m != nil {
+		return m.Spent
+	}
+	return false
+}
+
+func (m *Vout) GetSpentTxId() string {
+	if m != nil {
+		return m.SpentTxId
+	}
+	return ""
+}
+
+func (m *Vout) GetSpentIndex() int32 {
+	if m != nil {
+		return m.SpentIndex
+	}
+	return 0
+}
+
+func (m *Vout) GetSpentHeight() int32 {
+	if m != nil {
+		return m.SpentHeight
+	}
+	return 0
+}
+
+func (m *Vout) GetHex() string {
+	if m != nil {
+		return m.Hex
+	}
+	return ""
+}
+
+func (m *Vout) GetAddresses() []string {
+	if m != nil {
+		return m.Addresses
+	}
+	return nil
+}
+
+func (m *Vout) GetIsAddress() bool {
+	if m != nil {
+		return m.IsAddress
+	}
+	return false
+}
+
+func (m *Vout) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+type Tx struct {
+	Txid          string  `protobuf:"bytes,1,opt,name=txid" json:"txid,omitempty"`
+	Version       int32   `protobuf:"varint,2,opt,name=version" json:"version,omitempty"`
+	LockTime      uint32  `protobuf:"varint,3,opt,name=lock_time,json=lockTime" json:"lock_time,omitempty"`
+	Vin           []*Vin  `protobuf:"bytes,4,rep,name=vin" json:"vin,omitempty"`
+	Vout          []*Vout `protobuf:"bytes,5,rep,name=vout" json:"vout,omitempty"`
+	BlockHash     string  `protobuf:"bytes,6,opt,name=block_hash,json=blockHash" json:"block_hash,omitempty"`
+	BlockHeight   int32   `protobuf:"varint,7,opt,name=block_height,json=blockHeight" json:"block_height,omitempty"`
+	Confirmations uint32  `protobuf:"varint,8,opt,name=confirmations" json:"confirmations,omitempty"`
+	BlockTime     int64   `protobuf:"varint,9,opt,name=block_time,json=blockTime" json:"block_time,omitempty"`
+	Size          int32   `protobuf:"varint,10,opt,name=size" json:"size,omitempty"`
+	Value         string  `protobuf:"bytes,11,opt,name=value" json:"value,omitempty"`
+	ValueIn       string  `protobuf:"bytes,12,opt,name=value_in,json=valueIn" json:"value_in,omitempty"`
+	Fees          string  `protobuf:"bytes,13,opt,name=fees" json:"fees,omitempty"`
+	Hex           string  `protobuf:"bytes,14,opt,name=hex" json:"hex,omitempty"`
+	Rbf           bool    `protobuf:"varint,15,opt,name=rbf" json:"rbf,omitempty"`
+}
+
+func (m *Tx) Reset()                    { *m = Tx{} }
+func (m *Tx) String() string            { return proto.CompactTextString(m) }
+func (*Tx) ProtoMessage()               {}
+func (*Tx) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *Tx) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *Tx) GetVersion() int32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *Tx) GetLockTime() uint32 {
+	if m != nil {
+		return m.LockTime
+	}
+	return 0
+}
+
+func (m *Tx) GetVin() []*Vin {
+	if m != nil {
+		return m.Vin
+	}
+	return nil
+}
+
+func (m *Tx) GetVout() []*Vout {
+	if m != nil {
+		return m.Vout
+	}
+	return nil
+}
+
+func (m *Tx) GetBlockHash() string {
+	if m != nil {
+		return m.BlockHash
+	}
+	return ""
+}
+
+func (m *Tx) GetBlockHeight() int32 {
+	if m != nil {
+		return m.BlockHeight
+	}
+	return 0
+}
+
+func (m *Tx) GetConfirmations() uint32 {
+	if m != nil {
+		return m.Confirmations
+	}
+	return 0
+}
+
+func (m *Tx) GetBlockTime() int64 {
+	if m != nil {
+		return m.BlockTime
+	}
+	return 0
+}
+
+func (m *Tx) GetSize() int32 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *Tx) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *Tx) GetValueIn() string {
+	if m != nil {
+		return m.ValueIn
+	}
+	return ""
+}
+
+func (m *Tx) GetFees() string {
+	if m != nil {
+		return m.Fees
+	}
+	return ""
+}
+
+func (m *Tx) GetHex() string {
+	if m != nil {
+		return m.Hex
+	}
+	return ""
+}
+
+func (m *Tx) GetRbf() bool {
+	if m != nil {
+		return m.Rbf
+	}
+	return false
+}
+
+type Token struct {
+	Type          string `protobuf:"bytes,1,opt,name=type" json:"type,omitempty"`
+	Name          string `protobuf:"bytes,2,opt,name=name" json:"name,omitempty"`
+	Path          string `protobuf:"bytes,3,opt,name=path" json:"path,omitempty"`
+	Contract      string `protobuf:"bytes,4,opt,name=contract" json:"contract,omitempty"`
+	Transfers     int32  `protobuf:"varint,5,opt,name=transfers" json:"transfers,omitempty"`
+	Symbol        string `protobuf:"bytes,6,opt,name=symbol" json:"symbol,omitempty"`
+	Decimals      int32  `protobuf:"varint,7,opt,name=decimals" json:"decimals,omitempty"`
+	Balance       string `protobuf:"bytes,8,opt,name=balance" json:"balance,omitempty"`
+	TotalReceived string `protobuf:"bytes,9,opt,name=total_received,json=totalReceived" json:"total_received,omitempty"`
+	TotalSent     string `protobuf:"bytes,10,opt,name=total_sent,json=totalSent" json:"total_sent,omitempty"`
+}
+
+func (m *Token) Reset()                    { *m = Token{} }
+func (m *Token) String() string            { return proto.CompactTextString(m) }
+func (*Token) ProtoMessage()               {}
+func (*Token) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *Token) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *Token) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Token) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *Token) GetContract() string {
+	if m != nil {
+		return m.Contract
+	}
+	return ""
+}
+
+func (m *Token) GetTransfers() int32 {
+	if m != nil {
+		return m.Transfers
+	}
+	return 0
+}
+
+func (m *Token) GetSymbol() string {
+	if m != nil {
+		return m.Symbol
+	}
+	return ""
+}
+
+func (m *Token) GetDecimals() int32 {
+	if m != nil {
+		return m.Decimals
+	}
+	return 0
+}
+
+func (m *Token) GetBalance() string {
+	if m != nil {
+		return m.Balance
+	}
+	return ""
+}
+
+func (m *Token) GetTotalReceived() string {
+	if m != nil {
+		return m.TotalReceived
+	}
+	return ""
+}
+
+func (m *Token) GetTotalSent() string {
+	if m != nil {
+		return m.TotalSent
+	}
+	return ""
+}
+
+type Address struct {
+	Page               int32    `protobuf:"varint,1,opt,name=page" json:"page,omitempty"`
+	TotalPages         int32    `protobuf:"varint,2,opt,name=total_pages,json=totalPages" json:"total_pages,omitempty"`
+	ItemsOnPage        int32    `protobuf:"varint,3,opt,name=items_on_page,json=itemsOnPage" json:"items_on_page,omitempty"`
+	Address            string   `protobuf:"bytes,4,opt,name=address" json:"address,omitempty"`
+	Balance            string   `protobuf:"bytes,5,opt,name=balance" json:"balance,omitempty"`
+	TotalReceived      string   `protobuf:"bytes,6,opt,name=total_received,json=totalReceived" json:"total_received,omitempty"`
+	TotalSent          string   `protobuf:"bytes,7,opt,name=total_sent,json=totalSent" json:"total_sent,omitempty"`
+	UnconfirmedBalance string   `protobuf:"bytes,8,opt,name=unconfirmed_balance,json=unconfirmedBalance" json:"unconfirmed_balance,omitempty"`
+	UnconfirmedTxs     int32    `protobuf:"varint,9,opt,name=unconfirmed_txs,json=unconfirmedTxs" json:"unconfirmed_txs,omitempty"`
+	Txs                int32    `protobuf:"varint,10,opt,name=txs" json:"txs,omitempty"`
+	Transactions       []*Tx    `protobuf:"bytes,11,rep,name=transactions" json:"transactions,omitempty"`
+	Txids              []string `protobuf:"bytes,12,rep,name=txids" json:"txids,omitempty"`
+	UsedTokens         int32    `protobuf:"varint,13,opt,name=used_tokens,json=usedTokens" json:"used_tokens,omitempty"`
+	Tokens             []*Token `protobuf:"bytes,14,rep,name=tokens" json:"tokens,omitempty"`
+	NextCursor         string   `protobuf:"bytes,15,opt,name=next_cursor,json=nextCursor" json:"next_cursor,omitempty"`
+	Nonce              string   `protobuf:"bytes,16,opt,name=nonce" json:"nonce,omitempty"`
+	NonTokenTxs        int32    `protobuf:"varint,17,opt,name=non_token_txs,json=nonTokenTxs" json:"non_token_txs,omitempty"`
+}
+
+func (m *Address) Reset()                    { *m = Address{} }
+func (m *Address) String() string            { return proto.CompactTextString(m) }
+func (*Address) ProtoMessage()               {}
+func (*Address) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *Address) GetPage() int32 {
+	if m != nil {
+		return m.Page
+	}
+	return 0
+}
+
+func (m *Address) GetTotalPages() int32 {
+	if m != nil {
+		return m.TotalPages
+	}
+	return 0
+}
+
+func (m *Address) GetItemsOnPage() int32 {
+	if m != nil {
+		return m.ItemsOnPage
+	}
+	return 0
+}
+
+func (m *Address) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *Address) GetBalance() string {
+	if m != nil {
+		return m.Balance
+	}
+	return ""
+}
+
+func (m *Address) GetTotalReceived() string {
+	if m != nil {
+		return m.TotalReceived
+	}
+	return ""
+}
+
+func (m *Address) GetTotalSent() string {
+	if m != nil {
+		return m.TotalSent
+	}
+	return ""
+}
+
+func (m *Address) GetUnconfirmedBalance() string {
+	if m != nil {
+		return m.UnconfirmedBalance
+	}
+	return ""
+}
+
+func (m *Address) GetUnconfirmedTxs() int32 {
+	if m != nil {
+		return m.UnconfirmedTxs
+	}
+	return 0
+}
+
+func (m *Address) GetTxs() int32 {
+	if m != nil {
+		return m.Txs
+	}
+	return 0
+}
+
+func (m *Address) GetTransactions() []*Tx {
+	if m != nil {
+		return m.Transactions
+	}
+	return nil
+}
+
+func (m *Address) GetTxids() []string {
+	if m != nil {
+		return m.Txids
+	}
+	return nil
+}
+
+func (m *Address) GetUsedTokens() int32 {
+	if m != nil {
+		return m.UsedTokens
+	}
+	return 0
+}
+
+func (m *Address) GetTokens() []*Token {
+	if m != nil {
+		return m.Tokens
+	}
+	return nil
+}
+
+func (m *Address) GetNextCursor() string {
+	if m != nil {
+		return m.NextCursor
+	}
+	return ""
+}
+
+func (m *Address) GetNonce() string {
+	if m != nil {
+		return m.Nonce
+	}
+	return ""
+}
+
+func (m *Address) GetNonTokenTxs() int32 {
+	if m != nil {
+		return m.NonTokenTxs
+	}
+	return 0
+}
+
+type Utxo struct {
+	Txid          string `protobuf:"bytes,1,opt,name=txid" json:"txid,omitempty"`
+	Vout          int32  `protobuf:"varint,2,opt,name=vout" json:"vout,omitempty"`
+	Value         string `protobuf:"bytes,3,opt,name=value" json:"value,omitempty"`
+	Height        int32  `protobuf:"varint,4,opt,name=height" json:"height,omitempty"`
+	Confirmations int32  `protobuf:"varint,5,opt,name=confirmations" json:"confirmations,omitempty"`
+	Address       string `protobuf:"bytes,6,opt,name=address" json:"address,omitempty"`
+	Path          string `protobuf:"bytes,7,opt,name=path" json:"path,omitempty"`
+	LockTime      uint32 `protobuf:"varint,8,opt,name=lock_time,json=lockTime" json:"lock_time,omitempty"`
+	Coinbase      bool   `protobuf:"varint,9,opt,name=coinbase" json:"coinbase,omitempty"`
+}
+
+func (m *Utxo) Reset()                    { *m = Utxo{} }
+func (m *Utxo) String() string            { return proto.CompactTextString(m) }
+func (*Utxo) ProtoMessage()               {}
+func (*Utxo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *Utxo) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *Utxo) GetVout() int32 {
+	if m != nil {
+		return m.Vout
+	}
+	return 0
+}
+
+func (m *Utxo) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+func (m *Utxo) GetHeight() int32 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *Utxo) GetConfirmations() int32 {
+	if m != nil {
+		return m.Confirmations
+	}
+	return 0
+}
+
+func (m *Utxo) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *Utxo) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *Utxo) GetLockTime() uint32 {
+	if m != nil {
+		return m.LockTime
+	}
+	return 0
+}
+
+func (m *Utxo) GetCoinbase() bool {
+	if m != nil {
+		return m.Coinbase
+	}
+	return false
+}
+
+type UtxoList struct {
+	Utxos []*Utxo `protobuf:"bytes,1,rep,name=utxos" json:"utxos,omitempty"`
+}
+
+func (m *UtxoList) Reset()                    { *m = UtxoList{} }
+func (m *UtxoList) String() string            { return proto.CompactTextString(m) }
+func (*UtxoList) ProtoMessage()               {}
+func (*UtxoList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *UtxoList) GetUtxos() []*Utxo {
+	if m != nil {
+		return m.Utxos
+	}
+	return nil
+}
+
+type Block struct {
+	Page              int32  `protobuf:"varint,1,opt,name=page" json:"page,omitempty"`
+	TotalPages        int32  `protobuf:"varint,2,opt,name=total_pages,json=totalPages" json:"total_pages,omitempty"`
+	ItemsOnPage       int32  `protobuf:"varint,3,opt,name=items_on_page,json=itemsOnPage" json:"items_on_page,omitempty"`
+	Hash              string `protobuf:"bytes,4,opt,name=hash" json:"hash,omitempty"`
+	PreviousBlockHash string `protobuf:"bytes,5,opt,name=previous_block_hash,json=previousBlockHash" json:"previous_block_hash,omitempty"`
+	NextBlockHash     string `protobuf:"bytes,6,opt,name=next_block_hash,json=nextBlockHash" json:"next_block_hash,omitempty"`
+	Height            uint32 `protobuf:"varint,7,opt,name=height" json:"height,omitempty"`
+	Confirmations     int32  `protobuf:"varint,8,opt,name=confirmations" json:"confirmations,omitempty"`
+	Size              int32  `protobuf:"varint,9,opt,name=size" json:"size,omitempty"`
+	Time              int64  `protobuf:"varint,10,opt,name=time" json:"time,omitempty"`
+	Version           string `protobuf:"bytes,11,opt,name=version" json:"version,omitempty"`
+	MerkleRoot        string `protobuf:"bytes,12,opt,name=merkle_root,json=merkleRoot" json:"merkle_root,omitempty"`
+	Nonce             string `protobuf:"bytes,13,opt,name=nonce" json:"nonce,omitempty"`
+	Bits              string `protobuf:"bytes,14,opt,name=bits" json:"bits,omitempty"`
+	Difficulty        string `protobuf:"bytes,15,opt,name=difficulty" json:"difficulty,omitempty"`
+	TxCount           int32  `protobuf:"varint,16,opt,name=tx_count,json=txCount" json:"tx_count,omitempty"`
+	Txs               []*Tx  `protobuf:"bytes,17,rep,name=txs" json:"txs,omitempty"`
+}
+
+func (m *Block) Reset()                    { *m = Block{} }
+func (m *Block) String() string            { return proto.CompactTextString(m) }
+func (*Block) ProtoMessage()               {}
+func (*Block) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *Block) GetPage() int32 {
+	if m != nil {
+		return m.Page
+	}
+	return 0
+}
+
+func (m *Block) GetTotalPages() int32 {
+	if m != nil {
+		return m.TotalPages
+	}
+	return 0
+}
+
+func (m *Block) GetItemsOnPage() int32 {
+	if m != nil {
+		return m.ItemsOnPage
+	}
+	return 0
+}
+
+func (m *Block) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
+func (m *Block) GetPreviousBlockHash() string {
+	if m != nil {
+		return m.PreviousBlockHash
+	}
+	return ""
+}
+
+func (m *Block) GetNextBlockHash() string {
+	if m != nil {
+		return m.NextBlockHash
+	}
+	return ""
+}
+
+func (m *Block) GetHeight() uint32 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *Block) GetConfirmations() int32 {
+	if m != nil {
+		return m.Confirmations
+	}
+	return 0
+}
+
+func (m *Block) GetSize() int32 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *Block) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *Block) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *Block) GetMerkleRoot() string {
+	if m != nil {
+		return m.MerkleRoot
+	}
+	return ""
+}
+
+func (m *Block) GetNonce() string {
+	if m != nil {
+		return m.Nonce
+	}
+	return ""
+}
+
+func (m *Block) GetBits() string {
+	if m != nil {
+		return m.Bits
+	}
+	return ""
+}
+
+func (m *Block) GetDifficulty() string {
+	if m != nil {
+		return m.Difficulty
+	}
+	return ""
+}
+
+func (m *Block) GetTxCount() int32 {
+	if m != nil {
+		return m.TxCount
+	}
+	return 0
+}
+
+func (m *Block) GetTxs() []*Tx {
+	if m != nil {
+		return m.Txs
+	}
+	return nil
+}
+
+type NewBlock struct {
+	Height uint32 `protobuf:"varint,1,opt,name=height" json:"height,omitempty"`
+	Hash   string `protobuf:"bytes,2,opt,name=hash" json:"hash,omitempty"`
+}
+
+func (m *NewBlock) Reset()                    { *m = NewBlock{} }
+func (m *NewBlock) String() string            { return proto.CompactTextString(m) }
+func (*NewBlock) ProtoMessage()               {}
+func (*NewBlock) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *NewBlock) GetHeight() uint32 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *NewBlock) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
+type AddressTx struct {
+	Address string `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
+	Tx      *Tx    `protobuf:"bytes,2,opt,name=tx" json:"tx,omitempty"`
+}
+
+func (m *AddressTx) Reset()                    { *m = AddressTx{} }
+func (m *AddressTx) String() string            { return proto.CompactTextString(m) }
+func (*AddressTx) ProtoMessage()               {}
+func (*AddressTx) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{20} }
+
+func (m *AddressTx) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *AddressTx) GetTx() *Tx {
+	if m != nil {
+		return m.Tx
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*GetTransactionRequest)(nil), "blockbook.GetTransactionRequest")
+	proto.RegisterType((*GetAddressRequest)(nil), "blockbook.GetAddressRequest")
+	proto.RegisterType((*GetXpubRequest)(nil), "blockbook.GetXpubRequest")
+	proto.RegisterType((*GetUtxoRequest)(nil), "blockbook.GetUtxoRequest")
+	proto.RegisterType((*GetBlockRequest)(nil), "blockbook.GetBlockRequest")
+	proto.RegisterType((*EstimateFeeRequest)(nil), "blockbook.EstimateFeeRequest")
+	proto.RegisterType((*EstimateFeeResponse)(nil), "blockbook.EstimateFeeResponse")
+	proto.RegisterType((*SendTransactionRequest)(nil), "blockbook.SendTransactionRequest")
+	proto.RegisterType((*SendTransactionResponse)(nil), "blockbook.SendTransactionResponse")
+	proto.RegisterType((*SubscribeNewBlockRequest)(nil), "blockbook.SubscribeNewBlockRequest")
+	proto.RegisterType((*SubscribeAddressesRequest)(nil), "blockbook.SubscribeAddressesRequest")
+	proto.RegisterType((*Vin)(nil), "blockbook.Vin")
+	proto.RegisterType((*Vout)(nil), "blockbook.Vout")
+	proto.RegisterType((*Tx)(nil), "blockbook.Tx")
+	proto.RegisterType((*Token)(nil), "blockbook.Token")
+	proto.RegisterType((*Address)(nil), "blockbook.Address")
+	proto.RegisterType((*Utxo)(nil), "blockbook.Utxo")
+	proto.RegisterType((*UtxoList)(nil), "blockbook.UtxoList")
+	proto.RegisterType((*Block)(nil), "blockbook.Block")
+	proto.RegisterType((*NewBlock)(nil), "blockbook.NewBlock")
+	proto.RegisterType((*AddressTx)(nil), "blockbook.AddressTx")
+	proto.RegisterEnum("blockbook.AccountDetails", AccountDetails_name, AccountDetails_value)
+	proto.RegisterEnum("blockbook.TokensToReturn", TokensToReturn_name, TokensToReturn_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// Client API for Blockbook service
+
+type BlockbookClient interface {
+	GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*Tx, error)
+	GetAddress(ctx context.Context, in *GetAddressRequest, opts ...grpc.CallOption) (*Address, error)
+	GetXpub(ctx context.Context, in *GetXpubRequest, opts ...grpc.CallOption) (*Address, error)
+	GetUtxo(ctx context.Context, in *GetUtxoRequest, opts ...grpc.CallOption) (*UtxoList, error)
+	GetBlock(ctx context.Context, in *GetBlockRequest, opts ...grpc.CallOption) (*Block, error)
+	EstimateFee(ctx context.Context, in *EstimateFeeRequest, opts ...grpc.CallOption) (*EstimateFeeResponse, error)
+	SendTransaction(ctx context.Context, in *SendTransactionRequest, opts ...grpc.CallOption) (*SendTransactionResponse, error)
+	SubscribeNewBlock(ctx context.Context, in *SubscribeNewBlockRequest, opts ...grpc.CallOption) (Blockbook_SubscribeNewBlockClient, error)
+	SubscribeAddresses(ctx context.Context, in *SubscribeAddressesRequest, opts ...grpc.CallOption) (Blockbook_SubscribeAddressesClient, error)
+}
+
+type blockbookClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewBlockbookClient(cc *grpc.ClientConn) BlockbookClient {
+	return &blockbookClient{cc}
+}
+
+func (c *blockbookClient) GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*Tx, error) {
+	out := new(Tx)
+	err := grpc.Invoke(ctx, "/blockbook.Blockbook/GetTransaction", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockbookClient) GetAddress(ctx context.Context, in *GetAddressRequest, opts ...grpc.CallOption) (*Address, error) {
+	out := new(Address)
+	err := grpc.Invoke(ctx, "/blockbook.Blockbook/GetAddress", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockbookClient) GetXpub(ctx context.Context, in *GetXpubRequest, opts ...grpc.CallOption) (*Address, error) {
+	out := new(Address)
+	err := grpc.Invoke(ctx, "/blockbook.Blockbook/GetXpub", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockbookClient) GetUtxo(ctx context.Context, in *GetUtxoRequest, opts ...grpc.CallOption) (*UtxoList, error) {
+	out := new(UtxoList)
+	err := grpc.Invoke(ctx, "/blockbook.Blockbook/GetUtxo", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockbookClient) GetBlock(ctx context.Context, in *GetBlockRequest, opts ...grpc.CallOption) (*Block, error) {
+	out := new(Block)
+	err := grpc.Invoke(ctx, "/blockbook.Blockbook/GetBlock", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockbookClient) EstimateFee(ctx context.Context, in *EstimateFeeRequest, opts ...grpc.CallOption) (*EstimateFeeResponse, error) {
+	out := new(EstimateFeeResponse)
+	err := grpc.Invoke(ctx, "/blockbook.Blockbook/EstimateFee", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockbookClient) SendTransaction(ctx context.Context, in *SendTransactionRequest, opts ...grpc.CallOption) (*SendTransactionResponse, error) {
+	out := new(SendTransactionResponse)
+	err := grpc.Invoke(ctx, "/blockbook.Blockbook/SendTransaction", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockbookClient) SubscribeNewBlock(ctx context.Context, in *SubscribeNewBlockRequest, opts ...grpc.CallOption) (Blockbook_SubscribeNewBlockClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Blockbook_serviceDesc.Streams[0], c.cc, "/blockbook.Blockbook/SubscribeNewBlock", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &blockbookSubscribeNewBlockClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Blockbook_SubscribeNewBlockClient interface {
+	Recv() (*NewBlock, error)
+	grpc.ClientStream
+}
+
+type blockbookSubscribeNewBlockClient struct {
+	grpc.ClientStream
+}
+
+func (x *blockbookSubscribeNewBlockClient) Recv() (*NewBlock, error) {
+	m := new(NewBlock)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *blockbookClient) SubscribeAddresses(ctx context.Context, in *SubscribeAddressesRequest, opts ...grpc.CallOption) (Blockbook_SubscribeAddressesClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_Blockbook_serviceDesc.Streams[1], c.cc, "/blockbook.Blockbook/SubscribeAddresses", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &blockbookSubscribeAddressesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Blockbook_SubscribeAddressesClient interface {
+	Recv() (*AddressTx, error)
+	grpc.ClientStream
+}
+
+type blockbookSubscribeAddressesClient struct {
+	grpc.ClientStream
+}
+
+func (x *blockbookSubscribeAddressesClient) Recv() (*AddressTx, error) {
+	m := new(AddressTx)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for Blockbook service
+
+type BlockbookServer interface {
+	GetTransaction(context.Context, *GetTransactionRequest) (*Tx, error)
+	GetAddress(context.Context, *GetAddressRequest) (*Address, error)
+	GetXpub(context.Context, *GetXpubRequest) (*Address, error)
+	GetUtxo(context.Context, *GetUtxoRequest) (*UtxoList, error)
+	GetBlock(context.Context, *GetBlockRequest) (*Block, error)
+	EstimateFee(context.Context, *EstimateFeeRequest) (*EstimateFeeResponse, error)
+	SendTransaction(context.Context, *SendTransactionRequest) (*SendTransactionResponse, error)
+	SubscribeNewBlock(*SubscribeNewBlockRequest, Blockbook_SubscribeNewBlockServer) error
+	SubscribeAddresses(*SubscribeAddressesRequest, Blockbook_SubscribeAddressesServer) error
+}
+
+func RegisterBlockbookServer(s *grpc.Server, srv BlockbookServer) {
+	s.RegisterService(&_Blockbook_serviceDesc, srv)
+}
+
+func _Blockbook_GetTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockbookServer).GetTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/blockbook.Blockbook/GetTransaction",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockbookServer).GetTransaction(ctx, req.(*GetTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockbook_GetAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockbookServer).GetAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/blockbook.Blockbook/GetAddress",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockbookServer).GetAddress(ctx, req.(*GetAddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockbook_GetXpub_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetXpubRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockbookServer).GetXpub(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/blockbook.Blockbook/GetXpub",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockbookServer).GetXpub(ctx, req.(*GetXpubRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockbook_GetUtxo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUtxoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockbookServer).GetUtxo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/blockbook.Blockbook/GetUtxo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockbookServer).GetUtxo(ctx, req.(*GetUtxoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockbook_GetBlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockbookServer).GetBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/blockbook.Blockbook/GetBlock",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockbookServer).GetBlock(ctx, req.(*GetBlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockbook_EstimateFee_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EstimateFeeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockbookServer).EstimateFee(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/blockbook.Blockbook/EstimateFee",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockbookServer).EstimateFee(ctx, req.(*EstimateFeeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockbook_SendTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockbookServer).SendTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/blockbook.Blockbook/SendTransaction",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockbookServer).SendTransaction(ctx, req.(*SendTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Blockbook_SubscribeNewBlock_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeNewBlockRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BlockbookServer).SubscribeNewBlock(m, &blockbookSubscribeNewBlockServer{stream})
+}
+
+type Blockbook_SubscribeNewBlockServer interface {
+	Send(*NewBlock) error
+	grpc.ServerStream
+}
+
+type blockbookSubscribeNewBlockServer struct {
+	grpc.ServerStream
+}
+
+func (x *blockbookSubscribeNewBlockServer) Send(m *NewBlock) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Blockbook_SubscribeAddresses_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeAddressesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BlockbookServer).SubscribeAddresses(m, &blockbookSubscribeAddressesServer{stream})
+}
+
+type Blockbook_SubscribeAddressesServer interface {
+	Send(*AddressTx) error
+	grpc.ServerStream
+}
+
+type blockbookSubscribeAddressesServer struct {
+	grpc.ServerStream
+}
+
+func (x *blockbookSubscribeAddressesServer) Send(m *AddressTx) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _Blockbook_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "blockbook.Blockbook",
+	HandlerType: (*BlockbookServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetTransaction",
+			Handler:    _Blockbook_GetTransaction_Handler,
+		},
+		{
+			MethodName: "GetAddress",
+			Handler:    _Blockbook_GetAddress_Handler,
+		},
+		{
+			MethodName: "GetXpub",
+			Handler:    _Blockbook_GetXpub_Handler,
+		},
+		{
+			MethodName: "GetUtxo",
+			Handler:    _Blockbook_GetUtxo_Handler,
+		},
+		{
+			MethodName: "GetBlock",
+			Handler:    _Blockbook_GetBlock_Handler,
+		},
+		{
+			MethodName: "EstimateFee",
+			Handler:    _Blockbook_EstimateFee_Handler,
+		},
+		{
+			MethodName: "SendTransaction",
+			Handler:    _Blockbook_SendTransaction_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeNewBlock",
+			Handler:       _Blockbook_SubscribeNewBlock_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeAddresses",
+			Handler:       _Blockbook_SubscribeAddresses_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "blockbook.proto",
+}
+
+func init() { proto.RegisterFile("blockbook.proto", fileDescriptor0) }
+
+var fileDescriptor0 = []byte{
+	// 1814 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xd4, 0x58, 0xcd, 0x6e, 0x1b, 0xc9,
+	0x11, 0xde, 0x21, 0x39, 0xe4, 0x4c, 0x51, 0xa4, 0xa8, 0xb6, 0xe3, 0x50, 0x8c, 0x6d, 0x69, 0xc7,
+	0xbb, 0x89, 0xb0, 0x07, 0x67, 0x6d, 0x07, 0xc1, 0x2e, 0x02, 0x04, 0x10, 0x2d, 0x45, 0x2b, 0xac,
+	0x21, 0x19, 0x4d, 0xae, 0x21, 0xec, 0x65, 0x32, 0x24, 0x9b, 0xd2, 0x40, 0xe4, 0x34, 0x33, 0xdd,
+	0x64, 0x46, 0x7e, 0x81, 0x00, 0x01, 0x72, 0x08, 0x72, 0xcb, 0x8b, 0xe4, 0x55, 0xf2, 0x0a, 0x39,
+	0xe7, 0x05, 0x82, 0xae, 0xee, 0x19, 0xf6, 0x50, 0xb4, 0x7d, 0xc9, 0x65, 0x4f, 0xac, 0xaa, 0xfe,
+	0x99, 0xae, 0xbf, 0xaf, 0xaa, 0x08, 0xbb, 0xa3, 0x19, 0x1f, 0xdf, 0x8e, 0x38, 0xbf, 0x7d, 0xbe,
+	0x48, 0xb9, 0xe4, 0xc4, 0x2f, 0x04, 0xc1, 0x19, 0xfc, 0xec, 0x8c, 0xc9, 0x61, 0x1a, 0x25, 0x22,
+	0x1a, 0xcb, 0x98, 0x27, 0x94, 0xfd, 0x69, 0xc9, 0x84, 0x24, 0x04, 0x6a, 0x32, 0x8b, 0x27, 0x5d,
+	0xe7, 0xd0, 0x39, 0xf2, 0x29, 0xd2, 0xa4, 0x07, 0x9e, 0x58, 0xb0, 0x64, 0x12, 0x27, 0xd7, 0xdd,
+	0xca, 0xa1, 0x73, 0xe4, 0xd1, 0x82, 0x0f, 0xfe, 0xe3, 0xc0, 0xde, 0x19, 0x93, 0xc7, 0x93, 0x49,
+	0xca, 0x84, 0xc8, 0x6f, 0xe9, 0x42, 0x23, 0xd2, 0x12, 0x73, 0x51, 0xce, 0xaa, 0xfb, 0x17, 0xd1,
+	0x35, 0xc3, 0x7b, 0x5c, 0x8a, 0x34, 0xf9, 0x05, 0xf8, 0xea, 0x37, 0x14, 0xf1, 0x7b, 0xd6, 0xad,
+	0xe2, 0x82, 0xa7, 0x04, 0x83, 0xf8, 0x3d, 0x23, 0xaf, 0xa0, 0x31, 0x61, 0x32, 0x8a, 0x67, 0xa2,
+	0x5b, 0x3b, 0x74, 0x8e, 0xda, 0x2f, 0xf7, 0x9f, 0xaf, 0xf5, 0x3a, 0x1e, 0x8f, 0xf9, 0x32, 0x91,
+	0x27, 0x7a, 0x03, 0xcd, 0x77, 0x92, 0x03, 0x68, 0x4e, 0x53, 0x3e, 0x0f, 0x6f, 0x58, 0x7c, 0x7d,
+	0x23, 0xbb, 0xee, 0xa1, 0x73, 0xd4, 0xa2, 0xa0, 0x44, 0xdf, 0xa1, 0x44, 0x7d, 0x52, 0xf2, 0x7c,
+	0xb9, 0x8e, 0xcb, 0x9e, 0xe4, 0x66, 0xf1, 0x11, 0xd4, 0xc7, 0xcb, 0x54, 0xf0, 0xb4, 0xdb, 0xc0,
+	0xc7, 0x1b, 0x2e, 0xf8, 0x5b, 0x05, 0xda, 0x67, 0x4c, 0x5e, 0x2d, 0x96, 0x23, 0xcb, 0x5c, 0xd9,
+	0x62, 0x39, 0xca, 0xcd, 0xa5, 0xe8, 0x9f, 0x84, 0x8a, 0x2f, 0xa0, 0x2e, 0xf9, 0x2d, 0x4b, 0x04,
+	0xaa, 0x58, 0xfe, 0xe2, 0x10, 0x17, 0x86, 0x9c, 0x32, 0xb9, 0x4c, 0x13, 0x6a, 0x36, 0x92, 0x0e,
+	0x54, 0xaf, 0xa3, 0x45, 0xd7, 0xc3, 0xc7, 0x2b, 0x32, 0xf8, 0x23, 0x9a, 0xe3, 0x07, 0x99, 0xf1,
+	0xdc, 0x1c, 0x4f, 0x01, 0x26, 0x4c, 0x8c, 0xd3, 0x78, 0x21, 0x79, 0x6a, 0x8c, 0x62, 0x49, 0xc8,
+	0x63, 0xf0, 0xc7, 0x3c, 0x99, 0xc6, 0xe9, 0x9c, 0x4d, 0x4c, 0x28, 0xad, 0x05, 0xf9, 0x17, 0xaa,
+	0xeb, 0x2f, 0xfc, 0x0e, 0x76, 0xcf, 0x98, 0xec, 0xab, 0xa7, 0xe5, 0x9f, 0x78, 0x08, 0x2e, 0x3e,
+	0xd5, 0xdc, 0xae, 0x99, 0x6d, 0x36, 0x0f, 0xde, 0x02, 0x39, 0x15, 0x32, 0x9e, 0x47, 0x92, 0xfd,
+	0x81, 0xb1, 0xfc, 0xfc, 0x23, 0xa8, 0xe3, 0x11, 0x15, 0x99, 0xd5, 0x23, 0x97, 0x1a, 0x8e, 0x04,
+	0xb0, 0x33, 0xe6, 0x89, 0x60, 0xe9, 0x2a, 0x92, 0xf1, 0x8a, 0x99, 0xd7, 0x95, 0x64, 0xc1, 0x2b,
+	0x78, 0x50, 0xba, 0x51, 0x2c, 0xd4, 0x2a, 0x79, 0x0c, 0x30, 0x65, 0x2c, 0x5c, 0xb0, 0x34, 0xbc,
+	0x1d, 0xe1, 0xb5, 0x3e, 0xf5, 0xa6, 0x8c, 0xbd, 0x65, 0xe9, 0xf7, 0xa3, 0xe0, 0x12, 0x1e, 0x0d,
+	0x58, 0x32, 0xd9, 0x92, 0x6b, 0x1d, 0xa8, 0xde, 0xb0, 0xcc, 0x28, 0xa2, 0x48, 0xf2, 0x0c, 0x5a,
+	0xab, 0x68, 0x16, 0x4f, 0x22, 0xc9, 0x42, 0x9e, 0xcc, 0xee, 0xf2, 0x57, 0xe4, 0xc2, 0xcb, 0x64,
+	0x76, 0x17, 0xfc, 0xc3, 0x81, 0x9f, 0xdf, 0xbb, 0xd1, 0x3c, 0x65, 0x5b, 0xfa, 0x3e, 0x04, 0x77,
+	0x85, 0x71, 0xa7, 0x8d, 0xa3, 0x19, 0xf5, 0xf1, 0x29, 0xd3, 0xb1, 0xe8, 0x53, 0x45, 0x92, 0x7d,
+	0x50, 0x8f, 0x0e, 0xd3, 0x48, 0x32, 0x8c, 0x43, 0x87, 0x36, 0xa6, 0x8c, 0xd1, 0x48, 0x32, 0xf2,
+	0x39, 0x14, 0x4f, 0x98, 0x84, 0xa3, 0x3b, 0x8c, 0x36, 0x9f, 0x36, 0x0b, 0x59, 0xff, 0x2e, 0xe8,
+	0x41, 0x77, 0xb0, 0x1c, 0x29, 0x4f, 0x8f, 0xd8, 0x05, 0xfb, 0xb3, 0xed, 0xb3, 0xe0, 0x5b, 0xd8,
+	0x2f, 0xd6, 0x0c, 0x52, 0xb0, 0x02, 0x2b, 0x1e, 0x83, 0x1f, 0xe5, 0x32, 0x63, 0xbc, 0xb5, 0x20,
+	0xf8, 0xb7, 0x03, 0xd5, 0x77, 0x71, 0xb2, 0x55, 0x31, 0x02, 0xb5, 0x15, 0x5f, 0x4a, 0xd4, 0xab,
+	0x45, 0x91, 0x46, 0xac, 0x52, 0x17, 0x27, 0x63, 0xad, 0x5b, 0x95, 0x16, 0x3c, 0xd9, 0x01, 0x27,
+	0x41, 0xcd, 0x5c, 0xea, 0x24, 0xe5, 0xef, 0xba, 0x1b, 0xdf, 0x25, 0x4f, 0x00, 0x62, 0x11, 0xe6,
+	0x20, 0x56, 0xd7, 0xa1, 0x1a, 0x0b, 0xf3, 0x7a, 0xb4, 0x69, 0x34, 0x5b, 0x32, 0x83, 0x10, 0x9a,
+	0xc9, 0x1d, 0xea, 0xad, 0x1d, 0xda, 0x03, 0x6f, 0xcc, 0xe3, 0x64, 0x14, 0x09, 0xd6, 0xf5, 0x51,
+	0x5c, 0xf0, 0xc1, 0x5f, 0x2a, 0x50, 0x7b, 0xa7, 0xde, 0x5c, 0x5c, 0xe6, 0xd8, 0x97, 0xe1, 0x6b,
+	0x2b, 0xf9, 0x6b, 0x1f, 0x82, 0xab, 0x30, 0x57, 0xa2, 0x52, 0x1e, 0xd5, 0x0c, 0x79, 0x0a, 0x4d,
+	0x24, 0x42, 0x99, 0x85, 0xf1, 0x04, 0x75, 0xf3, 0xa9, 0x8f, 0xa2, 0x61, 0x76, 0x3e, 0x51, 0x20,
+	0xa1, 0xd7, 0xe3, 0x64, 0xc2, 0x32, 0x74, 0x9b, 0x4b, 0x01, 0x45, 0xe7, 0x4a, 0xa2, 0x1c, 0xab,
+	0x37, 0x58, 0x38, 0xe1, 0x52, 0x7d, 0xc8, 0x40, 0x85, 0x51, 0xaa, 0xb1, 0x56, 0xaa, 0x64, 0x39,
+	0xef, 0xe3, 0x96, 0xf3, 0x37, 0x2d, 0xa7, 0x1c, 0x79, 0xb7, 0x60, 0x5d, 0x30, 0x8e, 0xbc, 0x5b,
+	0xb0, 0xe0, 0x9f, 0x55, 0xa8, 0x0c, 0xb3, 0xad, 0x3e, 0xee, 0x42, 0x63, 0xc5, 0x52, 0x11, 0xf3,
+	0xdc, 0x16, 0x39, 0xab, 0xf0, 0x4d, 0xc5, 0x58, 0x28, 0xe3, 0xb9, 0x76, 0x75, 0x8b, 0x7a, 0x4a,
+	0x30, 0x8c, 0xe7, 0x8c, 0x1c, 0x42, 0x75, 0x15, 0x2b, 0x67, 0x57, 0x8f, 0x9a, 0x2f, 0xdb, 0x16,
+	0xb8, 0xbd, 0x8b, 0x13, 0xaa, 0x96, 0xc8, 0x33, 0x13, 0x3c, 0x2e, 0x6e, 0xd9, 0xb5, 0xb7, 0xf0,
+	0xa5, 0x34, 0xd1, 0xf4, 0x04, 0x00, 0xe5, 0xe1, 0x4d, 0x24, 0x6e, 0xd0, 0x38, 0x3e, 0xd5, 0x55,
+	0xf4, 0xbb, 0x48, 0xdc, 0x28, 0xeb, 0x99, 0x65, 0x6d, 0xbd, 0x86, 0xb6, 0x9e, 0xde, 0xa0, 0xad,
+	0xf7, 0x05, 0xb4, 0x0c, 0xc0, 0x45, 0x2a, 0x51, 0x05, 0x06, 0x47, 0x8b, 0x96, 0x85, 0xeb, 0xef,
+	0xa0, 0x32, 0x3e, 0xc6, 0xad, 0xfe, 0x0e, 0x6a, 0x43, 0xa0, 0x86, 0x09, 0x0c, 0x1a, 0xdd, 0x30,
+	0x7f, 0x8b, 0xa0, 0x69, 0xda, 0x41, 0xb3, 0x0f, 0x1e, 0x12, 0x61, 0x9c, 0x74, 0x77, 0x74, 0xe5,
+	0x45, 0xfe, 0x1c, 0x33, 0x68, 0xca, 0x98, 0xe8, 0xb6, 0xb4, 0x75, 0x15, 0x9d, 0xfb, 0xb6, 0xbd,
+	0xf6, 0x6d, 0x07, 0xaa, 0xe9, 0x68, 0xda, 0xdd, 0x45, 0xb7, 0x29, 0x32, 0xf8, 0x7b, 0x05, 0x5c,
+	0x2c, 0x09, 0x85, 0xeb, 0x9c, 0xb5, 0xeb, 0x94, 0x2c, 0x89, 0xe6, 0x1a, 0x5b, 0x7c, 0x8a, 0xb4,
+	0x06, 0x63, 0x79, 0x63, 0xb0, 0x05, 0x69, 0x9d, 0x08, 0x89, 0x4c, 0xa3, 0xb1, 0x34, 0x61, 0x5a,
+	0xf0, 0x2a, 0x9e, 0xa4, 0xc2, 0xb2, 0x29, 0x4b, 0x85, 0x89, 0xd1, 0xb5, 0x40, 0x01, 0xb6, 0xb8,
+	0x9b, 0x8f, 0xf8, 0xcc, 0xd8, 0xdf, 0x70, 0xea, 0xc6, 0x09, 0x1b, 0xc7, 0xf3, 0x68, 0x26, 0x8c,
+	0xe1, 0x0b, 0x5e, 0x45, 0xcd, 0x28, 0x9a, 0x45, 0x0a, 0x04, 0x74, 0x32, 0xe6, 0x2c, 0xf9, 0x12,
+	0xda, 0x92, 0xcb, 0x68, 0x16, 0xa6, 0x6c, 0xcc, 0xe2, 0x15, 0x9b, 0x98, 0xb4, 0x6c, 0xa1, 0x94,
+	0x1a, 0xa1, 0x72, 0x88, 0xde, 0x26, 0x54, 0xce, 0xe9, 0x58, 0xf5, 0x51, 0x32, 0x60, 0x89, 0x0c,
+	0xfe, 0x55, 0x83, 0xc6, 0xf1, 0x46, 0x47, 0xe3, 0x58, 0xe5, 0xfe, 0x00, 0x9a, 0xfa, 0xb8, 0xe2,
+	0x84, 0x89, 0x5c, 0x7d, 0xe3, 0x5b, 0x25, 0x21, 0x01, 0xb4, 0x62, 0xc9, 0xe6, 0x22, 0xe4, 0x09,
+	0xee, 0x31, 0x45, 0xaf, 0x89, 0xc2, 0xcb, 0x44, 0x6d, 0xb2, 0x9b, 0xa8, 0x5a, 0xb9, 0x89, 0xb2,
+	0xd4, 0x73, 0x3f, 0xa5, 0x5e, 0xfd, 0xd3, 0xea, 0x35, 0x36, 0xd4, 0x23, 0xbf, 0x86, 0x07, 0xcb,
+	0xa4, 0xa8, 0xcb, 0x61, 0xd9, 0x94, 0xc4, 0x5a, 0xea, 0x9b, 0xcf, 0xfe, 0x0a, 0x76, 0xed, 0x03,
+	0x32, 0xd3, 0x89, 0xef, 0xd2, 0xb6, 0x25, 0x1e, 0x66, 0x18, 0x70, 0x6a, 0x51, 0x07, 0xb2, 0x22,
+	0xc9, 0x0b, 0xd8, 0x91, 0xeb, 0x42, 0x26, 0xba, 0x4d, 0xcc, 0xc7, 0x96, 0xdd, 0x8f, 0x64, 0xb4,
+	0xb4, 0x45, 0x85, 0xbe, 0xc2, 0x06, 0xd1, 0xdd, 0x41, 0xec, 0xd1, 0x8c, 0xb2, 0xf9, 0x52, 0xa8,
+	0x8f, 0xeb, 0xbe, 0xa6, 0xa5, 0x6d, 0xae, 0x44, 0xba, 0xa1, 0x21, 0x47, 0x45, 0xcf, 0xd3, 0xc6,
+	0x6f, 0x74, 0x36, 0x7b, 0x9e, 0xa2, 0xd5, 0x39, 0x80, 0x66, 0xc2, 0x32, 0x19, 0x9a, 0x2e, 0x70,
+	0x57, 0xf7, 0x31, 0x4a, 0xf4, 0x1a, 0x25, 0xea, 0x05, 0x09, 0x57, 0x26, 0xe9, 0xe8, 0xe4, 0x43,
+	0x46, 0x39, 0x35, 0xe1, 0x89, 0x7e, 0x00, 0xda, 0x60, 0x4f, 0x3b, 0x35, 0xe1, 0x09, 0xde, 0x3f,
+	0xcc, 0x84, 0xea, 0x97, 0x6b, 0xaa, 0x63, 0xfa, 0x64, 0x41, 0x73, 0x0d, 0x04, 0x15, 0x79, 0x5e,
+	0xb5, 0xf3, 0xfc, 0x11, 0xd4, 0x0d, 0xe6, 0xe8, 0x7a, 0x66, 0xb8, 0xfb, 0x70, 0xa3, 0xd3, 0x69,
+	0x03, 0x6e, 0xac, 0xc8, 0xaa, 0x6f, 0x69, 0xcf, 0xe5, 0x8d, 0x09, 0x09, 0x9d, 0xba, 0x25, 0xa0,
+	0xf5, 0x36, 0x80, 0x76, 0xb3, 0xc0, 0x79, 0x56, 0x81, 0x7b, 0x01, 0x9e, 0x52, 0xf5, 0x4d, 0x2c,
+	0x24, 0xf9, 0x12, 0xdc, 0xa5, 0xcc, 0xb8, 0xae, 0xf0, 0x65, 0xbc, 0xc5, 0x06, 0x52, 0xaf, 0x06,
+	0xff, 0xad, 0x82, 0xdb, 0x2f, 0x75, 0x74, 0xff, 0xf7, 0xb4, 0x22, 0x50, 0x43, 0x34, 0xd7, 0x39,
+	0x85, 0x34, 0x79, 0x0e, 0x0f, 0x16, 0x29, 0x5b, 0xc5, 0x7c, 0x29, 0x42, 0x0b, 0xf0, 0x75, 0x72,
+	0xed, 0xe5, 0x4b, 0xfd, 0x02, 0xf8, 0x7f, 0x09, 0xbb, 0x18, 0x20, 0xf7, 0x8a, 0x43, 0x4b, 0x89,
+	0xd7, 0xfb, 0xd6, 0x6e, 0x6a, 0xa0, 0xdd, 0x3e, 0xe8, 0x26, 0x6f, 0x9b, 0x9b, 0x72, 0xd8, 0xf7,
+	0x2d, 0xd8, 0x57, 0x61, 0xa3, 0xfc, 0x00, 0x58, 0x23, 0x90, 0xb6, 0x6b, 0x64, 0xd3, 0x60, 0xbe,
+	0xa9, 0x91, 0x07, 0xd0, 0x9c, 0xb3, 0xf4, 0x76, 0xc6, 0xc2, 0x94, 0x73, 0x69, 0x2a, 0x02, 0x68,
+	0x11, 0xe5, 0x5c, 0xae, 0x03, 0xb9, 0x65, 0x07, 0x32, 0x81, 0xda, 0x28, 0x96, 0xc2, 0xd4, 0x05,
+	0xa4, 0xb1, 0xb5, 0x8f, 0xa7, 0xd3, 0x78, 0xbc, 0x9c, 0xc9, 0xbb, 0x3c, 0x25, 0xd6, 0x12, 0x55,
+	0x79, 0x64, 0x16, 0xe2, 0xac, 0x82, 0x59, 0xe1, 0xd2, 0x86, 0xcc, 0x5e, 0x2b, 0x96, 0x1c, 0xe8,
+	0xa4, 0xdf, 0xdb, 0x96, 0xd9, 0x6a, 0x25, 0xf8, 0x2d, 0x78, 0x79, 0xcb, 0x68, 0x99, 0xcc, 0x29,
+	0x99, 0x2c, 0x77, 0x5b, 0x65, 0xed, 0xb6, 0xe0, 0x04, 0x7c, 0x83, 0xc2, 0xc3, 0xec, 0x23, 0x33,
+	0xe7, 0x13, 0xa8, 0xc8, 0x0c, 0x0f, 0xde, 0xfb, 0x7c, 0x45, 0x66, 0x5f, 0x5d, 0x42, 0xbb, 0x3c,
+	0x64, 0x11, 0x1f, 0xdc, 0xe1, 0xd5, 0xf9, 0xc9, 0xa0, 0xf3, 0x99, 0x22, 0xfb, 0xc7, 0x83, 0xf3,
+	0xd7, 0x1d, 0x87, 0x00, 0xd4, 0x87, 0x97, 0xdf, 0x9f, 0x5e, 0x0c, 0x3a, 0x15, 0x42, 0xa0, 0x8d,
+	0x74, 0xd8, 0x3f, 0x7e, 0x73, 0x7c, 0xf1, 0xfa, 0x74, 0xd0, 0xa9, 0x92, 0x06, 0x54, 0x87, 0x57,
+	0x83, 0x4e, 0xed, 0xab, 0xdf, 0x40, 0xbb, 0x3c, 0x43, 0x91, 0x26, 0x34, 0x2e, 0x2e, 0x2f, 0x7e,
+	0x3c, 0xa5, 0x97, 0x9d, 0xcf, 0x88, 0x07, 0xb5, 0x1f, 0x06, 0xa7, 0x27, 0x1d, 0x47, 0x89, 0x4f,
+	0x4e, 0xe9, 0xf9, 0xbb, 0xd3, 0x93, 0x4e, 0xe5, 0xe5, 0x5f, 0x5d, 0xf0, 0xfb, 0xf9, 0xdb, 0xc8,
+	0x31, 0xce, 0x56, 0x56, 0x8b, 0x4f, 0x0e, 0xad, 0x97, 0x6f, 0x9d, 0xdd, 0x7b, 0x65, 0xdd, 0xc8,
+	0xef, 0x01, 0xd6, 0x93, 0x39, 0x79, 0x5c, 0x3e, 0x5e, 0x1e, 0xd8, 0x7b, 0xc4, 0x9e, 0x38, 0xcd,
+	0x89, 0x6f, 0xa0, 0x61, 0xa6, 0x5d, 0xb2, 0x5f, 0x3e, 0x6c, 0x4d, 0xc0, 0x5b, 0x4f, 0x7e, 0x8b,
+	0x27, 0x11, 0xe6, 0x36, 0x4e, 0x5a, 0xc3, 0x62, 0xef, 0xc1, 0x06, 0x06, 0x20, 0x4e, 0x7c, 0x03,
+	0x5e, 0x3e, 0xf1, 0x91, 0x5e, 0xf9, 0xac, 0x3d, 0x52, 0xf4, 0x6c, 0xf0, 0xd6, 0xbb, 0xdf, 0x40,
+	0xd3, 0x1a, 0xce, 0xc8, 0x13, 0x6b, 0xc3, 0xfd, 0x31, 0xb0, 0xf7, 0xf4, 0x43, 0xcb, 0x66, 0x90,
+	0xba, 0x82, 0xdd, 0x8d, 0x19, 0x8b, 0x7c, 0x6e, 0x1d, 0xd9, 0x3e, 0xd1, 0xf5, 0x82, 0x8f, 0x6d,
+	0x31, 0x37, 0x5f, 0xc2, 0xde, 0xbd, 0x41, 0x89, 0x3c, 0xb3, 0x0f, 0x7e, 0x60, 0x8c, 0x2a, 0x19,
+	0x2c, 0x5f, 0xfb, 0xda, 0x21, 0x14, 0xc8, 0xfd, 0xe9, 0x8a, 0x7c, 0xb1, 0xed, 0xc6, 0xcd, 0xe1,
+	0xab, 0xf7, 0xf0, 0xbe, 0xf7, 0x86, 0xd9, 0xd7, 0x4e, 0xdf, 0xff, 0xb1, 0x71, 0x9d, 0x2e, 0xc6,
+	0xd1, 0x22, 0x1e, 0xd5, 0xf1, 0xcf, 0xa3, 0x57, 0xff, 0x0b, 0x00, 0x00, 0xff, 0xff, 0x9e, 0x6b,
+	0x0d, 0x76, 0x4f, 0x12, 0x00, 0x00,
+}
@@ -9,6 +9,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/golang/glog"
 
@@ -26,14 +28,22 @@ type InternalServer struct {
 	mempool     bchain.Mempool
 	is          *common.InternalState
 	api         *api.Worker
+	webhooks    *webhookNotifier
+	admin       *adminJobs
 }
 
 // NewInternalServer creates new internal http interface to blockbook and returns its handle
-func NewInternalServer(binding, certFiles string, db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, is *common.InternalState) (*InternalServer, error) {
+// the admin api runs its jobs coordinated with syncWorker, notifies onReorg about the rolled back blocks and is enabled by adminKeys
+func NewInternalServer(binding, certFiles string, db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState,
+	syncWorker *db.SyncWorker, pushHandler func(bchain.NotificationType), onReorg db.OnReorgFunc, adminKeys []string) (*InternalServer, error) {
 	api, err := api.NewWorker(db, chain, mempool, txCache, is)
 	if err != nil {
 		return nil, err
 	}
+	webhooks, err := newWebhookNotifier(db, api, chain.GetChainParser(), metrics)
+	if err != nil {
+		return nil, err
+	}
 
 	addr, path := splitBinding(binding)
 	serveMux := http.NewServeMux()
@@ -51,10 +61,16 @@ func NewInternalServer(binding, certFiles string, db *db.RocksDB, chain bchain.B
 		mempool:     mempool,
 		is:          is,
 		api:         api,
+		webhooks:    webhooks,
+		admin:       newAdminJobs(db, api, is, syncWorker, pushHandler, onReorg, metrics, adminKeys),
 	}
 
 	serveMux.Handle(path+"favicon.ico", http.FileServer(http.Dir("./static/")))
 	serveMux.HandleFunc(path+"metrics", promhttp.Handler().ServeHTTP)
+	serveMux.HandleFunc(path+"api/webhooks", s.webhooksHandler)
+	serveMux.HandleFunc(path+"api/webhooks/", s.webhookHandler)
+	serveMux.HandleFunc(path+"api/admin/jobs", s.adminHandler(s.adminJobsHandler))
+	serveMux.HandleFunc(path+"api/admin/jobs/", s.adminHandler(s.adminJobHandler))
 	serveMux.HandleFunc(path, s.index)
 
 	return s, nil
@@ -73,13 +89,18 @@ func (s *InternalServer) Run() error {
 // Close closes the server
 func (s *InternalServer) Close() error {
 	glog.Infof("internal server: closing")
+	s.webhooks.Close()
+	s.admin.Close()
 	return s.https.Close()
 }
 
 // Shutdown shuts down the server
 func (s *InternalServer) Shutdown(ctx context.Context) error {
 	glog.Infof("internal server: shutdown")
-	return s.https.Shutdown(ctx)
+	err := s.https.Shutdown(ctx)
+	s.webhooks.Close()
+	s.admin.Close()
+	return err
 }
 
 func (s *InternalServer) index(w http.ResponseWriter, r *http.Request) {
@@ -98,3 +119,87 @@ func (s *InternalServer) index(w http.ResponseWriter, r *http.Request) {
 
 	w.Write(buf)
 }
+
+// OnNewBlock notifies the webhooks about the confirmations of their transactions
+func (s *InternalServer) OnNewBlock(hash string, height uint32) {
+	s.webhooks.OnNewBlock(hash, height)
+}
+
+// OnNewTxAddr notifies the webhooks registered to the address about a new transaction
+func (s *InternalServer) OnNewTxAddr(tx *bchain.Tx, desc bchain.AddressDescriptor) {
+	s.webhooks.OnNewTxAddr(tx, desc)
+}
+
+// errorCodeNotFound is the code of the public errors of the internal server which are returned with the status 404
+const errorCodeNotFound = "not_found"
+
+type internalError struct {
+	Text string `json:"error"`
+	Code string `json:"code,omitempty"`
+}
+
+func (s *InternalServer) writeJSON(w http.ResponseWriter, data interface{}, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err != nil {
+		if apiErr, ok := err.(*api.APIError); ok && apiErr.Public {
+			status := http.StatusBadRequest
+			if apiErr.Code == errorCodeNotFound {
+				status = http.StatusNotFound
+			}
+			w.WriteHeader(status)
+			data = internalError{Text: apiErr.Text, Code: apiErr.Code}
+		} else {
+			glog.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			data = internalError{Text: "Internal server error"}
+		}
+	}
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		glog.Error(err)
+	}
+}
+
+// webhooksHandler lists the webhooks (GET) or registers a new webhook (POST)
+func (s *InternalServer) webhooksHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.writeJSON(w, s.webhooks.Webhooks(), nil)
+	case http.MethodPost:
+		var req WebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeJSON(w, nil, api.NewAPIError("Invalid request, "+err.Error(), true))
+			return
+		}
+		wh, err := s.webhooks.Register(&req)
+		s.writeJSON(w, wh, err)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// webhookHandler returns (GET) or deletes (DELETE) the webhook api/webhooks/<id>
+// or returns the delivery log of the webhook api/webhooks/<id>/deliveries?limit=<n>
+func (s *InternalServer) webhookHandler(w http.ResponseWriter, r *http.Request) {
+	i := strings.Index(r.URL.Path, "api/webhooks/")
+	parts := strings.Split(r.URL.Path[i+len("api/webhooks/"):], "/")
+	id := parts[0]
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		wh, err := s.webhooks.Webhook(id)
+		s.writeJSON(w, wh, err)
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		err := s.webhooks.Unregister(id)
+		s.writeJSON(w, struct {
+			Result string `json:"result"`
+		}{"deleted"}, err)
+	case len(parts) == 2 && parts[1] == "deliveries" && r.Method == http.MethodGet:
+		limit, ec := strconv.Atoi(r.URL.Query().Get("limit"))
+		if ec != nil || limit <= 0 {
+			limit = 100
+		}
+		ds, err := s.webhooks.Deliveries(id, limit)
+		s.writeJSON(w, ds, err)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
@@ -0,0 +1,590 @@
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/common"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// openAPIParam is a path or query parameter of a route
+type openAPIParam struct {
+	name        string
+	in          string
+	typ         string
+	description string
+}
+
+func pathParam(name string, description string) openAPIParam {
+	return openAPIParam{name: name, in: "path", typ: "string", description: description}
+}
+
+func queryParam(name string, typ string, description string) openAPIParam {
+	return openAPIParam{name: name, in: "query", typ: typ, description: description}
+}
+
+// openAPIRoute describes one operation of the REST API V2
+// pattern is the pattern of the route as mapped by handleAPI, path is the path of the operation in the OpenAPI document
+type openAPIRoute struct {
+	pattern string
+	path    string
+	method  string
+	id      string
+	summary string
+	params  []openAPIParam
+	// body is the type of the request body, string means plain text
+	body interface{}
+	// responses are the possible types of the successful response
+	responses []interface{}
+	// csv is set if the route returns also the csv export (parameter format=csv)
+	csv bool
+}
+
+var addressQueryParams = []openAPIParam{
+	queryParam("page", "integer", "page of the transactions, starting from 1"),
+	queryParam("pageSize", "integer", "number of transactions on page"),
+	queryParam("from", "integer", "return only transactions from the block height"),
+	queryParam("to", "integer", "return only transactions up to the block height"),
+	queryParam("details", "string", "basic, tokens, tokenBalances, txids or txs"),
+	queryParam("filter", "string", "inputs, outputs or the index of the output"),
+}
+
+var exportQueryParams = []openAPIParam{
+	queryParam("format", "string", "csv to export the history of the transactions"),
+	queryParam("fromTime", "string", "start of the exported history, unix timestamp or date YYYY-MM-DD"),
+	queryParam("toTime", "string", "end of the exported history, unix timestamp or date YYYY-MM-DD"),
+}
+
+func concatParams(params ...[]openAPIParam) []openAPIParam {
+	var rv []openAPIParam
+	for _, p := range params {
+		rv = append(rv, p...)
+	}
+	return rv
+}
+
+// openAPIRoutes documents the routes of the REST API V2 mapped in NewPublicServer and ConnectFullPublicInterface
+var openAPIRoutes = []openAPIRoute{
+	{
+		pattern:   "api/",
+		path:      "/api/v2/",
+		method:    http.MethodGet,
+		id:        "getStatus",
+		summary:   "Status of Blockbook and of the backend",
+		responses: []interface{}{api.SystemInfo{}},
+	},
+	{
+		pattern:   "api/v2/block-index/",
+		path:      "/api/v2/block-index/{height}",
+		method:    http.MethodGet,
+		id:        "getBlockHash",
+		summary:   "Hash of the block at the height",
+		params:    []openAPIParam{pathParam("height", "height of the block")},
+		responses: []interface{}{resultBlockIndex{}},
+	},
+	{
+		pattern:   "api/v2/tx-specific/",
+		path:      "/api/v2/tx-specific/{txid}",
+		method:    http.MethodGet,
+		id:        "getTransactionSpecific",
+		summary:   "Transaction in the format of the backend",
+		params:    []openAPIParam{pathParam("txid", "id of the transaction")},
+		responses: []interface{}{json.RawMessage{}},
+	},
+	{
+		pattern: "api/v2/tx/",
+		path:    "/api/v2/tx/{txid}",
+		method:  http.MethodGet,
+		id:      "getTransaction",
+		summary: "Transaction",
+		params: []openAPIParam{
+			pathParam("txid", "id of the transaction"),
+			queryParam("spending", "boolean", "return the spending transactions of the outputs"),
+		},
+		responses: []interface{}{api.Tx{}},
+	},
+	{
+		pattern: "api/v2/address/",
+		path:    "/api/v2/address/{address}",
+		method:  http.MethodGet,
+		id:      "getAddress",
+		summary: "Balances and transactions of the address",
+		params: concatParams(
+			[]openAPIParam{pathParam("address", "the address")},
+			addressQueryParams,
+			[]openAPIParam{queryParam("cursor", "string", "nextCursor of the previous page")},
+			exportQueryParams,
+		),
+		responses: []interface{}{api.Address{}},
+		csv:       true,
+	},
+	{
+		pattern: "api/v2/addresses",
+		path:    "/api/v2/addresses",
+		method:  http.MethodPost,
+		id:      "getAddresses",
+		summary: "Balances and transactions of several addresses",
+		params: []openAPIParam{
+			queryParam("pageSize", "integer", "number of transactions of each address"),
+			queryParam("details", "string", "basic, tokens, tokenBalances, txids or txs"),
+			queryParam("utxo", "boolean", "return also the utxos of the addresses"),
+		},
+		body:      addressesReq{},
+		responses: []interface{}{[]api.AddressesItem{}},
+	},
+	{
+		pattern: "api/v2/xpub/",
+		path:    "/api/v2/xpub/{xpub}",
+		method:  http.MethodGet,
+		id:      "getXpub",
+		summary: "Balances and transactions of the xpub or of the output descriptor",
+		params: concatParams(
+			[]openAPIParam{pathParam("xpub", "the xpub or the output descriptor")},
+			addressQueryParams,
+			[]openAPIParam{
+				queryParam("tokens", "string", "nonzero, used or derived addresses to return"),
+				queryParam("gap", "integer", "gap limit of the derived addresses"),
+			},
+			exportQueryParams,
+		),
+		responses: []interface{}{api.Address{}},
+		csv:       true,
+	},
+	{
+		pattern: "api/v2/utxo/",
+		path:    "/api/v2/utxo/{descriptor}",
+		method:  http.MethodGet,
+		id:      "getUtxo",
+		summary: "Unspent outputs of the address or of the xpub",
+		params: []openAPIParam{
+			pathParam("descriptor", "the address, xpub or output descriptor"),
+			queryParam("confirmed", "boolean", "return only confirmed utxos"),
+			queryParam("gap", "integer", "gap limit of the derived addresses"),
+		},
+		responses: []interface{}{[]api.Utxo{}},
+	},
+	{
+		pattern: "api/v2/block/",
+		path:    "/api/v2/block/{block}",
+		method:  http.MethodGet,
+		id:      "getBlock",
+		summary: "Block with its transactions",
+		params: []openAPIParam{
+			pathParam("block", "hash or height of the block"),
+			queryParam("page", "integer", "page of the transactions, starting from 1"),
+		},
+		responses: []interface{}{api.Block{}},
+	},
+	{
+		pattern:   "api/v2/orphaned-blocks",
+		path:      "/api/v2/orphaned-blocks",
+		method:    http.MethodGet,
+		id:        "getOrphanedBlocks",
+		summary:   "Blocks disconnected by reorgs and rollbacks",
+		params:    []openAPIParam{queryParam("page", "integer", "page of the blocks, starting from 1")},
+		responses: []interface{}{api.OrphanedBlocks{}},
+	},
+	{
+		pattern: "api/v2/sendtx/",
+		path:    "/api/v2/sendtx/{hex}",
+		method:  http.MethodGet,
+		id:      "sendTransaction",
+		summary: "Send the transaction or only validate it",
+		params: []openAPIParam{
+			pathParam("hex", "the serialized transaction in hex"),
+			queryParam("validateOnly", "boolean", "only validate the transaction"),
+		},
+		responses: []interface{}{resultSendTransaction{}, api.TxValidation{}},
+	},
+	{
+		pattern:   "api/v2/sendtx/",
+		path:      "/api/v2/sendtx/",
+		method:    http.MethodPost,
+		id:        "sendTransactionPost",
+		summary:   "Send the transaction in the body of the request or only validate it",
+		params:    []openAPIParam{queryParam("validateOnly", "boolean", "only validate the transaction")},
+		body:      "",
+		responses: []interface{}{resultSendTransaction{}, api.TxValidation{}},
+	},
+	{
+		pattern: "api/v2/estimatefee/",
+		path:    "/api/v2/estimatefee/{blocks}",
+		method:  http.MethodGet,
+		id:      "estimateFee",
+		summary: "Fee per kilobyte for the confirmation in the number of blocks",
+		params: []openAPIParam{
+			pathParam("blocks", "number of blocks"),
+			queryParam("conservative", "boolean", "conservative estimate, true by default"),
+		},
+		responses: []interface{}{resultEstimateFeeAsString{}},
+	},
+	{
+		pattern:   "api/v2/feestats/",
+		path:      "/api/v2/feestats/{block}",
+		method:    http.MethodGet,
+		id:        "getFeeStats",
+		summary:   "Fee statistics of the block",
+		params:    []openAPIParam{pathParam("block", "hash or height of the block")},
+		responses: []interface{}{api.FeeStats{}},
+	},
+	{
+		pattern: "api/v2/name/",
+		path:    "/api/v2/name/{name}",
+		method:  http.MethodGet,
+		id:      "getName",
+		summary: "State and history of the name",
+		params: []openAPIParam{
+			pathParam("name", "the name"),
+			queryParam("name", "string", "the name containing // or dot segments, used instead of the path"),
+		},
+		responses: []interface{}{api.Name{}},
+	},
+	{
+		pattern:   "api/v2/merkleproof/",
+		path:      "/api/v2/merkleproof/{txid}",
+		method:    http.MethodGet,
+		id:        "getMerkleProof",
+		summary:   "Merkle inclusion proof of the transaction",
+		params:    []openAPIParam{pathParam("txid", "id of the transaction")},
+		responses: []interface{}{api.MerkleProof{}},
+	},
+	{
+		pattern: "api/v2/blockfilters",
+		path:    "/api/v2/blockfilters",
+		method:  http.MethodGet,
+		id:      "getBlockFilters",
+		summary: "Basic filters of a range of blocks",
+		params: []openAPIParam{
+			queryParam("from", "integer", "height of the first block"),
+			queryParam("to", "integer", "height of the last block"),
+		},
+		responses: []interface{}{api.BlockFilters{}},
+	},
+	{
+		pattern:   "api/v2/buildtx",
+		path:      "/api/v2/buildtx",
+		method:    http.MethodPost,
+		id:        "buildTransaction",
+		summary:   "Unsigned transaction spending the utxos of the xpub or of the address",
+		body:      api.BuildTxRequest{},
+		responses: []interface{}{api.BuildTxResult{}},
+	},
+	{
+		pattern:   "api/v2/psbt/decode",
+		path:      "/api/v2/psbt/decode",
+		method:    http.MethodPost,
+		id:        "decodePsbt",
+		summary:   "Decode the psbt in base64 in the body of the request",
+		body:      "",
+		responses: []interface{}{api.PsbtInfo{}},
+	},
+	{
+		pattern:   "api/v2/psbt/broadcast",
+		path:      "/api/v2/psbt/broadcast",
+		method:    http.MethodPost,
+		id:        "broadcastPsbt",
+		summary:   "Finalize the psbt in base64 in the body of the request and send the transaction",
+		body:      "",
+		responses: []interface{}{resultSendTransaction{}},
+	},
+	{
+		pattern:   "api/v2/openapi.json",
+		path:      "/api/v2/openapi.json",
+		method:    http.MethodGet,
+		id:        "getOpenAPI",
+		summary:   "This OpenAPI document",
+		responses: []interface{}{json.RawMessage{}},
+	},
+}
+
+var (
+	amountType     = reflect.TypeOf(api.Amount{})
+	bigIntType     = reflect.TypeOf(big.Int{})
+	jsonNumberType = reflect.TypeOf(json.Number(""))
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	timeType       = reflect.TypeOf(time.Time{})
+)
+
+// openAPISchemas generates the schemas of the types by reflection, following the rules of encoding/json
+// the named structs are stored as components and referenced
+type openAPISchemas struct {
+	components map[string]interface{}
+	names      map[reflect.Type]string
+}
+
+func newOpenAPISchemas() *openAPISchemas {
+	return &openAPISchemas{
+		components: make(map[string]interface{}),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+// schema returns the schema of the type, it fails for the types which cannot be described (interfaces, channels etc.)
+func (g *openAPISchemas) schema(t reflect.Type) (map[string]interface{}, error) {
+	switch t {
+	case amountType:
+		return map[string]interface{}{"type": "string", "description": "amount in the base units of the coin"}, nil
+	case bigIntType, jsonNumberType:
+		return map[string]interface{}{"type": "number"}, nil
+	case rawMessageType:
+		return map[string]interface{}{"type": "object", "additionalProperties": true}, nil
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}, nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return map[string]interface{}{"type": "integer", "format": "int32"}, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}, nil
+		}
+		items, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		s := map[string]interface{}{"type": "array", "items": items}
+		if t.Kind() == reflect.Array {
+			s["minItems"] = t.Len()
+			s["maxItems"] = t.Len()
+		}
+		return s, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			break
+		}
+		values, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+	return nil, errors.Errorf("No schema for type %v", t)
+}
+
+func (g *openAPISchemas) structSchema(t reflect.Type) (map[string]interface{}, error) {
+	if name, ok := g.names[t]; ok {
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}, nil
+	}
+	name := strings.Title(t.Name())
+	if name != "" {
+		// the same name in different packages, e.g. api.BlockInfo and db.BlockInfo
+		if _, taken := g.components[name]; taken {
+			name = strings.Title(path.Base(t.PkgPath())) + name
+		}
+		// register the name before the fields are processed to support recursive types
+		g.names[t] = name
+		g.components[name] = nil
+	}
+	properties := make(map[string]interface{})
+	required := make(map[string]bool)
+	if err := g.fields(t, properties, required, false); err != nil {
+		return nil, err
+	}
+	s := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		r := make([]string, 0, len(required))
+		for n := range required {
+			r = append(r, n)
+		}
+		sort.Strings(r)
+		s["required"] = r
+	}
+	if name == "" {
+		return s, nil
+	}
+	g.components[name] = s
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}, nil
+}
+
+// fields adds the properties of the struct fields, the fields of the embedded structs are promoted
+// unless the outer struct has a field of the same name
+func (g *openAPISchemas) fields(t reflect.Type, properties map[string]interface{}, required map[string]bool, embedded bool) error {
+	var promoted []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if j := strings.IndexByte(tag, ','); j >= 0 {
+			name, opts = tag[:j], tag[j+1:]
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				promoted = append(promoted, f.Type)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if _, ok := properties[name]; ok && embedded {
+			continue
+		}
+		s, err := g.schema(f.Type)
+		if err != nil {
+			return errors.Annotatef(err, "%v.%v", t.Name(), f.Name)
+		}
+		properties[name] = s
+		if !embedded && !strings.Contains(opts, "omitempty") {
+			required[name] = true
+		}
+	}
+	for _, ft := range promoted {
+		// the fields of an embedded pointer are missing if it is nil, the promoted fields are not required
+		optional := embedded || ft.Kind() == reflect.Ptr
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if err := g.fields(ft, properties, required, optional); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var openAPIErrorSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"error": map[string]interface{}{"type": "string"},
+		"code":  map[string]interface{}{"type": "string", "description": "classification of the error, e.g. fee_too_low"},
+	},
+	"required": []string{"error"},
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// openAPIOperation returns the OpenAPI operation object of the route
+func (g *openAPISchemas) openAPIOperation(r *openAPIRoute) (map[string]interface{}, error) {
+	if len(r.responses) == 0 {
+		return nil, errors.Errorf("Route %v %v has no response", r.method, r.path)
+	}
+	schemas := make([]interface{}, len(r.responses))
+	for i, t := range r.responses {
+		s, err := g.schema(reflect.TypeOf(t))
+		if err != nil {
+			return nil, errors.Annotatef(err, "Route %v %v", r.method, r.path)
+		}
+		schemas[i] = s
+	}
+	var schema interface{} = schemas[0]
+	if len(schemas) > 1 {
+		schema = map[string]interface{}{"oneOf": schemas}
+	}
+	content := jsonContent(schema)
+	if r.csv {
+		content["text/csv"] = map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}
+	}
+	errorResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content":     jsonContent(map[string]interface{}{"$ref": "#/components/schemas/Error"}),
+		}
+	}
+	op := map[string]interface{}{
+		"operationId": r.id,
+		"summary":     r.summary,
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{"description": "OK", "content": content},
+			"400": errorResponse("Invalid request or not found"),
+			"500": errorResponse("Internal server error"),
+		},
+	}
+	if len(r.params) > 0 {
+		params := make([]interface{}, len(r.params))
+		for i, p := range r.params {
+			params[i] = map[string]interface{}{
+				"name":        p.name,
+				"in":          p.in,
+				"description": p.description,
+				"required":    p.in == "path",
+				"schema":      map[string]interface{}{"type": p.typ},
+			}
+		}
+		op["parameters"] = params
+	}
+	if r.body != nil {
+		var content map[string]interface{}
+		if _, ok := r.body.(string); ok {
+			content = map[string]interface{}{"text/plain": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}}}
+		} else {
+			s, err := g.schema(reflect.TypeOf(r.body))
+			if err != nil {
+				return nil, errors.Annotatef(err, "Route %v %v", r.method, r.path)
+			}
+			content = jsonContent(s)
+		}
+		op["requestBody"] = map[string]interface{}{"required": true, "content": content}
+	}
+	return op, nil
+}
+
+// openAPIDocument generates the OpenAPI 3 document of the REST API V2 from the route table and the api types
+func openAPIDocument(routes []openAPIRoute, basePath string, coin string) (map[string]interface{}, error) {
+	g := newOpenAPISchemas()
+	paths := make(map[string]interface{})
+	for i := range routes {
+		r := &routes[i]
+		op, err := g.openAPIOperation(r)
+		if err != nil {
+			return nil, err
+		}
+		item, ok := paths[r.path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[r.path] = item
+		}
+		item[strings.ToLower(r.method)] = op
+	}
+	g.components["Error"] = openAPIErrorSchema
+	doc := map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Blockbook " + coin + " REST API",
+			"version": common.GetVersionInfo().Version,
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": g.components},
+	}
+	if p := strings.TrimSuffix(basePath, "/"); p != "" {
+		doc["servers"] = []interface{}{map[string]interface{}{"url": p}}
+	}
+	return doc, nil
+}
+
+func (s *PublicServer) apiOpenAPI(r *http.Request, apiVersion int) (interface{}, error) {
+	if s.openAPI == nil {
+		return nil, errors.New("OpenAPI document not available")
+	}
+	return s.openAPI, nil
+}
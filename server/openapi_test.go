@@ -0,0 +1,128 @@
+// +build unittest
+
+package server
+
+import (
+	"blockbook/api"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func Test_openAPISchemas_schema(t *testing.T) {
+	type inner struct {
+		Page int `json:"page,omitempty"`
+		Name string
+	}
+	type testStruct struct {
+		inner
+		Name    string            `json:"name"`
+		Amount  *api.Amount       `json:"amount"`
+		Items   []string          `json:"items,omitempty"`
+		Values  map[string]uint32 `json:"values,omitempty"`
+		Skipped string            `json:"-"`
+		hidden  int
+	}
+	g := newOpenAPISchemas()
+	s, err := g.schema(reflect.TypeOf(testStruct{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, map[string]interface{}{"$ref": "#/components/schemas/TestStruct"}) {
+		t.Errorf("schema() = %v", s)
+	}
+	want := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"page":   map[string]interface{}{"type": "integer", "format": "int64"},
+			"Name":   map[string]interface{}{"type": "string"},
+			"name":   map[string]interface{}{"type": "string"},
+			"amount": map[string]interface{}{"type": "string", "description": "amount in the base units of the coin"},
+			"items":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			"values": map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "integer", "format": "int64"}},
+		},
+		"required": []string{"Name", "amount", "name"},
+	}
+	if got := g.components["TestStruct"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("components[TestStruct] = %v, want %v", got, want)
+	}
+
+	type withInterface struct {
+		Data interface{} `json:"data"`
+	}
+	if _, err := g.schema(reflect.TypeOf(withInterface{})); err == nil {
+		t.Error("schema() expected error for interface field")
+	}
+}
+
+// Test_openAPIDocument fails if a response field of some route has no schema
+func Test_openAPIDocument(t *testing.T) {
+	doc, err := openAPIDocument(openAPIRoutes, "/btc/", "Bitcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]bool)
+	for _, r := range openAPIRoutes {
+		if ids[r.id] {
+			t.Errorf("Duplicate operation id %v", r.id)
+		}
+		ids[r.id] = true
+	}
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	for _, n := range []string{"Tx", "Vin", "Vout", "Address", "Utxo", "Block", "FeeStats", "SystemInfo", "BlockbookInfo", "Error"} {
+		if schemas[n] == nil {
+			t.Errorf("Missing schema %v", n)
+		}
+	}
+	if s := doc["servers"]; !reflect.DeepEqual(s, []interface{}{map[string]interface{}{"url": "/btc"}}) {
+		t.Errorf("servers = %v", s)
+	}
+}
+
+// openAPITestsBitcoinType checks that every route mapped by the public server is described by the OpenAPI document and vice versa
+func openAPITestsBitcoinType(t *testing.T, s *PublicServer, ts *httptest.Server) {
+	documented := make(map[string]bool)
+	for _, r := range openAPIRoutes {
+		documented[r.pattern] = true
+	}
+	mapped := make(map[string]bool)
+	for _, p := range s.apiRoutes {
+		mapped[p] = true
+		if !documented[p] {
+			t.Errorf("Route %v is missing in openAPIRoutes", p)
+		}
+	}
+	for p := range documented {
+		if !mapped[p] {
+			t.Errorf("Route %v of openAPIRoutes is not mapped", p)
+		}
+	}
+
+	resp, err := ts.Client().Get(ts.URL + "/api/v2/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("openapi.json status %v, body %v", resp.StatusCode, string(b))
+	}
+	var doc struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.0.3" || doc.Paths["/api/v2/tx/{txid}"] == nil || doc.Paths["/api/v2/utxo/{descriptor}"] == nil {
+		t.Errorf("openapi.json = %v", string(b))
+	}
+}
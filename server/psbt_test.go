@@ -0,0 +1,94 @@
+// +build unittest
+
+package server
+
+import (
+	"blockbook/bchain"
+	"blockbook/bchain/coins/btc"
+	"blockbook/tests/dbtestdata"
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/martinboehm/btcd/wire"
+)
+
+func psbtTestsBitcoinType(t *testing.T, s *PublicServer) {
+	// the P2PK output is stored in the index as P2PKH, the psbt must be finalized using the real script of the output
+	pubKey, _ := hex.DecodeString("0330d54fd0dd420a6e5f8d3624f5f3482cae350f79d5f0753bf5beef9c2d91af3c")
+	p2pk, _ := hex.DecodeString("210330d54fd0dd420a6e5f8d3624f5f3482cae350f79d5f0753bf5beef9c2d91af3cac")
+	sig := []byte{0x30, 0x01, 0x01}
+	prev := wire.NewMsgTx(1)
+	prev.AddTxOut(wire.NewTxOut(5000000000, p2pk))
+	var b bytes.Buffer
+	if err := prev.Serialize(&b); err != nil {
+		t.Fatal(err)
+	}
+	prevHash := prev.TxHash()
+	block := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{
+			Height: 225495,
+			Hash:   "000000000000000000000000000000000000000000000000000000000000f00d",
+			Time:   1534860000,
+		},
+		Txs: []bchain.Tx{
+			{
+				Txid: prevHash.String(),
+				Vout: []bchain.Vout{
+					{
+						N:            0,
+						ScriptPubKey: bchain.ScriptPubKey{Hex: hex.EncodeToString(p2pk)},
+						ValueSat:     *big.NewInt(5000000000),
+					},
+				},
+				Blocktime: 1534860000,
+				Time:      1534860000,
+			},
+		},
+	}
+	if err := s.db.ConnectBlock(block); err != nil {
+		t.Fatal(err)
+	}
+
+	outScript, _ := hex.DecodeString(dbtestdata.AddressToPubKeyHex(dbtestdata.Addr1, s.chainParser))
+	tx := wire.NewMsgTx(2)
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, 0), nil, nil))
+	tx.AddTxOut(wire.NewTxOut(4999990000, outScript))
+	p := btc.NewPsbt(tx)
+	p.Inputs[0].NonWitnessUtxo = b.Bytes()
+	p.Inputs[0].PartialSigs = []btc.PartialSig{{PubKey: pubKey, Signature: sig}}
+	b64, err := p.B64Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := s.api.DecodePsbt(b64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := &info.Vin[0]
+	if in.ValueSat == nil || (*big.Int)(in.ValueSat).Int64() != 5000000000 || in.Error != "" || in.RequiredSignatures != 1 || in.Signatures != 1 {
+		t.Errorf("DecodePsbt() input = %+v", in)
+	}
+	if info.FeeSat == nil || (*big.Int)(info.FeeSat).Int64() != 10000 || !info.Complete {
+		t.Errorf("DecodePsbt() = %+v", info)
+	}
+
+	signed, err := s.api.FinalizePsbt(b64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hexTx, err := hex.DecodeString(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stx wire.MsgTx
+	if err = stx.Deserialize(bytes.NewReader(hexTx)); err != nil {
+		t.Fatal(err)
+	}
+	// P2PK is spent only by the signature, without the public key
+	if got, want := hex.EncodeToString(stx.TxIn[0].SignatureScript), "03"+hex.EncodeToString(sig); got != want {
+		t.Errorf("FinalizePsbt() scriptSig = %v, want %v", got, want)
+	}
+}
@@ -6,12 +6,14 @@ import (
 	"blockbook/common"
 	"blockbook/db"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"html/template"
 	"io/ioutil"
 	"math/big"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"reflect"
 	"regexp"
@@ -22,9 +24,11 @@ import (
 	"time"
 
 	"github.com/golang/glog"
+	"github.com/juju/errors"
 )
 
 const txsOnPage = 25
+const csvExportFlushRows = 100
 const blocksOnPage = 50
 const mempoolTxsOnPage = 50
 const txsInAPI = 1000
@@ -41,6 +45,7 @@ type PublicServer struct {
 	certFiles        string
 	socketio         *SocketIoServer
 	websocket        *WebsocketServer
+	sse              *sseServer
 	https            *http.Server
 	db               *db.RocksDB
 	txCache          *db.TxCache
@@ -54,23 +59,35 @@ type PublicServer struct {
 	is               *common.InternalState
 	templates        []*template.Template
 	debug            bool
+	addressesLimit   int
+	serveMux         *http.ServeMux
+	limiter          *accessLimiter
+	accessLog        *AccessLog
+	// apiRoutes are the patterns mapped by handleAPI, openAPI is the document describing them
+	apiRoutes []string
+	openAPI   json.RawMessage
 }
 
 // NewPublicServer creates new public server http interface to blockbook and returns its handle
 // only basic functionality is mapped, to map all functions, call
-func NewPublicServer(binding string, certFiles string, db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, explorerURL string, metrics *common.Metrics, is *common.InternalState, debugMode bool) (*PublicServer, error) {
+// accessConfig configures the api keys, rate limits and allowed origins, nil means unlimited access
+// accessLog receives the entries of the requests of the REST API and of the explorer, it can be nil
+func NewPublicServer(binding string, certFiles string, db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, explorerURL string, metrics *common.Metrics, is *common.InternalState, debugMode bool, addressesLimit int, accessConfig *AccessConfig, accessLog *AccessLog) (*PublicServer, error) {
 
 	api, err := api.NewWorker(db, chain, mempool, txCache, is)
 	if err != nil {
 		return nil, err
 	}
 
-	socketio, err := NewSocketIoServer(db, chain, mempool, txCache, metrics, is)
+	// the limiter is shared by all interfaces, the client has one quota
+	limiter := newAccessLimiter(accessConfig, chain.GetChainParser(), metrics)
+
+	socketio, err := NewSocketIoServer(db, chain, mempool, txCache, metrics, is, limiter)
 	if err != nil {
 		return nil, err
 	}
 
-	websocket, err := NewWebsocketServer(db, chain, mempool, txCache, metrics, is)
+	websocket, err := NewWebsocketServer(db, chain, mempool, txCache, metrics, is, addressesLimit, limiter)
 	if err != nil {
 		return nil, err
 	}
@@ -79,7 +96,7 @@ func NewPublicServer(binding string, certFiles string, db *db.RocksDB, chain bch
 	serveMux := http.NewServeMux()
 	https := &http.Server{
 		Addr:    addr,
-		Handler: serveMux,
+		Handler: limiter.handler(path, serveMux),
 	}
 
 	s := &PublicServer{
@@ -89,6 +106,7 @@ func NewPublicServer(binding string, certFiles string, db *db.RocksDB, chain bch
 		api:              api,
 		socketio:         socketio,
 		websocket:        websocket,
+		sse:              newSSEServer(api, chain.GetChainParser(), metrics, limiter),
 		db:               db,
 		txCache:          txCache,
 		chain:            chain,
@@ -99,6 +117,10 @@ func NewPublicServer(binding string, certFiles string, db *db.RocksDB, chain bch
 		metrics:          metrics,
 		is:               is,
 		debug:            debugMode,
+		addressesLimit:   addressesLimit,
+		serveMux:         serveMux,
+		limiter:          limiter,
+		accessLog:        accessLog,
 	}
 	s.templates = s.parseTemplates()
 
@@ -108,7 +130,7 @@ func NewPublicServer(binding string, certFiles string, db *db.RocksDB, chain bch
 	// default handler
 	serveMux.HandleFunc(path, s.htmlTemplateHandler(s.explorerIndex))
 	// default API handler
-	serveMux.HandleFunc(path+"api/", s.jsonHandler(s.apiIndex, apiV2))
+	s.handleAPI("api/", s.jsonHandler(s.apiIndex, apiV2))
 
 	return s, nil
 }
@@ -125,7 +147,7 @@ func (s *PublicServer) Run() error {
 
 // ConnectFullPublicInterface enables complete public functionality
 func (s *PublicServer) ConnectFullPublicInterface() {
-	serveMux := s.https.Handler.(*http.ServeMux)
+	serveMux := s.serveMux
 	_, path := splitBinding(s.binding)
 	// support for test pages
 	serveMux.Handle(path+"test-socketio.html", http.FileServer(http.Dir("./static/")))
@@ -137,10 +159,12 @@ func (s *PublicServer) ConnectFullPublicInterface() {
 		serveMux.HandleFunc(path+"xpub/", s.htmlTemplateHandler(s.explorerXpub))
 		serveMux.HandleFunc(path+"search/", s.htmlTemplateHandler(s.explorerSearch))
 		serveMux.HandleFunc(path+"blocks", s.htmlTemplateHandler(s.explorerBlocks))
+		serveMux.HandleFunc(path+"orphaned-blocks", s.htmlTemplateHandler(s.explorerOrphanedBlocks))
 		serveMux.HandleFunc(path+"block/", s.htmlTemplateHandler(s.explorerBlock))
 		serveMux.HandleFunc(path+"spending/", s.htmlTemplateHandler(s.explorerSpendingTx))
 		serveMux.HandleFunc(path+"sendtx", s.htmlTemplateHandler(s.explorerSendTx))
 		serveMux.HandleFunc(path+"mempool", s.htmlTemplateHandler(s.explorerMempool))
+		serveMux.HandleFunc(path+"name/", s.htmlTemplateHandler(s.explorerName))
 	} else {
 		// redirect to wallet requests for tx and address, possibly to external site
 		serveMux.HandleFunc(path+"tx/", s.txRedirect)
@@ -176,44 +200,97 @@ func (s *PublicServer) ConnectFullPublicInterface() {
 	serveMux.HandleFunc(path+"api/sendtx/", s.jsonHandler(s.apiSendTx, apiDefault))
 	serveMux.HandleFunc(path+"api/estimatefee/", s.jsonHandler(s.apiEstimateFee, apiDefault))
 	// v2 format
-	serveMux.HandleFunc(path+"api/v2/block-index/", s.jsonHandler(s.apiBlockIndex, apiV2))
-	serveMux.HandleFunc(path+"api/v2/tx-specific/", s.jsonHandler(s.apiTxSpecific, apiV2))
-	serveMux.HandleFunc(path+"api/v2/tx/", s.jsonHandler(s.apiTx, apiV2))
-	serveMux.HandleFunc(path+"api/v2/address/", s.jsonHandler(s.apiAddress, apiV2))
-	serveMux.HandleFunc(path+"api/v2/xpub/", s.jsonHandler(s.apiXpub, apiV2))
-	serveMux.HandleFunc(path+"api/v2/utxo/", s.jsonHandler(s.apiUtxo, apiV2))
-	serveMux.HandleFunc(path+"api/v2/block/", s.jsonHandler(s.apiBlock, apiV2))
-	serveMux.HandleFunc(path+"api/v2/sendtx/", s.jsonHandler(s.apiSendTx, apiV2))
-	serveMux.HandleFunc(path+"api/v2/estimatefee/", s.jsonHandler(s.apiEstimateFee, apiV2))
-	serveMux.HandleFunc(path+"api/v2/feestats/", s.jsonHandler(s.apiFeeStats, apiV2))
+	s.handleAPI("api/v2/block-index/", s.jsonHandler(s.apiBlockIndex, apiV2))
+	s.handleAPI("api/v2/tx-specific/", s.jsonHandler(s.apiTxSpecific, apiV2))
+	s.handleAPI("api/v2/tx/", s.jsonHandler(s.apiTx, apiV2))
+	s.handleAPI("api/v2/address/", s.csvExportHandler(s.apiAddressExport, s.jsonHandler(s.apiAddress, apiV2)))
+	s.handleAPI("api/v2/addresses", s.jsonHandler(s.apiAddresses, apiV2))
+	s.handleAPI("api/v2/xpub/", s.csvExportHandler(s.apiXpubExport, s.jsonHandler(s.apiXpub, apiV2)))
+	s.handleAPI("api/v2/utxo/", s.jsonHandler(s.apiUtxo, apiV2))
+	s.handleAPI("api/v2/block/", s.jsonHandler(s.apiBlock, apiV2))
+	s.handleAPI("api/v2/orphaned-blocks", s.jsonHandler(s.apiOrphanedBlocks, apiV2))
+	s.handleAPI("api/v2/sendtx/", s.jsonHandler(s.apiSendTx, apiV2))
+	s.handleAPI("api/v2/estimatefee/", s.jsonHandler(s.apiEstimateFee, apiV2))
+	s.handleAPI("api/v2/feestats/", s.jsonHandler(s.apiFeeStats, apiV2))
+	s.handleAPI("api/v2/name/", s.jsonHandler(s.apiName, apiV2))
+	s.handleAPI("api/v2/merkleproof/", s.jsonHandler(s.apiMerkleProof, apiV2))
+	s.handleAPI("api/v2/blockfilters", s.jsonHandler(s.apiBlockFilters, apiV2))
+	s.handleAPI("api/v2/buildtx", s.jsonHandler(s.apiBuildTx, apiV2))
+	s.handleAPI("api/v2/psbt/decode", s.jsonHandler(s.apiPsbtDecode, apiV2))
+	s.handleAPI("api/v2/psbt/broadcast", s.jsonHandler(s.apiPsbtBroadcast, apiV2))
+	s.handleAPI("api/v2/openapi.json", s.jsonHandler(s.apiOpenAPI, apiV2))
 	// socket.io interface
 	serveMux.Handle(path+"socket.io/", s.socketio.GetHandler())
 	// websocket interface
 	serveMux.Handle(path+"websocket", s.websocket.GetHandler())
+	// Server-Sent Events and long-poll interface
+	serveMux.Handle(path+"api/v2/events/", s.sse)
+
+	doc, err := openAPIDocument(openAPIRoutes, path, s.is.Coin)
+	if err == nil {
+		s.openAPI, err = json.Marshal(doc)
+	}
+	if err != nil {
+		glog.Error("OpenAPI document: ", err)
+	}
+}
+
+// handleAPI maps the handler of the REST API to the pattern (relative to the path of the binding),
+// the patterns are checked against the routes of the OpenAPI document
+func (s *PublicServer) handleAPI(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	_, path := splitBinding(s.binding)
+	s.serveMux.HandleFunc(path+pattern, handler)
+	s.apiRoutes = append(s.apiRoutes, pattern)
 }
 
 // Close closes the server
 func (s *PublicServer) Close() error {
 	glog.Infof("public server: closing")
-	return s.https.Close()
+	err := s.https.Close()
+	if s.accessLog != nil {
+		s.accessLog.Close()
+	}
+	return err
 }
 
 // Shutdown shuts down the server
 func (s *PublicServer) Shutdown(ctx context.Context) error {
 	glog.Infof("public server: shutdown")
-	return s.https.Shutdown(ctx)
+	err := s.https.Shutdown(ctx)
+	if s.accessLog != nil {
+		s.accessLog.Close()
+	}
+	return err
 }
 
 // OnNewBlock notifies users subscribed to bitcoind/hashblock about new block
 func (s *PublicServer) OnNewBlock(hash string, height uint32) {
 	s.socketio.OnNewBlockHash(hash)
 	s.websocket.OnNewBlock(hash, height)
+	s.sse.OnNewBlock(hash, height)
+}
+
+// OnReorg notifies users subscribed to new blocks and to the affected addresses about the blocks disconnected by a reorg
+func (s *PublicServer) OnReorg(blocks []*db.OrphanedBlock) {
+	s.websocket.OnReorg(blocks)
+	s.sse.OnReorg(blocks)
+}
+
+// OnNewChainLock notifies users subscribed to new blocks about a ChainLocked block
+func (s *PublicServer) OnNewChainLock(hash string, height uint32) {
+	s.websocket.OnNewChainLock(hash, height)
+}
+
+// OnNewInstantLock notifies users subscribed to addresses of the transaction about its InstantSend lock
+func (s *PublicServer) OnNewInstantLock(txid string) {
+	s.websocket.OnNewInstantLock(txid)
 }
 
 // OnNewTxAddr notifies users subscribed to bitcoind/addresstxid about new block
 func (s *PublicServer) OnNewTxAddr(tx *bchain.Tx, desc bchain.AddressDescriptor) {
 	s.socketio.OnNewTxAddr(tx.Txid, desc)
 	s.websocket.OnNewTxAddr(tx, desc)
+	s.sse.OnNewTxAddr(tx, desc)
 }
 
 func (s *PublicServer) txRedirect(w http.ResponseWriter, r *http.Request) {
@@ -244,6 +321,11 @@ func joinURL(base string, part string) string {
 	return part
 }
 
+// worker returns the api worker tracing its calls as spans of the request
+func (s *PublicServer) worker(r *http.Request) *api.Worker {
+	return s.api.WithContext(r.Context())
+}
+
 func getFunctionName(i interface{}) string {
 	return runtime.FuncForPC(reflect.ValueOf(i).Pointer()).Name()
 }
@@ -251,20 +333,32 @@ func getFunctionName(i interface{}) string {
 func (s *PublicServer) jsonHandler(handler func(r *http.Request, apiVersion int) (interface{}, error), apiVersion int) func(w http.ResponseWriter, r *http.Request) {
 	type jsonError struct {
 		Text       string `json:"error"`
+		Code       string `json:"code,omitempty"`
 		HTTPStatus int    `json:"-"`
 	}
-	return func(w http.ResponseWriter, r *http.Request) {
+	route := handlerRoute(handler)
+	return s.observeHandler(route, func(w http.ResponseWriter, r *http.Request) {
 		var data interface{}
 		var err error
+		var span *common.Span
+		if common.TracingEnabled() {
+			var ctx context.Context
+			ctx, span = common.StartSpan(r.Context(), "http "+route, common.StringAttr("http.method", r.Method))
+			r = r.WithContext(ctx)
+		}
 		defer func() {
 			if e := recover(); e != nil {
 				glog.Error(getFunctionName(handler), " recovered from panic: ", e)
 				debug.PrintStack()
 				if s.debug {
-					data = jsonError{fmt.Sprint("Internal server error: recovered from panic ", e), http.StatusInternalServerError}
+					data = jsonError{fmt.Sprint("Internal server error: recovered from panic ", e), "", http.StatusInternalServerError}
 				} else {
-					data = jsonError{"Internal server error", http.StatusInternalServerError}
+					data = jsonError{"Internal server error", "", http.StatusInternalServerError}
 				}
+				err = errors.Errorf("panic %v", e)
+			}
+			if span != nil {
+				common.EndSpan(span, err)
 			}
 			w.Header().Set("Content-Type", "application/json; charset=utf-8")
 			if e, isError := data.(jsonError); isError {
@@ -279,9 +373,9 @@ func (s *PublicServer) jsonHandler(handler func(r *http.Request, apiVersion int)
 		if err != nil || data == nil {
 			if apiErr, ok := err.(*api.APIError); ok {
 				if apiErr.Public {
-					data = jsonError{apiErr.Error(), http.StatusBadRequest}
+					data = jsonError{apiErr.Error(), apiErr.Code, http.StatusBadRequest}
 				} else {
-					data = jsonError{apiErr.Error(), http.StatusInternalServerError}
+					data = jsonError{apiErr.Error(), apiErr.Code, http.StatusInternalServerError}
 				}
 			} else {
 				if err != nil {
@@ -289,15 +383,88 @@ func (s *PublicServer) jsonHandler(handler func(r *http.Request, apiVersion int)
 				}
 				if s.debug {
 					if data != nil {
-						data = jsonError{fmt.Sprintf("Internal server error: %v, data %+v", err, data), http.StatusInternalServerError}
+						data = jsonError{fmt.Sprintf("Internal server error: %v, data %+v", err, data), "", http.StatusInternalServerError}
 					} else {
-						data = jsonError{fmt.Sprintf("Internal server error: %v", err), http.StatusInternalServerError}
+						data = jsonError{fmt.Sprintf("Internal server error: %v", err), "", http.StatusInternalServerError}
 					}
 				} else {
-					data = jsonError{"Internal server error", http.StatusInternalServerError}
+					data = jsonError{"Internal server error", "", http.StatusInternalServerError}
 				}
 			}
 		}
+	})
+}
+
+// csvExportHandler streams the history returned by the export function in csv format if the query parameter format=csv is specified,
+// otherwise the request is served by handler
+func (s *PublicServer) csvExportHandler(export func(r *http.Request, onRow func(*api.HistoryRow) error) error, handler http.HandlerFunc) http.HandlerFunc {
+	exportHandler := s.observeHandler(handlerRoute(export), func(w http.ResponseWriter, r *http.Request) {
+		var cw *csv.Writer
+		defer func() {
+			if e := recover(); e != nil {
+				glog.Error(getFunctionName(export), " recovered from panic: ", e)
+				debug.PrintStack()
+			}
+		}()
+		writeHeader := func() {
+			filename := "export.csv"
+			if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 && i < len(r.URL.Path)-1 {
+				filename = r.URL.Path[i+1:] + ".csv"
+			}
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", "attachment; filename=\""+filename+"\"")
+			cw = csv.NewWriter(w)
+			cw.Write([]string{"date", "height", "txid", "received", "sent", "fee", "balance"})
+		}
+		rows := 0
+		err := export(r, func(row *api.HistoryRow) error {
+			if cw == nil {
+				writeHeader()
+			}
+			var fee string
+			if row.FeeSat != nil {
+				fee = s.chainParser.AmountToDecimalString(row.FeeSat)
+			}
+			cw.Write([]string{
+				time.Unix(row.Time, 0).UTC().Format(time.RFC3339),
+				strconv.Itoa(int(row.Height)),
+				row.Txid,
+				s.chainParser.AmountToDecimalString(row.ReceivedSat),
+				s.chainParser.AmountToDecimalString(row.SentSat),
+				fee,
+				s.chainParser.AmountToDecimalString(row.BalanceSat),
+			})
+			rows++
+			// send the rows to the client continuously
+			if rows%csvExportFlushRows == 0 {
+				cw.Flush()
+				return cw.Error()
+			}
+			return nil
+		})
+		if err != nil {
+			if cw == nil {
+				// nothing was sent yet, return the error the same way as the json api
+				s.jsonHandler(func(r *http.Request, apiVersion int) (interface{}, error) {
+					return nil, err
+				}, apiV2)(w, r)
+				return
+			}
+			// the response is already being sent, it can be only truncated
+			glog.Error(getFunctionName(export), " error: ", err)
+			return
+		}
+		if cw == nil {
+			writeHeader()
+		}
+		cw.Flush()
+	})
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") != "csv" {
+			handler(w, r)
+			return
+		}
+		exportHandler(w, r)
 	}
 }
 
@@ -319,7 +486,7 @@ func (s *PublicServer) newTemplateDataWithError(text string) *TemplateData {
 }
 
 func (s *PublicServer) htmlTemplateHandler(handler func(w http.ResponseWriter, r *http.Request) (tpl, *TemplateData, error)) func(w http.ResponseWriter, r *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
+	return s.observeHandler(handlerRoute(handler), func(w http.ResponseWriter, r *http.Request) {
 		var t tpl
 		var data *TemplateData
 		var err error
@@ -371,7 +538,7 @@ func (s *PublicServer) htmlTemplateHandler(handler func(w http.ResponseWriter, r
 				}
 			}
 		}
-	}
+	})
 }
 
 type tpl int
@@ -386,8 +553,10 @@ const (
 	xpubTpl
 	blocksTpl
 	blockTpl
+	orphanedBlocksTpl
 	sendTransactionTpl
 	mempoolTpl
+	nameTpl
 
 	tplCount
 )
@@ -405,8 +574,10 @@ type TemplateData struct {
 	Error                *api.APIError
 	Blocks               *api.Blocks
 	Block                *api.Block
+	OrphanedBlocks       *api.OrphanedBlocks
 	Info                 *api.SystemInfo
 	MempoolTxids         *api.MempoolTxids
+	Name                 *api.Name
 	Page                 int
 	PrevPage             int
 	NextPage             int
@@ -471,6 +642,7 @@ func (s *PublicServer) parseTemplates() []*template.Template {
 	t[errorInternalTpl] = createTemplate("./static/templates/error.html", "./static/templates/base.html")
 	t[indexTpl] = createTemplate("./static/templates/index.html", "./static/templates/base.html")
 	t[blocksTpl] = createTemplate("./static/templates/blocks.html", "./static/templates/paging.html", "./static/templates/base.html")
+	t[orphanedBlocksTpl] = createTemplate("./static/templates/orphaned.html", "./static/templates/paging.html", "./static/templates/base.html")
 	t[sendTransactionTpl] = createTemplate("./static/templates/sendtx.html", "./static/templates/base.html")
 	if s.chainParser.GetChainType() == bchain.ChainEthereumType {
 		t[txTpl] = createTemplate("./static/templates/tx.html", "./static/templates/txdetail_ethereumtype.html", "./static/templates/base.html")
@@ -483,6 +655,7 @@ func (s *PublicServer) parseTemplates() []*template.Template {
 	}
 	t[xpubTpl] = createTemplate("./static/templates/xpub.html", "./static/templates/txdetail.html", "./static/templates/paging.html", "./static/templates/base.html")
 	t[mempoolTpl] = createTemplate("./static/templates/mempool.html", "./static/templates/paging.html", "./static/templates/base.html")
+	t[nameTpl] = createTemplate("./static/templates/name.html", "./static/templates/base.html")
 	return t
 }
 
@@ -637,6 +810,7 @@ func (s *PublicServer) getAddressQueryParams(r *http.Request, accountDetails api
 		TokensToReturn: tokensToReturn,
 		FromHeight:     uint32(from),
 		ToHeight:       uint32(to),
+		Cursor:         r.URL.Query().Get("cursor"),
 	}, filterParam, gap
 }
 
@@ -716,6 +890,23 @@ func (s *PublicServer) explorerBlocks(w http.ResponseWriter, r *http.Request) (t
 	return blocksTpl, data, nil
 }
 
+func (s *PublicServer) explorerOrphanedBlocks(w http.ResponseWriter, r *http.Request) (tpl, *TemplateData, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "orphaned-blocks"}).Inc()
+	page, ec := strconv.Atoi(r.URL.Query().Get("page"))
+	if ec != nil {
+		page = 0
+	}
+	blocks, err := s.api.GetOrphanedBlocks(page, blocksOnPage)
+	if err != nil {
+		return errorTpl, nil, err
+	}
+	data := s.newTemplateData()
+	data.OrphanedBlocks = blocks
+	data.Page = blocks.Page
+	data.PagingRange, data.PrevPage, data.NextPage = getPagingRange(blocks.Page, blocks.TotalPages)
+	return orphanedBlocksTpl, data, nil
+}
+
 func (s *PublicServer) explorerBlock(w http.ResponseWriter, r *http.Request) (tpl, *TemplateData, error) {
 	var block *api.Block
 	var err error
@@ -778,10 +969,40 @@ func (s *PublicServer) explorerSearch(w http.ResponseWriter, r *http.Request) (t
 			http.Redirect(w, r, joinURL("/address/", address.AddrStr), 302)
 			return noTpl, nil, nil
 		}
+		if s.chainParser.GetNameExpirationDepth() != 0 {
+			name, err := s.api.GetName(q)
+			if err == nil {
+				http.Redirect(w, r, joinURL("/name/?name=", url.QueryEscape(name.Name)), 302)
+				return noTpl, nil, nil
+			}
+		}
 	}
 	return errorTpl, nil, api.NewAPIError(fmt.Sprintf("No matching records found for '%v'", q), true)
 }
 
+// getNameParam returns the name from the query parameter name or the part of the path after the name/ route,
+// the names with empty path segments or dot segments must be passed in the query, ServeMux cleans the path
+func getNameParam(r *http.Request) string {
+	if name := r.URL.Query().Get("name"); name != "" {
+		return name
+	}
+	if i := strings.Index(r.URL.Path, "name/"); i >= 0 {
+		return r.URL.Path[i+len("name/"):]
+	}
+	return ""
+}
+
+func (s *PublicServer) explorerName(w http.ResponseWriter, r *http.Request) (tpl, *TemplateData, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "name"}).Inc()
+	name, err := s.api.GetName(getNameParam(r))
+	if err != nil {
+		return errorTpl, nil, err
+	}
+	data := s.newTemplateData()
+	data.Name = name
+	return nameTpl, data, nil
+}
+
 func (s *PublicServer) explorerSendTx(w http.ResponseWriter, r *http.Request) (tpl, *TemplateData, error) {
 	s.metrics.ExplorerViews.With(common.Labels{"action": "sendtx"}).Inc()
 	data := s.newTemplateData()
@@ -883,13 +1104,14 @@ func getPagingRange(page int, total int) ([]int, int, int) {
 
 func (s *PublicServer) apiIndex(r *http.Request, apiVersion int) (interface{}, error) {
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-index"}).Inc()
-	return s.api.GetSystemInfo(false)
+	return s.worker(r).GetSystemInfo(false)
+}
+
+type resultBlockIndex struct {
+	BlockHash string `json:"blockHash"`
 }
 
 func (s *PublicServer) apiBlockIndex(r *http.Request, apiVersion int) (interface{}, error) {
-	type resBlockIndex struct {
-		BlockHash string `json:"blockHash"`
-	}
 	var err error
 	var hash string
 	height := -1
@@ -907,7 +1129,7 @@ func (s *PublicServer) apiBlockIndex(r *http.Request, apiVersion int) (interface
 		glog.Error(err)
 		return nil, err
 	}
-	return resBlockIndex{
+	return resultBlockIndex{
 		BlockHash: hash,
 	}, nil
 }
@@ -932,7 +1154,7 @@ func (s *PublicServer) apiTx(r *http.Request, apiVersion int) (interface{}, erro
 			return nil, api.NewAPIError("Parameter 'spending' cannot be converted to boolean", true)
 		}
 	}
-	tx, err = s.api.GetTransaction(txid, spendingTxs, false)
+	tx, err = s.worker(r).GetTransaction(txid, spendingTxs, false)
 	if err == nil && apiVersion == apiV1 {
 		return s.api.TxToV1(tx), nil
 	}
@@ -968,13 +1190,44 @@ func (s *PublicServer) apiAddress(r *http.Request, apiVersion int) (interface{},
 	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-address"}).Inc()
 	page, pageSize, details, filter, _, _ := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
-	address, err = s.api.GetAddress(addressParam, page, pageSize, details, filter)
+	address, err = s.worker(r).GetAddress(addressParam, page, pageSize, details, filter)
 	if err == nil && apiVersion == apiV1 {
 		return s.api.AddressToV1(address), nil
 	}
 	return address, err
 }
 
+type addressesReq struct {
+	Addresses []string `json:"addresses"`
+	Utxo      bool     `json:"utxo"`
+}
+
+func (s *PublicServer) apiAddresses(r *http.Request, apiVersion int) (interface{}, error) {
+	if r.Method != http.MethodPost {
+		return nil, api.NewAPIError("Addresses must be sent using POST method", true)
+	}
+	var req addressesReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, api.NewAPIError("Invalid request, "+err.Error(), true)
+	}
+	if len(req.Addresses) == 0 {
+		return nil, api.NewAPIError("Missing addresses", true)
+	}
+	if len(req.Addresses) > s.addressesLimit {
+		return nil, api.NewAPIError(fmt.Sprintf("Too many addresses, the limit is %d", s.addressesLimit), true)
+	}
+	if u := r.URL.Query().Get("utxo"); len(u) > 0 {
+		utxo, err := strconv.ParseBool(u)
+		if err != nil {
+			return nil, api.NewAPIError("Parameter 'utxo' cannot be converted to boolean", true)
+		}
+		req.Utxo = utxo
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-addresses"}).Inc()
+	_, pageSize, details, filter, _, _ := s.getAddressQueryParams(r, api.AccountDetailsBasic, txsInAPI)
+	return s.worker(r).GetAddresses(req.Addresses, pageSize, details, filter, req.Utxo)
+}
+
 func (s *PublicServer) apiXpub(r *http.Request, apiVersion int) (interface{}, error) {
 	var xpub string
 	i := strings.LastIndexByte(r.URL.Path, '/')
@@ -988,13 +1241,77 @@ func (s *PublicServer) apiXpub(r *http.Request, apiVersion int) (interface{}, er
 	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-xpub"}).Inc()
 	page, pageSize, details, filter, _, gap := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
-	address, err = s.api.GetXpubAddress(xpub, page, pageSize, details, filter, gap)
+	address, err = s.worker(r).GetXpubAddress(xpub, page, pageSize, details, filter, gap)
 	if err == nil && apiVersion == apiV1 {
 		return s.api.AddressToV1(address), nil
 	}
 	return address, err
 }
 
+// getExportQueryParams returns the filter and the time range of the history export
+// the time can be specified as unix timestamp or as date in the format YYYY-MM-DD, the date toTime includes the whole day
+func (s *PublicServer) getExportQueryParams(r *http.Request) (*api.AddressFilter, int64, int64, int, error) {
+	_, _, _, filter, _, gap := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+	parseTime := func(name string, endOfDay bool) (int64, error) {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			return 0, nil
+		}
+		if t, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return t, nil
+		}
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return 0, api.NewAPIError("Parameter '"+name+"' is not a unix timestamp or a date YYYY-MM-DD", true)
+		}
+		if endOfDay {
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		return t.Unix(), nil
+	}
+	fromTime, err := parseTime("fromTime", false)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	toTime, err := parseTime("toTime", true)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	return filter, fromTime, toTime, gap, nil
+}
+
+func (s *PublicServer) apiAddressExport(r *http.Request, onRow func(*api.HistoryRow) error) error {
+	var addressParam string
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		addressParam = r.URL.Path[i+1:]
+	}
+	if len(addressParam) == 0 {
+		return api.NewAPIError("Missing address", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-address-export"}).Inc()
+	filter, fromTime, toTime, _, err := s.getExportQueryParams(r)
+	if err != nil {
+		return err
+	}
+	return s.api.ExportAddressHistory(addressParam, filter, fromTime, toTime, onRow)
+}
+
+func (s *PublicServer) apiXpubExport(r *http.Request, onRow func(*api.HistoryRow) error) error {
+	var xpub string
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		xpub = r.URL.Path[i+1:]
+	}
+	if len(xpub) == 0 {
+		return api.NewAPIError("Missing xpub", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-xpub-export"}).Inc()
+	filter, fromTime, toTime, gap, err := s.getExportQueryParams(r)
+	if err != nil {
+		return err
+	}
+	return s.api.ExportXpubHistory(xpub, filter, fromTime, toTime, gap, onRow)
+}
+
 func (s *PublicServer) apiUtxo(r *http.Request, apiVersion int) (interface{}, error) {
 	var utxo []api.Utxo
 	var err error
@@ -1011,11 +1328,11 @@ func (s *PublicServer) apiUtxo(r *http.Request, apiVersion int) (interface{}, er
 		if ec != nil {
 			gap = 0
 		}
-		utxo, err = s.api.GetXpubUtxo(r.URL.Path[i+1:], onlyConfirmed, gap)
+		utxo, err = s.worker(r).GetXpubUtxo(r.URL.Path[i+1:], onlyConfirmed, gap)
 		if err == nil {
 			s.metrics.ExplorerViews.With(common.Labels{"action": "api-xpub-utxo"}).Inc()
 		} else {
-			utxo, err = s.api.GetAddressUtxo(r.URL.Path[i+1:], onlyConfirmed)
+			utxo, err = s.worker(r).GetAddressUtxo(r.URL.Path[i+1:], onlyConfirmed)
 			s.metrics.ExplorerViews.With(common.Labels{"action": "api-address-utxo"}).Inc()
 		}
 		if err == nil && apiVersion == apiV1 {
@@ -1034,7 +1351,7 @@ func (s *PublicServer) apiBlock(r *http.Request, apiVersion int) (interface{}, e
 		if ec != nil {
 			page = 0
 		}
-		block, err = s.api.GetBlock(r.URL.Path[i+1:], page, txsInAPI)
+		block, err = s.worker(r).GetBlock(r.URL.Path[i+1:], page, txsInAPI)
 		if err == nil && apiVersion == apiV1 {
 			return s.api.BlockToV1(block), nil
 		}
@@ -1042,16 +1359,109 @@ func (s *PublicServer) apiBlock(r *http.Request, apiVersion int) (interface{}, e
 	return block, err
 }
 
+func (s *PublicServer) apiOrphanedBlocks(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-orphaned-blocks"}).Inc()
+	page, ec := strconv.Atoi(r.URL.Query().Get("page"))
+	if ec != nil {
+		page = 0
+	}
+	return s.worker(r).GetOrphanedBlocks(page, blocksOnPage)
+}
+
 func (s *PublicServer) apiFeeStats(r *http.Request, apiVersion int) (interface{}, error) {
 	var feeStats *api.FeeStats
 	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-feestats"}).Inc()
 	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
-		feeStats, err = s.api.GetFeeStats(r.URL.Path[i+1:])
+		feeStats, err = s.worker(r).GetFeeStats(r.URL.Path[i+1:])
 	}
 	return feeStats, err
 }
 
+func (s *PublicServer) apiMerkleProof(r *http.Request, apiVersion int) (interface{}, error) {
+	var txid string
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		txid = r.URL.Path[i+1:]
+	}
+	if len(txid) == 0 {
+		return nil, api.NewAPIError("Missing txid", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-merkleproof"}).Inc()
+	return s.worker(r).GetTransactionProof(txid)
+}
+
+func (s *PublicServer) apiBlockFilters(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-blockfilters"}).Inc()
+	from, ec := strconv.Atoi(r.URL.Query().Get("from"))
+	if ec != nil {
+		from = -1
+	}
+	to, ec := strconv.Atoi(r.URL.Query().Get("to"))
+	if ec != nil {
+		to = -1
+	}
+	return s.worker(r).GetBlockFilters(from, to)
+}
+
+func (s *PublicServer) apiName(r *http.Request, apiVersion int) (interface{}, error) {
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-name"}).Inc()
+	return s.worker(r).GetName(getNameParam(r))
+}
+
+func (s *PublicServer) apiBuildTx(r *http.Request, apiVersion int) (interface{}, error) {
+	if r.Method != http.MethodPost {
+		return nil, api.NewAPIError("Request must be sent using POST method", true)
+	}
+	var req api.BuildTxRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, api.NewAPIError("Invalid request, "+err.Error(), true)
+	}
+	if len(req.Descriptor) == 0 {
+		return nil, api.NewAPIError("Missing descriptor", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-buildtx"}).Inc()
+	return s.worker(r).BuildTx(&req)
+}
+
+// getPsbtParam returns the psbt in base64 sent in the body of the POST request
+func getPsbtParam(r *http.Request) (string, error) {
+	if r.Method != http.MethodPost {
+		return "", api.NewAPIError("Psbt must be sent using POST method", true)
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil || len(strings.TrimSpace(string(data))) == 0 {
+		return "", api.NewAPIError("Missing psbt", true)
+	}
+	return string(data), nil
+}
+
+func (s *PublicServer) apiPsbtDecode(r *http.Request, apiVersion int) (interface{}, error) {
+	psbt, err := getPsbtParam(r)
+	if err != nil {
+		return nil, err
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-psbt-decode"}).Inc()
+	return s.worker(r).DecodePsbt(psbt)
+}
+
+func (s *PublicServer) apiPsbtBroadcast(r *http.Request, apiVersion int) (interface{}, error) {
+	psbt, err := getPsbtParam(r)
+	if err != nil {
+		return nil, err
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-psbt-broadcast"}).Inc()
+	hex, err := s.worker(r).FinalizePsbt(psbt)
+	if err != nil {
+		return nil, err
+	}
+	var res resultSendTransaction
+	res.Result, err = s.chain.SendRawTransaction(hex)
+	if err != nil {
+		return nil, api.SendTxError(err)
+	}
+	return res, nil
+}
+
 type resultSendTransaction struct {
 	Result string `json:"result"`
 }
@@ -1073,9 +1483,12 @@ func (s *PublicServer) apiSendTx(r *http.Request, apiVersion int) (interface{},
 		}
 	}
 	if len(hex) > 0 {
+		if validateOnly, _ := strconv.ParseBool(r.URL.Query().Get("validateOnly")); validateOnly {
+			return s.worker(r).ValidateTransaction(hex)
+		}
 		res.Result, err = s.chain.SendRawTransaction(hex)
 		if err != nil {
-			return nil, api.NewAPIError(err.Error(), true)
+			return nil, api.SendTxError(err)
 		}
 		return res, nil
 	}
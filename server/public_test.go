@@ -101,7 +101,7 @@ func setupPublicHTTPServer(t *testing.T) (*PublicServer, string) {
 	}
 
 	// s.Run is never called, binding can be to any port
-	s, err := NewPublicServer("localhost:12345", "", d, chain, mempool, txCache, "", metrics, is, false)
+	s, err := NewPublicServer("localhost:12345", "", d, chain, mempool, txCache, "", metrics, is, false, 1000, nil, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -505,6 +505,35 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"error":"Missing address"}`,
 			},
 		},
+		{
+			name:        "apiAddress v2 format=csv",
+			r:           newGetRequest(ts.URL + "/api/v2/address/mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw?format=csv"),
+			status:      http.StatusOK,
+			contentType: "text/csv; charset=utf-8",
+			body: []string{
+				"date,height,txid,received,sent,fee,balance\n",
+				"2018-08-21T13:45:23Z,225494,7c3be24063f268aaa1ed81b64776798f56088757641a34fb156c4f51ed2e9d25,0,12345.67890123,0.00000346,0\n",
+				"2018-08-21T13:27:01Z,225493,effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75,12345.67890123,0,,12345.67890123\n",
+			},
+		},
+		{
+			name:        "apiAddresses v2",
+			r:           newPostRequest(ts.URL+"/api/v2/addresses?details=basic", `{"addresses":["mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw"]}`),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`[{"address":"mv9uLThosiEnGRbVPS7Vhyw6VssbVRsiAw","balance":"0","totalReceived":"1234567890123","totalSent":"1234567890123","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2}]`,
+			},
+		},
+		{
+			name:        "apiAddresses v2 GET",
+			r:           newGetRequest(ts.URL + "/api/v2/addresses"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Addresses must be sent using POST method"}`,
+			},
+		},
 		{
 			name:        "apiXpub v2 default",
 			r:           newGetRequest(ts.URL + "/api/v2/xpub/" + dbtestdata.Xpub),
@@ -631,6 +660,42 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"error":"Missing tx blob"}`,
 			},
 		},
+		{
+			name:        "apiSendTx validateOnly",
+			r:           newPostRequest(ts.URL+"/api/v2/sendtx?validateOnly=true", "010000000171dbebb0e2762121f7d723d12a01e8a98fd15e8752fb9fe145dc26d05ed1903d0000000000ffffffff01e4dc9c9f1b0000001976a9143f8ba3fda3ba7b69f5818086e12223c6dd25e3c888ac00000000"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"d00f08ff85c46480e949fcedb714483941f803aad3d344da7cf4ad8efa1948b7","vsize":85,"fee":"1000","feeRate":11.764705882352942,"validatedBy":"index"}`,
+			},
+		},
+		{
+			name:        "apiSendTx validateOnly fee too low",
+			r:           newPostRequest(ts.URL+"/api/v2/sendtx?validateOnly=true", "010000000171dbebb0e2762121f7d723d12a01e8a98fd15e8752fb9fe145dc26d05ed1903d0000000000ffffffff01cce09c9f1b0000001976a9143f8ba3fda3ba7b69f5818086e12223c6dd25e3c888ac00000000"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"min relay fee not met, 0 \u003c 85","code":"fee_too_low"}`,
+			},
+		},
+		{
+			name:        "apiSendTx validateOnly spent input",
+			r:           newPostRequest(ts.URL+"/api/v2/sendtx?validateOnly=true", "010000000175acb49486d6bb2240fdbef2a421f5fb8e4c43bff58a1c6b533d3809f59efdef0100000000ffffffff0100000000000000001976a9143f8ba3fda3ba7b69f5818086e12223c6dd25e3c888ac00000000"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"code":"inputs_spent"`,
+			},
+		},
+		{
+			name:        "apiSendTx validateOnly invalid",
+			r:           newPostRequest(ts.URL+"/api/v2/sendtx?validateOnly=true", "123456"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`"code":"invalid_tx"`,
+			},
+		},
 		{
 			name:        "apiEstimateFee",
 			r:           newGetRequest(ts.URL + "/api/estimatefee/123?conservative=false"),
@@ -965,6 +1030,28 @@ func websocketTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 			},
 			want: `{"id":"16","data":{}}`,
 		},
+		{
+			name: "websocket subscribeAddresses with confirmations",
+			req: websocketReq{
+				Method: "subscribeAddresses",
+				Params: map[string]interface{}{
+					"addresses":     []string{dbtestdata.Addr1},
+					"confirmations": []int{6, 1, 6},
+				},
+			},
+			want: `{"id":"17","data":{"subscribed":true}}`,
+		},
+		{
+			name: "websocket subscribeAddresses invalid confirmations",
+			req: websocketReq{
+				Method: "subscribeAddresses",
+				Params: map[string]interface{}{
+					"addresses":     []string{dbtestdata.Addr1},
+					"confirmations": []int{0},
+				},
+			},
+			want: `{"id":"18","data":{"error":{"message":"Invalid confirmations 0"}}}`,
+		},
 	}
 
 	// send all requests at once
@@ -1025,4 +1112,35 @@ func Test_PublicServer_BitcoinType(t *testing.T) {
 	httpTestsBitcoinType(t, ts)
 	socketioTestsBitcoinType(t, ts)
 	websocketTestsBitcoinType(t, ts)
+	openAPITestsBitcoinType(t, s, ts)
+	grpcTestsBitcoinType(t, s)
+	webhooksTestsBitcoinType(t, s)
+	psbtTestsBitcoinType(t, s)
+}
+
+func Test_getNameParam(t *testing.T) {
+	var got string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/name/", func(w http.ResponseWriter, r *http.Request) {
+		got = getNameParam(r)
+	})
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "/api/v2/name/d/example", want: "d/example"},
+		{url: "/api/v2/name/?name=" + url.QueryEscape("d//example"), want: "d//example"},
+		{url: "/api/v2/name/?name=" + url.QueryEscape("d/./x/../y"), want: "d/./x/../y"},
+	}
+	for _, tt := range tests {
+		got = ""
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if rr.Code != http.StatusOK {
+			t.Errorf("%v: status %v, want %v", tt.url, rr.Code, http.StatusOK)
+		}
+		if got != tt.want {
+			t.Errorf("getNameParam(%v) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
 }
@@ -30,10 +30,11 @@ type SocketIoServer struct {
 	metrics     *common.Metrics
 	is          *common.InternalState
 	api         *api.Worker
+	limiter     *accessLimiter
 }
 
 // NewSocketIoServer creates new SocketIo interface to blockbook and returns its handle
-func NewSocketIoServer(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState) (*SocketIoServer, error) {
+func NewSocketIoServer(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState, limiter *accessLimiter) (*SocketIoServer, error) {
 	api, err := api.NewWorker(db, chain, mempool, txCache, is)
 	if err != nil {
 		return nil, err
@@ -69,6 +70,7 @@ func NewSocketIoServer(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.M
 		metrics:     metrics,
 		is:          is,
 		api:         api,
+		limiter:     limiter,
 	}
 
 	server.On("message", s.onMessage)
@@ -155,6 +157,7 @@ var onMessageHandlers = map[string]func(*SocketIoServer, json.RawMessage) (inter
 type resultError struct {
 	Error struct {
 		Message string `json:"message"`
+		Code    string `json:"code,omitempty"`
 	} `json:"error"`
 }
 
@@ -174,10 +177,13 @@ func (s *SocketIoServer) onMessage(c *gosocketio.Channel, req map[string]json.Ra
 	params := req["params"]
 	defer s.metrics.SocketIOReqDuration.With(common.Labels{"method": method}).Observe(float64(time.Since(t)) / 1e3) // in microseconds
 	f, ok := onMessageHandlers[method]
-	if ok {
-		rv, err = f(s, params)
-	} else {
+	if !ok {
 		err = errors.New("unknown method")
+	} else if !s.limiter.take(s.limiter.socketioClient(c.RequestHeader().Get("X-API-Key"), c.Ip()), s.limiter.socketioCost(method)) {
+		s.limiter.reject("socketio", accessErrorRateLimited)
+		err = errRateLimited
+	} else {
+		rv, err = f(s, params)
 	}
 	if err == nil {
 		glog.V(1).Info(c.Id(), " onMessage ", method, " success")
@@ -188,6 +194,9 @@ func (s *SocketIoServer) onMessage(c *gosocketio.Channel, req map[string]json.Ra
 	s.metrics.SocketIORequests.With(common.Labels{"method": method, "status": "failure"}).Inc()
 	e := resultError{}
 	e.Error.Message = err.Error()
+	if apiErr, ok := err.(*api.APIError); ok {
+		e.Error.Code = apiErr.Code
+	}
 	return e
 }
 
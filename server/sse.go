@@ -0,0 +1,528 @@
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/bchain"
+	"blockbook/common"
+	"blockbook/db"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// sseRingSize is the number of the recent events kept for the resume of the interrupted streams
+	sseRingSize = 1000
+	// sseKeepAlive is the interval of the comments sent to keep the idle streams open through the proxies
+	sseKeepAlive = 30 * time.Second
+	// sseRetry is the reconnection time in milliseconds advised to the clients
+	sseRetry = 5000
+	// ssePollTimeout is the maximum time the long-poll request waits for the events
+	ssePollTimeout = 60 * time.Second
+	// sseResumeWindow is the time after the disconnect of the last client during which the events of its addresses
+	// and of the mempool are still recorded, so that the client can resume the stream
+	sseResumeWindow = 5 * time.Minute
+	// sseRecentTxids is the number of the recently notified transactions remembered to send each transaction
+	// to the mempool feed only once, the notifications of the addresses of a transaction may come from concurrent goroutines
+	sseRecentTxids = 10000
+)
+
+// the feeds of the events
+const (
+	sseFeedBlocks    = "blocks"
+	sseFeedAddresses = "addresses"
+	sseFeedMempool   = "mempool"
+)
+
+// the types of the events
+const (
+	sseEventBlock   = "block"
+	sseEventReorg   = "reorg"
+	sseEventAddress = "address"
+	sseEventMempool = "mempool"
+)
+
+type sseEvent struct {
+	id uint64
+	// eventID is the id sent to the clients, the id prefixed by the epoch of the ring
+	eventID  string
+	event    string
+	addrDesc string
+	data     json.RawMessage
+}
+
+// feed returns the feed of the event
+func (e *sseEvent) feed() string {
+	switch e.event {
+	case sseEventAddress:
+		return sseFeedAddresses
+	case sseEventMempool:
+		return sseFeedMempool
+	}
+	return sseFeedBlocks
+}
+
+// sseRing keeps the most recent events, the event with id is stored at the index id%len(events)
+// the ids restart with each start of the process, the epoch in the event ids distinguishes them
+type sseRing struct {
+	events []*sseEvent
+	lastID uint64
+	epoch  string
+}
+
+func newSSERing(size int) *sseRing {
+	return &sseRing{
+		events: make([]*sseEvent, size),
+		epoch:  strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+}
+
+// add assigns the next id to the event and stores it, overwriting the oldest event
+func (r *sseRing) add(e *sseEvent) {
+	r.lastID++
+	e.id = r.lastID
+	e.eventID = r.formatID(e.id)
+	r.events[e.id%uint64(len(r.events))] = e
+}
+
+func (r *sseRing) formatID(id uint64) string {
+	return r.epoch + "-" + strconv.FormatUint(id, 10)
+}
+
+// parseID returns the id of the event from the event id sent to the client, false if the event id is not of this ring,
+// e.g. from before a restart
+func (r *sseRing) parseID(eventID string) (uint64, bool) {
+	i := strings.LastIndexByte(eventID, '-')
+	if i < 0 || eventID[:i] != r.epoch {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(eventID[i+1:], 10, 64)
+	if err != nil || id > r.lastID {
+		return 0, false
+	}
+	return id, true
+}
+
+// since returns the kept events with id greater than lastID
+func (r *sseRing) since(lastID uint64) []*sseEvent {
+	if lastID >= r.lastID {
+		return nil
+	}
+	first := lastID + 1
+	if size := uint64(len(r.events)); r.lastID >= size && first <= r.lastID-size {
+		first = r.lastID - size + 1
+	}
+	rv := make([]*sseEvent, 0, r.lastID-first+1)
+	for id := first; id <= r.lastID; id++ {
+		rv = append(rv, r.events[id%uint64(len(r.events))])
+	}
+	return rv
+}
+
+// sseRecentTxs is a set of the most recent txids, the oldest txid is forgotten when the set is full
+type sseRecentTxs struct {
+	txids map[string]struct{}
+	fifo  []string
+	next  int
+}
+
+func newSSERecentTxs(size int) *sseRecentTxs {
+	return &sseRecentTxs{
+		txids: make(map[string]struct{}, size),
+		fifo:  make([]string, size),
+	}
+}
+
+// add adds the txid to the set, returns false if the txid is already in the set
+func (r *sseRecentTxs) add(txid string) bool {
+	if _, found := r.txids[txid]; found {
+		return false
+	}
+	if old := r.fifo[r.next]; old != "" {
+		delete(r.txids, old)
+	}
+	r.fifo[r.next] = txid
+	r.next = (r.next + 1) % len(r.fifo)
+	r.txids[txid] = struct{}{}
+	return true
+}
+
+type sseClient struct {
+	feed      string
+	addrDescs map[string]struct{}
+	out       chan *sseEvent
+}
+
+// matches returns true if the event belongs to the subscription of the client
+func (c *sseClient) matches(e *sseEvent) bool {
+	if e.feed() != c.feed {
+		return false
+	}
+	if c.feed == sseFeedAddresses {
+		_, ok := c.addrDescs[e.addrDesc]
+		return ok
+	}
+	return true
+}
+
+// sseSubscription counts the clients subscribed to an address or to the mempool
+type sseSubscription struct {
+	clients  int
+	released time.Time
+}
+
+func (s *sseSubscription) active(now time.Time) bool {
+	return s.clients > 0 || now.Sub(s.released) < sseResumeWindow
+}
+
+// sseServer provides the subscription feeds of the websocket interface as Server-Sent Events streams
+// and as long-poll http requests
+type sseServer struct {
+	api         *api.Worker
+	chainParser bchain.BlockChainParser
+	metrics     *common.Metrics
+	limiter     *accessLimiter
+	lock        sync.Mutex
+	ring        *sseRing
+	clients     map[*sseClient]struct{}
+	addrDescs   map[string]*sseSubscription
+	mempool     sseSubscription
+	recentTxs   *sseRecentTxs
+}
+
+func newSSEServer(api *api.Worker, chainParser bchain.BlockChainParser, metrics *common.Metrics, limiter *accessLimiter) *sseServer {
+	return &sseServer{
+		api:         api,
+		chainParser: chainParser,
+		metrics:     metrics,
+		limiter:     limiter,
+		ring:        newSSERing(sseRingSize),
+		clients:     make(map[*sseClient]struct{}),
+		addrDescs:   make(map[string]*sseSubscription),
+		recentTxs:   newSSERecentTxs(sseRecentTxids),
+	}
+}
+
+// subscribe registers the client and returns the events after lastID which the client missed and the id of the last recorded event
+func (s *sseServer) subscribe(c *sseClient, lastID uint64) ([]*sseEvent, uint64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.clients[c] = struct{}{}
+	switch c.feed {
+	case sseFeedAddresses:
+		for ad := range c.addrDescs {
+			as, ok := s.addrDescs[ad]
+			if !ok {
+				as = &sseSubscription{}
+				s.addrDescs[ad] = as
+			}
+			as.clients++
+		}
+	case sseFeedMempool:
+		s.mempool.clients++
+	}
+	s.metrics.SSEClients.Inc()
+	var missed []*sseEvent
+	for _, e := range s.ring.since(lastID) {
+		if c.matches(e) {
+			missed = append(missed, e)
+		}
+	}
+	return missed, s.ring.lastID
+}
+
+func (s *sseServer) unsubscribe(c *sseClient) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.unsubscribeLocked(c)
+}
+
+func (s *sseServer) unsubscribeLocked(c *sseClient) {
+	if _, ok := s.clients[c]; !ok {
+		return
+	}
+	delete(s.clients, c)
+	now := time.Now()
+	switch c.feed {
+	case sseFeedAddresses:
+		for ad := range c.addrDescs {
+			if as, ok := s.addrDescs[ad]; ok {
+				as.clients--
+				as.released = now
+			}
+		}
+		// drop the addresses released before the resume window
+		for ad, as := range s.addrDescs {
+			if !as.active(now) {
+				delete(s.addrDescs, ad)
+			}
+		}
+	case sseFeedMempool:
+		s.mempool.clients--
+		s.mempool.released = now
+	}
+	s.metrics.SSEClients.Dec()
+}
+
+// broadcast stores the event and sends it to the subscribed clients, the clients which do not keep up are disconnected
+func (s *sseServer) broadcast(e *sseEvent) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.ring.add(e)
+	for c := range s.clients {
+		if c.matches(e) {
+			select {
+			case c.out <- e:
+			default:
+				glog.Warning("sse client too slow, disconnecting")
+				s.unsubscribeLocked(c)
+				close(c.out)
+			}
+		}
+	}
+}
+
+func (s *sseServer) broadcastJSON(event string, addrDesc string, data interface{}) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		glog.Error("sse ", event, " marshal error ", err)
+		return
+	}
+	s.broadcast(&sseEvent{event: event, addrDesc: addrDesc, data: b})
+}
+
+// OnNewBlock records and sends the new block to the clients of the blocks feed
+func (s *sseServer) OnNewBlock(hash string, height uint32) {
+	s.broadcastJSON(sseEventBlock, "", struct {
+		Height uint32 `json:"height"`
+		Hash   string `json:"hash"`
+	}{height, hash})
+}
+
+// OnReorg records and sends the blocks disconnected by a reorg to the clients of the blocks feed
+func (s *sseServer) OnReorg(blocks []*db.OrphanedBlock) {
+	rbs := make([]reorgBlock, len(blocks))
+	for i, b := range blocks {
+		rbs[i] = reorgBlock{Height: b.Height, Hash: b.Hash, Txs: b.Txids}
+	}
+	s.broadcastJSON(sseEventReorg, "", &reorgNotification{Reorg: true, Blocks: rbs})
+}
+
+// OnNewTxAddr records and sends the new transaction to the clients of its address and, once per transaction, to the clients of the mempool feed
+func (s *sseServer) OnNewTxAddr(tx *bchain.Tx, addrDesc bchain.AddressDescriptor) {
+	now := time.Now()
+	s.lock.Lock()
+	as, addrActive := s.addrDescs[string(addrDesc)]
+	addrActive = addrActive && as.active(now)
+	// the transaction is notified for each its address, only the first notification is sent to the mempool feed
+	mempoolActive := s.recentTxs.add(tx.Txid) && s.mempool.active(now)
+	s.lock.Unlock()
+	if mempoolActive {
+		s.broadcastJSON(sseEventMempool, "", struct {
+			Txid string `json:"txid"`
+		}{tx.Txid})
+	}
+	if addrActive {
+		addr, _, err := s.chainParser.GetAddressesFromAddrDesc(addrDesc)
+		if err != nil || len(addr) != 1 {
+			return
+		}
+		atx, err := s.api.GetTransactionFromBchainTx(tx, 0, false, false)
+		if err != nil {
+			glog.Error("GetTransactionFromBchainTx error ", err, " for ", tx.Txid)
+			return
+		}
+		s.broadcastJSON(sseEventAddress, string(addrDesc), struct {
+			Address string  `json:"address"`
+			Tx      *api.Tx `json:"tx"`
+		}{addr[0], atx})
+	}
+}
+
+// writeSSEEvent writes the event in the text/event-stream format
+func writeSSEEvent(w http.ResponseWriter, e *sseEvent) error {
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.eventID, e.event, e.data)
+	return err
+}
+
+// parseSSEClient returns the client of the feed from the path of the request api/v2/events/<feed>
+func (s *sseServer) parseSSEClient(r *http.Request) (*sseClient, error) {
+	c := &sseClient{out: make(chan *sseEvent, outChannelSize)}
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i >= 0 {
+		c.feed = r.URL.Path[i+1:]
+	}
+	switch c.feed {
+	case sseFeedBlocks, sseFeedMempool:
+	case sseFeedAddresses:
+		var addresses []string
+		if a := r.URL.Query().Get("addresses"); a != "" {
+			addresses = strings.Split(a, ",")
+		}
+		if len(addresses) == 0 {
+			return nil, api.NewAPIError("Missing addresses", true)
+		}
+		if max := s.limiter.config.MaxSubscribedAddresses; max > 0 && len(addresses) > max {
+			s.limiter.reject("sse", accessErrorSubscriptionLimit)
+			return nil, api.NewAPIErrorWithCode(fmt.Sprintf("Too many subscribed addresses, the limit is %d", max), accessErrorSubscriptionLimit)
+		}
+		c.addrDescs = make(map[string]struct{}, len(addresses))
+		for _, a := range addresses {
+			ad, err := s.chainParser.GetAddrDescFromAddress(a)
+			if err != nil {
+				return nil, api.NewAPIError("Invalid address "+a+", "+err.Error(), true)
+			}
+			c.addrDescs[string(ad)] = struct{}{}
+		}
+	default:
+		return nil, api.NewAPIError("Unknown feed "+c.feed, true)
+	}
+	return c, nil
+}
+
+// lastEventID returns the event id of the last event received by the client from the header Last-Event-ID
+// or from the parameter lastEventId, which can be used by the long-poll requests and by the EventSource without the header support
+func lastEventID(r *http.Request) string {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("lastEventId")
+	}
+	return v
+}
+
+func writeSSEError(w http.ResponseWriter, err error) {
+	if apiErr, ok := err.(*api.APIError); ok {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(struct {
+			Text string `json:"error"`
+			Code string `json:"code,omitempty"`
+		}{apiErr.Text, apiErr.Code})
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// ServeHTTP streams the events of the feed, or with the parameter poll returns the events in json as a long-poll request
+func (s *sseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, ErrorMethodNotAllowed.Error(), http.StatusMethodNotAllowed)
+		return
+	}
+	c, err := s.parseSSEClient(r)
+	if err != nil {
+		writeSSEError(w, err)
+		return
+	}
+	s.lock.Lock()
+	lastID, resume := s.ring.parseID(lastEventID(r))
+	s.lock.Unlock()
+	if _, poll := r.URL.Query()["poll"]; poll {
+		s.metrics.ExplorerViews.With(common.Labels{"action": "api-events-poll"}).Inc()
+		s.poll(w, r, c, lastID, resume)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-events"}).Inc()
+	if !resume {
+		lastID = ^uint64(0)
+	}
+	missed, _ := s.subscribe(c, lastID)
+	defer s.unsubscribe(c)
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	// disable the response buffering of nginx
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry)
+	for _, e := range missed {
+		if err := writeSSEEvent(w, e); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case e, ok := <-c.out:
+			if !ok {
+				return
+			}
+			if err := writeSSEEvent(w, e); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+type ssePollEvent struct {
+	ID    string          `json:"id"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+type ssePollResult struct {
+	LastEventID string         `json:"lastEventId"`
+	Events      []ssePollEvent `json:"events"`
+}
+
+// poll returns the events after lastID or waits for the next events; without lastID only the new events are returned
+func (s *sseServer) poll(w http.ResponseWriter, r *http.Request, c *sseClient, lastID uint64, resume bool) {
+	timeout := ssePollTimeout
+	if t, err := strconv.Atoi(r.URL.Query().Get("timeout")); err == nil && t > 0 && time.Duration(t)*time.Second < timeout {
+		timeout = time.Duration(t) * time.Second
+	}
+	if !resume {
+		lastID = ^uint64(0)
+	}
+	events, ringID := s.subscribe(c, lastID)
+	if len(events) == 0 {
+		select {
+		case e, ok := <-c.out:
+			if ok {
+				events = append(events, e)
+			}
+		case <-time.After(timeout):
+		case <-r.Context().Done():
+		}
+	}
+	s.unsubscribe(c)
+	// collect the events which came in the meantime
+	for len(c.out) > 0 {
+		if e, ok := <-c.out; ok {
+			events = append(events, e)
+		}
+	}
+	rv := ssePollResult{Events: make([]ssePollEvent, len(events))}
+	for i, e := range events {
+		rv.Events[i] = ssePollEvent{ID: e.eventID, Event: e.event, Data: e.data}
+	}
+	if len(events) > 0 {
+		rv.LastEventID = rv.Events[len(events)-1].ID
+	} else {
+		// the events recorded before the subscription did not belong to it
+		rv.LastEventID = s.ring.formatID(ringID)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	json.NewEncoder(w).Encode(&rv)
+}
@@ -0,0 +1,156 @@
+// +build unittest
+
+package server
+
+import (
+	"blockbook/bchain"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func ringIDs(events []*sseEvent) []uint64 {
+	ids := []uint64{}
+	for _, e := range events {
+		ids = append(ids, e.id)
+	}
+	return ids
+}
+
+func Test_sseRing_since(t *testing.T) {
+	r := newSSERing(4)
+	if got := r.since(0); got != nil {
+		t.Errorf("since() of empty ring = %v, want nil", got)
+	}
+	for i := 0; i < 3; i++ {
+		r.add(&sseEvent{event: sseEventBlock})
+	}
+	tests := []struct {
+		name   string
+		lastID uint64
+		want   []uint64
+	}{
+		{"all", 0, []uint64{1, 2, 3}},
+		{"missed", 1, []uint64{2, 3}},
+		{"up to date", 3, []uint64{}},
+		{"unknown", 10, []uint64{}},
+	}
+	for _, tt := range tests {
+		if got := ringIDs(r.since(tt.lastID)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: since(%d) = %v, want %v", tt.name, tt.lastID, got, tt.want)
+		}
+	}
+	// overwrite the oldest events, only the last 4 are kept
+	for i := 0; i < 3; i++ {
+		r.add(&sseEvent{event: sseEventBlock})
+	}
+	if got, want := ringIDs(r.since(0)), []uint64{3, 4, 5, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("since(0) after overwrite = %v, want %v", got, want)
+	}
+	if got, want := ringIDs(r.since(4)), []uint64{5, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("since(4) after overwrite = %v, want %v", got, want)
+	}
+}
+
+func Test_sseRing_parseID(t *testing.T) {
+	r := newSSERing(4)
+	r.epoch = "restarted"
+	for i := 0; i < 5; i++ {
+		r.add(&sseEvent{event: sseEventBlock})
+	}
+	if got, want := r.events[5%4].eventID, "restarted-5"; got != want {
+		t.Errorf("eventID = %v, want %v", got, want)
+	}
+	tests := []struct {
+		name    string
+		eventID string
+		want    uint64
+		resume  bool
+	}{
+		{"same epoch", "restarted-2", 2, true},
+		{"last", "restarted-5", 5, true},
+		// the id from before the restart is lower than the last id of the ring, it must not replay the new events
+		{"before restart", "started-2", 0, false},
+		{"number only", "2", 0, false},
+		{"future", "restarted-6", 0, false},
+		{"invalid", "restarted-x", 0, false},
+		{"empty", "", 0, false},
+	}
+	for _, tt := range tests {
+		if got, resume := r.parseID(tt.eventID); got != tt.want || resume != tt.resume {
+			t.Errorf("%s: parseID(%q) = %v, %v, want %v, %v", tt.name, tt.eventID, got, resume, tt.want, tt.resume)
+		}
+	}
+}
+
+func Test_sseClient_matches(t *testing.T) {
+	c := &sseClient{feed: sseFeedAddresses, addrDescs: map[string]struct{}{"a": {}}}
+	tests := []struct {
+		e    *sseEvent
+		want bool
+	}{
+		{&sseEvent{event: sseEventAddress, addrDesc: "a"}, true},
+		{&sseEvent{event: sseEventAddress, addrDesc: "b"}, false},
+		{&sseEvent{event: sseEventBlock}, false},
+		{&sseEvent{event: sseEventMempool}, false},
+	}
+	for _, tt := range tests {
+		if got := c.matches(tt.e); got != tt.want {
+			t.Errorf("matches(%v %q) = %v, want %v", tt.e.event, tt.e.addrDesc, got, tt.want)
+		}
+	}
+	c = &sseClient{feed: sseFeedBlocks}
+	if !c.matches(&sseEvent{event: sseEventReorg}) {
+		t.Error("blocks feed does not match reorg event")
+	}
+}
+
+func Test_writeSSEEvent(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := writeSSEEvent(w, &sseEvent{id: 7, eventID: "e-7", event: sseEventBlock, data: []byte(`{"height":1,"hash":"00"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	want := "id: e-7\nevent: block\ndata: {\"height\":1,\"hash\":\"00\"}\n\n"
+	if got := w.Body.String(); got != want {
+		t.Errorf("writeSSEEvent() = %q, want %q", got, want)
+	}
+}
+
+func Test_sseServer_OnNewTxAddr(t *testing.T) {
+	s := newSSEServer(nil, nil, nil, nil)
+	s.recentTxs = newSSERecentTxs(2)
+	s.mempool.clients = 1
+	tx1 := &bchain.Tx{Txid: "tx1"}
+	tx2 := &bchain.Tx{Txid: "tx2"}
+	tx3 := &bchain.Tx{Txid: "tx3"}
+	// the addresses of the transactions are notified interleaved by concurrent goroutines
+	for _, n := range []struct {
+		tx       *bchain.Tx
+		addrDesc bchain.AddressDescriptor
+	}{
+		{tx1, bchain.AddressDescriptor{1}},
+		{tx2, bchain.AddressDescriptor{1}},
+		{tx1, bchain.AddressDescriptor{2}},
+		{tx2, bchain.AddressDescriptor{3}},
+		{tx1, bchain.AddressDescriptor{3}},
+		// tx1 is forgotten after two more transactions
+		{tx3, bchain.AddressDescriptor{1}},
+		{tx1, bchain.AddressDescriptor{4}},
+	} {
+		s.OnNewTxAddr(n.tx, n.addrDesc)
+	}
+	var txids []string
+	for _, e := range s.ring.since(0) {
+		var d struct {
+			Txid string `json:"txid"`
+		}
+		if err := json.Unmarshal(e.data, &d); err != nil || e.event != sseEventMempool {
+			t.Fatalf("event %v %s, %v", e.event, e.data, err)
+		}
+		txids = append(txids, d.Txid)
+	}
+	if want := []string{"tx1", "tx2", "tx3", "tx1"}; !reflect.DeepEqual(txids, want) {
+		t.Errorf("mempool events = %v, want %v", txids, want)
+	}
+}
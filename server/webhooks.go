@@ -0,0 +1,828 @@
+package server
+
+import (
+	"blockbook/api"
+	"blockbook/bchain"
+	"blockbook/common"
+	"blockbook/db"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// webhook events
+const (
+	webhookEventTx            = "tx"
+	webhookEventConfirmations = "confirmations"
+)
+
+const (
+	webhookWorkers          = 4
+	webhookQueueSize        = 10000
+	webhookTimeout          = 10 * time.Second
+	webhookMaxAttempts      = 10
+	webhookRetryBase        = 10 * time.Second
+	webhookRetryMax         = time.Hour
+	webhookDeliveryLogSize  = 1000
+	webhookTxExpiration     = 24 * time.Hour
+	webhookMaxConfirmations = 1000
+	webhookMaxAddresses     = 10000
+	webhookMaxResponseSize  = 64 * 1024
+	webhookRequeueInterval  = time.Minute
+	webhookMaxScannedBlocks = 100
+)
+
+// WebhookRequest is the registration of the webhook sent to the internal server
+type WebhookRequest struct {
+	URL           string   `json:"url"`
+	Secret        string   `json:"secret"`
+	Addresses     []string `json:"addresses"`
+	Xpubs         []string `json:"xpubs"`
+	Confirmations []int    `json:"confirmations"`
+}
+
+// webhookPayload is the body of the POST request sent to the webhook url
+type webhookPayload struct {
+	ID            uint64  `json:"id"`
+	Webhook       string  `json:"webhook"`
+	Event         string  `json:"event"`
+	Txid          string  `json:"txid"`
+	Address       string  `json:"address,omitempty"`
+	Confirmations int     `json:"confirmations,omitempty"`
+	Time          int64   `json:"time"`
+	Tx            *api.Tx `json:"tx,omitempty"`
+}
+
+type webhookJob struct {
+	webhookID string
+	delivery  *db.WebhookDelivery
+}
+
+// webhookNotifier posts the notifications about the transactions of the registered addresses and xpubs to the webhooks
+type webhookNotifier struct {
+	db          *db.RocksDB
+	api         *api.Worker
+	chainParser bchain.BlockChainParser
+	metrics     *common.Metrics
+	client      *http.Client
+	retryDelay  func(attempts int) time.Duration
+	queue       chan *webhookJob
+	blocks      chan struct{}
+	done        chan struct{}
+	wg          sync.WaitGroup
+	mux         sync.Mutex
+	webhooks    map[string]*db.Webhook
+	// addrDescs maps the address descriptors to the ids of the webhooks and the address or xpub which registered them
+	addrDescs map[string]map[string]string
+	// webhookAddrDescs are the address descriptors registered by the webhooks
+	webhookAddrDescs map[string][]string
+	// xpubs caches the address descriptors derived from the registered xpubs
+	xpubs map[string][]string
+	// scheduled are the ids of the deliveries waiting in the queue or for the retry and of the deliveries being delivered
+	scheduled map[uint64]struct{}
+	// dropped is set when a delivery did not fit to the queue, the undelivered deliveries are then queued again from the db
+	dropped bool
+	lastID  uint64
+	txMux   sync.Mutex
+	// lastHeight and lastHash are the last block scanned for the transactions not seen in the mempool, used only by blockLoop
+	lastHeight uint32
+	lastHash   string
+}
+
+// newWebhookNotifier loads the registered webhooks and starts the delivery of the notifications which were not yet delivered
+func newWebhookNotifier(d *db.RocksDB, w *api.Worker, parser bchain.BlockChainParser, metrics *common.Metrics) (*webhookNotifier, error) {
+	n := &webhookNotifier{
+		db:               d,
+		api:              w,
+		chainParser:      parser,
+		metrics:          metrics,
+		client:           &http.Client{Timeout: webhookTimeout},
+		retryDelay:       webhookRetryDelay,
+		queue:            make(chan *webhookJob, webhookQueueSize),
+		blocks:           make(chan struct{}, 1),
+		done:             make(chan struct{}),
+		webhooks:         make(map[string]*db.Webhook),
+		addrDescs:        make(map[string]map[string]string),
+		webhookAddrDescs: make(map[string][]string),
+		xpubs:            make(map[string][]string),
+		scheduled:        make(map[uint64]struct{}),
+	}
+	ws, err := d.GetWebhooks()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetWebhooks")
+	}
+	if n.lastHeight, n.lastHash, err = d.GetBestBlock(); err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	for _, wh := range ws {
+		n.webhooks[wh.ID] = wh
+		ads, err := n.addrDescsOf(wh, nil)
+		if err != nil {
+			glog.Error("webhook ", wh.ID, ": ", err)
+		}
+		n.index(wh.ID, ads)
+	}
+	for i := 0; i < webhookWorkers; i++ {
+		n.wg.Add(1)
+		go n.deliverLoop()
+	}
+	n.wg.Add(1)
+	go n.blockLoop()
+	for _, wh := range ws {
+		if err = n.requeue(wh.ID); err != nil {
+			return nil, err
+		}
+	}
+	glog.Info("webhooks: loaded ", len(ws), " webhooks")
+	return n, nil
+}
+
+// requeue schedules the undelivered deliveries of the webhook from the db, which are not already scheduled
+func (n *webhookNotifier) requeue(id string) error {
+	ds, err := n.db.GetWebhookDeliveries(id)
+	if err != nil {
+		return errors.Annotatef(err, "GetWebhookDeliveries %v", id)
+	}
+	for _, r := range ds {
+		if !r.Delivered && !r.Failed {
+			n.schedule(&webhookJob{webhookID: id, delivery: r}, time.Until(r.NextAttempt))
+		}
+	}
+	return nil
+}
+
+// Close stops the delivery, the notifications not yet delivered are delivered after the restart
+func (n *webhookNotifier) Close() {
+	close(n.done)
+	n.wg.Wait()
+}
+
+func randomHex(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// xpubAddrDescs returns the address descriptors of the addresses derived from the xpub, from the cache unless rescan is set
+// the derivation scans the whole gap of the xpub, it is repeated only when a transaction of the xpub is confirmed
+func (n *webhookNotifier) xpubAddrDescs(xpub string, rescan bool) ([]string, error) {
+	if !rescan {
+		n.mux.Lock()
+		ads, found := n.xpubs[xpub]
+		n.mux.Unlock()
+		if found {
+			return ads, nil
+		}
+	}
+	a, err := n.api.GetXpubAddress(xpub, 0, 1, api.AccountDetailsTokens, &api.AddressFilter{Vout: api.AddressFilterVoutOff, TokensToReturn: api.TokensToReturnDerived}, 0)
+	if err != nil {
+		return nil, err
+	}
+	ads := make([]string, 0, len(a.Tokens))
+	for i := range a.Tokens {
+		ad, err := n.chainParser.GetAddrDescFromAddress(a.Tokens[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		ads = append(ads, string(ad))
+	}
+	n.mux.Lock()
+	n.xpubs[xpub] = ads
+	n.mux.Unlock()
+	return ads, nil
+}
+
+// addrDescsOf returns the address descriptors registered by the webhook, mapped to the address or xpub
+// the addresses of the xpubs in rescan are derived again, the others are taken from the cache
+func (n *webhookNotifier) addrDescsOf(wh *db.Webhook, rescan map[string]struct{}) (map[string]string, error) {
+	ads := make(map[string]string)
+	for _, a := range wh.Addresses {
+		ad, err := n.chainParser.GetAddrDescFromAddress(a)
+		if err != nil {
+			return nil, api.NewAPIError("Invalid address "+a+", "+err.Error(), true)
+		}
+		ads[string(ad)] = a
+	}
+	for _, xpub := range wh.Xpubs {
+		_, r := rescan[xpub]
+		xads, err := n.xpubAddrDescs(xpub, r)
+		if err != nil {
+			if err == api.ErrUnsupportedXpub {
+				return nil, api.NewAPIError("Invalid xpub "+xpub, true)
+			}
+			return nil, err
+		}
+		for _, ad := range xads {
+			if _, found := ads[ad]; !found {
+				ads[ad] = xpub
+			}
+		}
+	}
+	return ads, nil
+}
+
+// index replaces the address descriptors registered by the webhook
+func (n *webhookNotifier) index(id string, ads map[string]string) {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	n.unindexLocked(id)
+	list := make([]string, 0, len(ads))
+	for ad, a := range ads {
+		m, found := n.addrDescs[ad]
+		if !found {
+			m = make(map[string]string)
+			n.addrDescs[ad] = m
+		}
+		m[id] = a
+		list = append(list, ad)
+	}
+	n.webhookAddrDescs[id] = list
+}
+
+func (n *webhookNotifier) unindexLocked(id string) {
+	for _, ad := range n.webhookAddrDescs[id] {
+		m := n.addrDescs[ad]
+		delete(m, id)
+		if len(m) == 0 {
+			delete(n.addrDescs, ad)
+		}
+	}
+	delete(n.webhookAddrDescs, id)
+}
+
+// Register validates and stores the webhook, the secret is generated if it is not specified
+func (n *webhookNotifier) Register(req *WebhookRequest) (*db.Webhook, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, api.NewAPIError("Invalid url", true)
+	}
+	if len(req.Addresses) == 0 && len(req.Xpubs) == 0 {
+		return nil, api.NewAPIError("Missing addresses or xpubs", true)
+	}
+	if len(req.Addresses)+len(req.Xpubs) > webhookMaxAddresses {
+		return nil, api.NewAPIError("Too many addresses, the limit is "+strconv.Itoa(webhookMaxAddresses), true)
+	}
+	confirmations := make([]int, 0, len(req.Confirmations))
+	for _, c := range req.Confirmations {
+		if c < 1 || c > webhookMaxConfirmations {
+			return nil, api.NewAPIError("Invalid confirmations "+strconv.Itoa(c), true)
+		}
+		if !containsInt(confirmations, c) {
+			confirmations = append(confirmations, c)
+		}
+	}
+	sort.Ints(confirmations)
+	wh := &db.Webhook{
+		URL:           req.URL,
+		Secret:        req.Secret,
+		Addresses:     req.Addresses,
+		Xpubs:         req.Xpubs,
+		Confirmations: confirmations,
+		Created:       time.Now().UTC(),
+	}
+	ads, err := n.addrDescsOf(wh, nil)
+	if err != nil {
+		return nil, err
+	}
+	if wh.ID, err = randomHex(16); err != nil {
+		return nil, err
+	}
+	if wh.Secret == "" {
+		if wh.Secret, err = randomHex(32); err != nil {
+			return nil, err
+		}
+	}
+	if err = n.db.StoreWebhook(wh); err != nil {
+		return nil, err
+	}
+	n.mux.Lock()
+	n.webhooks[wh.ID] = wh
+	n.mux.Unlock()
+	n.index(wh.ID, ads)
+	glog.Info("webhooks: registered ", wh.ID, " ", wh.URL, ", ", len(ads), " addresses")
+	return wh, nil
+}
+
+func containsInt(a []int, v int) bool {
+	for _, i := range a {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(a []string, v string) bool {
+	for _, s := range a {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Unregister deletes the webhook, its delivery log and stops its notifications
+func (n *webhookNotifier) Unregister(id string) error {
+	n.mux.Lock()
+	wh, found := n.webhooks[id]
+	delete(n.webhooks, id)
+	n.unindexLocked(id)
+	if found {
+		n.uncacheXpubsLocked(wh.Xpubs)
+	}
+	n.mux.Unlock()
+	if !found {
+		return api.NewAPIErrorWithCode("Webhook not found", errorCodeNotFound)
+	}
+	glog.Info("webhooks: unregistered ", id)
+	return n.db.DeleteWebhook(id)
+}
+
+// uncacheXpubsLocked removes the xpubs which are not registered by any webhook from the cache
+func (n *webhookNotifier) uncacheXpubsLocked(xpubs []string) {
+	for _, xpub := range xpubs {
+		used := false
+		for _, wh := range n.webhooks {
+			if containsString(wh.Xpubs, xpub) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			delete(n.xpubs, xpub)
+		}
+	}
+}
+
+// Webhooks returns the registered webhooks sorted by the time of registration
+func (n *webhookNotifier) Webhooks() []*db.Webhook {
+	n.mux.Lock()
+	ws := make([]*db.Webhook, 0, len(n.webhooks))
+	for _, wh := range n.webhooks {
+		ws = append(ws, wh)
+	}
+	n.mux.Unlock()
+	sort.Slice(ws, func(i, j int) bool { return ws[i].Created.Before(ws[j].Created) })
+	return ws
+}
+
+// Webhook returns the registered webhook
+func (n *webhookNotifier) Webhook(id string) (*db.Webhook, error) {
+	n.mux.Lock()
+	wh, found := n.webhooks[id]
+	n.mux.Unlock()
+	if !found {
+		return nil, api.NewAPIErrorWithCode("Webhook not found", errorCodeNotFound)
+	}
+	return wh, nil
+}
+
+// Deliveries returns at most limit newest records of the delivery log of the webhook, the newest first
+func (n *webhookNotifier) Deliveries(id string, limit int) ([]*db.WebhookDelivery, error) {
+	if _, err := n.Webhook(id); err != nil {
+		return nil, err
+	}
+	ds, err := n.db.GetWebhookDeliveries(id)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(ds) > limit {
+		ds = ds[len(ds)-limit:]
+	}
+	for i, j := 0, len(ds)-1; i < j; i, j = i+1, j-1 {
+		ds[i], ds[j] = ds[j], ds[i]
+	}
+	return ds, nil
+}
+
+// nextID returns unique increasing id of the delivery
+func (n *webhookNotifier) nextID() uint64 {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	id := uint64(time.Now().UnixNano())
+	if id <= n.lastID {
+		id = n.lastID + 1
+	}
+	n.lastID = id
+	return id
+}
+
+// enqueue stores the notification to the delivery log and schedules its delivery
+func (n *webhookNotifier) enqueue(webhookID, event, txid, address string, confirmations int, tx *api.Tx) error {
+	now := time.Now().UTC()
+	p := webhookPayload{
+		ID:            n.nextID(),
+		Webhook:       webhookID,
+		Event:         event,
+		Txid:          txid,
+		Address:       address,
+		Confirmations: confirmations,
+		Time:          now.Unix(),
+		Tx:            tx,
+	}
+	b, err := json.Marshal(&p)
+	if err != nil {
+		return errors.Annotatef(err, "Marshal")
+	}
+	r := &db.WebhookDelivery{
+		ID:            p.ID,
+		Event:         event,
+		Txid:          txid,
+		Address:       address,
+		Confirmations: confirmations,
+		Created:       now,
+		NextAttempt:   now,
+		Payload:       b,
+	}
+	if err = n.db.StoreWebhookDelivery(webhookID, r); err != nil {
+		return err
+	}
+	n.schedule(&webhookJob{webhookID: webhookID, delivery: r}, 0)
+	return nil
+}
+
+// schedule queues the job after the delay, the job is skipped if its delivery is already scheduled
+// the job is dropped if the queue is full; it stays in the delivery log and it is queued again by requeueDropped
+func (n *webhookNotifier) schedule(job *webhookJob, delay time.Duration) {
+	n.mux.Lock()
+	if _, found := n.scheduled[job.delivery.ID]; found {
+		n.mux.Unlock()
+		return
+	}
+	n.scheduled[job.delivery.ID] = struct{}{}
+	n.mux.Unlock()
+	push := func() {
+		select {
+		case <-n.done:
+		case n.queue <- job:
+		default:
+			glog.Error("webhooks: queue full, delivery ", job.delivery.ID, " of webhook ", job.webhookID, " postponed")
+			n.mux.Lock()
+			delete(n.scheduled, job.delivery.ID)
+			n.dropped = true
+			n.mux.Unlock()
+		}
+	}
+	if delay <= 0 {
+		push()
+	} else {
+		time.AfterFunc(delay, push)
+	}
+}
+
+func (n *webhookNotifier) unschedule(id uint64) {
+	n.mux.Lock()
+	delete(n.scheduled, id)
+	n.mux.Unlock()
+}
+
+// requeueDropped queues again the deliveries of all webhooks if some delivery was dropped because of the full queue
+func (n *webhookNotifier) requeueDropped() {
+	n.mux.Lock()
+	dropped := n.dropped
+	n.dropped = false
+	n.mux.Unlock()
+	if !dropped {
+		return
+	}
+	for _, wh := range n.Webhooks() {
+		if err := n.requeue(wh.ID); err != nil {
+			glog.Error("webhooks: ", err)
+		}
+	}
+}
+
+func (n *webhookNotifier) deliverLoop() {
+	defer n.wg.Done()
+	for {
+		select {
+		case <-n.done:
+			return
+		case job := <-n.queue:
+			n.deliver(job)
+		}
+	}
+}
+
+// signWebhookPayload returns the hex encoded HMAC-SHA256 of the payload
+func signWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryDelay returns the delay of the next attempt, doubled after each failed attempt
+func webhookRetryDelay(attempts int) time.Duration {
+	d := webhookRetryBase
+	for i := 1; i < attempts && d < webhookRetryMax; i++ {
+		d *= 2
+	}
+	if d > webhookRetryMax {
+		d = webhookRetryMax
+	}
+	return d
+}
+
+// post sends the payload to the webhook, returns the http status
+func (n *webhookNotifier) post(wh *db.Webhook, r *db.WebhookDelivery) (int, error) {
+	req, err := http.NewRequest("POST", wh.URL, bytes.NewReader(r.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Blockbook")
+	req.Header.Set("X-Blockbook-Event", r.Event)
+	req.Header.Set("X-Blockbook-Delivery", strconv.FormatUint(r.ID, 10))
+	req.Header.Set("X-Blockbook-Signature", "sha256="+signWebhookPayload(wh.Secret, r.Payload))
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, webhookMaxResponseSize))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.Errorf("status %v", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (n *webhookNotifier) deliver(job *webhookJob) {
+	n.mux.Lock()
+	wh, found := n.webhooks[job.webhookID]
+	n.mux.Unlock()
+	r := job.delivery
+	if !found {
+		n.unschedule(r.ID)
+		return
+	}
+	status, err := n.post(wh, r)
+	r.Attempts++
+	r.LastAttempt = time.Now().UTC()
+	r.Status = status
+	var result string
+	var retry time.Duration
+	if err == nil {
+		r.Delivered = true
+		r.Error = ""
+		r.NextAttempt = time.Time{}
+		result = "delivered"
+	} else {
+		r.Error = err.Error()
+		if r.Attempts >= webhookMaxAttempts {
+			r.Failed = true
+			r.NextAttempt = time.Time{}
+			result = "failed"
+			glog.Warning("webhooks: delivery ", r.ID, " to ", wh.URL, " failed after ", r.Attempts, " attempts: ", err)
+		} else {
+			retry = n.retryDelay(r.Attempts)
+			r.NextAttempt = r.LastAttempt.Add(retry)
+			result = "retry"
+		}
+	}
+	if n.metrics != nil {
+		n.metrics.WebhookDeliveries.With(common.Labels{"event": r.Event, "result": result}).Inc()
+	}
+	if err = n.db.StoreWebhookDelivery(job.webhookID, r); err != nil {
+		glog.Error("webhooks: StoreWebhookDelivery ", r.ID, ": ", err)
+	}
+	// the delivery is unscheduled only after it is stored, requeue must not read it from the db as undelivered
+	n.unschedule(r.ID)
+	if retry > 0 {
+		n.schedule(job, retry)
+	}
+}
+
+// OnNewTxAddr notifies the webhooks registered to the address about a new transaction, each transaction is notified only once
+func (n *webhookNotifier) OnNewTxAddr(tx *bchain.Tx, desc bchain.AddressDescriptor) {
+	n.notifyNewTx(tx.Txid, n.hooksOf(string(desc)), func() (*api.Tx, error) {
+		return n.api.GetTransactionFromBchainTx(tx, 0, false, false)
+	})
+}
+
+// hooksOf returns the ids of the webhooks registered to the address descriptor mapped to the address or xpub
+func (n *webhookNotifier) hooksOf(ad string) map[string]string {
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	m := n.addrDescs[ad]
+	hooks := make(map[string]string, len(m))
+	for id, a := range m {
+		hooks[id] = a
+	}
+	return hooks
+}
+
+// notifyNewTx notifies the webhooks about the transaction, if it was not notified to them yet
+// the transaction is converted by getTx outside of txMux, only the check and the store of the notification are done under it
+func (n *webhookNotifier) notifyNewTx(txid string, hooks map[string]string, getTx func() (*api.Tx, error)) {
+	if len(hooks) == 0 {
+		return
+	}
+	n.txMux.Lock()
+	hooks = n.unnotifiedLocked(txid, hooks)
+	n.txMux.Unlock()
+	if len(hooks) == 0 {
+		return
+	}
+	atx, err := getTx()
+	if err != nil {
+		glog.Error("webhooks: GetTransaction ", txid, ": ", err)
+		return
+	}
+	n.txMux.Lock()
+	defer n.txMux.Unlock()
+	// the transaction could be notified by a concurrent call during the conversion
+	for id, address := range n.unnotifiedLocked(txid, hooks) {
+		t := &db.WebhookTx{Txid: txid, Address: address, Seen: time.Now().UTC()}
+		if err = n.db.StoreWebhookTx(id, t); err != nil {
+			glog.Error("webhooks: StoreWebhookTx ", id, " ", txid, ": ", err)
+			continue
+		}
+		if err = n.enqueue(id, webhookEventTx, txid, address, 0, atx); err != nil {
+			glog.Error("webhooks: enqueue ", id, " ", txid, ": ", err)
+		}
+	}
+}
+
+// unnotifiedLocked returns the hooks to which the transaction was not notified yet, txMux must be locked
+func (n *webhookNotifier) unnotifiedLocked(txid string, hooks map[string]string) map[string]string {
+	r := make(map[string]string, len(hooks))
+	for id, address := range hooks {
+		t, err := n.db.GetWebhookTx(id, txid)
+		if err != nil {
+			glog.Error("webhooks: GetWebhookTx ", id, " ", txid, ": ", err)
+			continue
+		}
+		if t == nil {
+			r[id] = address
+		}
+	}
+	return r
+}
+
+// OnNewBlock triggers the notification of the transactions in the new blocks not seen in the mempool,
+// the check of the confirmations of the notified transactions and the derivation of new xpub addresses
+func (n *webhookNotifier) OnNewBlock(hash string, height uint32) {
+	select {
+	case n.blocks <- struct{}{}:
+	default:
+	}
+}
+
+// blockLoop processes the new blocks and periodically queues again the deliveries dropped because of the full queue
+func (n *webhookNotifier) blockLoop() {
+	defer n.wg.Done()
+	tick := time.NewTicker(webhookRequeueInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-n.done:
+			return
+		case <-n.blocks:
+			n.scanBlocks()
+			for _, wh := range n.Webhooks() {
+				n.processBlock(wh)
+			}
+		case <-tick.C:
+			n.requeueDropped()
+		}
+	}
+}
+
+// scanBlocks notifies the transactions of the registered addresses in the blocks connected since the last scan,
+// which were not notified from the mempool (for example the transactions mined without being seen in the mempool)
+func (n *webhookNotifier) scanBlocks() {
+	best, hash, err := n.db.GetBestBlock()
+	if err != nil {
+		glog.Error("webhooks: GetBestBlock: ", err)
+		return
+	}
+	from := n.lastHeight + 1
+	if best < from {
+		// the tip was replaced by a reorg
+		if hash == n.lastHash {
+			return
+		}
+		from = best
+	}
+	if best-from >= webhookMaxScannedBlocks {
+		from = best - webhookMaxScannedBlocks + 1
+	}
+	n.lastHeight, n.lastHash = best, hash
+	n.mux.Lock()
+	ads := make([]string, 0, len(n.addrDescs))
+	for ad := range n.addrDescs {
+		ads = append(ads, ad)
+	}
+	n.mux.Unlock()
+	// the registered addresses are looked up in the index, the transactions are mapped to the webhooks and registered addresses
+	txs := make(map[string]map[string]string)
+	for _, ad := range ads {
+		hooks := n.hooksOf(ad)
+		err := n.db.GetAddrDescTransactions(bchain.AddressDescriptor(ad), from, best, func(txid string, height uint32, indexes []int32) error {
+			m, found := txs[txid]
+			if !found {
+				m = make(map[string]string)
+				txs[txid] = m
+			}
+			for id, address := range hooks {
+				if _, found := m[id]; !found {
+					m[id] = address
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			glog.Error("webhooks: GetAddrDescTransactions: ", err)
+			return
+		}
+	}
+	for txid, hooks := range txs {
+		txid := txid
+		n.notifyNewTx(txid, hooks, func() (*api.Tx, error) {
+			return n.api.GetTransaction(txid, false, false)
+		})
+	}
+}
+
+func (n *webhookNotifier) processBlock(wh *db.Webhook) {
+	n.txMux.Lock()
+	defer n.txMux.Unlock()
+	ts, err := n.db.GetWebhookTxs(wh.ID)
+	if err != nil {
+		glog.Error("webhooks: GetWebhookTxs ", wh.ID, ": ", err)
+		return
+	}
+	// without confirmation thresholds the transaction is kept only to prevent duplicate notifications until it is mined
+	final := 1
+	if len(wh.Confirmations) > 0 {
+		final = wh.Confirmations[len(wh.Confirmations)-1]
+	}
+	// the confirmed transaction of the xpub can use the addresses from the gap, the xpub must be derived again
+	rescan := make(map[string]struct{})
+	for _, t := range ts {
+		confirmed, err := n.checkConfirmations(wh, t, final)
+		if err != nil {
+			glog.Error("webhooks: webhook ", wh.ID, " tx ", t.Txid, ": ", err)
+		}
+		if confirmed && containsString(wh.Xpubs, t.Address) {
+			rescan[t.Address] = struct{}{}
+		}
+	}
+	if len(rescan) > 0 {
+		ads, err := n.addrDescsOf(wh, rescan)
+		if err != nil {
+			glog.Error("webhooks: webhook ", wh.ID, ": ", err)
+		} else {
+			n.index(wh.ID, ads)
+		}
+	}
+	if err = n.db.TrimWebhookDeliveries(wh.ID, webhookDeliveryLogSize); err != nil {
+		glog.Error("webhooks: TrimWebhookDeliveries ", wh.ID, ": ", err)
+	}
+}
+
+// checkConfirmations notifies the reached confirmation thresholds of the transaction, returns true if the transaction was confirmed
+// since the last check
+func (n *webhookNotifier) checkConfirmations(wh *db.Webhook, t *db.WebhookTx, final int) (bool, error) {
+	tx, err := n.api.GetTransaction(t.Txid, false, false)
+	if err != nil {
+		// the transaction was removed from the mempool
+		if _, ok := err.(*api.APIError); ok && time.Since(t.Seen) > webhookTxExpiration {
+			return false, n.db.DeleteWebhookTx(wh.ID, t.Txid)
+		}
+		return false, nil
+	}
+	confirmations := int(tx.Confirmations)
+	if confirmations <= t.Confirmations {
+		return false, nil
+	}
+	confirmed := t.Confirmations == 0
+	for _, c := range wh.Confirmations {
+		if c > t.Confirmations && c <= confirmations {
+			if err = n.enqueue(wh.ID, webhookEventConfirmations, t.Txid, t.Address, c, tx); err != nil {
+				return confirmed, err
+			}
+		}
+	}
+	if confirmations >= final {
+		return confirmed, n.db.DeleteWebhookTx(wh.ID, t.Txid)
+	}
+	t.Confirmations = confirmations
+	return confirmed, n.db.StoreWebhookTx(wh.ID, t)
+}
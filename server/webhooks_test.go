@@ -0,0 +1,242 @@
+// +build unittest
+
+package server
+
+import (
+	"blockbook/bchain"
+	"blockbook/db"
+	"blockbook/tests/dbtestdata"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_signWebhookPayload(t *testing.T) {
+	// HMAC-SHA256 test vector of RFC 4231, test case 2
+	got := signWebhookPayload("Jefe", []byte("what do ya want for nothing?"))
+	want := "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	if got != want {
+		t.Errorf("signWebhookPayload() = %v, want %v", got, want)
+	}
+}
+
+func Test_webhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{4, 80 * time.Second},
+		{9, 2560 * time.Second},
+		{10, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("webhookRetryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func Test_webhookNotifier_schedule(t *testing.T) {
+	n := &webhookNotifier{
+		queue:     make(chan *webhookJob, 1),
+		done:      make(chan struct{}),
+		scheduled: make(map[uint64]struct{}),
+	}
+	job := func(id uint64) *webhookJob {
+		return &webhookJob{webhookID: "wh", delivery: &db.WebhookDelivery{ID: id}}
+	}
+	n.schedule(job(1), 0)
+	// the delivery read again from the db is already scheduled and must not be queued twice
+	n.schedule(job(1), 0)
+	if len(n.queue) != 1 || n.dropped {
+		t.Fatalf("queue length %d, dropped %v, want 1, false", len(n.queue), n.dropped)
+	}
+	// the queue is full, the delivery is dropped and can be scheduled again
+	n.schedule(job(2), 0)
+	if _, found := n.scheduled[2]; found || !n.dropped {
+		t.Errorf("dropped delivery scheduled %v, dropped %v, want false, true", found, n.dropped)
+	}
+	<-n.queue
+	n.unschedule(1)
+	n.schedule(job(2), 0)
+	if j := <-n.queue; j.delivery.ID != 2 {
+		t.Errorf("queued delivery %v, want 2", j.delivery.ID)
+	}
+}
+
+type webhookTestRequest struct {
+	header  http.Header
+	payload webhookPayload
+	body    []byte
+}
+
+func waitForWebhookRequest(t *testing.T, requests chan *webhookTestRequest) *webhookTestRequest {
+	select {
+	case r := <-requests:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout while waiting for webhook request")
+	}
+	return nil
+}
+
+func webhooksTestsBitcoinType(t *testing.T, s *PublicServer) {
+	// the receiver fails the first request, the delivery must be retried
+	requests := make(chan *webhookTestRequest, 10)
+	var received int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		req := &webhookTestRequest{header: r.Header, body: body}
+		if err = json.Unmarshal(body, &req.payload); err != nil {
+			t.Error(err)
+		}
+		if atomic.AddInt32(&received, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		requests <- req
+	}))
+	defer ts.Close()
+
+	n, err := newWebhookNotifier(s.db, s.api, s.chainParser, s.metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	n.retryDelay = func(attempts int) time.Duration { return 10 * time.Millisecond }
+	wh, err := n.Register(&WebhookRequest{
+		URL:           ts.URL,
+		Addresses:     []string{dbtestdata.Addr6},
+		Xpubs:         []string{dbtestdata.Xpub},
+		Confirmations: []int{1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Unregister(wh.ID)
+	addr4, _ := s.chainParser.GetAddrDescFromAddress(dbtestdata.Addr4)
+	n.mux.Lock()
+	cached := containsString(n.xpubs[dbtestdata.Xpub], string(addr4))
+	n.mux.Unlock()
+	if !cached {
+		t.Error("address derived from xpub is not cached")
+	}
+
+	newTx := func(txid string) *bchain.Tx {
+		tx, err := s.chain.GetTransaction(txid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the transaction is notified from the mempool
+		mtx := *tx
+		mtx.Confirmations = 0
+		return &mtx
+	}
+	checkRequest := func(r *webhookTestRequest, event, txid, address string, confirmations int) {
+		if got := r.header.Get("X-Blockbook-Event"); got != event {
+			t.Errorf("X-Blockbook-Event = %v, want %v", got, event)
+		}
+		if got, want := r.header.Get("X-Blockbook-Delivery"), strconv.FormatUint(r.payload.ID, 10); got != want {
+			t.Errorf("X-Blockbook-Delivery = %v, want %v", got, want)
+		}
+		if got, want := r.header.Get("X-Blockbook-Signature"), "sha256="+signWebhookPayload(wh.Secret, r.body); got != want {
+			t.Errorf("X-Blockbook-Signature = %v, want %v", got, want)
+		}
+		p := &r.payload
+		if p.Webhook != wh.ID || p.Event != event || p.Txid != txid || p.Address != address || p.Confirmations != confirmations || p.Tx == nil || p.Tx.Txid != txid {
+			t.Errorf("payload = %+v", p)
+		}
+	}
+
+	// the transaction of the address, delivered in the second attempt
+	addr6, _ := s.chainParser.GetAddrDescFromAddress(dbtestdata.Addr6)
+	n.OnNewTxAddr(newTx(dbtestdata.TxidB2T1), addr6)
+	r1 := waitForWebhookRequest(t, requests)
+	checkRequest(r1, webhookEventTx, dbtestdata.TxidB2T1, dbtestdata.Addr6, 0)
+	r2 := waitForWebhookRequest(t, requests)
+	checkRequest(r2, webhookEventTx, dbtestdata.TxidB2T1, dbtestdata.Addr6, 0)
+	if r1.payload.ID != r2.payload.ID {
+		t.Errorf("retried delivery id %v, want %v", r2.payload.ID, r1.payload.ID)
+	}
+	// each transaction is notified only once
+	n.OnNewTxAddr(newTx(dbtestdata.TxidB2T1), addr6)
+
+	// the transaction of the address derived from the xpub
+	n.OnNewTxAddr(newTx(dbtestdata.TxidB2T2), addr4)
+	checkRequest(waitForWebhookRequest(t, requests), webhookEventTx, dbtestdata.TxidB2T2, dbtestdata.Xpub, 0)
+
+	// the webhook of the address of the transaction, which was not seen in the mempool
+	wh2, err := n.Register(&WebhookRequest{
+		URL:       ts.URL,
+		Addresses: []string{dbtestdata.AddrA},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Unregister(wh2.ID)
+
+	// the transactions are mined in the block, both reach the confirmation threshold
+	// the transaction of the second webhook is found in the block
+	n.lastHeight = 225493
+	n.OnNewBlock("", 225494)
+	txids := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		r := waitForWebhookRequest(t, requests)
+		if r.payload.Webhook == wh2.ID {
+			p := &r.payload
+			if p.Event != webhookEventTx || p.Txid != dbtestdata.TxidB2T4 || p.Address != dbtestdata.AddrA || p.Tx == nil || p.Tx.Txid != dbtestdata.TxidB2T4 {
+				t.Errorf("payload = %+v", p)
+			}
+			continue
+		}
+		address := dbtestdata.Addr6
+		if r.payload.Txid == dbtestdata.TxidB2T2 {
+			address = dbtestdata.Xpub
+		}
+		checkRequest(r, webhookEventConfirmations, r.payload.Txid, address, 1)
+		txids[r.payload.Txid] = true
+	}
+	if !txids[dbtestdata.TxidB2T1] || !txids[dbtestdata.TxidB2T2] {
+		t.Errorf("confirmations notified for %v", txids)
+	}
+	select {
+	case r := <-requests:
+		t.Errorf("unexpected request %+v", r.payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// the delivery log is stored after the response, wait until all deliveries are stored
+	var ds []*db.WebhookDelivery
+	for i := 0; i < 50; i++ {
+		if ds, err = n.Deliveries(wh.ID, 0); err != nil {
+			t.Fatal(err)
+		}
+		delivered := 0
+		for _, r := range ds {
+			if r.Delivered {
+				delivered++
+			}
+		}
+		if delivered == 4 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if len(ds) != 4 {
+		t.Fatalf("Deliveries() returned %d deliveries, want 4", len(ds))
+	}
+	// the newest first, the first delivery was retried
+	if r := ds[3]; !r.Delivered || r.Attempts != 2 || r.Status != http.StatusOK || r.Error != "" {
+		t.Errorf("retried delivery = %+v", r)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"blockbook/bchain"
 	"blockbook/common"
 	"blockbook/db"
+	"context"
 	"encoding/json"
+	"fmt"
 	"math/big"
 	"net/http"
 	"runtime/debug"
@@ -34,6 +36,8 @@ type websocketReq struct {
 	ID     string          `json:"id"`
 	Method string          `json:"method"`
 	Params json.RawMessage `json:"params"`
+	// ctx is the context of the traced request
+	ctx context.Context
 }
 
 type websocketRes struct {
@@ -47,31 +51,36 @@ type websocketChannel struct {
 	out           chan *websocketRes
 	ip            string
 	requestHeader http.Header
+	client        *accessClient
 	alive         bool
 	aliveLock     sync.Mutex
 }
 
 // WebsocketServer is a handle to websocket server
 type WebsocketServer struct {
-	socket                    *websocket.Conn
-	upgrader                  *websocket.Upgrader
-	db                        *db.RocksDB
-	txCache                   *db.TxCache
-	chain                     bchain.BlockChain
-	chainParser               bchain.BlockChainParser
-	mempool                   bchain.Mempool
-	metrics                   *common.Metrics
-	is                        *common.InternalState
-	api                       *api.Worker
-	block0hash                string
-	newBlockSubscriptions     map[*websocketChannel]string
-	newBlockSubscriptionsLock sync.Mutex
-	addressSubscriptions      map[string]map[*websocketChannel]string
-	addressSubscriptionsLock  sync.Mutex
+	socket                        *websocket.Conn
+	upgrader                      *websocket.Upgrader
+	db                            *db.RocksDB
+	txCache                       *db.TxCache
+	chain                         bchain.BlockChain
+	chainParser                   bchain.BlockChainParser
+	mempool                       bchain.Mempool
+	metrics                       *common.Metrics
+	is                            *common.InternalState
+	api                           *api.Worker
+	block0hash                    string
+	newBlockSubscriptions         map[*websocketChannel]string
+	newBlockSubscriptionsLock     sync.Mutex
+	addressSubscriptions          map[string]map[*websocketChannel]string
+	addressSubscriptionsLock      sync.Mutex
+	confirmationSubscriptions     map[*websocketChannel]*confirmationSubscription
+	confirmationSubscriptionsLock sync.Mutex
+	addressesLimit                int
+	limiter                       *accessLimiter
 }
 
 // NewWebsocketServer creates new websocket interface to blockbook and returns its handle
-func NewWebsocketServer(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState) (*WebsocketServer, error) {
+func NewWebsocketServer(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState, addressesLimit int, limiter *accessLimiter) (*WebsocketServer, error) {
 	api, err := api.NewWorker(db, chain, mempool, txCache, is)
 	if err != nil {
 		return nil, err
@@ -84,34 +93,38 @@ func NewWebsocketServer(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.
 		upgrader: &websocket.Upgrader{
 			ReadBufferSize:  1024 * 32,
 			WriteBufferSize: 1024 * 32,
-			CheckOrigin:     checkOrigin,
+			CheckOrigin:     limiter.checkOrigin,
 		},
-		db:                    db,
-		txCache:               txCache,
-		chain:                 chain,
-		chainParser:           chain.GetChainParser(),
-		mempool:               mempool,
-		metrics:               metrics,
-		is:                    is,
-		api:                   api,
-		block0hash:            b0,
-		newBlockSubscriptions: make(map[*websocketChannel]string),
-		addressSubscriptions:  make(map[string]map[*websocketChannel]string),
+		db:                        db,
+		txCache:                   txCache,
+		chain:                     chain,
+		chainParser:               chain.GetChainParser(),
+		mempool:                   mempool,
+		metrics:                   metrics,
+		is:                        is,
+		api:                       api,
+		block0hash:                b0,
+		newBlockSubscriptions:     make(map[*websocketChannel]string),
+		addressSubscriptions:      make(map[string]map[*websocketChannel]string),
+		confirmationSubscriptions: make(map[*websocketChannel]*confirmationSubscription),
+		addressesLimit:            addressesLimit,
+		limiter:                   limiter,
 	}
 	return s, nil
 }
 
-// allow all origins
-func checkOrigin(r *http.Request) bool {
-	return true
-}
-
 // ServeHTTP sets up handler of websocket channel
 func (s *WebsocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, upgradeFailed+ErrorMethodNotAllowed.Error(), 503)
 		return
 	}
+	client := s.limiter.requestClient(r)
+	if client == nil {
+		s.limiter.reject("websocket", accessErrorUnauthorized)
+		http.Error(w, upgradeFailed+"Missing or invalid API key", http.StatusUnauthorized)
+		return
+	}
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		http.Error(w, upgradeFailed+err.Error(), 503)
@@ -123,6 +136,7 @@ func (s *WebsocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		out:           make(chan *websocketRes, outChannelSize),
 		ip:            r.RemoteAddr,
 		requestHeader: r.Header,
+		client:        client,
 		alive:         true,
 	}
 	go s.inputLoop(c)
@@ -222,7 +236,15 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *webs
 	"getAccountInfo": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
 		r, err := unmarshalGetAccountInfoRequest(req.Params)
 		if err == nil {
-			rv, err = s.getAccountInfo(r)
+			rv, err = s.getAccountInfo(s.worker(req), r)
+		}
+		return
+	},
+	"getAddresses": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		var r addressesInfoReq
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.getAddresses(s.worker(req), &r)
 		}
 		return
 	},
@@ -245,7 +267,7 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *webs
 		}{}
 		err = json.Unmarshal(req.Params, &r)
 		if err == nil {
-			rv, err = s.getAccountUtxo(r.Descriptor)
+			rv, err = s.getAccountUtxo(s.worker(req), r.Descriptor)
 		}
 		return
 	},
@@ -255,7 +277,7 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *webs
 		}{}
 		err = json.Unmarshal(req.Params, &r)
 		if err == nil {
-			rv, err = s.getTransaction(r.Txid)
+			rv, err = s.getTransaction(s.worker(req), r.Txid)
 		}
 		return
 	},
@@ -269,16 +291,42 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *webs
 		}
 		return
 	},
+	"getTransactionProof": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		r := struct {
+			Txid string `json:"txid"`
+		}{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.worker(req).GetTransactionProof(r.Txid)
+		}
+		return
+	},
+	"getBlockFilters": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
+		r := struct {
+			From int `json:"from"`
+			To   int `json:"to"`
+		}{From: -1, To: -1}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.worker(req).GetBlockFilters(r.From, r.To)
+		}
+		return
+	},
 	"estimateFee": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
 		return s.estimateFee(c, req.Params)
 	},
 	"sendTransaction": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
 		r := struct {
-			Hex string `json:"hex"`
+			Hex          string `json:"hex"`
+			ValidateOnly bool   `json:"validateOnly"`
 		}{}
 		err = json.Unmarshal(req.Params, &r)
 		if err == nil {
-			rv, err = s.sendTransaction(r.Hex)
+			if r.ValidateOnly {
+				rv, err = s.worker(req).ValidateTransaction(r.Hex)
+			} else {
+				rv, err = s.sendTransaction(r.Hex)
+			}
 		}
 		return
 	},
@@ -291,7 +339,11 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *webs
 	"subscribeAddresses": func(s *WebsocketServer, c *websocketChannel, req *websocketReq) (rv interface{}, err error) {
 		ad, err := s.unmarshalAddresses(req.Params)
 		if err == nil {
-			rv, err = s.subscribeAddresses(c, ad, req)
+			var confirmations []int
+			confirmations, err = unmarshalConfirmations(req.Params)
+			if err == nil {
+				rv, err = s.subscribeAddresses(c, ad, confirmations, req)
+			}
 		}
 		return
 	},
@@ -319,6 +371,14 @@ func sendResponse(c *websocketChannel, req *websocketReq, data interface{}) {
 func (s *WebsocketServer) onRequest(c *websocketChannel, req *websocketReq) {
 	var err error
 	var data interface{}
+	var span *common.Span
+	if common.TracingEnabled() {
+		name := "websocket " + req.Method
+		if _, ok := requestHandlers[req.Method]; !ok {
+			name = "websocket unknown"
+		}
+		req.ctx, span = common.StartSpan(context.Background(), name)
+	}
 	defer func() {
 		if r := recover(); r != nil {
 			glog.Error("Client ", c.id, ", onRequest ", req.Method, " recovered from panic: ", r)
@@ -326,6 +386,10 @@ func (s *WebsocketServer) onRequest(c *websocketChannel, req *websocketReq) {
 			e := resultError{}
 			e.Error.Message = "Internal error"
 			data = e
+			err = errors.Errorf("panic %v", r)
+		}
+		if span != nil {
+			common.EndSpan(span, err)
 		}
 		// nil data means no response
 		if data != nil {
@@ -335,10 +399,13 @@ func (s *WebsocketServer) onRequest(c *websocketChannel, req *websocketReq) {
 	t := time.Now()
 	defer s.metrics.WebsocketReqDuration.With(common.Labels{"method": req.Method}).Observe(float64(time.Since(t)) / 1e3) // in microseconds
 	f, ok := requestHandlers[req.Method]
-	if ok {
-		data, err = f(s, c, req)
-	} else {
+	if !ok {
 		err = errors.New("unknown method")
+	} else if !s.limiter.take(c.client, s.limiter.websocketCost(req)) {
+		s.limiter.reject("websocket", accessErrorRateLimited)
+		err = errRateLimited
+	} else {
+		data, err = f(s, c, req)
 	}
 	if err == nil {
 		glog.V(1).Info("Client ", c.id, " onRequest ", req.Method, " success")
@@ -348,6 +415,9 @@ func (s *WebsocketServer) onRequest(c *websocketChannel, req *websocketReq) {
 		s.metrics.WebsocketRequests.With(common.Labels{"method": req.Method, "status": "failure"}).Inc()
 		e := resultError{}
 		e.Error.Message = err.Error()
+		if apiErr, ok := err.(*api.APIError); ok {
+			e.Error.Code = apiErr.Code
+		}
 		data = e
 	}
 }
@@ -362,6 +432,7 @@ type accountInfoReq struct {
 	ToHeight       int    `json:"to"`
 	ContractFilter string `json:"contractFilter"`
 	Gap            int    `json:"gap"`
+	Cursor         string `json:"cursor"`
 }
 
 func unmarshalGetAccountInfoRequest(params []byte) (*accountInfoReq, error) {
@@ -373,7 +444,7 @@ func unmarshalGetAccountInfoRequest(params []byte) (*accountInfoReq, error) {
 	return &r, nil
 }
 
-func (s *WebsocketServer) getAccountInfo(req *accountInfoReq) (res *api.Address, err error) {
+func (s *WebsocketServer) getAccountInfo(w *api.Worker, req *accountInfoReq) (res *api.Address, err error) {
 	var opt api.AccountDetails
 	switch req.Details {
 	case "tokens":
@@ -402,27 +473,64 @@ func (s *WebsocketServer) getAccountInfo(req *accountInfoReq) (res *api.Address,
 		Contract:       req.ContractFilter,
 		Vout:           api.AddressFilterVoutOff,
 		TokensToReturn: tokensToReturn,
+		Cursor:         req.Cursor,
 	}
 	if req.PageSize == 0 {
 		req.PageSize = txsOnPage
 	}
-	a, err := s.api.GetXpubAddress(req.Descriptor, req.Page, req.PageSize, opt, &filter, req.Gap)
+	a, err := w.GetXpubAddress(req.Descriptor, req.Page, req.PageSize, opt, &filter, req.Gap)
 	if err != nil {
-		return s.api.GetAddress(req.Descriptor, req.Page, req.PageSize, opt, &filter)
+		return w.GetAddress(req.Descriptor, req.Page, req.PageSize, opt, &filter)
 	}
 	return a, nil
 }
 
-func (s *WebsocketServer) getAccountUtxo(descriptor string) (interface{}, error) {
-	utxo, err := s.api.GetXpubUtxo(descriptor, false, 0)
+type addressesInfoReq struct {
+	Addresses  []string `json:"addresses"`
+	Details    string   `json:"details"`
+	Utxo       bool     `json:"utxo"`
+	PageSize   int      `json:"pageSize"`
+	FromHeight int      `json:"from"`
+	ToHeight   int      `json:"to"`
+}
+
+func (s *WebsocketServer) getAddresses(w *api.Worker, req *addressesInfoReq) (interface{}, error) {
+	if len(req.Addresses) == 0 {
+		return nil, api.NewAPIError("Missing addresses", true)
+	}
+	if len(req.Addresses) > s.addressesLimit {
+		return nil, api.NewAPIError(fmt.Sprintf("Too many addresses, the limit is %d", s.addressesLimit), true)
+	}
+	opt := api.AccountDetailsBasic
+	if req.Details == "txids" {
+		opt = api.AccountDetailsTxidHistory
+	}
+	filter := api.AddressFilter{
+		FromHeight: uint32(req.FromHeight),
+		ToHeight:   uint32(req.ToHeight),
+		Vout:       api.AddressFilterVoutOff,
+	}
+	if req.PageSize == 0 {
+		req.PageSize = txsOnPage
+	}
+	return w.GetAddresses(req.Addresses, req.PageSize, opt, &filter, req.Utxo)
+}
+
+func (s *WebsocketServer) getAccountUtxo(w *api.Worker, descriptor string) (interface{}, error) {
+	utxo, err := w.GetXpubUtxo(descriptor, false, 0)
 	if err != nil {
-		return s.api.GetAddressUtxo(descriptor, false)
+		return w.GetAddressUtxo(descriptor, false)
 	}
 	return utxo, nil
 }
 
-func (s *WebsocketServer) getTransaction(txid string) (interface{}, error) {
-	return s.api.GetTransaction(txid, false, false)
+func (s *WebsocketServer) getTransaction(w *api.Worker, txid string) (interface{}, error) {
+	return w.GetTransaction(txid, false, false)
+}
+
+// worker returns the api worker tracing its calls as spans of the request
+func (s *WebsocketServer) worker(req *websocketReq) *api.Worker {
+	return s.api.WithContext(req.ctx)
 }
 
 func (s *WebsocketServer) getTransactionSpecific(txid string) (interface{}, error) {
@@ -539,7 +647,7 @@ func (s *WebsocketServer) estimateFee(c *websocketChannel, params []byte) (inter
 func (s *WebsocketServer) sendTransaction(tx string) (res resultSendTransaction, err error) {
 	txid, err := s.chain.SendRawTransaction(tx)
 	if err != nil {
-		return res, err
+		return res, api.SendTxError(err)
 	}
 	res.Result = txid
 	return
@@ -582,9 +690,18 @@ func (s *WebsocketServer) unmarshalAddresses(params []byte) ([]bchain.AddressDes
 	return rv, nil
 }
 
-func (s *WebsocketServer) subscribeAddresses(c *websocketChannel, addrDesc []bchain.AddressDescriptor, req *websocketReq) (res interface{}, err error) {
+// subscribeAddresses subscribes the channel to the new transactions of the addresses and, if the confirmation thresholds are specified,
+// to the notifications when the transactions reach the thresholds
+func (s *WebsocketServer) subscribeAddresses(c *websocketChannel, addrDesc []bchain.AddressDescriptor, confirmations []int, req *websocketReq) (res interface{}, err error) {
+	if max := s.limiter.config.MaxSubscribedAddresses; max > 0 && len(addrDesc) > max {
+		s.limiter.reject("websocket", accessErrorSubscriptionLimit)
+		return nil, api.NewAPIErrorWithCode(fmt.Sprintf("Too many subscribed addresses, the limit is %d", max), accessErrorSubscriptionLimit)
+	}
 	// unsubscribe all previous subscriptions
 	s.unsubscribeAddresses(c)
+	if len(confirmations) > 0 {
+		s.subscribeConfirmations(c, addrDesc, confirmations, req.ID)
+	}
 	s.addressSubscriptionsLock.Lock()
 	defer s.addressSubscriptionsLock.Unlock()
 	for i := range addrDesc {
@@ -601,6 +718,7 @@ func (s *WebsocketServer) subscribeAddresses(c *websocketChannel, addrDesc []bch
 
 // unsubscribeAddresses unsubscribes all address subscriptions by this channel
 func (s *WebsocketServer) unsubscribeAddresses(c *websocketChannel) (res interface{}, err error) {
+	s.unsubscribeConfirmations(c)
 	s.addressSubscriptionsLock.Lock()
 	defer s.addressSubscriptionsLock.Unlock()
 	for _, sa := range s.addressSubscriptions {
@@ -633,6 +751,154 @@ func (s *WebsocketServer) OnNewBlock(hash string, height uint32) {
 		}
 	}
 	glog.Info("broadcasting new block ", height, " ", hash, " to ", len(s.newBlockSubscriptions), " channels")
+	s.notifyConfirmations(height)
+}
+
+// OnNewChainLock is a callback that broadcasts info about a new ChainLocked block to clients subscribed to new blocks
+func (s *WebsocketServer) OnNewChainLock(hash string, height uint32) {
+	s.newBlockSubscriptionsLock.Lock()
+	defer s.newBlockSubscriptionsLock.Unlock()
+	data := struct {
+		Height    uint32 `json:"height"`
+		Hash      string `json:"hash"`
+		ChainLock bool   `json:"chainLock"`
+	}{
+		Height:    height,
+		Hash:      hash,
+		ChainLock: true,
+	}
+	for c, id := range s.newBlockSubscriptions {
+		if c.IsAlive() {
+			c.out <- &websocketRes{
+				ID:   id,
+				Data: &data,
+			}
+		}
+	}
+	glog.Info("broadcasting chainlock ", height, " ", hash, " to ", len(s.newBlockSubscriptions), " channels")
+}
+
+type reorgBlock struct {
+	Height uint32   `json:"height"`
+	Hash   string   `json:"hash"`
+	Txs    []string `json:"txs"`
+}
+
+type reorgNotification struct {
+	Reorg     bool         `json:"reorg"`
+	Blocks    []reorgBlock `json:"blocks"`
+	Addresses []string     `json:"addresses,omitempty"`
+}
+
+// OnReorg is a callback that broadcasts the blocks disconnected by a reorg to clients subscribed to new blocks
+// and to clients subscribed to the addresses affected by the reorg
+func (s *WebsocketServer) OnReorg(blocks []*db.OrphanedBlock) {
+	rbs := make([]reorgBlock, len(blocks))
+	for i, b := range blocks {
+		rbs[i] = reorgBlock{Height: b.Height, Hash: b.Hash, Txs: b.Txids}
+	}
+	s.newBlockSubscriptionsLock.Lock()
+	for c, id := range s.newBlockSubscriptions {
+		if c.IsAlive() {
+			c.out <- &websocketRes{
+				ID:   id,
+				Data: &reorgNotification{Reorg: true, Blocks: rbs},
+			}
+		}
+	}
+	glog.Info("broadcasting reorg of ", len(blocks), " blocks to ", len(s.newBlockSubscriptions), " channels")
+	s.newBlockSubscriptionsLock.Unlock()
+	// collect the affected addresses subscribed by each channel
+	type channelAddresses struct {
+		id        string
+		addresses []string
+	}
+	affected := make(map[*websocketChannel]*channelAddresses)
+	seen := make(map[string]struct{})
+	s.addressSubscriptionsLock.Lock()
+	defer s.addressSubscriptionsLock.Unlock()
+	for _, b := range blocks {
+		for _, ad := range b.AddrDescs {
+			if _, found := seen[string(ad)]; found {
+				continue
+			}
+			seen[string(ad)] = struct{}{}
+			as, ok := s.addressSubscriptions[string(ad)]
+			if !ok || len(as) == 0 {
+				continue
+			}
+			addr, _, err := s.chainParser.GetAddressesFromAddrDesc(ad)
+			if err != nil || len(addr) != 1 {
+				continue
+			}
+			for c, id := range as {
+				ca, ok := affected[c]
+				if !ok {
+					ca = &channelAddresses{id: id}
+					affected[c] = ca
+				}
+				ca.addresses = append(ca.addresses, addr[0])
+			}
+		}
+	}
+	for c, ca := range affected {
+		if c.IsAlive() {
+			c.out <- &websocketRes{
+				ID:   ca.id,
+				Data: &reorgNotification{Reorg: true, Blocks: rbs, Addresses: ca.addresses},
+			}
+		}
+	}
+	if len(affected) > 0 {
+		glog.Info("broadcasting reorg to ", len(affected), " channels subscribed to affected addresses")
+	}
+}
+
+// OnNewInstantLock is a callback that broadcasts InstantSend locked tx to clients subscribed to its addresses
+func (s *WebsocketServer) OnNewInstantLock(txid string) {
+	tx, err := s.api.GetTransaction(txid, false, false)
+	if err != nil {
+		glog.Error("GetTransaction error ", err, " for ", txid)
+		return
+	}
+	addresses := make(map[string]struct{})
+	for i := range tx.Vin {
+		for _, a := range tx.Vin[i].Addresses {
+			addresses[a] = struct{}{}
+		}
+	}
+	for i := range tx.Vout {
+		for _, a := range tx.Vout[i].Addresses {
+			addresses[a] = struct{}{}
+		}
+	}
+	s.addressSubscriptionsLock.Lock()
+	defer s.addressSubscriptionsLock.Unlock()
+	for a := range addresses {
+		addrDesc, err := s.chainParser.GetAddrDescFromAddress(a)
+		if err != nil {
+			continue
+		}
+		as, ok := s.addressSubscriptions[string(addrDesc)]
+		if ok && len(as) > 0 {
+			data := struct {
+				Address string  `json:"address"`
+				Tx      *api.Tx `json:"tx"`
+			}{
+				Address: a,
+				Tx:      tx,
+			}
+			for c, id := range as {
+				if c.IsAlive() {
+					c.out <- &websocketRes{
+						ID:   id,
+						Data: &data,
+					}
+				}
+			}
+			glog.Info("broadcasting instantlock ", txid, " for addr ", a, " to ", len(as), " channels")
+		}
+	}
 }
 
 // OnNewTxAddr is a callback that broadcasts info about a tx affecting subscribed address
@@ -671,6 +937,7 @@ func (s *WebsocketServer) OnNewTxAddr(tx *bchain.Tx, addrDesc bchain.AddressDesc
 							ID:   id,
 							Data: &data,
 						}
+						s.trackTx(c, tx.Txid, addr[0])
 					}
 				}
 				glog.Info("broadcasting new tx ", tx.Txid, " for addr ", addr[0], " to ", len(as), " channels")